@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/config"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/repositories"
+	"qwen-go-proxy/internal/infrastructure/services"
+	"qwen-go-proxy/internal/usecases/auth"
+)
+
+const authUsage = `Usage: qwen-go-proxy auth [flags]
+
+Authenticates a Qwen account with OAuth2 device flow and saves its credentials,
+so that a running or later started proxy can use them.
+
+Flags:
+`
+
+// authStatusInterval is how often the CLI checks whether the device flow has finished
+const authStatusInterval = time.Second
+
+// runAuthCommand runs the auth subcommand and returns the process exit code
+func runAuthCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("auth", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, authUsage)
+		flags.PrintDefaults()
+	}
+	account := flags.String("account", "", "Authenticate this pooled account, stored in QWEN_DIR/accounts/<name> with the file store (default: the default account)")
+	force := flags.Bool("force", false, "Start device flow even when valid credentials are stored")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	store, err := repositories.NewCredentialStore(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open credential store: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+	name := *account
+	if name == "" {
+		name = repositories.DefaultAccountName
+	}
+	// Where the credentials are stored, for the messages below
+	location := fmt.Sprintf("the %s credential store", cfg.CredentialStore)
+	if fileStore, ok := store.(*repositories.FileCredentialStore); ok {
+		location = fileStore.AccountDir(name)
+	}
+
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+	authUseCase := auth.NewAuthUseCase(cfg, services.NewOAuthService(cfg.QWENOAuthBaseURL),
+		store.Repository(name), services.NewWebhookNotifier(cfg.AuthWebhookURL), logger)
+
+	if !*force {
+		if _, err := authUseCase.CheckAuthentication(); err == nil {
+			fmt.Fprintf(stdout, "Already authenticated, credentials are stored in %s. Use -force to authenticate again.\n", location)
+			return 0
+		}
+	}
+
+	status, err := authUseCase.StartDeviceFlow(context.Background())
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to start device authentication: %v\n", err)
+		return 1
+	}
+	verificationURL := status.VerificationURIComplete
+	if verificationURL == "" {
+		verificationURL = status.VerificationURI
+	}
+	fmt.Fprintf(stdout, "Open %s in a browser and approve the request.\n", verificationURL)
+	fmt.Fprintf(stdout, "User code: %s (expires at %s)\n", status.UserCode, status.ExpiresAt.Local().Format(time.Kitchen))
+	fmt.Fprintln(stdout, "Waiting for approval...")
+
+	for status.State == entities.DeviceFlowPending {
+		time.Sleep(authStatusInterval)
+		status = authUseCase.DeviceFlowStatus()
+	}
+	if status.State != entities.DeviceFlowSucceeded {
+		fmt.Fprintf(stderr, "Authentication failed: %s\n", status.Error)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Authenticated, credentials saved in %s\n", location)
+	return 0
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/config"
+	"qwen-go-proxy/internal/infrastructure/repositories"
+)
+
+const journalUsage = `Usage: qwen-go-proxy journal <command> [flags]
+
+Commands:
+  search    List matching journal entries
+  export    Write matching journal entries as JSON lines or a JSON array
+
+Run "qwen-go-proxy journal <command> -h" for the flags of a command.
+`
+
+// journalDir returns the directory of the request journal
+func journalDir(cfg *entities.Config) string {
+	if cfg.JournalDir != "" {
+		return cfg.JournalDir
+	}
+	return filepath.Join(cfg.QWENDir, "journal")
+}
+
+// runJournalCommand runs the journal subcommand and returns the process exit code
+func runJournalCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, journalUsage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	command := args[0]
+	if command != "search" && command != "export" {
+		fmt.Fprintf(stderr, "Unknown journal command %q\n\n%s", command, journalUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("journal "+command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", "", "Journal directory (default: JOURNAL_DIR, or QWEN_DIR/journal)")
+	requestID := flags.String("request-id", "", "Only entries with this request ID")
+	keyID := flags.String("key", "", "Only entries made with this API key ID")
+	model := flags.String("model", "", "Only entries for this requested or upstream model")
+	since := flags.String("since", "", "Only entries at or after this RFC 3339 time, or this long ago (e.g. 24h)")
+	until := flags.String("until", "", "Only entries before this RFC 3339 time, or this long ago")
+	contains := flags.String("contains", "", "Only entries containing this text (case-insensitive)")
+	limit := flags.Int("limit", 0, "Keep only the most recent entries (0 for all)")
+	output := flags.String("o", "", "Export to this file instead of standard output")
+	format := flags.String("format", "jsonl", "Export format: jsonl or json")
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	now := time.Now()
+	query := entities.JournalQuery{
+		RequestID: *requestID,
+		APIKeyID:  *keyID,
+		Model:     *model,
+		Contains:  *contains,
+		Limit:     *limit,
+	}
+	var err error
+	if query.Since, err = parseJournalTime(*since, now); err != nil {
+		fmt.Fprintf(stderr, "Invalid -since: %v\n", err)
+		return 2
+	}
+	if query.Until, err = parseJournalTime(*until, now); err != nil {
+		fmt.Fprintf(stderr, "Invalid -until: %v\n", err)
+		return 2
+	}
+	if *format != "jsonl" && *format != "json" {
+		fmt.Fprintf(stderr, "Invalid -format %q, must be jsonl or json\n", *format)
+		return 2
+	}
+
+	if *dir == "" {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to load configuration: %v\n", err)
+			return 1
+		}
+		*dir = journalDir(cfg)
+	}
+
+	journal := repositories.NewFileJournal(*dir, 0, 0)
+	defer journal.Close()
+	entries, err := journal.Search(query)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to search journal: %v\n", err)
+		return 1
+	}
+
+	if command == "search" {
+		printJournalEntries(stdout, entries)
+		return 0
+	}
+
+	out := stdout
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to create %s: %v\n", *output, err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := exportJournalEntries(out, entries, *format); err != nil {
+		fmt.Fprintf(stderr, "Failed to export journal: %v\n", err)
+		return 1
+	}
+	if *output != "" {
+		fmt.Fprintf(stderr, "Exported %d entries to %s\n", len(entries), *output)
+	}
+	return 0
+}
+
+// parseJournalTime parses an RFC 3339 time or a duration before now; an empty value is the zero time
+func parseJournalTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// printJournalEntries writes a one-line summary of every entry as a table
+func printJournalEntries(w io.Writer, entries []*entities.JournalEntry) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TIME\tREQUEST ID\tKEY\tMODEL\tSTREAM\tTOKENS\tLATENCY\tSTATUS")
+	for _, entry := range entries {
+		model := entry.Model
+		if entry.ModelUsed != "" && entry.ModelUsed != entry.Model {
+			model += " -> " + entry.ModelUsed
+		}
+		tokens := "-"
+		if entry.Usage != nil {
+			tokens = fmt.Sprintf("%d", entry.Usage.TotalTokens)
+		}
+		status := "ok"
+		if entry.Error != "" {
+			status = "error: " + strings.SplitN(entry.Error, "\n", 2)[0]
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%t\t%s\t%dms\t%s\n",
+			entry.Timestamp.Local().Format(time.RFC3339), entry.RequestID, valueOrDash(entry.APIKeyID),
+			valueOrDash(model), entry.Stream, tokens, entry.LatencyMs, status)
+	}
+	table.Flush()
+}
+
+// exportJournalEntries writes entries as JSON lines or as a single JSON array
+func exportJournalEntries(w io.Writer, entries []*entities.JournalEntry, format string) error {
+	if format == "json" {
+		if entries == nil {
+			entries = []*entities.JournalEntry{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueOrDash returns the value, or a dash when it is empty
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -6,19 +6,28 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
 	"qwen-go-proxy/internal/infrastructure/config"
 	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/metrics"
 	"qwen-go-proxy/internal/infrastructure/middleware"
 	"qwen-go-proxy/internal/infrastructure/repositories"
 	"qwen-go-proxy/internal/infrastructure/services"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/infrastructure/tracing"
 	"qwen-go-proxy/internal/interfaces/controllers"
+	"qwen-go-proxy/internal/interfaces/gateways"
+	"qwen-go-proxy/internal/usecases/apikey"
 	"qwen-go-proxy/internal/usecases/auth"
+	"qwen-go-proxy/internal/usecases/models"
 	"qwen-go-proxy/internal/usecases/proxy"
 	"qwen-go-proxy/internal/usecases/streaming"
 )
@@ -30,6 +39,14 @@ var (
 )
 
 func main() {
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "journal" {
+		os.Exit(runJournalCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "auth" {
+		os.Exit(runAuthCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	startTime := time.Now()
 
 	// Load configuration
@@ -44,20 +61,166 @@ func main() {
 		log.Fatalf("Failed to initialize logger")
 	}
 
+	// Initialize tracing before any spans are created
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		ServiceName:  cfg.TracingServiceName,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Initialize repository implementation (domain interface) in the configured credential store
+	credentialStore, err := repositories.NewCredentialStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open credential store: %v", err)
+	}
+	credentialRepo := credentialStore.Repository(repositories.DefaultAccountName)
+	responseRepo := repositories.NewFileResponseRepository(cfg.QWENDir, cfg.ResponseStoreMaxEntries, cfg.ResponseStoreTTL)
+	apiKeyRepo := repositories.NewFileAPIKeyRepository(cfg.QWENDir)
+
+	// Discover additional Qwen accounts for the credential pool
+	accounts, err := credentialStore.Accounts()
+	if err != nil {
+		log.Fatalf("Failed to discover Qwen accounts: %v", err)
+	}
+	// Pool the accounts whenever a named account exists, so that one configured without the default account is used
+	pooled := len(accounts) > 1 || (len(accounts) == 1 && accounts[0] != repositories.DefaultAccountName)
+
 	// Initialize infrastructure services (domain interfaces)
 	oauthService := services.NewOAuthService(cfg.QWENOAuthBaseURL)
-	aiService := services.NewAIService(cfg)
+	deviceFlowNotifier := services.NewWebhookNotifier(cfg.AuthWebhookURL)
+	retryPolicy := gateways.RetryPolicy{
+		MaxAttempts:    cfg.RetryMaxAttempts,
+		InitialBackoff: cfg.RetryInitialBackoff,
+		MaxBackoff:     cfg.RetryMaxBackoff,
+	}
+	// Rate limited Qwen requests fail over to another pooled account rather than being retried on the same one
+	qwenRetryPolicy := retryPolicy
+	qwenRetryPolicy.FailOverRateLimits = pooled
+	// Every attempt is instrumented on its own, so that upstream latency does not include retries
+	newGateway := func(service interfaces.AIService, policy gateways.RetryPolicy) gateways.QwenAPIGateway {
+		return gateways.NewRetryingQwenAPIGateway(gateways.NewInstrumentedQwenAPIGateway(service), policy, logger)
+	}
+	var aiService gateways.QwenAPIGateway = newGateway(services.NewAIService(cfg), qwenRetryPolicy)
 
-	// Initialize repository implementation (domain interface)
-	credentialRepo := repositories.NewFileCredentialRepository(cfg.QWENDir)
+	// Route models to further upstream providers when configured; Qwen serves every other model
+	var providerConfigs []entities.ProviderConfig
+	if cfg.ProvidersFile != "" {
+		providerConfigs, err = config.LoadProviders(cfg.ProvidersFile)
+		if err != nil {
+			log.Fatalf("Failed to load providers: %v", err)
+		}
+		providers := make([]gateways.Provider, 0, len(providerConfigs)+1)
+		hasQwen := false
+		for _, providerConfig := range providerConfigs {
+			switch {
+			case providerConfig.AuthType != entities.ProviderAuthQwenOAuth:
+				providers = append(providers, gateways.Provider{Config: providerConfig, Gateway: newGateway(services.NewOpenAICompatibleService(providerConfig), retryPolicy)})
+			case providerConfig.BaseURL != "":
+				qwenConfig := *cfg
+				qwenConfig.APIBaseURL = providerConfig.BaseURL
+				providers = append(providers, gateways.Provider{Config: providerConfig, Gateway: newGateway(services.NewAIService(&qwenConfig), qwenRetryPolicy)})
+				hasQwen = true
+			default:
+				providers = append(providers, gateways.Provider{Config: providerConfig, Gateway: aiService})
+				hasQwen = true
+			}
+		}
+		if !hasQwen {
+			providers = append(providers, gateways.Provider{Config: entities.ProviderConfig{Name: "qwen", AuthType: entities.ProviderAuthQwenOAuth}, Gateway: aiService})
+		}
+		aiService = gateways.NewProviderRegistry(providers)
+		logger.Info("Upstream providers loaded", "file", cfg.ProvidersFile, "providers", len(providers))
+	}
 
 	// Initialize use cases (application interfaces)
-	authUseCase := auth.NewAuthUseCase(cfg, oauthService, credentialRepo, logger)
-	streamingUseCase := streaming.NewStreamingUseCase(logger)
-	proxyUseCase := proxy.NewProxyUseCase(authUseCase, aiService, streamingUseCase, logger, cfg.DefaultModel)
+	defaultAuthUseCase := auth.NewAuthUseCase(cfg, oauthService, credentialRepo, deviceFlowNotifier, logger)
+	var authUseCase auth.AuthUseCaseInterface = defaultAuthUseCase
+	refreshAccounts := []auth.RefreshAccount{{Name: repositories.DefaultAccountName, AuthUseCase: defaultAuthUseCase}}
+	if pooled {
+		poolAccounts := make([]auth.PoolAccount, len(accounts))
+		refreshAccounts = make([]auth.RefreshAccount, len(accounts))
+		for i, account := range accounts {
+			accountRepo := credentialStore.Repository(account)
+			accountAuthUseCase := auth.NewAuthUseCase(cfg, oauthService, accountRepo, deviceFlowNotifier, logger)
+			poolAccounts[i] = auth.PoolAccount{Name: account, AuthUseCase: accountAuthUseCase}
+			refreshAccounts[i] = auth.RefreshAccount{Name: account, AuthUseCase: accountAuthUseCase}
+		}
+		authUseCase = auth.NewCredentialPool(poolAccounts, cfg.CredentialPoolStrategy, cfg.AccountCooldown, logger)
+		logger.Info("Credential pool enabled", "accounts", len(poolAccounts), "strategy", cfg.CredentialPoolStrategy)
+	}
+
+	// Refresh tokens in the background, so that requests do not wait for a refresh
+	var tokenRefresher *auth.TokenRefresher
+	if cfg.TokenRefreshBackground {
+		tokenRefresher = auth.NewTokenRefresher(refreshAccounts, cfg, logger)
+	}
+	// Log a summary of every reassembled stream, in debug mode
+	streamingUseCase := streaming.NewStreamingUseCase(logger, streaming.LoggingHook(logger))
+
+	// Load the model catalog, falling back to the built-in models when no file is configured
+	var catalogEntries []entities.ModelCatalogEntry
+	if cfg.ModelCatalogFile != "" {
+		catalogEntries, err = config.LoadModelCatalog(cfg.ModelCatalogFile)
+		if err != nil {
+			log.Fatalf("Failed to load model catalog: %v", err)
+		}
+		logger.Info("Model catalog loaded", "file", cfg.ModelCatalogFile, "models", len(catalogEntries))
+	}
+	if len(providerConfigs) > 0 {
+		catalogEntries = models.WithProviderModels(catalogEntries, providerConfigs)
+	}
+	modelCatalog := models.NewModelCatalog(authUseCase, aiService, catalogEntries, cfg.DefaultModel, cfg.ModelDiscoveryTTL, logger)
+	// Count tokens with the Qwen vocabulary when one is configured, and estimate them otherwise
+	tokenCounter, err := tokenizer.New(cfg.TokenizerFile)
+	if err != nil {
+		log.Fatalf("Failed to load tokenizer: %v", err)
+	}
+	if cfg.TokenizerFile != "" {
+		logger.Info("Tokenizer loaded", "file", cfg.TokenizerFile)
+	}
+	var proxyUseCase proxy.ProxyUseCaseInterface = proxy.NewProxyUseCase(authUseCase, aiService, streamingUseCase, modelCatalog, logger, cfg.DefaultModel, tokenCounter,
+		proxy.ContextPolicy{Overflow: cfg.ContextOverflow, Strategy: cfg.ContextStrategy})
+
+	// Serve deterministic chat completions from the response cache when enabled
+	var completionCache interfaces.CompletionCache
+	switch cfg.CacheBackend {
+	case "memory":
+		completionCache = repositories.NewMemoryCompletionCache(cfg.CacheMaxEntries, cfg.CacheTTL)
+	case "disk":
+		completionCache = repositories.NewFileCompletionCache(cfg.QWENDir, cfg.CacheMaxEntries, cfg.CacheTTL)
+	}
+	if completionCache != nil {
+		proxyUseCase = proxy.NewCachingProxyUseCase(proxyUseCase, completionCache, logger)
+		logger.Info("Response cache enabled", "backend", cfg.CacheBackend, "ttl", cfg.CacheTTL, "max_entries", cfg.CacheMaxEntries)
+	}
+
+	// Route requested model names to Qwen models; routing runs before the cache so that routed requests share entries
+	if cfg.ModelRoutesFile != "" {
+		routes, err := config.LoadModelRoutes(cfg.ModelRoutesFile)
+		if err != nil {
+			log.Fatalf("Failed to load model routes: %v", err)
+		}
+		proxyUseCase = proxy.NewRoutingProxyUseCase(proxyUseCase, routes, logger)
+		logger.Info("Model routing enabled", "file", cfg.ModelRoutesFile, "routes", len(routes))
+	}
+
+	// Record every chat completion in the audit journal, as sent by the client and before routing
+	var journal interfaces.Journal
+	if cfg.JournalEnabled {
+		journal = repositories.NewFileJournal(journalDir(cfg), int64(cfg.JournalMaxSizeMB)<<20, cfg.JournalMaxFiles)
+		proxyUseCase = proxy.NewJournalingProxyUseCase(proxyUseCase, journal, cfg.JournalRedaction, logger)
+		logger.Info("Request journal enabled", "dir", journalDir(cfg), "redaction", cfg.JournalRedaction)
+	}
+	apiKeyUseCase := apikey.NewAPIKeyUseCase(apiKeyRepo, logger)
 
 	// Initialize controllers
-	apiController := controllers.NewAPIController(proxyUseCase, logger)
+	apiController := controllers.NewAPIController(proxyUseCase, tokenCounter, logger)
+	responsesController := controllers.NewResponsesController(proxyUseCase, responseRepo, tokenCounter, logger)
+	tokenizeController := controllers.NewTokenizeController(proxyUseCase, tokenCounter, logger)
+	adminController := controllers.NewAdminController(apiKeyUseCase, logger)
 
 	// Setup graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -68,8 +231,24 @@ func main() {
 
 	// Add middleware
 	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
 	router.Use(middleware.RequestLogging(logger, cfg.DebugMode))
-	router.Use(middleware.RateLimit(cfg.RateLimitRequestsPerSecond, cfg.RateLimitBurst))
+	if cfg.MetricsEnabled {
+		router.Use(middleware.Metrics(modelCatalog.IsCatalogModel))
+	}
+	rateLimiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+		RequestsPerSecond:         float64(cfg.RateLimitRequestsPerSecond),
+		Burst:                     cfg.RateLimitBurst,
+		PromptTokensPerMinute:     cfg.RateLimitPromptTPM,
+		CompletionTokensPerMinute: cfg.RateLimitCompletionTPM,
+		KeyBy:                     cfg.RateLimitKey,
+		DefaultModel:              cfg.DefaultModel,
+	})
+	// Limits keyed by API key or model need the authenticated request, so they are applied to the model endpoints below
+	limitByIP := cfg.RateLimitKey == "" || cfg.RateLimitKey == middleware.RateLimitByIP
+	if limitByIP {
+		router.Use(rateLimiter.Middleware())
+	}
 	router.Use(middleware.CORS())
 
 	// Add security headers middleware
@@ -112,8 +291,9 @@ func main() {
 			},
 		}
 
-		// Check authentication status
-		credentials, err := authUseCase.EnsureAuthenticated()
+		// Check authentication status, without starting device flow
+		health["auth_state"] = authUseCase.AuthState()
+		credentials, err := authUseCase.EnsureAuthenticated(r.Context())
 		if err != nil {
 			logger.Warn("Health check authentication failed", "request_id", requestID, "error", err)
 			health["auth_status"] = "unauthenticated"
@@ -123,32 +303,94 @@ func main() {
 			health["auth_info"] = credentials.Sanitize()
 		}
 
+		// Report the background token refreshes
+		if tokenRefresher != nil {
+			health["token_refresh"] = tokenRefresher.Statuses()
+		}
+
+		// Report per-account status when requests are spread over a credential pool
+		if pool, ok := authUseCase.(auth.CredentialPoolInterface); ok {
+			health["accounts"] = pool.AccountStatuses()
+		}
+
 		logger.Info("Health check requested", "request_id", requestID, "status", "healthy")
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(health)
 	})
 
-	// Authentication endpoint
-	router.Get("/auth", apiController.AuthenticateHandler)
+	// Prometheus metrics endpoint
+	if cfg.MetricsEnabled {
+		router.Handle("/metrics", metrics.Handler())
+	}
 
-	// OpenAI compatible endpoints
-	router.Get("/v1/models", apiController.OpenAIModelsHandler)
-	router.Post("/v1/completions", apiController.OpenAICompletionsHandler)
-	router.Post("/v1/chat/completions", apiController.ChatCompletionsHandler)
+	// Authentication endpoints. Starting device authentication decides which Qwen account the proxy uses,
+	// so it requires the admin key, or a proxy API key when no admin key is configured; the status is public
+	router.Group(func(r chi.Router) {
+		if cfg.AdminAPIKey != "" {
+			r.Use(middleware.AdminAuth(cfg.AdminAPIKey, logger))
+		} else if cfg.RequireAPIKey {
+			r.Use(middleware.APIKeyAuth(apiKeyUseCase, cfg.DefaultModel, logger))
+		}
+		r.Get("/auth", apiController.AuthenticateHandler)
+	})
+	router.Get("/auth/status", apiController.AuthStatusHandler)
+
+	// Model API endpoints, protected by proxy API keys when enabled
+	router.Group(func(r chi.Router) {
+		if cfg.RequireAPIKey {
+			r.Use(middleware.APIKeyAuth(apiKeyUseCase, cfg.DefaultModel, logger))
+		}
+		if !limitByIP {
+			r.Use(rateLimiter.Middleware())
+		}
+		if completionCache != nil {
+			r.Use(middleware.ResponseCache())
+		}
+		r.Use(middleware.ModelReporting())
+
+		// OpenAI compatible endpoints
+		r.Get("/v1/models", apiController.OpenAIModelsHandler)
+		r.Get("/v1/models/*", apiController.OpenAIModelHandler)
+		r.Post("/v1/completions", apiController.OpenAICompletionsHandler)
+		r.Post("/v1/chat/completions", apiController.ChatCompletionsHandler)
+		r.Post("/v1/embeddings", apiController.EmbeddingsHandler)
+		r.Post("/v1/responses", responsesController.ResponsesHandler)
+		r.Get("/v1/responses/{response_id}", responsesController.GetResponseHandler)
+		r.Post("/v1/tokenize", tokenizeController.TokenizeHandler)
+
+		// Anthropic compatible endpoints
+		r.Post("/v1/messages", apiController.MessagesHandler)
+	})
+
+	// Admin endpoints, only available when an admin key is configured
+	if cfg.AdminAPIKey != "" {
+		router.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.AdminAuth(cfg.AdminAPIKey, logger))
+			r.Post("/keys", adminController.CreateAPIKeyHandler)
+			r.Get("/keys", adminController.ListAPIKeysHandler)
+			r.Delete("/keys/{key_id}", adminController.RevokeAPIKeyHandler)
+		})
+	}
 
 	// Startup authentication check
 	logger.Info("Starting Qwen Proxy")
 
-	// Check if credentials exist (this is handled by the use case now)
-	_, err = authUseCase.EnsureAuthenticated()
+	// Check if credentials exist, without waiting for device authentication
+	_, err = authUseCase.CheckAuthentication()
 	if err != nil {
-		logger.Info("No Qwen OAuth credentials found")
-		logger.Info("The server will automatically handle OAuth2 device authentication when first accessed")
-		logger.Info("Follow the prompts to authenticate with your Qwen account")
+		logger.Warn("No usable Qwen OAuth credentials, inference requests are rejected until authenticated", "auth_state", authUseCase.AuthState())
+		logger.Info("Open /auth or run `qwen-go-proxy auth` to start OAuth2 device authentication, and /auth/status to follow its progress")
 	} else {
 		logger.Info("Qwen proxy is ready and authenticated")
 	}
+	if tokenRefresher != nil {
+		tokenRefresher.Start()
+	}
+
+	if cfg.RequireAPIKey && cfg.AdminAPIKey == "" {
+		logger.Warn("REQUIRE_API_KEY is enabled but ADMIN_API_KEY is empty, so no new API keys can be created")
+	}
 
 	logger.Info("Starting server", "host", cfg.ServerHost, "port", cfg.ServerPort)
 
@@ -190,7 +432,23 @@ func main() {
 
 	// Cleanup resources
 	logger.Info("Cleaning up resources...")
+	if tokenRefresher != nil {
+		if err := tokenRefresher.Stop(shutdownCtx); err != nil {
+			logger.Error("Failed to stop token refresher", "error", err)
+		}
+	}
 	// Add any cleanup logic here for gateways, repositories, etc.
+	if err := credentialStore.Close(); err != nil {
+		logger.Error("Failed to close credential store", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("Failed to flush traces", "error", err)
+	}
+	if journal != nil {
+		if err := journal.Close(); err != nil {
+			logger.Error("Failed to close journal", "error", err)
+		}
+	}
 
 	logger.Info("Shutdown complete")
 }
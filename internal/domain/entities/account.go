@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+// AccountCredentials pairs the name of a pooled Qwen account with its current credentials
+type AccountCredentials struct {
+	Name        string
+	Credentials *Credentials
+}
+
+// AccountStatus reports the health of a pooled Qwen account
+type AccountStatus struct {
+	Name           string     `json:"name"`
+	Throttled      bool       `json:"throttled"`
+	ThrottledUntil *time.Time `json:"throttled_until,omitempty"`
+	ThrottleCount  int        `json:"throttle_count"`
+}
+
+// TokenRefreshStatus reports the token refreshes of a Qwen account
+type TokenRefreshStatus struct {
+	Account             string     `json:"account"`
+	LastRefreshAt       *time.Time `json:"last_refresh_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	NextRefreshAt       *time.Time `json:"next_refresh_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
@@ -0,0 +1,95 @@
+package entities
+
+import "encoding/json"
+
+// AnthropicMessagesRequest represents a request to the Anthropic Messages API (/v1/messages).
+// This entity is translated onto ChatCompletionRequest before being forwarded upstream.
+type AnthropicMessagesRequest struct {
+	Model         string             `json:"model"`
+	Messages      []AnthropicMessage `json:"messages"`
+	System        any                `json:"system,omitempty"` // string or []AnthropicContentBlock
+	MaxTokens     int                `json:"max_tokens"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	TopK          *int               `json:"top_k,omitempty"`
+	Tools         []AnthropicTool    `json:"tools,omitempty"`
+	ToolChoice    *AnthropicChoice   `json:"tool_choice,omitempty"`
+	Metadata      *AnthropicMetadata `json:"metadata,omitempty"`
+}
+
+// AnthropicMessage represents a single conversation turn in an Anthropic request
+type AnthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"` // string or []AnthropicContentBlock
+}
+
+// AnthropicContentBlock represents a content block in Anthropic messages and responses.
+// Only the fields relevant to the block type are populated.
+type AnthropicContentBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *AnthropicImageSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   any    `json:"content,omitempty"` // string or []AnthropicContentBlock
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// thinking
+	Thinking string `json:"thinking,omitempty"`
+}
+
+// AnthropicImageSource represents the source of an image content block
+type AnthropicImageSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// AnthropicTool represents a tool definition in an Anthropic request
+type AnthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema,omitempty"`
+}
+
+// AnthropicChoice represents the tool_choice option in an Anthropic request
+type AnthropicChoice struct {
+	Type string `json:"type"` // "auto", "any", "tool" or "none"
+	Name string `json:"name,omitempty"`
+}
+
+// AnthropicMetadata represents request metadata in an Anthropic request
+type AnthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// AnthropicMessagesResponse represents a non-streaming Anthropic Messages API response
+type AnthropicMessagesResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []AnthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        AnthropicUsage          `json:"usage"`
+}
+
+// AnthropicUsage represents token usage in Anthropic format
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
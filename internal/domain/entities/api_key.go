@@ -0,0 +1,66 @@
+package entities
+
+import "time"
+
+// APIKey is an inbound proxy API key and its access policy.
+// Security note: only the SHA-256 hash of the key is stored; the plaintext key is shown once at creation.
+type APIKey struct {
+	ID            string     `json:"id"`
+	Label         string     `json:"label"`
+	Hash          string     `json:"hash"`   // Sensitive: never log or return this value
+	Prefix        string     `json:"prefix"` // First characters of the key, for identification only
+	AllowedModels []string   `json:"allowed_models,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RateLimit     int        `json:"rate_limit,omitempty"` // Requests per minute, 0 means unlimited
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyCreateRequest holds the policy for a new API key
+type APIKeyCreateRequest struct {
+	Label         string     `json:"label"`
+	AllowedModels []string   `json:"allowed_models,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RateLimit     int        `json:"rate_limit,omitempty"`
+}
+
+// IsExpired reports whether the key has passed its expiry time
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// AllowsModel reports whether the key may be used with the given model.
+// A key without allowed models may use any model.
+func (k *APIKey) AllowsModel(model string) bool {
+	if len(k.AllowedModels) == 0 {
+		return true
+	}
+	for _, allowed := range k.AllowedModels {
+		if allowed == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize returns a safe representation of the key for logging and admin listings (without the hash).
+func (k *APIKey) Sanitize() map[string]any {
+	if k == nil {
+		return nil
+	}
+	return map[string]any{
+		"id":             k.ID,
+		"label":          k.Label,
+		"prefix":         k.Prefix,
+		"allowed_models": k.AllowedModels,
+		"expires_at":     k.ExpiresAt,
+		"rate_limit":     k.RateLimit,
+		"created_at":     k.CreatedAt,
+		"revoked_at":     k.RevokedAt,
+	}
+}
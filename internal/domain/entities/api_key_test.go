@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyPolicy(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	key := &APIKey{
+		AllowedModels: []string{"qwen3-coder-plus"},
+		ExpiresAt:     &expiresAt,
+	}
+
+	assert.False(t, key.IsExpired(now))
+	assert.True(t, key.IsExpired(expiresAt))
+	assert.False(t, key.IsRevoked())
+	assert.True(t, key.AllowsModel("qwen3-coder-plus"))
+	assert.False(t, key.AllowsModel("qwen3-coder-flash"))
+
+	// Keys without restrictions never expire and allow every model
+	unrestricted := &APIKey{}
+	assert.False(t, unrestricted.IsExpired(now.Add(100*365*24*time.Hour)))
+	assert.True(t, unrestricted.AllowsModel("anything"))
+}
+
+func TestAPIKeySanitize(t *testing.T) {
+	key := &APIKey{ID: "key_1", Label: "ci", Hash: "secret-hash", Prefix: "sk-qwen-abcd"}
+
+	sanitized := key.Sanitize()
+
+	assert.Equal(t, "key_1", sanitized["id"])
+	assert.Equal(t, "sk-qwen-abcd", sanitized["prefix"])
+	assert.NotContains(t, sanitized, "hash")
+
+	var nilKey *APIKey
+	assert.Nil(t, nilKey.Sanitize())
+}
@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+// Authentication states of a Qwen account
+const (
+	AuthStateUnauthenticated = "unauthenticated"
+	AuthStateAuthenticating  = "authenticating"
+	AuthStateValid           = "valid"
+	AuthStateRefreshFailed   = "refresh_failed"
+)
+
+// Device flow states
+const (
+	DeviceFlowPending   = "pending"
+	DeviceFlowSucceeded = "succeeded"
+	DeviceFlowFailed    = "failed"
+)
+
+var (
+	// ErrAuthorizationPending is returned while the user has not yet approved a device authorization
+	ErrAuthorizationPending = errors.New("authorization pending")
+	// ErrSlowDown is returned when the authorization server asks to poll less often
+	ErrSlowDown = errors.New("slow down")
+)
+
+// DeviceAuthorization is a started OAuth2 device authorization that is polled for a token
+type DeviceAuthorization struct {
+	DeviceCode              string // Sensitive: never log this value
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresAt               time.Time
+	Interval                time.Duration // Minimum time between polls
+	CodeVerifier            string        // Sensitive: PKCE verifier sent with every poll
+}
+
+// DeviceFlowStatus reports the progress of a device authorization flow
+type DeviceFlowStatus struct {
+	State                   string     `json:"state"`
+	UserCode                string     `json:"user_code"`
+	VerificationURI         string     `json:"verification_uri"`
+	VerificationURIComplete string     `json:"verification_uri_complete,omitempty"`
+	ExpiresAt               time.Time  `json:"expires_at"`
+	StartedAt               time.Time  `json:"started_at"`
+	CompletedAt             *time.Time `json:"completed_at,omitempty"`
+	Polls                   int        `json:"polls"`
+	LastPollAt              *time.Time `json:"last_poll_at,omitempty"`
+	Error                   string     `json:"error,omitempty"`
+}
@@ -3,6 +3,7 @@
 package entities
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -15,8 +16,16 @@ type Credentials struct {
 	RefreshToken string `json:"refresh_token"` // Sensitive: never log this value
 	ExpiryDate   int64  `json:"expiry_date"`   // Unix timestamp in milliseconds
 	ResourceURL  string `json:"resource_url,omitempty"`
+	// Version is the stored version of the credentials in repositories that support compare-and-swap.
+	// Saving credentials with a version replaces them only while that version is still stored;
+	// zero replaces them unconditionally.
+	Version int64 `json:"-"`
 }
 
+// ErrCredentialConflict is returned when credentials are saved over a version that another writer
+// has already replaced
+var ErrCredentialConflict = errors.New("credentials were changed by another writer")
+
 // Sanitize returns a safe representation of credentials for logging (without sensitive data).
 // This is a domain method that doesn't depend on external infrastructure.
 func (c *Credentials) Sanitize() map[string]any {
@@ -45,22 +54,87 @@ type Config struct {
 	QWENOAuthClientID      string `json:"qwen_oauth_client_id" env:"QWEN_OAUTH_CLIENT_ID" env-required:"true"`
 	QWENOAuthScope         string `json:"qwen_oauth_scope" env:"QWEN_OAUTH_SCOPE" env-default:"openid profile email model.completion"`
 	QWENOAuthDeviceAuthURL string `json:"qwen_oauth_device_auth_url" env:"QWEN_OAUTH_DEVICE_AUTH_URL" env-required:"true"`
+	AuthWebhookURL         string `json:"auth_webhook_url" env:"AUTH_WEBHOOK_URL" env-default:""`
 
 	// Storage and file paths
 	QWENDir string `json:"qwen_dir" env:"QWEN_DIR" env-default:".qwen"`
 
+	// Credential storage backend: "file", "sqlite" or "vault"
+	CredentialStore      string `json:"credential_store" env:"CREDENTIAL_STORE" env-default:"file"`
+	CredentialSQLitePath string `json:"credential_sqlite_path" env:"CREDENTIAL_SQLITE_PATH"`
+	VaultAddress         string `json:"vault_address" env:"VAULT_ADDR"`
+	VaultToken           string `json:"-" env:"VAULT_TOKEN"` // Sensitive: never log this value
+	VaultNamespace       string `json:"vault_namespace" env:"VAULT_NAMESPACE"`
+	VaultKVMount         string `json:"vault_kv_mount" env:"VAULT_KV_MOUNT" env-default:"secret"`
+	VaultKVPath          string `json:"vault_kv_path" env:"VAULT_KV_PATH" env-default:"qwen-go-proxy"`
+
+	// Credential encryption at rest
+	CredentialEncryptionKey        string `json:"-" env:"CREDENTIAL_ENCRYPTION_KEY"` // Sensitive: never log this value
+	CredentialEncryptionKeyFile    string `json:"credential_encryption_key_file" env:"CREDENTIAL_ENCRYPTION_KEY_FILE"`
+	CredentialEncryptionPassphrase string `json:"-" env:"CREDENTIAL_ENCRYPTION_PASSPHRASE"` // Sensitive: never log this value
+
 	// Token management
-	TokenRefreshBuffer time.Duration `json:"token_refresh_buffer" env:"TOKEN_REFRESH_BUFFER" env-default:"5m"`
-	ShutdownTimeout    time.Duration `json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" env-default:"30s"`
+	TokenRefreshBuffer     time.Duration `json:"token_refresh_buffer" env:"TOKEN_REFRESH_BUFFER" env-default:"5m"`
+	TokenRefreshBackground bool          `json:"token_refresh_background" env:"TOKEN_REFRESH_BACKGROUND" env-default:"true"`
+	TokenRefreshJitter     time.Duration `json:"token_refresh_jitter" env:"TOKEN_REFRESH_JITTER" env-default:"1m"`
+	TokenRefreshMaxBackoff time.Duration `json:"token_refresh_max_backoff" env:"TOKEN_REFRESH_MAX_BACKOFF" env-default:"5m"`
+	ShutdownTimeout        time.Duration `json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" env-default:"30s"`
+
+	// Multi-account credential pool
+	CredentialPoolStrategy string        `json:"credential_pool_strategy" env:"CREDENTIAL_POOL_STRATEGY" env-default:"round_robin"`
+	AccountCooldown        time.Duration `json:"account_cooldown" env:"ACCOUNT_COOLDOWN" env-default:"60s"`
+
+	// Upstream retries
+	RetryMaxAttempts    int           `json:"retry_max_attempts" env:"RETRY_MAX_ATTEMPTS" env-default:"3"`
+	RetryInitialBackoff time.Duration `json:"retry_initial_backoff" env:"RETRY_INITIAL_BACKOFF" env-default:"500ms"`
+	RetryMaxBackoff     time.Duration `json:"retry_max_backoff" env:"RETRY_MAX_BACKOFF" env-default:"10s"`
 
 	// Logging configuration
 	DebugMode bool   `json:"debug_mode" env:"DEBUG_MODE" env-default:"false"`
 	LogLevel  string `json:"log_level" env:"LOG_LEVEL" env-default:"info"`
 	LogFormat string `json:"log_format" env:"LOG_FORMAT" env-default:"json"`
 
+	// Metrics
+	MetricsEnabled bool `json:"metrics_enabled" env:"METRICS_ENABLED" env-default:"true"`
+
+	// Tracing
+	TracingExporter     string `json:"tracing_exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	TracingOTLPEndpoint string `json:"tracing_otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" env-default:"http://localhost:4318"`
+	TracingServiceName  string `json:"tracing_service_name" env:"TRACING_SERVICE_NAME" env-default:"qwen-go-proxy"`
+
+	// Model catalog
+	ModelCatalogFile  string        `json:"model_catalog_file" env:"MODEL_CATALOG_FILE" env-default:""`
+	ModelDiscoveryTTL time.Duration `json:"model_discovery_ttl" env:"MODEL_DISCOVERY_TTL" env-default:"10m"`
+	ModelRoutesFile   string        `json:"model_routes_file" env:"MODEL_ROUTES_FILE" env-default:""`
+	ProvidersFile     string        `json:"providers_file" env:"PROVIDERS_FILE" env-default:""`
+
+	// Response cache
+	CacheBackend    string        `json:"cache_backend" env:"CACHE_BACKEND" env-default:"none"`
+	CacheTTL        time.Duration `json:"cache_ttl" env:"CACHE_TTL" env-default:"1h"`
+	CacheMaxEntries int           `json:"cache_max_entries" env:"CACHE_MAX_ENTRIES" env-default:"1000"`
+
+	// Responses API store
+	ResponseStoreTTL        time.Duration `json:"response_store_ttl" env:"RESPONSE_STORE_TTL" env-default:"720h"`
+	ResponseStoreMaxEntries int           `json:"response_store_max_entries" env:"RESPONSE_STORE_MAX_ENTRIES" env-default:"10000"`
+
+	// Request journal
+	JournalEnabled   bool   `json:"journal_enabled" env:"JOURNAL_ENABLED" env-default:"false"`
+	JournalDir       string `json:"journal_dir" env:"JOURNAL_DIR" env-default:""`
+	JournalMaxSizeMB int    `json:"journal_max_size_mb" env:"JOURNAL_MAX_SIZE_MB" env-default:"100"`
+	JournalMaxFiles  int    `json:"journal_max_files" env:"JOURNAL_MAX_FILES" env-default:"10"`
+	JournalRedaction string `json:"journal_redaction" env:"JOURNAL_REDACTION" env-default:"none"`
+
+	// Context window preflight
+	TokenizerFile   string `json:"tokenizer_file" env:"TOKENIZER_FILE" env-default:""`
+	ContextOverflow string `json:"context_overflow" env:"CONTEXT_OVERFLOW" env-default:"reject"`
+	ContextStrategy string `json:"context_strategy" env:"CONTEXT_STRATEGY" env-default:"keep_tool_pairs"`
+
 	// Rate limiting
-	RateLimitRequestsPerSecond int `json:"rate_limit_rps" env:"RATE_LIMIT_RPS" env-default:"10"`
-	RateLimitBurst             int `json:"rate_limit_burst" env:"RATE_LIMIT_BURST" env-default:"20"`
+	RateLimitRequestsPerSecond int    `json:"rate_limit_rps" env:"RATE_LIMIT_RPS" env-default:"10"`
+	RateLimitBurst             int    `json:"rate_limit_burst" env:"RATE_LIMIT_BURST" env-default:"20"`
+	RateLimitKey               string `json:"rate_limit_key" env:"RATE_LIMIT_KEY" env-default:"ip"`
+	RateLimitPromptTPM         int    `json:"rate_limit_prompt_tpm" env:"RATE_LIMIT_PROMPT_TPM" env-default:"0"`
+	RateLimitCompletionTPM     int    `json:"rate_limit_completion_tpm" env:"RATE_LIMIT_COMPLETION_TPM" env-default:"0"`
 
 	// API configuration
 	APIBaseURL   string `json:"api_base_url" env:"API_BASE_URL" env-required:"true"`
@@ -68,6 +142,8 @@ type Config struct {
 
 	// Security
 	TrustedProxies []string `json:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+	RequireAPIKey  bool     `json:"require_api_key" env:"REQUIRE_API_KEY" env-default:"false"`
+	AdminAPIKey    string   `json:"-" env:"ADMIN_API_KEY"` // Sensitive: never log this value
 }
 
 // GetServerAddress returns the full server address for HTTP server configuration.
@@ -84,7 +160,7 @@ type CompletionRequest struct {
 	Model            string         `json:"model,omitempty" validate:"omitempty,min=1,max=100"`
 	Prompt           interface{}    `json:"prompt" validate:"required"` // string or []string
 	MaxTokens        int            `json:"max_tokens,omitempty" validate:"omitempty,min=1,max=4096"`
-	Temperature      float64        `json:"temperature,omitempty" validate:"omitempty,min=0,max=2"`
+	Temperature      *float64       `json:"temperature,omitempty" validate:"omitempty,min=0,max=2"`
 	TopP             float64        `json:"top_p,omitempty" validate:"omitempty,min=0,max=1"`
 	Stream           bool           `json:"stream,omitempty"`
 	Logprobs         int            `json:"logprobs,omitempty" validate:"omitempty,min=0,max=5"`
@@ -105,7 +181,7 @@ type ChatCompletionRequest struct {
 	Model            string          `json:"model,omitempty" validate:"omitempty,min=1,max=100"`
 	Messages         []ChatMessage   `json:"messages" validate:"required,min=1,dive"`
 	MaxTokens        int             `json:"max_tokens,omitempty" validate:"omitempty,min=1,max=4096"`
-	Temperature      float64         `json:"temperature,omitempty" validate:"omitempty,min=0,max=2"`
+	Temperature      *float64        `json:"temperature,omitempty" validate:"omitempty,min=0,max=2"`
 	TopP             float64         `json:"top_p,omitempty" validate:"omitempty,min=0,max=1"`
 	Stream           bool            `json:"stream,omitempty"`
 	Stop             interface{}     `json:"stop,omitempty"` // string or []string
@@ -127,14 +203,18 @@ type ChatCompletionRequest struct {
 // ChatMessage represents a message in chat completion.
 // This entity contains the message structure for chat conversations.
 type ChatMessage struct {
-	Role      string     `json:"role" validate:"required,oneof=system user assistant tool"`
-	Content   any        `json:"content" validate:"required"` // Can be string or []ContentBlock
-	ToolCalls []ToolCall `json:"tool_calls,omitempty" validate:"omitempty,dive"`
+	Role             string     `json:"role" validate:"required,oneof=system user assistant tool"`
+	Content          any        `json:"content" validate:"required"` // Can be string or []ContentBlock
+	ReasoningContent string     `json:"reasoning_content,omitempty"` // Thinking output of reasoning models
+	Name             string     `json:"name,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty" validate:"omitempty,dive"`
+	ToolCallID       string     `json:"tool_call_id,omitempty"` // Set on role "tool" messages
 }
 
 // ToolCall represents a tool call in a message.
 // This entity represents function calling specifications.
 type ToolCall struct {
+	Index    *int     `json:"index,omitempty"` // Only present in streaming deltas
 	ID       string   `json:"id"`
 	Type     string   `json:"type"`
 	Function Function `json:"function"`
@@ -170,6 +250,7 @@ type Function struct {
 	Name        string      `json:"name"`
 	Description string      `json:"description,omitempty"`
 	Parameters  interface{} `json:"parameters,omitempty"`
+	Arguments   string      `json:"arguments,omitempty"` // JSON-encoded arguments of a tool call
 }
 
 // ToolChoice represents tool choice options
@@ -242,18 +323,162 @@ type TopLogprobEntry struct {
 
 // Usage represents token usage information
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	TotalTokens      int  `json:"total_tokens"`
+	Estimated        bool `json:"estimated,omitempty"` // Counted locally because the upstream did not report usage
+}
+
+// TokenizeRequest is a request to count the tokens of a prompt, or of chat messages
+// including the chat template around them
+type TokenizeRequest struct {
+	Model    string        `json:"model,omitempty"`
+	Prompt   string        `json:"prompt,omitempty"`
+	Messages []ChatMessage `json:"messages,omitempty"`
+	Tools    []Tool        `json:"tools,omitempty"`
+}
+
+// TokenizeResponse reports the token count of a tokenize request.
+// Tokens are only listed for prompts counted with a tokenizer vocabulary.
+type TokenizeResponse struct {
+	Model       string `json:"model,omitempty"`
+	Count       int    `json:"count"`
+	MaxModelLen int    `json:"max_model_len,omitempty"`
+	Tokens      []int  `json:"tokens,omitempty"`
+	Estimated   bool   `json:"estimated,omitempty"`
 }
 
-// ModelInfo represents model information
+// EmbeddingRequest is an OpenAI embeddings request.
+// Input is a string, an array of strings, an array of token IDs or an array of token ID arrays.
+type EmbeddingRequest struct {
+	Model          string `json:"model"`
+	Input          any    `json:"input"`
+	EncodingFormat string `json:"encoding_format,omitempty"`
+	Dimensions     int    `json:"dimensions,omitempty"`
+	User           string `json:"user,omitempty"`
+}
+
+// EmbeddingResponse is an OpenAI embeddings response
+type EmbeddingResponse struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  *Usage      `json:"usage,omitempty"`
+}
+
+// Embedding is the embedding of one input.
+// Embedding holds a float array, or a base64 string of little-endian float32 values.
+type Embedding struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding any    `json:"embedding"`
+}
+
+// Embedding encoding formats
+const (
+	EncodingFormatFloat  = "float"
+	EncodingFormatBase64 = "base64"
+)
+
+// ModelInfo represents model information.
+// The capability fields are only present for models declared in the model catalog.
 type ModelInfo struct {
-	ID         string            `json:"id"`
-	Object     string            `json:"object"`
-	Created    int64             `json:"created"`
-	OwnedBy    string            `json:"owned_by"`
-	Permission []ModelPermission `json:"permission"`
+	ID              string            `json:"id"`
+	Object          string            `json:"object"`
+	Created         int64             `json:"created"`
+	OwnedBy         string            `json:"owned_by"`
+	Permission      []ModelPermission `json:"permission"`
+	Aliases         []string          `json:"aliases,omitempty"`
+	ContextWindow   int               `json:"context_window,omitempty"`
+	MaxOutputTokens int               `json:"max_output_tokens,omitempty"`
+	Modalities      []string          `json:"modalities,omitempty"`
+	SupportsTools   *bool             `json:"supports_tools,omitempty"`
+	Deprecated      bool              `json:"deprecated,omitempty"`
+	ReplacedBy      string            `json:"replaced_by,omitempty"`
+}
+
+// ModelCatalogEntry declares a model, its aliases and its capabilities in the model catalog
+type ModelCatalogEntry struct {
+	ID              string   `json:"id"`
+	OwnedBy         string   `json:"owned_by,omitempty"`
+	Created         int64    `json:"created,omitempty"`
+	Aliases         []string `json:"aliases,omitempty"`
+	ContextWindow   int      `json:"context_window,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+	Modalities      []string `json:"modalities,omitempty"`
+	SupportsTools   *bool    `json:"supports_tools,omitempty"`
+	Deprecated      bool     `json:"deprecated,omitempty"`
+	ReplacedBy      string   `json:"replaced_by,omitempty"`
+}
+
+// Context overflow policies for requests that exceed the context window of their model
+const (
+	ContextOverflowOff    = "off"
+	ContextOverflowReject = "reject"
+	ContextOverflowTrim   = "trim"
+)
+
+// Context strategies that decide which messages are elided when a request is trimmed
+const (
+	ContextStrategyDropOldest    = "drop_oldest"
+	ContextStrategyKeepToolPairs = "keep_tool_pairs"
+	ContextStrategySummarize     = "summarize"
+)
+
+// ModelCatalogFile is the format of the model catalog configuration file
+type ModelCatalogFile struct {
+	Models []ModelCatalogEntry `json:"models"`
+}
+
+// Upstream error classes that make a route fall back to its next model
+const (
+	FallbackOnServerError = "server_error"
+	FallbackOnOverloaded  = "overloaded"
+	FallbackOnRateLimited = "rate_limited"
+	FallbackOnTimeout     = "timeout"
+	FallbackOnConnection  = "connection"
+)
+
+// ModelRoute maps requested model names to a target model and overrides request parameters.
+// Match is an exact model name or a glob pattern as understood by path.Match.
+// When the target fails with one of the FallbackOn error classes, the Fallbacks are tried in order.
+type ModelRoute struct {
+	Match       string   `json:"match"`
+	Target      string   `json:"target"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Fallbacks   []string `json:"fallbacks,omitempty"`
+	FallbackOn  []string `json:"fallback_on,omitempty"`
+}
+
+// ModelRoutesFile is the format of the model routing table configuration file
+type ModelRoutesFile struct {
+	Routes []ModelRoute `json:"routes"`
+}
+
+// Provider authentication types
+const (
+	ProviderAuthQwenOAuth = "qwen_oauth"
+	ProviderAuthBearer    = "bearer"
+	ProviderAuthNone      = "none"
+)
+
+// ProviderConfig declares a named upstream that serves a list of models.
+// Qwen OAuth providers use the proxy's Qwen credentials; other providers are
+// OpenAI-compatible servers authenticated with a static bearer key or not at all.
+type ProviderConfig struct {
+	Name      string   `json:"name"`
+	BaseURL   string   `json:"base_url,omitempty"`
+	AuthType  string   `json:"auth_type"`
+	APIKey    string   `json:"api_key,omitempty"`
+	APIKeyEnv string   `json:"api_key_env,omitempty"`
+	Models    []string `json:"models,omitempty"`
+}
+
+// ProvidersFile is the format of the upstream providers configuration file
+type ProvidersFile struct {
+	Providers []ProviderConfig `json:"providers"`
 }
 
 // ModelPermission represents model permissions
@@ -81,27 +81,29 @@ func TestConfigGetServerAddress_Custom(t *testing.T) {
 
 func TestCompletionRequestValidation(t *testing.T) {
 	// Test valid request
+	temperature := 0.5
 	req := &CompletionRequest{
 		Model:       "test-model",
 		Prompt:      "test prompt",
 		MaxTokens:   100,
-		Temperature: 0.5,
+		Temperature: &temperature,
 	}
 
 	// Since we don't have a validation function exposed, we'll just test that the struct can be created
 	assert.Equal(t, "test-model", req.Model)
 	assert.Equal(t, "test prompt", req.Prompt)
 	assert.Equal(t, 100, req.MaxTokens)
-	assert.Equal(t, 0.5, req.Temperature)
+	assert.Equal(t, 0.5, *req.Temperature)
 }
 
 func TestChatCompletionRequestValidation(t *testing.T) {
 	// Test valid request
+	temperature := 0.5
 	req := &ChatCompletionRequest{
 		Model:       "test-model",
 		Messages:    []ChatMessage{{Role: "user", Content: "test message"}},
 		MaxTokens:   100,
-		Temperature: 0.5,
+		Temperature: &temperature,
 	}
 
 	// Since we don't have a validation function exposed, we'll just test that the struct can be created
@@ -110,7 +112,7 @@ func TestChatCompletionRequestValidation(t *testing.T) {
 	assert.Equal(t, "user", req.Messages[0].Role)
 	assert.Equal(t, "test message", req.Messages[0].Content)
 	assert.Equal(t, 100, req.MaxTokens)
-	assert.Equal(t, 0.5, req.Temperature)
+	assert.Equal(t, 0.5, *req.Temperature)
 }
 
 func TestChatMessageValidation(t *testing.T) {
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// Journal redaction modes for message content
+const (
+	JournalRedactionNone   = "none"
+	JournalRedactionRedact = "redact"
+	JournalRedactionHash   = "hash"
+)
+
+// JournalEntry is the audit record of a single chat completion.
+// Streamed responses are reassembled into a complete response before they are recorded.
+type JournalEntry struct {
+	Timestamp   time.Time               `json:"timestamp"`
+	RequestID   string                  `json:"request_id"`
+	APIKeyID    string                  `json:"api_key_id,omitempty"`
+	APIKeyLabel string                  `json:"api_key_label,omitempty"`
+	Model       string                  `json:"model"`                // Model requested by the client
+	ModelUsed   string                  `json:"model_used,omitempty"` // Upstream model that served the request
+	Stream      bool                    `json:"stream"`
+	Request     *ChatCompletionRequest  `json:"request"`
+	Response    *ChatCompletionResponse `json:"response,omitempty"`
+	Usage       *Usage                  `json:"usage,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	LatencyMs   int64                   `json:"latency_ms"`
+}
+
+// JournalQuery selects journal entries. Empty fields match every entry.
+type JournalQuery struct {
+	RequestID string
+	APIKeyID  string
+	Model     string // Matches the requested or the upstream model
+	Since     time.Time
+	Until     time.Time
+	Contains  string // Case-insensitive text anywhere in the recorded entry
+	Limit     int    // Maximum number of entries, the most recent ones are kept; 0 means unlimited
+}
+
+// Matches reports whether an entry satisfies the structured fields of the query.
+// Contains is matched against the serialized entry by the journal implementation.
+func (q JournalQuery) Matches(entry *JournalEntry) bool {
+	if q.RequestID != "" && entry.RequestID != q.RequestID {
+		return false
+	}
+	if q.APIKeyID != "" && entry.APIKeyID != q.APIKeyID {
+		return false
+	}
+	if q.Model != "" && !strings.EqualFold(entry.Model, q.Model) && !strings.EqualFold(entry.ModelUsed, q.Model) {
+		return false
+	}
+	if !q.Since.IsZero() && entry.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !entry.Timestamp.Before(q.Until) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package entities
+
+import "time"
+
+// ResponsesRequest represents a request to the OpenAI Responses API (/v1/responses).
+// This entity is translated onto ChatCompletionRequest before being forwarded upstream.
+type ResponsesRequest struct {
+	Model              string            `json:"model,omitempty"`
+	Input              any               `json:"input"` // string or []ResponseInputItem
+	Instructions       string            `json:"instructions,omitempty"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty"`
+	Stream             bool              `json:"stream,omitempty"`
+	Store              *bool             `json:"store,omitempty"` // Defaults to true
+	Temperature        *float64          `json:"temperature,omitempty"`
+	TopP               *float64          `json:"top_p,omitempty"`
+	MaxOutputTokens    int               `json:"max_output_tokens,omitempty"`
+	Tools              []ResponseTool    `json:"tools,omitempty"`
+	ToolChoice         any               `json:"tool_choice,omitempty"` // string or {"type": "function", "name": ...}
+	User               string            `json:"user,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// ResponseInputItem represents an item in the Responses API input list.
+// Messages carry Role and Content; function calls and their outputs carry CallID.
+type ResponseInputItem struct {
+	Type    string `json:"type,omitempty"` // "message", "function_call" or "function_call_output"
+	Role    string `json:"role,omitempty"`
+	Content any    `json:"content,omitempty"` // string or []ResponseContentPart
+
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+}
+
+// ResponseContentPart represents a content part of a Responses API message
+type ResponseContentPart struct {
+	Type        string `json:"type"` // "input_text", "input_image", "output_text"
+	Text        string `json:"text,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	Annotations []any  `json:"annotations"`
+}
+
+// ResponseTool represents a function tool in the Responses API (flattened compared to chat completions)
+type ResponseTool struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// ResponseObject represents a Responses API response
+type ResponseObject struct {
+	ID                 string               `json:"id"`
+	Object             string               `json:"object"`
+	CreatedAt          int64                `json:"created_at"`
+	Status             string               `json:"status"`
+	Model              string               `json:"model"`
+	Output             []ResponseOutputItem `json:"output"`
+	OutputText         string               `json:"output_text,omitempty"`
+	Instructions       string               `json:"instructions,omitempty"`
+	PreviousResponseID string               `json:"previous_response_id,omitempty"`
+	Usage              *ResponseUsage       `json:"usage,omitempty"`
+	Metadata           map[string]string    `json:"metadata,omitempty"`
+}
+
+// ResponseOutputItem represents an output item (message or function call) of a response
+type ResponseOutputItem struct {
+	Type    string                `json:"type"`
+	ID      string                `json:"id"`
+	Status  string                `json:"status"`
+	Role    string                `json:"role,omitempty"`
+	Content []ResponseContentPart `json:"content,omitempty"`
+
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// ResponseUsage represents token usage in Responses API format
+type ResponseUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// StoredResponse is the locally persisted state of a response, used to resolve previous_response_id.
+// Messages holds the full conversation up to and including the response output, excluding instructions.
+type StoredResponse struct {
+	ID                 string         `json:"id"`
+	Model              string         `json:"model"`
+	PreviousResponseID string         `json:"previous_response_id,omitempty"`
+	Messages           []ChatMessage  `json:"messages"`
+	Response           ResponseObject `json:"response"`
+	CreatedAt          time.Time      `json:"created_at"`
+}
@@ -4,6 +4,7 @@
 package interfaces
 
 import (
+	"context"
 	"net/http"
 
 	"qwen-go-proxy/internal/domain/entities"
@@ -20,6 +21,64 @@ type CredentialRepository interface {
 	Save(credentials *entities.Credentials) error
 }
 
+// CredentialStore defines the interface for the storage backend of the credentials of all accounts.
+// Shared backends let several proxy replicas use one set of credentials.
+type CredentialStore interface {
+	// Repository returns the credential repository of an account
+	Repository(account string) CredentialRepository
+
+	// Accounts lists the accounts with stored credentials, the default account first
+	Accounts() ([]string, error)
+
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// ResponseRepository defines the interface for storing Responses API state.
+// Stored responses allow clients to chain requests with previous_response_id
+// without resending the conversation history.
+type ResponseRepository interface {
+	// Load retrieves a stored response by its ID
+	Load(id string) (*entities.StoredResponse, error)
+
+	// Save persists a response so it can be referenced by later requests
+	Save(response *entities.StoredResponse) error
+}
+
+// APIKeyRepository defines the interface for storing inbound proxy API keys.
+// Keys are stored by hash only; the plaintext key never reaches the repository.
+type APIKeyRepository interface {
+	// List retrieves every stored API key, including revoked ones
+	List() ([]*entities.APIKey, error)
+
+	// Save creates or updates an API key, matched by its ID
+	Save(key *entities.APIKey) error
+}
+
+// CompletionCache defines the interface for caching chat completion responses.
+// Keys are derived from the request, so identical deterministic requests share an entry.
+// Implementations are responsible for expiring entries and bounding their size.
+type CompletionCache interface {
+	// Get retrieves a cached response; found is false when the key is missing or expired
+	Get(key string) (response *entities.ChatCompletionResponse, found bool, err error)
+
+	// Set stores a response under the given key
+	Set(key string, response *entities.ChatCompletionResponse) error
+}
+
+// Journal defines the interface for the audit journal of chat completions.
+// Entries are only ever appended; implementations are responsible for rotating old entries out.
+type Journal interface {
+	// Append records an entry at the end of the journal
+	Append(entry *entities.JournalEntry) error
+
+	// Search returns the entries matching the query, oldest first
+	Search(query entities.JournalQuery) ([]*entities.JournalEntry, error)
+
+	// Close releases the journal's open files
+	Close() error
+}
+
 // OAuthService defines the interface for OAuth authentication operations.
 // This interface represents the contract for OAuth-related functionality
 // that our domain needs, abstracting the external OAuth provider.
@@ -27,15 +86,34 @@ type OAuthService interface {
 	// RefreshToken refreshes an existing access token using the refresh token
 	RefreshToken(refreshToken, clientID string) (*entities.Credentials, error)
 
-	// AuthenticateWithDeviceFlow performs OAuth2 device authorization flow
-	AuthenticateWithDeviceFlow(clientID, scope string) (*entities.Credentials, error)
+	// StartDeviceFlow requests a device authorization that the user approves in a browser
+	StartDeviceFlow(ctx context.Context, clientID, scope string) (*entities.DeviceAuthorization, error)
+
+	// PollDeviceToken asks once for the token of a device authorization. It returns
+	// entities.ErrAuthorizationPending until the user approves it, and entities.ErrSlowDown
+	// when polling too often.
+	PollDeviceToken(ctx context.Context, clientID string, authorization *entities.DeviceAuthorization) (*entities.Credentials, error)
+}
+
+// DeviceFlowNotifier defines the interface for announcing the outcome of device authorization flows,
+// so that operators of a headless proxy learn when authentication succeeds or fails.
+type DeviceFlowNotifier interface {
+	// NotifyDeviceFlow reports a device flow that succeeded or failed
+	NotifyDeviceFlow(ctx context.Context, status *entities.DeviceFlowStatus) error
 }
 
 // AIService defines the interface for AI model interactions.
 // This interface abstracts the external AI API that our application uses.
 type AIService interface {
-	// ChatCompletions sends a chat completion request to the AI service
-	ChatCompletions(req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error)
+	// ChatCompletions sends a chat completion request to the AI service.
+	// The request is cancelled when ctx is done, so a disconnected client stops the upstream call.
+	ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error)
+
+	// Embeddings sends an embeddings request to the AI service
+	Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error)
+
+	// ListModels requests the list of models available to the credentials from the AI service
+	ListModels(ctx context.Context, credentials *entities.Credentials) (*http.Response, error)
 
 	// GetBaseURL returns the appropriate base URL for API calls
 	GetBaseURL(credentials *entities.Credentials, defaultURL string) (string, error)
@@ -46,4 +124,14 @@ type AIService interface {
 type StreamingService interface {
 	// ProcessStreamingResponse processes a streaming response from the AI service
 	ProcessStreamingResponse(response any, writer any) error
-}
\ No newline at end of file
+}
+
+// Tokenizer defines the interface for counting tokens locally.
+// It lets the proxy account for requests and responses without asking the upstream API.
+type Tokenizer interface {
+	// CountTokens returns the number of tokens the text encodes to
+	CountTokens(text string) int
+
+	// Encode returns the token IDs of the text, or false when the tokenizer only estimates counts
+	Encode(text string) ([]int, bool)
+}
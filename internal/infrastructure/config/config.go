@@ -18,25 +18,70 @@ func LoadConfig() (*entities.Config, error) {
 	godotenv.Load()
 
 	config := &entities.Config{
-		ServerPort:                 getEnvIntWithDefault("SERVER_PORT", 8080),
-		ServerHost:                 getEnvWithDefault("SERVER_HOST", "0.0.0.0"),
-		ReadTimeout:                getEnvDurationWithDefault("READ_TIMEOUT", 30*time.Second),
-		WriteTimeout:               getEnvDurationWithDefault("WRITE_TIMEOUT", 30*time.Second),
-		QWENOAuthBaseURL:           getEnvWithDefault("QWEN_OAUTH_BASE_URL", "https://chat.qwen.ai"),
-		QWENOAuthClientID:          getEnvWithDefault("QWEN_OAUTH_CLIENT_ID", "f0304373b74a44d2b584a3fb70ca9e56"),
-		QWENOAuthScope:             getEnvWithDefault("QWEN_OAUTH_SCOPE", "openid profile email model.completion"),
-		QWENOAuthDeviceAuthURL:     getEnvWithDefault("QWEN_OAUTH_DEVICE_AUTH_URL", "https://chat.qwen.ai/api/v1/oauth2/device/code"),
-		QWENDir:                    getEnvWithDefault("QWEN_DIR", ".qwen"),
-		TokenRefreshBuffer:         getEnvDurationWithDefault("TOKEN_REFRESH_BUFFER", 5*time.Minute),
-		ShutdownTimeout:            getEnvDurationWithDefault("SHUTDOWN_TIMEOUT", 30*time.Second),
-		DebugMode:                  getEnvBoolWithDefault("DEBUG_MODE", false),
-		LogLevel:                   getEnvWithDefault("LOG_LEVEL", "info"),
-		LogFormat:                  getEnvWithDefault("LOG_FORMAT", "json"),
-		RateLimitRequestsPerSecond: getEnvIntWithDefault("RATE_LIMIT_RPS", 10),
-		RateLimitBurst:             getEnvIntWithDefault("RATE_LIMIT_BURST", 20),
-		APIBaseURL:                 getEnvWithDefault("API_BASE_URL", "https://portal.qwen.ai/v1"),
-		DefaultModel:               getEnvWithDefault("DEFAULT_MODEL", "qwen3-coder-plus"),
-		TrustedProxies:             getEnvSliceWithDefault("TRUSTED_PROXIES", []string{}),
+		ServerPort:                     getEnvIntWithDefault("SERVER_PORT", 8080),
+		ServerHost:                     getEnvWithDefault("SERVER_HOST", "0.0.0.0"),
+		ReadTimeout:                    getEnvDurationWithDefault("READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:                   getEnvDurationWithDefault("WRITE_TIMEOUT", 30*time.Second),
+		QWENOAuthBaseURL:               getEnvWithDefault("QWEN_OAUTH_BASE_URL", "https://chat.qwen.ai"),
+		QWENOAuthClientID:              getEnvWithDefault("QWEN_OAUTH_CLIENT_ID", "f0304373b74a44d2b584a3fb70ca9e56"),
+		QWENOAuthScope:                 getEnvWithDefault("QWEN_OAUTH_SCOPE", "openid profile email model.completion"),
+		QWENOAuthDeviceAuthURL:         getEnvWithDefault("QWEN_OAUTH_DEVICE_AUTH_URL", "https://chat.qwen.ai/api/v1/oauth2/device/code"),
+		AuthWebhookURL:                 getEnvWithDefault("AUTH_WEBHOOK_URL", ""),
+		QWENDir:                        getEnvWithDefault("QWEN_DIR", ".qwen"),
+		CredentialStore:                getEnvWithDefault("CREDENTIAL_STORE", "file"),
+		CredentialSQLitePath:           getEnvWithDefault("CREDENTIAL_SQLITE_PATH", ""),
+		VaultAddress:                   getEnvWithDefault("VAULT_ADDR", ""),
+		VaultToken:                     getEnvWithDefault("VAULT_TOKEN", ""),
+		VaultNamespace:                 getEnvWithDefault("VAULT_NAMESPACE", ""),
+		VaultKVMount:                   getEnvWithDefault("VAULT_KV_MOUNT", "secret"),
+		VaultKVPath:                    getEnvWithDefault("VAULT_KV_PATH", "qwen-go-proxy"),
+		CredentialEncryptionKey:        getEnvWithDefault("CREDENTIAL_ENCRYPTION_KEY", ""),
+		CredentialEncryptionKeyFile:    getEnvWithDefault("CREDENTIAL_ENCRYPTION_KEY_FILE", ""),
+		CredentialEncryptionPassphrase: getEnvWithDefault("CREDENTIAL_ENCRYPTION_PASSPHRASE", ""),
+		TokenRefreshBuffer:             getEnvDurationWithDefault("TOKEN_REFRESH_BUFFER", 5*time.Minute),
+		TokenRefreshBackground:         getEnvBoolWithDefault("TOKEN_REFRESH_BACKGROUND", true),
+		TokenRefreshJitter:             getEnvDurationWithDefault("TOKEN_REFRESH_JITTER", time.Minute),
+		TokenRefreshMaxBackoff:         getEnvDurationWithDefault("TOKEN_REFRESH_MAX_BACKOFF", 5*time.Minute),
+		ShutdownTimeout:                getEnvDurationWithDefault("SHUTDOWN_TIMEOUT", 30*time.Second),
+		CredentialPoolStrategy:         getEnvWithDefault("CREDENTIAL_POOL_STRATEGY", "round_robin"),
+		AccountCooldown:                getEnvDurationWithDefault("ACCOUNT_COOLDOWN", 60*time.Second),
+		RetryMaxAttempts:               getEnvIntWithDefault("RETRY_MAX_ATTEMPTS", 3),
+		RetryInitialBackoff:            getEnvDurationWithDefault("RETRY_INITIAL_BACKOFF", 500*time.Millisecond),
+		RetryMaxBackoff:                getEnvDurationWithDefault("RETRY_MAX_BACKOFF", 10*time.Second),
+		DebugMode:                      getEnvBoolWithDefault("DEBUG_MODE", false),
+		LogLevel:                       getEnvWithDefault("LOG_LEVEL", "info"),
+		LogFormat:                      getEnvWithDefault("LOG_FORMAT", "json"),
+		MetricsEnabled:                 getEnvBoolWithDefault("METRICS_ENABLED", true),
+		TracingExporter:                getEnvWithDefault("TRACING_EXPORTER", "none"),
+		TracingOTLPEndpoint:            getEnvWithDefault("TRACING_OTLP_ENDPOINT", "http://localhost:4318"),
+		TracingServiceName:             getEnvWithDefault("TRACING_SERVICE_NAME", "qwen-go-proxy"),
+		ModelCatalogFile:               getEnvWithDefault("MODEL_CATALOG_FILE", ""),
+		ModelDiscoveryTTL:              getEnvDurationWithDefault("MODEL_DISCOVERY_TTL", 10*time.Minute),
+		ModelRoutesFile:                getEnvWithDefault("MODEL_ROUTES_FILE", ""),
+		ProvidersFile:                  getEnvWithDefault("PROVIDERS_FILE", ""),
+		CacheBackend:                   getEnvWithDefault("CACHE_BACKEND", "none"),
+		CacheTTL:                       getEnvDurationWithDefault("CACHE_TTL", time.Hour),
+		CacheMaxEntries:                getEnvIntWithDefault("CACHE_MAX_ENTRIES", 1000),
+		ResponseStoreTTL:               getEnvDurationWithDefault("RESPONSE_STORE_TTL", 30*24*time.Hour),
+		ResponseStoreMaxEntries:        getEnvIntWithDefault("RESPONSE_STORE_MAX_ENTRIES", 10000),
+		JournalEnabled:                 getEnvBoolWithDefault("JOURNAL_ENABLED", false),
+		JournalDir:                     getEnvWithDefault("JOURNAL_DIR", ""),
+		JournalMaxSizeMB:               getEnvIntWithDefault("JOURNAL_MAX_SIZE_MB", 100),
+		JournalMaxFiles:                getEnvIntWithDefault("JOURNAL_MAX_FILES", 10),
+		JournalRedaction:               getEnvWithDefault("JOURNAL_REDACTION", "none"),
+		TokenizerFile:                  getEnvWithDefault("TOKENIZER_FILE", ""),
+		ContextOverflow:                getEnvWithDefault("CONTEXT_OVERFLOW", "reject"),
+		ContextStrategy:                getEnvWithDefault("CONTEXT_STRATEGY", "keep_tool_pairs"),
+		RateLimitRequestsPerSecond:     getEnvIntWithDefault("RATE_LIMIT_RPS", 10),
+		RateLimitBurst:                 getEnvIntWithDefault("RATE_LIMIT_BURST", 20),
+		RateLimitKey:                   getEnvWithDefault("RATE_LIMIT_KEY", "ip"),
+		RateLimitPromptTPM:             getEnvIntWithDefault("RATE_LIMIT_PROMPT_TPM", 0),
+		RateLimitCompletionTPM:         getEnvIntWithDefault("RATE_LIMIT_COMPLETION_TPM", 0),
+		APIBaseURL:                     getEnvWithDefault("API_BASE_URL", "https://portal.qwen.ai/v1"),
+		DefaultModel:                   getEnvWithDefault("DEFAULT_MODEL", "qwen3-coder-plus"),
+		TrustedProxies:                 getEnvSliceWithDefault("TRUSTED_PROXIES", []string{}),
+		RequireAPIKey:                  getEnvBoolWithDefault("REQUIRE_API_KEY", false),
+		AdminAPIKey:                    getEnvWithDefault("ADMIN_API_KEY", ""),
 	}
 
 	// Validate the configuration using the infrastructure validation
@@ -28,7 +28,21 @@ func TestLoadConfig_DefaultValues(t *testing.T) {
 	assert.Equal(t, "https://chat.qwen.ai", config.QWENOAuthBaseURL)
 	assert.Equal(t, "f0304373b74a44d2b584a3fb70ca9e56", config.QWENOAuthClientID)
 	assert.Equal(t, ".qwen", config.QWENDir)
+	assert.Empty(t, config.AuthWebhookURL)
+	assert.Equal(t, "file", config.CredentialStore)
+	assert.Empty(t, config.CredentialSQLitePath)
+	assert.Empty(t, config.VaultAddress)
+	assert.Empty(t, config.VaultToken)
+	assert.Empty(t, config.VaultNamespace)
+	assert.Equal(t, "secret", config.VaultKVMount)
+	assert.Equal(t, "qwen-go-proxy", config.VaultKVPath)
+	assert.Empty(t, config.CredentialEncryptionKey)
+	assert.Empty(t, config.CredentialEncryptionKeyFile)
+	assert.Empty(t, config.CredentialEncryptionPassphrase)
 	assert.Equal(t, 5*time.Minute, config.TokenRefreshBuffer)
+	assert.True(t, config.TokenRefreshBackground)
+	assert.Equal(t, time.Minute, config.TokenRefreshJitter)
+	assert.Equal(t, 5*time.Minute, config.TokenRefreshMaxBackoff)
 	assert.Equal(t, 30*time.Second, config.ShutdownTimeout)
 	assert.False(t, config.DebugMode)
 	assert.Equal(t, "info", config.LogLevel)
@@ -37,6 +51,37 @@ func TestLoadConfig_DefaultValues(t *testing.T) {
 	assert.Equal(t, 20, config.RateLimitBurst)
 	assert.Equal(t, "https://portal.qwen.ai/v1", config.APIBaseURL)
 	assert.Empty(t, config.TrustedProxies)
+	assert.Equal(t, "round_robin", config.CredentialPoolStrategy)
+	assert.Equal(t, 60*time.Second, config.AccountCooldown)
+	assert.False(t, config.RequireAPIKey)
+	assert.Empty(t, config.AdminAPIKey)
+	assert.Equal(t, "ip", config.RateLimitKey)
+	assert.Equal(t, 0, config.RateLimitPromptTPM)
+	assert.Equal(t, 0, config.RateLimitCompletionTPM)
+	assert.Equal(t, 3, config.RetryMaxAttempts)
+	assert.Equal(t, 500*time.Millisecond, config.RetryInitialBackoff)
+	assert.Equal(t, 10*time.Second, config.RetryMaxBackoff)
+	assert.True(t, config.MetricsEnabled)
+	assert.Equal(t, "none", config.TracingExporter)
+	assert.Equal(t, "http://localhost:4318", config.TracingOTLPEndpoint)
+	assert.Equal(t, "qwen-go-proxy", config.TracingServiceName)
+	assert.Equal(t, "", config.ModelCatalogFile)
+	assert.Equal(t, 10*time.Minute, config.ModelDiscoveryTTL)
+	assert.Equal(t, "", config.ModelRoutesFile)
+	assert.Equal(t, "", config.ProvidersFile)
+	assert.Equal(t, "none", config.CacheBackend)
+	assert.Equal(t, time.Hour, config.CacheTTL)
+	assert.Equal(t, 1000, config.CacheMaxEntries)
+	assert.Equal(t, 720*time.Hour, config.ResponseStoreTTL)
+	assert.Equal(t, 10000, config.ResponseStoreMaxEntries)
+	assert.False(t, config.JournalEnabled)
+	assert.Equal(t, "", config.JournalDir)
+	assert.Equal(t, 100, config.JournalMaxSizeMB)
+	assert.Equal(t, 10, config.JournalMaxFiles)
+	assert.Equal(t, "none", config.JournalRedaction)
+	assert.Equal(t, "", config.TokenizerFile)
+	assert.Equal(t, "reject", config.ContextOverflow)
+	assert.Equal(t, "keep_tool_pairs", config.ContextStrategy)
 }
 
 func TestLoadConfig_WithEnvVars(t *testing.T) {
@@ -258,9 +303,46 @@ func TestValidateConfig_InvalidValues(t *testing.T) {
 		error string
 	}{
 		{"negative token refresh buffer", func(c *entities.Config) { c.TokenRefreshBuffer = -1 * time.Minute }, "TOKEN_REFRESH_BUFFER must be non-negative"},
+		{"negative token refresh jitter", func(c *entities.Config) { c.TokenRefreshJitter = -time.Second }, "TOKEN_REFRESH_JITTER and TOKEN_REFRESH_MAX_BACKOFF must be non-negative"},
+		{"invalid credential store", func(c *entities.Config) { c.CredentialStore = "redis" }, "CREDENTIAL_STORE must be one of"},
+		{"vault store without token", func(c *entities.Config) {
+			c.CredentialStore = "vault"
+			c.VaultAddress = "http://127.0.0.1:8200"
+		}, "VAULT_ADDR and VAULT_TOKEN are required when CREDENTIAL_STORE is vault"},
+		{"invalid vault address", func(c *entities.Config) {
+			c.CredentialStore = "vault"
+			c.VaultAddress = "127.0.0.1:8200"
+			c.VaultToken = "test-token"
+		}, "VAULT_ADDR must be an http or https URL"},
+		{"encrypted sqlite store", func(c *entities.Config) {
+			c.CredentialStore = "sqlite"
+			c.CredentialEncryptionPassphrase = "correct horse battery staple"
+		}, "credential encryption is only supported with CREDENTIAL_STORE=file"},
+		{"two credential encryption keys", func(c *entities.Config) {
+			c.CredentialEncryptionKeyFile = "/run/secrets/qwen-key"
+			c.CredentialEncryptionPassphrase = "correct horse battery staple"
+		}, "only one of CREDENTIAL_ENCRYPTION_KEY, CREDENTIAL_ENCRYPTION_KEY_FILE and CREDENTIAL_ENCRYPTION_PASSPHRASE can be set"},
+		{"short credential encryption passphrase", func(c *entities.Config) { c.CredentialEncryptionPassphrase = "short" }, "CREDENTIAL_ENCRYPTION_PASSPHRASE must be at least 16 characters long"},
 		{"negative shutdown timeout", func(c *entities.Config) { c.ShutdownTimeout = -1 * time.Second }, "SHUTDOWN_TIMEOUT must be non-negative"},
 		{"zero rate limit rps", func(c *entities.Config) { c.RateLimitRequestsPerSecond = 0 }, "RATE_LIMIT_REQUESTS_PER_SECOND must be positive"},
 		{"negative rate limit burst", func(c *entities.Config) { c.RateLimitBurst = -1 }, "RATE_LIMIT_BURST must be positive"},
+		{"negative retry attempts", func(c *entities.Config) { c.RetryMaxAttempts = -1 }, "RETRY_MAX_ATTEMPTS must be non-negative"},
+		{"negative retry backoff", func(c *entities.Config) { c.RetryMaxBackoff = -time.Second }, "RETRY_INITIAL_BACKOFF and RETRY_MAX_BACKOFF must be non-negative"},
+		{"invalid rate limit key", func(c *entities.Config) { c.RateLimitKey = "user" }, "RATE_LIMIT_KEY must be one of"},
+		{"negative prompt tpm", func(c *entities.Config) { c.RateLimitPromptTPM = -1 }, "RATE_LIMIT_PROMPT_TPM and RATE_LIMIT_COMPLETION_TPM must be non-negative"},
+		{"invalid tracing exporter", func(c *entities.Config) { c.TracingExporter = "jaeger" }, "TRACING_EXPORTER must be one of"},
+		{"invalid otlp endpoint", func(c *entities.Config) { c.TracingExporter = "otlp"; c.TracingOTLPEndpoint = "localhost:4318" }, "TRACING_OTLP_ENDPOINT must be an http or https URL"},
+		{"invalid auth webhook url", func(c *entities.Config) { c.AuthWebhookURL = "hooks.example.com/qwen" }, "AUTH_WEBHOOK_URL must be an http or https URL"},
+		{"negative model discovery ttl", func(c *entities.Config) { c.ModelDiscoveryTTL = -time.Second }, "MODEL_DISCOVERY_TTL must be non-negative"},
+		{"invalid cache backend", func(c *entities.Config) { c.CacheBackend = "redis" }, "CACHE_BACKEND must be one of"},
+		{"negative cache ttl", func(c *entities.Config) { c.CacheTTL = -time.Second }, "CACHE_TTL must be non-negative"},
+		{"negative cache max entries", func(c *entities.Config) { c.CacheMaxEntries = -1 }, "CACHE_MAX_ENTRIES must be non-negative"},
+		{"negative response store ttl", func(c *entities.Config) { c.ResponseStoreTTL = -time.Second }, "RESPONSE_STORE_TTL and RESPONSE_STORE_MAX_ENTRIES must be non-negative"},
+		{"negative response store max entries", func(c *entities.Config) { c.ResponseStoreMaxEntries = -1 }, "RESPONSE_STORE_TTL and RESPONSE_STORE_MAX_ENTRIES must be non-negative"},
+		{"invalid journal redaction", func(c *entities.Config) { c.JournalRedaction = "drop" }, "JOURNAL_REDACTION must be one of"},
+		{"negative journal max size", func(c *entities.Config) { c.JournalMaxSizeMB = -1 }, "JOURNAL_MAX_SIZE_MB and JOURNAL_MAX_FILES must be non-negative"},
+		{"invalid context overflow", func(c *entities.Config) { c.ContextOverflow = "truncate" }, "CONTEXT_OVERFLOW must be one of"},
+		{"invalid context strategy", func(c *entities.Config) { c.ContextStrategy = "truncate" }, "CONTEXT_STRATEGY must be one of"},
 	}
 
 	for _, tt := range tests {
@@ -339,10 +421,20 @@ func TestHelperFunctions(t *testing.T) {
 func clearEnvVars() {
 	envVars := []string{
 		"SERVER_PORT", "SERVER_HOST", "READ_TIMEOUT", "WRITE_TIMEOUT",
-		"QWEN_OAUTH_BASE_URL", "QWEN_OAUTH_CLIENT_ID", "QWEN_DIR",
-		"TOKEN_REFRESH_BUFFER", "SHUTDOWN_TIMEOUT", "DEBUG_MODE",
+		"QWEN_OAUTH_BASE_URL", "QWEN_OAUTH_CLIENT_ID", "QWEN_DIR", "AUTH_WEBHOOK_URL",
+		"CREDENTIAL_STORE", "CREDENTIAL_SQLITE_PATH", "VAULT_ADDR", "VAULT_TOKEN", "VAULT_NAMESPACE", "VAULT_KV_MOUNT", "VAULT_KV_PATH",
+		"CREDENTIAL_ENCRYPTION_KEY", "CREDENTIAL_ENCRYPTION_KEY_FILE", "CREDENTIAL_ENCRYPTION_PASSPHRASE",
+		"TOKEN_REFRESH_BUFFER", "TOKEN_REFRESH_BACKGROUND", "TOKEN_REFRESH_JITTER", "TOKEN_REFRESH_MAX_BACKOFF", "SHUTDOWN_TIMEOUT", "DEBUG_MODE",
 		"LOG_LEVEL", "LOG_FORMAT", "RATE_LIMIT_RPS", "RATE_LIMIT_BURST",
 		"API_BASE_URL", "TRUSTED_PROXIES",
+		"CREDENTIAL_POOL_STRATEGY", "ACCOUNT_COOLDOWN", "REQUIRE_API_KEY", "ADMIN_API_KEY",
+		"RATE_LIMIT_KEY", "RATE_LIMIT_PROMPT_TPM", "RATE_LIMIT_COMPLETION_TPM",
+		"RETRY_MAX_ATTEMPTS", "RETRY_INITIAL_BACKOFF", "RETRY_MAX_BACKOFF", "METRICS_ENABLED",
+		"TRACING_EXPORTER", "TRACING_OTLP_ENDPOINT", "TRACING_SERVICE_NAME",
+		"MODEL_CATALOG_FILE", "MODEL_DISCOVERY_TTL", "MODEL_ROUTES_FILE", "PROVIDERS_FILE",
+		"CACHE_BACKEND", "CACHE_TTL", "CACHE_MAX_ENTRIES", "RESPONSE_STORE_TTL", "RESPONSE_STORE_MAX_ENTRIES",
+		"JOURNAL_ENABLED", "JOURNAL_DIR", "JOURNAL_MAX_SIZE_MB", "JOURNAL_MAX_FILES", "JOURNAL_REDACTION",
+		"TOKENIZER_FILE", "CONTEXT_OVERFLOW", "CONTEXT_STRATEGY",
 		"TEST_VAR", "TEST_INT", "TEST_DURATION", "TEST_BOOL", "TEST_SLICE",
 	}
 
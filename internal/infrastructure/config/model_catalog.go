@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"qwen-go-proxy/internal/domain/entities"
+)
+
+// LoadModelCatalog reads and validates a model catalog file.
+// Model IDs and aliases must be non-empty and unique across the whole catalog.
+func LoadModelCatalog(path string) ([]entities.ModelCatalogEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model catalog %s: %w", path, err)
+	}
+
+	var catalog entities.ModelCatalogFile
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse model catalog %s: %w", path, err)
+	}
+	if len(catalog.Models) == 0 {
+		return nil, fmt.Errorf("model catalog %s declares no models", path)
+	}
+
+	names := make(map[string]bool)
+	for _, entry := range catalog.Models {
+		if entry.ID == "" {
+			return nil, fmt.Errorf("model catalog %s: model id cannot be empty", path)
+		}
+		for _, name := range append([]string{entry.ID}, entry.Aliases...) {
+			if name == "" {
+				return nil, fmt.Errorf("model catalog %s: alias of %s cannot be empty", path, entry.ID)
+			}
+			if names[name] {
+				return nil, fmt.Errorf("model catalog %s: %s is declared more than once", path, name)
+			}
+			names[name] = true
+		}
+		if entry.ContextWindow < 0 || entry.MaxOutputTokens < 0 {
+			return nil, fmt.Errorf("model catalog %s: token limits of %s must be non-negative", path, entry.ID)
+		}
+	}
+
+	return catalog.Models, nil
+}
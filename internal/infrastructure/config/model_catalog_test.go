@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCatalog(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "models.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadModelCatalog(t *testing.T) {
+	path := writeCatalog(t, `{
+		"models": [
+			{
+				"id": "qwen3-coder-plus",
+				"aliases": ["coder"],
+				"context_window": 1000000,
+				"max_output_tokens": 65536,
+				"modalities": ["text"],
+				"supports_tools": true
+			},
+			{"id": "qwen-old", "deprecated": true, "replaced_by": "qwen3-coder-plus"}
+		]
+	}`)
+
+	entries, err := LoadModelCatalog(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "qwen3-coder-plus", entries[0].ID)
+	assert.Equal(t, []string{"coder"}, entries[0].Aliases)
+	assert.Equal(t, 1000000, entries[0].ContextWindow)
+	assert.Equal(t, 65536, entries[0].MaxOutputTokens)
+	assert.Equal(t, []string{"text"}, entries[0].Modalities)
+	require.NotNil(t, entries[0].SupportsTools)
+	assert.True(t, *entries[0].SupportsTools)
+	assert.True(t, entries[1].Deprecated)
+	assert.Equal(t, "qwen3-coder-plus", entries[1].ReplacedBy)
+}
+
+func TestLoadModelCatalog_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid json", `{"models": [`, "failed to parse model catalog"},
+		{"no models", `{"models": []}`, "declares no models"},
+		{"empty id", `{"models": [{"id": ""}]}`, "model id cannot be empty"},
+		{"empty alias", `{"models": [{"id": "a", "aliases": [""]}]}`, "alias of a cannot be empty"},
+		{"duplicate id", `{"models": [{"id": "a"}, {"id": "a"}]}`, "a is declared more than once"},
+		{"alias clashes with id", `{"models": [{"id": "a"}, {"id": "b", "aliases": ["a"]}]}`, "a is declared more than once"},
+		{"negative context window", `{"models": [{"id": "a", "context_window": -1}]}`, "must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadModelCatalog(writeCatalog(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadModelCatalog_MissingFile(t *testing.T) {
+	_, err := LoadModelCatalog(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read model catalog")
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"qwen-go-proxy/internal/domain/entities"
+)
+
+// fallbackClasses are the upstream error classes a route can fall back on
+var fallbackClasses = []string{
+	entities.FallbackOnServerError,
+	entities.FallbackOnOverloaded,
+	entities.FallbackOnRateLimited,
+	entities.FallbackOnTimeout,
+	entities.FallbackOnConnection,
+}
+
+// LoadModelRoutes reads and validates a model routing table file.
+// Routes are kept in file order, since the first matching route wins.
+func LoadModelRoutes(file string) ([]entities.ModelRoute, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model routes %s: %w", file, err)
+	}
+
+	var table entities.ModelRoutesFile
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse model routes %s: %w", file, err)
+	}
+	if len(table.Routes) == 0 {
+		return nil, fmt.Errorf("model routes %s declare no routes", file)
+	}
+
+	for i, route := range table.Routes {
+		if route.Match == "" {
+			return nil, fmt.Errorf("model routes %s: match of route %d cannot be empty", file, i)
+		}
+		if _, err := path.Match(route.Match, ""); err != nil {
+			return nil, fmt.Errorf("model routes %s: invalid pattern %q: %w", file, route.Match, err)
+		}
+		if route.Target == "" {
+			return nil, fmt.Errorf("model routes %s: target of %s cannot be empty", file, route.Match)
+		}
+		if route.MaxTokens < 0 {
+			return nil, fmt.Errorf("model routes %s: max_tokens of %s must be non-negative", file, route.Match)
+		}
+		if route.Temperature != nil && (*route.Temperature < 0 || *route.Temperature > 2) {
+			return nil, fmt.Errorf("model routes %s: temperature of %s must be between 0 and 2", file, route.Match)
+		}
+		if route.TopP != nil && (*route.TopP < 0 || *route.TopP > 1) {
+			return nil, fmt.Errorf("model routes %s: top_p of %s must be between 0 and 1", file, route.Match)
+		}
+		for _, fallback := range route.Fallbacks {
+			if fallback == "" {
+				return nil, fmt.Errorf("model routes %s: fallback of %s cannot be empty", file, route.Match)
+			}
+		}
+		for _, class := range route.FallbackOn {
+			if !slices.Contains(fallbackClasses, class) {
+				return nil, fmt.Errorf("model routes %s: fallback_on of %s must be one of: %s", file, route.Match, strings.Join(fallbackClasses, ", "))
+			}
+		}
+	}
+
+	return table.Routes, nil
+}
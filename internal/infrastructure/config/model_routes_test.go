@@ -0,0 +1,67 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadModelRoutes(t *testing.T) {
+	path := writeCatalog(t, `{
+		"routes": [
+			{"match": "gpt-4o", "target": "qwen3-coder-plus", "max_tokens": 2048, "temperature": 0.2},
+			{"match": "gpt-*", "target": "qwen3-coder-flash", "top_p": 0.9},
+			{"match": "qwen3-coder-plus", "target": "qwen3-coder-plus", "fallbacks": ["qwen3-coder-flash"], "fallback_on": ["server_error", "timeout"]}
+		]
+	}`)
+
+	routes, err := LoadModelRoutes(path)
+	require.NoError(t, err)
+	require.Len(t, routes, 3)
+	assert.Equal(t, "gpt-4o", routes[0].Match)
+	assert.Equal(t, "qwen3-coder-plus", routes[0].Target)
+	assert.Equal(t, 2048, routes[0].MaxTokens)
+	require.NotNil(t, routes[0].Temperature)
+	assert.Equal(t, 0.2, *routes[0].Temperature)
+	assert.Nil(t, routes[0].TopP)
+	assert.Equal(t, "gpt-*", routes[1].Match)
+	require.NotNil(t, routes[1].TopP)
+	assert.Equal(t, 0.9, *routes[1].TopP)
+	assert.Equal(t, []string{"qwen3-coder-flash"}, routes[2].Fallbacks)
+	assert.Equal(t, []string{"server_error", "timeout"}, routes[2].FallbackOn)
+}
+
+func TestLoadModelRoutes_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid json", `{"routes": [`, "failed to parse model routes"},
+		{"no routes", `{"routes": []}`, "declare no routes"},
+		{"empty match", `{"routes": [{"match": "", "target": "a"}]}`, "match of route 0 cannot be empty"},
+		{"invalid pattern", `{"routes": [{"match": "gpt-[", "target": "a"}]}`, "invalid pattern"},
+		{"empty target", `{"routes": [{"match": "gpt-4o"}]}`, "target of gpt-4o cannot be empty"},
+		{"negative max tokens", `{"routes": [{"match": "a", "target": "b", "max_tokens": -1}]}`, "must be non-negative"},
+		{"temperature out of range", `{"routes": [{"match": "a", "target": "b", "temperature": 3}]}`, "must be between 0 and 2"},
+		{"top_p out of range", `{"routes": [{"match": "a", "target": "b", "top_p": 1.5}]}`, "must be between 0 and 1"},
+		{"empty fallback", `{"routes": [{"match": "a", "target": "b", "fallbacks": [""]}]}`, "fallback of a cannot be empty"},
+		{"invalid fallback class", `{"routes": [{"match": "a", "target": "b", "fallback_on": ["4xx"]}]}`, "fallback_on of a must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadModelRoutes(writeCatalog(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadModelRoutes_MissingFile(t *testing.T) {
+	_, err := LoadModelRoutes(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read model routes")
+}
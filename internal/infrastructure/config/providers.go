@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"qwen-go-proxy/internal/domain/entities"
+)
+
+// LoadProviders reads and validates an upstream providers file.
+// Bearer keys given by api_key_env are resolved from the environment.
+// Provider names and model names must be unique across the whole file.
+func LoadProviders(file string) ([]entities.ProviderConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read providers %s: %w", file, err)
+	}
+
+	var providersFile entities.ProvidersFile
+	if err := json.Unmarshal(data, &providersFile); err != nil {
+		return nil, fmt.Errorf("failed to parse providers %s: %w", file, err)
+	}
+	if len(providersFile.Providers) == 0 {
+		return nil, fmt.Errorf("providers %s declare no providers", file)
+	}
+
+	names := make(map[string]bool)
+	modelNames := make(map[string]string)
+	for i := range providersFile.Providers {
+		provider := &providersFile.Providers[i]
+		if provider.Name == "" {
+			return nil, fmt.Errorf("providers %s: name of provider %d cannot be empty", file, i)
+		}
+		if names[provider.Name] {
+			return nil, fmt.Errorf("providers %s: provider %s is declared more than once", file, provider.Name)
+		}
+		names[provider.Name] = true
+
+		switch provider.AuthType {
+		case entities.ProviderAuthQwenOAuth:
+		case entities.ProviderAuthBearer:
+			if provider.APIKeyEnv != "" {
+				provider.APIKey = os.Getenv(provider.APIKeyEnv)
+			}
+			if provider.APIKey == "" {
+				return nil, fmt.Errorf("providers %s: provider %s requires api_key or api_key_env", file, provider.Name)
+			}
+		case entities.ProviderAuthNone:
+		default:
+			return nil, fmt.Errorf("providers %s: auth_type of %s must be one of: %s, %s, %s", file, provider.Name,
+				entities.ProviderAuthQwenOAuth, entities.ProviderAuthBearer, entities.ProviderAuthNone)
+		}
+
+		if provider.BaseURL == "" && provider.AuthType != entities.ProviderAuthQwenOAuth {
+			return nil, fmt.Errorf("providers %s: provider %s requires a base_url", file, provider.Name)
+		}
+		if provider.BaseURL != "" {
+			parsed, err := url.Parse(provider.BaseURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return nil, fmt.Errorf("providers %s: base_url of %s must be an http or https URL", file, provider.Name)
+			}
+		}
+		if len(provider.Models) == 0 && provider.AuthType != entities.ProviderAuthQwenOAuth {
+			return nil, fmt.Errorf("providers %s: provider %s declares no models", file, provider.Name)
+		}
+
+		for _, model := range provider.Models {
+			if model == "" {
+				return nil, fmt.Errorf("providers %s: model of %s cannot be empty", file, provider.Name)
+			}
+			if owner, ok := modelNames[model]; ok {
+				return nil, fmt.Errorf("providers %s: model %s is served by both %s and %s", file, model, owner, provider.Name)
+			}
+			modelNames[model] = provider.Name
+		}
+	}
+
+	return providersFile.Providers, nil
+}
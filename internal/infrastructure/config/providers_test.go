@@ -0,0 +1,63 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProviders(t *testing.T) {
+	t.Setenv("TEST_PROVIDER_KEY", "sk-from-env")
+	path := writeCatalog(t, `{
+		"providers": [
+			{"name": "qwen", "auth_type": "qwen_oauth"},
+			{"name": "vllm", "base_url": "http://localhost:8000/v1", "auth_type": "bearer", "api_key_env": "TEST_PROVIDER_KEY", "models": ["llama-3.1-8b"]},
+			{"name": "stub", "base_url": "http://localhost:9000", "auth_type": "none", "models": ["stub-model"]}
+		]
+	}`)
+
+	providers, err := LoadProviders(path)
+	require.NoError(t, err)
+	require.Len(t, providers, 3)
+	assert.Equal(t, entities.ProviderAuthQwenOAuth, providers[0].AuthType)
+	assert.Equal(t, "sk-from-env", providers[1].APIKey)
+	assert.Equal(t, []string{"llama-3.1-8b"}, providers[1].Models)
+	assert.Equal(t, "http://localhost:9000", providers[2].BaseURL)
+}
+
+func TestLoadProviders_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid json", `{"providers": [`, "failed to parse providers"},
+		{"no providers", `{"providers": []}`, "declare no providers"},
+		{"empty name", `{"providers": [{"auth_type": "none"}]}`, "name of provider 0 cannot be empty"},
+		{"duplicate name", `{"providers": [{"name": "a", "auth_type": "qwen_oauth"}, {"name": "a", "auth_type": "qwen_oauth"}]}`, "a is declared more than once"},
+		{"invalid auth type", `{"providers": [{"name": "a", "auth_type": "basic"}]}`, "auth_type of a must be one of"},
+		{"bearer without key", `{"providers": [{"name": "a", "auth_type": "bearer", "base_url": "http://x", "models": ["m"]}]}`, "requires api_key or api_key_env"},
+		{"missing base url", `{"providers": [{"name": "a", "auth_type": "none", "models": ["m"]}]}`, "requires a base_url"},
+		{"invalid base url", `{"providers": [{"name": "a", "auth_type": "none", "base_url": "localhost:8000", "models": ["m"]}]}`, "must be an http or https URL"},
+		{"no models", `{"providers": [{"name": "a", "auth_type": "none", "base_url": "http://x"}]}`, "a declares no models"},
+		{"duplicate model", `{"providers": [{"name": "a", "auth_type": "none", "base_url": "http://x", "models": ["m"]}, {"name": "b", "auth_type": "none", "base_url": "http://y", "models": ["m"]}]}`, "m is served by both a and b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadProviders(writeCatalog(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadProviders_MissingFile(t *testing.T) {
+	_, err := LoadProviders(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read providers")
+}
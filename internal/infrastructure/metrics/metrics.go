@@ -0,0 +1,156 @@
+// Package metrics exposes proxy metrics in the Prometheus text format.
+// Collectors are registered on a dedicated registry so that /metrics only reports proxy
+// metrics plus the standard Go runtime and process collectors.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "qwen_proxy"
+
+// Token types used for the tokens counter
+const (
+	TokenTypePrompt     = "prompt"
+	TokenTypeCompletion = "completion"
+)
+
+// Token refresh results
+const (
+	RefreshResultSuccess = "success"
+	RefreshResultFailure = "failure"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "HTTP requests handled by the proxy, by route, model and status code.",
+	}, []string{"route", "model", "status"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Time until the upstream API returned response headers, including retries.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"model", "status"})
+
+	timeToFirstToken = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stream_time_to_first_token_seconds",
+		Help:      "Time from sending a streaming request upstream until its first byte arrived.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"model"})
+
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Tokens reported in upstream usage, by model and type (prompt or completion).",
+	}, []string{"model", "type"})
+
+	streamChunksTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_chunks_total",
+		Help:      "Stream chunks processed.",
+	})
+
+	streamErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_errors_total",
+		Help:      "Malformed or unknown stream chunks encountered.",
+	})
+
+	streamStutterEventsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_stutter_events_total",
+		Help:      "Repeated leading chunks suppressed by stutter detection.",
+	})
+
+	tokenRefreshesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_refreshes_total",
+		Help:      "OAuth token refresh attempts, by result (success or failure).",
+	}, []string{"result"})
+
+	cacheRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Response cache lookups, by result (HIT, MISS, REFRESH or BYPASS).",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		upstreamDuration,
+		timeToFirstToken,
+		tokensTotal,
+		streamChunksTotal,
+		streamErrorsTotal,
+		streamStutterEventsTotal,
+		tokenRefreshesTotal,
+		cacheRequestsTotal,
+	)
+}
+
+// Handler returns the HTTP handler serving metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest counts a handled HTTP request
+func ObserveRequest(route, model string, status int) {
+	requestsTotal.WithLabelValues(route, model, strconv.Itoa(status)).Inc()
+}
+
+// ObserveUpstream records the latency of an upstream request; status 0 means the request failed without a response
+func ObserveUpstream(model string, status int, duration time.Duration) {
+	upstreamDuration.WithLabelValues(model, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveTimeToFirstToken records how long a stream took to produce its first byte
+func ObserveTimeToFirstToken(model string, duration time.Duration) {
+	timeToFirstToken.WithLabelValues(model).Observe(duration.Seconds())
+}
+
+// AddUsage counts the prompt and completion tokens of a request
+func AddUsage(model string, usage *entities.Usage) {
+	if usage == nil {
+		return
+	}
+	tokensTotal.WithLabelValues(model, TokenTypePrompt).Add(float64(usage.PromptTokens))
+	tokensTotal.WithLabelValues(model, TokenTypeCompletion).Add(float64(usage.CompletionTokens))
+}
+
+// ObserveStream adds the counters of a finished stream
+func ObserveStream(chunks, errors, stutterEvents int) {
+	streamChunksTotal.Add(float64(chunks))
+	streamErrorsTotal.Add(float64(errors))
+	streamStutterEventsTotal.Add(float64(stutterEvents))
+}
+
+// ObserveTokenRefresh counts a token refresh attempt
+func ObserveTokenRefresh(err error) {
+	if err != nil {
+		tokenRefreshesTotal.WithLabelValues(RefreshResultFailure).Inc()
+		return
+	}
+	tokenRefreshesTotal.WithLabelValues(RefreshResultSuccess).Inc()
+}
+
+// ObserveCache counts a response cache lookup by its result
+func ObserveCache(result string) {
+	cacheRequestsTotal.WithLabelValues(result).Inc()
+}
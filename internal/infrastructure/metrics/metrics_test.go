@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveRequest(t *testing.T) {
+	counter := requestsTotal.WithLabelValues("/v1/chat/completions", "qwen3-coder-plus", "200")
+	before := testutil.ToFloat64(counter)
+
+	ObserveRequest("/v1/chat/completions", "qwen3-coder-plus", 200)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestAddUsage(t *testing.T) {
+	prompt := tokensTotal.WithLabelValues("usage-model", TokenTypePrompt)
+	completion := tokensTotal.WithLabelValues("usage-model", TokenTypeCompletion)
+	promptBefore, completionBefore := testutil.ToFloat64(prompt), testutil.ToFloat64(completion)
+
+	AddUsage("usage-model", &entities.Usage{PromptTokens: 12, CompletionTokens: 30, TotalTokens: 42})
+	AddUsage("usage-model", nil)
+
+	assert.Equal(t, promptBefore+12, testutil.ToFloat64(prompt))
+	assert.Equal(t, completionBefore+30, testutil.ToFloat64(completion))
+}
+
+func TestObserveStream(t *testing.T) {
+	chunks, errs, stutters := testutil.ToFloat64(streamChunksTotal), testutil.ToFloat64(streamErrorsTotal), testutil.ToFloat64(streamStutterEventsTotal)
+
+	ObserveStream(10, 1, 2)
+
+	assert.Equal(t, chunks+10, testutil.ToFloat64(streamChunksTotal))
+	assert.Equal(t, errs+1, testutil.ToFloat64(streamErrorsTotal))
+	assert.Equal(t, stutters+2, testutil.ToFloat64(streamStutterEventsTotal))
+}
+
+func TestObserveTokenRefresh(t *testing.T) {
+	success := tokenRefreshesTotal.WithLabelValues(RefreshResultSuccess)
+	failure := tokenRefreshesTotal.WithLabelValues(RefreshResultFailure)
+	successBefore, failureBefore := testutil.ToFloat64(success), testutil.ToFloat64(failure)
+
+	ObserveTokenRefresh(nil)
+	ObserveTokenRefresh(errors.New("invalid_grant"))
+
+	assert.Equal(t, successBefore+1, testutil.ToFloat64(success))
+	assert.Equal(t, failureBefore+1, testutil.ToFloat64(failure))
+}
+
+func TestObserveCache(t *testing.T) {
+	hits := cacheRequestsTotal.WithLabelValues("HIT")
+	before := testutil.ToFloat64(hits)
+
+	ObserveCache("HIT")
+
+	assert.Equal(t, before+1, testutil.ToFloat64(hits))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveUpstream("handler-model", 200, 300*time.Millisecond)
+	ObserveTimeToFirstToken("handler-model", 150*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, 200, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `qwen_proxy_upstream_request_duration_seconds_count{model="handler-model",status="200"} 1`)
+	assert.Contains(t, body, `qwen_proxy_stream_time_to_first_token_seconds_count{model="handler-model"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+)
+
+const (
+	APIKeyContextKey contextKey = "api_key"
+)
+
+// maxModelCheckBytes is how much of a request body is read to find the requested model
+var maxModelCheckBytes int64 = 32 << 20
+
+// errModelCheckTooLarge is returned when a request body is too large to find the requested model in
+var errModelCheckTooLarge = errors.New("request body too large to check the requested model")
+
+// APIKeyAuthenticator verifies plaintext API keys presented by clients
+type APIKeyAuthenticator interface {
+	Authenticate(rawKey string) (*entities.APIKey, error)
+}
+
+// APIKeyAuth rejects requests that do not carry a valid proxy API key and enforces the key's policy.
+// Keys are accepted as "Authorization: Bearer <key>" or in the "x-api-key" header used by Anthropic clients.
+// Requests without a model are checked against defaultModel, which is what the proxy will use for them.
+func APIKeyAuth(authenticator APIKeyAuthenticator, defaultModel string, logger logging.LoggerInterface) func(http.Handler) http.Handler {
+	limiter := &keyRateLimiter{buckets: make(map[string]*tokenBucket)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := GetRequestID(r.Context())
+
+			rawKey := extractAPIKey(r)
+			if rawKey == "" {
+				logger.Warn("Request rejected without API key", "request_id", requestID, "path", r.URL.Path)
+				writeOpenAIError(w, http.StatusUnauthorized, "authentication_error", "missing_api_key",
+					"You didn't provide an API key. You need to provide your API key in an Authorization header using Bearer auth (i.e. Authorization: Bearer YOUR_KEY).")
+				return
+			}
+
+			key, err := authenticator.Authenticate(rawKey)
+			if err != nil {
+				logger.Warn("Request rejected with invalid API key", "request_id", requestID, "path", r.URL.Path, "error", err)
+				writeOpenAIError(w, http.StatusUnauthorized, "authentication_error", "invalid_api_key",
+					fmt.Sprintf("Incorrect API key provided: %s (%v).", maskAPIKey(rawKey), err))
+				return
+			}
+
+			if key.RateLimit > 0 {
+				if retryAfter, allowed := limiter.allow(key.ID, key.RateLimit, time.Now()); !allowed {
+					logger.Warn("API key rate limit exceeded", "request_id", requestID, "key_id", key.ID)
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+					writeOpenAIError(w, http.StatusTooManyRequests, "rate_limit_error", "rate_limit_exceeded",
+						fmt.Sprintf("Rate limit of %d requests per minute exceeded for API key %s.", key.RateLimit, key.Prefix))
+					return
+				}
+			}
+
+			if len(key.AllowedModels) > 0 {
+				model, err := requestedModel(r)
+				if err != nil {
+					logger.Warn("Request body too large for the model check", "request_id", requestID, "key_id", key.ID)
+					writeOpenAIError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "request_too_large",
+						fmt.Sprintf("The request body exceeds %d bytes, the most that is read to check the model of a restricted API key.", maxModelCheckBytes))
+					return
+				}
+				if model == "" {
+					model = defaultModel
+				}
+				if !key.AllowsModel(model) {
+					logger.Warn("API key not allowed to use model", "request_id", requestID, "key_id", key.ID, "model", model)
+					writeOpenAIError(w, http.StatusForbidden, "invalid_request_error", "model_not_allowed",
+						fmt.Sprintf("The API key %s is not allowed to use model '%s'.", key.Prefix, model))
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), APIKeyContextKey, key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AdminAuth protects administrative endpoints with a static admin token sent as a Bearer token
+func AdminAuth(adminKey string, logger logging.LoggerInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := extractAPIKey(r)
+			if adminKey == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminKey)) != 1 {
+				logger.Warn("Admin request rejected", "request_id", GetRequestID(r.Context()), "path", r.URL.Path)
+				writeOpenAIError(w, http.StatusUnauthorized, "authentication_error", "invalid_admin_key",
+					"A valid admin key is required for this endpoint.")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetAPIKey retrieves the authenticated API key from context, or nil when API keys are not in use
+func GetAPIKey(ctx context.Context) *entities.APIKey {
+	if key, ok := ctx.Value(APIKeyContextKey).(*entities.APIKey); ok {
+		return key
+	}
+	return nil
+}
+
+// extractAPIKey reads a key from the Authorization Bearer header or the x-api-key header
+func extractAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("x-api-key"))
+}
+
+// requestedModel peeks at the JSON request body to find the requested model, restoring the body afterwards.
+// At most maxModelCheckBytes are read; larger bodies are reported with errModelCheckTooLarge, since the
+// model could be anywhere in them.
+func requestedModel(r *http.Request) (string, error) {
+	if r.Body == nil || r.Method != http.MethodPost {
+		return "", nil
+	}
+	bodyBytes, err := io.ReadAll(io.LimitReader(r.Body, maxModelCheckBytes+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(bodyBytes), r.Body), r.Body}
+	if err != nil {
+		return "", nil
+	}
+	if int64(len(bodyBytes)) > maxModelCheckBytes {
+		return "", errModelCheckTooLarge
+	}
+
+	var body struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return "", nil
+	}
+	return body.Model, nil
+}
+
+// maskAPIKey hides all but the first characters of a key for use in error messages
+func maskAPIKey(rawKey string) string {
+	if len(rawKey) <= 12 {
+		return "****"
+	}
+	return rawKey[:12] + "****"
+}
+
+// writeOpenAIError writes an error body in the OpenAI API error format
+func writeOpenAIError(w http.ResponseWriter, statusCode int, errorType, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errorType,
+			"param":   nil,
+			"code":    code,
+		},
+	})
+}
+
+// keyRateLimiter enforces each API key's requests per minute with a token bucket
+type keyRateLimiter struct {
+	buckets map[string]*tokenBucket
+	mu      sync.Mutex
+}
+
+// allow takes a request token for the key and reports whether it fits in the limit.
+// When the limit is exceeded it returns the time until a token is available.
+func (l *keyRateLimiter) allow(keyID string, limit int, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[keyID]
+	if !ok || bucket.capacity != float64(limit) {
+		bucket = newTokenBucket(float64(limit), float64(limit)/60, now)
+		l.buckets[keyID] = bucket
+	}
+	return bucket.take(1, now)
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Response cache statuses reported in the X-Cache header
+const (
+	CacheStatusHit     = "HIT"
+	CacheStatusMiss    = "MISS"
+	CacheStatusRefresh = "REFRESH"
+	CacheStatusBypass  = "BYPASS"
+
+	CacheStatusHeader = "X-Cache"
+
+	CacheControlKey contextKey = "cache_control"
+)
+
+// CacheControl carries the cache directives of a request to the response cache and
+// the cache status back to the client
+type CacheControl struct {
+	// NoCache skips the cache lookup but still stores the fresh response
+	NoCache bool
+	// NoStore neither reads from nor writes to the cache
+	NoStore bool
+
+	mu     sync.Mutex
+	status string
+}
+
+// SetStatus records the cache status reported in the X-Cache response header
+func (c *CacheControl) SetStatus(status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+}
+
+// Status returns the recorded cache status, or an empty string when the cache was not consulted
+func (c *CacheControl) Status() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// GetCacheControl returns the cache directives of the request in ctx.
+// Requests that did not pass through ResponseCache get default directives.
+func GetCacheControl(ctx context.Context) *CacheControl {
+	if control, ok := ctx.Value(CacheControlKey).(*CacheControl); ok {
+		return control
+	}
+	return &CacheControl{}
+}
+
+// parseCacheControl reads the no-cache and no-store directives from the request headers
+func parseCacheControl(r *http.Request) *CacheControl {
+	control := &CacheControl{}
+	for _, value := range r.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "no-cache":
+				control.NoCache = true
+			case "no-store":
+				control.NoStore = true
+			}
+		}
+	}
+	if strings.EqualFold(r.Header.Get("Pragma"), "no-cache") {
+		control.NoCache = true
+	}
+	return control
+}
+
+// cacheStatusWriter adds the X-Cache header before the response headers are written
+type cacheStatusWriter struct {
+	http.ResponseWriter
+	control     *CacheControl
+	wroteHeader bool
+}
+
+// WriteHeader sets the cache status header and writes the status code
+func (cw *cacheStatusWriter) WriteHeader(status int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		if cacheStatus := cw.control.Status(); cacheStatus != "" {
+			cw.ResponseWriter.Header().Set(CacheStatusHeader, cacheStatus)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the headers on the first write and forwards the data
+func (cw *cacheStatusWriter) Write(data []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// Flush implements the http.Flusher interface
+func (cw *cacheStatusWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// ResponseCache returns middleware that reads the Cache-Control and Pragma request headers for the
+// response cache and reports whether a response was served from cache in the X-Cache header
+func ResponseCache() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			control := parseCacheControl(r)
+			ctx := context.WithValue(r.Context(), CacheControlKey, control)
+			next.ServeHTTP(&cacheStatusWriter{ResponseWriter: w, control: control}, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		noCache bool
+		noStore bool
+	}{
+		{"no headers", nil, false, false},
+		{"no-cache", map[string]string{"Cache-Control": "no-cache"}, true, false},
+		{"no-store", map[string]string{"Cache-Control": "no-store"}, false, true},
+		{"multiple directives", map[string]string{"Cache-Control": "max-age=0, No-Cache ,no-store"}, true, true},
+		{"pragma", map[string]string{"Pragma": "no-cache"}, true, false},
+		{"unrelated directive", map[string]string{"Cache-Control": "max-age=60"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			control := parseCacheControl(req)
+			assert.Equal(t, tt.noCache, control.NoCache)
+			assert.Equal(t, tt.noStore, control.NoStore)
+		})
+	}
+}
+
+func TestGetCacheControl_Default(t *testing.T) {
+	control := GetCacheControl(context.Background())
+	require.NotNil(t, control)
+	assert.False(t, control.NoCache)
+	assert.False(t, control.NoStore)
+	assert.Empty(t, control.Status())
+}
+
+func TestResponseCache_SetsStatusHeader(t *testing.T) {
+	handler := ResponseCache()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		control := GetCacheControl(r.Context())
+		assert.True(t, control.NoCache)
+		control.SetStatus(CacheStatusRefresh)
+		w.Write([]byte("{}"))
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("Cache-Control", "no-cache")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, CacheStatusRefresh, rec.Header().Get(CacheStatusHeader))
+}
+
+func TestResponseCache_NoStatusWhenCacheNotConsulted(t *testing.T) {
+	handler := ResponseCache()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get(CacheStatusHeader))
+}
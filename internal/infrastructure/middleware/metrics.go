@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"qwen-go-proxy/internal/infrastructure/metrics"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// routeNotFound labels requests that did not match any route, keeping the route label bounded
+const routeNotFound = "not_found"
+
+// statusRecorder captures the status code written by a handler while keeping streaming support
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Write records an implicit 200 status when the handler writes without calling WriteHeader
+func (sr *statusRecorder) Write(data []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(data)
+}
+
+// Flush implements the http.Flusher interface
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := sr.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("underlying response writer does not implement http.Hijacker")
+}
+
+// statusCode returns the recorded status code, which is 200 when the handler wrote nothing
+func (sr *statusRecorder) statusCode() int {
+	if sr.status == 0 {
+		return http.StatusOK
+	}
+	return sr.status
+}
+
+// routePattern returns the matched route pattern of a routed request, keeping labels bounded
+func routePattern(r *http.Request) string {
+	if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+		return routeCtx.RoutePattern()
+	}
+	return routeNotFound
+}
+
+// otherModel labels requests served by models outside the catalog, keeping the model label bounded
+const otherModel = "other"
+
+// Metrics returns middleware that counts requests by route, model and status code, and adds the
+// token usage reported by handlers through RecordUsage to the token counters.
+// Requests are labeled with the model that served them, as recorded in their ModelReport, rather
+// than with the model the client asked for: requests rejected before reaching the proxy, such as
+// unauthenticated or rate limited ones, have no model label, and models for which catalogModel
+// returns false are labeled "other".
+func Metrics(catalogModel func(model string) bool) func(http.Handler) http.Handler {
+	if catalogModel == nil {
+		panic("catalogModel cannot be nil")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// ModelReporting reuses this report, so that the model it records is seen here
+			report := &ModelReport{}
+			r = r.WithContext(context.WithValue(r.Context(), ModelReportKey, report))
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			r, usage := withUsageRecorder(r)
+			next.ServeHTTP(recorder, r)
+
+			model := report.Model()
+			if model != "" && !catalogModel(model) {
+				model = otherModel
+			}
+			metrics.ObserveRequest(routePattern(r), model, recorder.statusCode())
+			metrics.AddUsage(model, usage.Usage())
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestMetrics_CountsRequestsAndTokens(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Metrics(func(model string) bool { return model == "metrics-model" }))
+	router.Use(ModelReporting())
+	router.Post("/v1/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// The proxy reports the model that served the request, whatever the client asked for
+		GetModelReport(r.Context()).SetModel(r.URL.Query().Get("served"))
+		RecordUsage(r.Context(), &entities.Usage{PromptTokens: 5, CompletionTokens: 7, TotalTokens: 12})
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/items/42?served=metrics-model", strings.NewReader(`{"model":"metrics-alias"}`)))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "metrics-model", rec.Header().Get(ModelUsedHeader))
+
+	// Models outside the catalog share one label
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/items/43?served=metrics-unknown-model", strings.NewReader(`{}`)))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unknown-metrics-path", nil))
+
+	body := scrapeMetrics(t)
+	// The route pattern is used as the label rather than the raw path
+	assert.Contains(t, body, `qwen_proxy_requests_total{model="metrics-model",route="/v1/items/{id}",status="418"} 1`)
+	assert.Contains(t, body, `qwen_proxy_requests_total{model="other",route="/v1/items/{id}",status="418"}`)
+	assert.Contains(t, body, `qwen_proxy_requests_total{model="",route="not_found",status="404"}`)
+	assert.Contains(t, body, `qwen_proxy_tokens_total{model="metrics-model",type="prompt"} 5`)
+	assert.Contains(t, body, `qwen_proxy_tokens_total{model="metrics-model",type="completion"} 7`)
+	assert.NotContains(t, body, `metrics-alias`)
+	assert.NotContains(t, body, `metrics-unknown-model`)
+}
+
+func TestMetrics_RejectedRequestsHaveNoModel(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Metrics(func(string) bool { return true }))
+	router.Route("/v1", func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			})
+		})
+		r.Use(ModelReporting())
+		r.Post("/rejected", func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("rejected requests must not reach the handler")
+		})
+	})
+
+	// The model requested by an unauthenticated client does not create a series
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/rejected", strings.NewReader(`{"model":"metrics-attacker-model"}`)))
+
+	body := scrapeMetrics(t)
+	assert.Contains(t, body, `qwen_proxy_requests_total{model="",route="/v1/*",status="401"} 1`)
+	assert.NotContains(t, body, `metrics-attacker-model`)
+}
+
+func TestMetrics_NilCatalog(t *testing.T) {
+	assert.PanicsWithValue(t, "catalogModel cannot be nil", func() { Metrics(nil) })
+}
+
+func TestStatusRecorder(t *testing.T) {
+	rec := httptest.NewRecorder()
+	recorder := &statusRecorder{ResponseWriter: rec}
+
+	// An implicit 200 is recorded on the first write, and later status codes are ignored
+	recorder.Write([]byte("data"))
+	recorder.WriteHeader(http.StatusInternalServerError)
+	recorder.Flush()
+
+	assert.Equal(t, http.StatusOK, recorder.status)
+	assert.True(t, rec.Flushed)
+}
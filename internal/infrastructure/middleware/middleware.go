@@ -12,7 +12,6 @@ import (
 	"net"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"qwen-go-proxy/internal/infrastructure/logging"
@@ -188,105 +187,13 @@ func GetRequestID(ctx context.Context) string {
 	return "unknown"
 }
 
-// RateLimit implements rate limiting middleware with improved concurrency and cleanup
-func RateLimit(requestsPerSecond int, burst int) func(http.Handler) http.Handler {
-	// Use sync.Map for concurrent access without global mutex bottleneck
-	var requestCounts sync.Map
-
-	// Start cleanup goroutine to prevent memory leaks
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute) // Cleanup every 5 minutes
-		defer ticker.Stop()
-
-		for range ticker.C {
-			now := time.Now()
-			cutoff := now.Add(-10 * time.Minute) // Remove entries older than 10 minutes
-
-			requestCounts.Range(func(key, value interface{}) bool {
-				tracker := value.(*RequestTracker)
-				tracker.Mu.Lock()
-				// Remove tracker if no recent requests
-				if len(tracker.Requests) == 0 ||
-					(len(tracker.Requests) > 0 && tracker.Requests[len(tracker.Requests)-1].Before(cutoff)) {
-					tracker.Mu.Unlock()
-					requestCounts.Delete(key)
-				} else {
-					tracker.Mu.Unlock()
-				}
-				return true
-			})
-		}
-	}()
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			clientIP := getClientIP(r)
-			now := time.Now()
-
-			// Get or create tracker for this IP
-			trackerValue, _ := requestCounts.LoadOrStore(clientIP, &RequestTracker{
-				Requests: make([]time.Time, 0, burst),
-				Mu:       &sync.Mutex{},
-			})
-			tracker := trackerValue.(*RequestTracker)
-
-			tracker.Mu.Lock()
-
-			// Remove requests older than 1 second (sliding window)
-			cutoff := now.Add(-time.Second)
-			validRequests := make([]time.Time, 0, len(tracker.Requests))
-			for _, reqTime := range tracker.Requests {
-				if reqTime.After(cutoff) {
-					validRequests = append(validRequests, reqTime)
-				}
-			}
-			tracker.Requests = validRequests
-
-			// Check if we've exceeded the rate limit
-			if len(tracker.Requests) >= requestsPerSecond {
-				tracker.Mu.Unlock()
-				w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", requestsPerSecond))
-				w.Header().Set("X-RateLimit-Remaining", "0")
-				w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(time.Second).Unix()))
-				w.Header().Set("Retry-After", "1")
-
-				w.WriteHeader(http.StatusTooManyRequests)
-				w.Write([]byte(`{"error": {"message": "Rate limit exceeded", "type": "rate_limit_error", "code": "rate_limit_exceeded"}}`))
-				return
-			}
-
-			// Add current request
-			tracker.Requests = append(tracker.Requests, now)
-			remaining := requestsPerSecond - len(tracker.Requests)
-			if remaining < 0 {
-				remaining = 0
-			}
-
-			tracker.Mu.Unlock()
-
-			// Set rate limit headers
-			w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", requestsPerSecond))
-			w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-			w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(time.Second).Unix()))
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
-// RequestTracker holds request times and mutex for a specific IP
-type RequestTracker struct {
-	Requests []time.Time
-	Mu       *sync.Mutex
-}
-
 // CORS returns CORS middleware
 func CORS() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept, X-API-Key")
 			w.Header().Set("Access-Control-Max-Age", "86400")
 
 			if r.Method == "OPTIONS" {
@@ -233,7 +233,7 @@ func TestCORS(t *testing.T) {
 	// Check CORS headers
 	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
 	assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", rec.Header().Get("Access-Control-Allow-Methods"))
-	assert.Equal(t, "Content-Type, Authorization, Accept", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "Content-Type, Authorization, Accept, X-API-Key", rec.Header().Get("Access-Control-Allow-Headers"))
 	assert.Equal(t, "86400", rec.Header().Get("Access-Control-Max-Age"))
 }
 
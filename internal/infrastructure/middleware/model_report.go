@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+const (
+	// ModelUsedHeader reports the upstream model that served a request
+	ModelUsedHeader = "X-Model-Used"
+	// ContextElidedHeader reports how many messages were elided to fit the context window
+	ContextElidedHeader = "X-Context-Elided"
+
+	ModelReportKey contextKey = "model_report"
+)
+
+// ModelReport carries the upstream model that served a request, and the number of messages
+// elided to fit its context window, back to the client.
+// When a request falls back to another model, the last model tried is reported.
+type ModelReport struct {
+	mu     sync.Mutex
+	model  string
+	elided int
+}
+
+// SetModel records the model reported in the X-Model-Used response header
+func (m *ModelReport) SetModel(model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.model = model
+}
+
+// Model returns the recorded model, or an empty string when no upstream request was made
+func (m *ModelReport) Model() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.model
+}
+
+// SetElidedMessages records the number of messages reported in the X-Context-Elided response header
+func (m *ModelReport) SetElidedMessages(elided int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.elided = elided
+}
+
+// ElidedMessages returns the recorded number of elided messages
+func (m *ModelReport) ElidedMessages() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.elided
+}
+
+// GetModelReport returns the model report of the request in ctx.
+// Requests that did not pass through ModelReporting get a report that is never sent.
+func GetModelReport(ctx context.Context) *ModelReport {
+	if report, ok := ctx.Value(ModelReportKey).(*ModelReport); ok {
+		return report
+	}
+	return &ModelReport{}
+}
+
+// modelReportWriter adds the X-Model-Used and X-Context-Elided headers before the response headers are written
+type modelReportWriter struct {
+	http.ResponseWriter
+	report      *ModelReport
+	wroteHeader bool
+}
+
+// WriteHeader sets the report headers and writes the status code
+func (mw *modelReportWriter) WriteHeader(status int) {
+	if !mw.wroteHeader {
+		mw.wroteHeader = true
+		if model := mw.report.Model(); model != "" {
+			mw.ResponseWriter.Header().Set(ModelUsedHeader, model)
+		}
+		if elided := mw.report.ElidedMessages(); elided > 0 {
+			mw.ResponseWriter.Header().Set(ContextElidedHeader, strconv.Itoa(elided))
+		}
+	}
+	mw.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the headers on the first write and forwards the data
+func (mw *modelReportWriter) Write(data []byte) (int, error) {
+	if !mw.wroteHeader {
+		mw.WriteHeader(http.StatusOK)
+	}
+	return mw.ResponseWriter.Write(data)
+}
+
+// Flush implements the http.Flusher interface
+func (mw *modelReportWriter) Flush() {
+	if flusher, ok := mw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// ModelReporting returns middleware that reports the upstream model that served a request
+// in the X-Model-Used response header, and the messages elided from it in X-Context-Elided
+func ModelReporting() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reuse the report created by Metrics, which labels the request with the reported model
+			report, ok := r.Context().Value(ModelReportKey).(*ModelReport)
+			if !ok {
+				report = &ModelReport{}
+				r = r.WithContext(context.WithValue(r.Context(), ModelReportKey, report))
+			}
+			next.ServeHTTP(&modelReportWriter{ResponseWriter: w, report: report}, r)
+		})
+	}
+}
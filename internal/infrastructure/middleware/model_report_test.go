@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelReporting_SetsHeader(t *testing.T) {
+	handler := ModelReporting()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := GetModelReport(r.Context())
+		report.SetModel("qwen3-coder-plus")
+		// A later fallback replaces the reported model
+		report.SetModel("qwen3-coder-flash")
+		w.Write([]byte("{}"))
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+	assert.Equal(t, "qwen3-coder-flash", recorder.Header().Get(ModelUsedHeader))
+}
+
+func TestModelReporting_ElidedMessages(t *testing.T) {
+	handler := ModelReporting()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetModelReport(r.Context()).SetElidedMessages(3)
+		w.Write([]byte("{}"))
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+	assert.Equal(t, "3", recorder.Header().Get(ContextElidedHeader))
+}
+
+func TestModelReporting_NoModel(t *testing.T) {
+	handler := ModelReporting()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v1/models/unknown", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Empty(t, recorder.Header().Get(ModelUsedHeader))
+	assert.Empty(t, recorder.Header().Get(ContextElidedHeader))
+}
+
+func TestGetModelReport_WithoutMiddleware(t *testing.T) {
+	report := GetModelReport(context.Background())
+	report.SetModel("qwen3-coder-plus")
+	assert.Equal(t, "qwen3-coder-plus", report.Model())
+}
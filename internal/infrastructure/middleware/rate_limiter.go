@@ -0,0 +1,335 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+)
+
+// Rate limit key types
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByAPIKey = "api_key"
+	RateLimitByModel  = "model"
+
+	UsageRecorderKey contextKey = "usage_recorder"
+
+	// limiterIdleTimeout is how long an unused limiter entry is kept before it is evicted
+	limiterIdleTimeout = 10 * time.Minute
+)
+
+// RateLimitConfig configures a token bucket rate limiter
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained request rate; Burst is the bucket size
+	RequestsPerSecond float64
+	Burst             int
+	// PromptTokensPerMinute and CompletionTokensPerMinute limit LLM token usage; 0 disables the limit
+	PromptTokensPerMinute     int
+	CompletionTokensPerMinute int
+	// KeyBy selects what limits are tracked per: ip, api_key or model
+	KeyBy string
+	// DefaultModel is used as the key for requests without a model when keyed by model
+	DefaultModel string
+}
+
+// tokenBucket is a classic token bucket refilled continuously at a fixed rate.
+// The balance may go negative when usage is charged after the fact, which delays later requests.
+type tokenBucket struct {
+	capacity float64
+	rate     float64 // tokens added per second
+	tokens   float64
+	last     time.Time
+}
+
+// newTokenBucket creates a full bucket
+func newTokenBucket(capacity, ratePerSecond float64, now time.Time) *tokenBucket {
+	return &tokenBucket{capacity: capacity, rate: ratePerSecond, tokens: capacity, last: now}
+}
+
+// refill adds the tokens accumulated since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns how long until the bucket holds at least n tokens
+func (b *tokenBucket) wait(n float64) time.Duration {
+	if b.tokens >= n || b.rate <= 0 {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// take removes n tokens if available, otherwise it reports how long to wait
+func (b *tokenBucket) take(n float64, now time.Time) (time.Duration, bool) {
+	b.refill(now)
+	if b.tokens < n {
+		return b.wait(n), false
+	}
+	b.tokens -= n
+	return 0, true
+}
+
+// charge removes n tokens unconditionally, allowing the balance to go negative
+func (b *tokenBucket) charge(n float64, now time.Time) {
+	b.refill(now)
+	b.tokens -= n
+}
+
+// remaining returns the whole tokens currently available
+func (b *tokenBucket) remaining() int {
+	if b.tokens < 0 {
+		return 0
+	}
+	return int(b.tokens)
+}
+
+// resetAt returns when the bucket will be full again
+func (b *tokenBucket) resetAt(now time.Time) time.Time {
+	if b.rate <= 0 {
+		return now
+	}
+	return now.Add(time.Duration((b.capacity - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// limiterEntry holds the buckets for a single rate limit key
+type limiterEntry struct {
+	requests         *tokenBucket
+	promptTokens     *tokenBucket
+	completionTokens *tokenBucket
+	lastSeen         time.Time
+}
+
+// RateLimiter enforces request and token budgets with token buckets keyed by IP, API key or model
+type RateLimiter struct {
+	config    RateLimitConfig
+	entries   map[string]*limiterEntry
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewRateLimiter creates a new token bucket rate limiter
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.Burst <= 0 {
+		config.Burst = int(math.Max(1, math.Ceil(config.RequestsPerSecond)))
+	}
+	if config.KeyBy == "" {
+		config.KeyBy = RateLimitByIP
+	}
+	return &RateLimiter{
+		config:    config,
+		entries:   make(map[string]*limiterEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// RateLimit returns per-IP request rate limiting middleware using a token bucket
+func RateLimit(requestsPerSecond int, burst int) func(http.Handler) http.Handler {
+	return NewRateLimiter(RateLimitConfig{
+		RequestsPerSecond: float64(requestsPerSecond),
+		Burst:             burst,
+		KeyBy:             RateLimitByIP,
+	}).Middleware()
+}
+
+// Middleware returns the HTTP middleware for this limiter.
+// Token usage recorded by handlers through RecordUsage is charged once the handler returns.
+func (l *RateLimiter) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := l.keyFor(r)
+			now := time.Now()
+
+			l.mu.Lock()
+			entry := l.entryFor(key, now)
+			retryAfter, allowed := l.admit(entry, now)
+			l.setHeaders(w, entry, now)
+			l.mu.Unlock()
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				writeOpenAIError(w, http.StatusTooManyRequests, "rate_limit_error", "rate_limit_exceeded",
+					fmt.Sprintf("Rate limit exceeded. Please retry after %d seconds.", int(math.Ceil(retryAfter.Seconds()))))
+				return
+			}
+
+			if !l.tracksTokens() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r, recorder := withUsageRecorder(r)
+			next.ServeHTTP(w, r)
+
+			if usage := recorder.Usage(); usage != nil {
+				l.mu.Lock()
+				l.chargeUsage(entry, usage, time.Now())
+				l.mu.Unlock()
+			}
+		})
+	}
+}
+
+// tracksTokens reports whether any token per minute limit is configured
+func (l *RateLimiter) tracksTokens() bool {
+	return l.config.PromptTokensPerMinute > 0 || l.config.CompletionTokensPerMinute > 0
+}
+
+// keyFor derives the rate limit key for a request
+func (l *RateLimiter) keyFor(r *http.Request) string {
+	switch l.config.KeyBy {
+	case RateLimitByAPIKey:
+		if key := GetAPIKey(r.Context()); key != nil {
+			return "key:" + key.ID
+		}
+	case RateLimitByModel:
+		// Bodies too large to find the model in are counted against the default model
+		model, _ := requestedModel(r)
+		if model == "" {
+			model = l.config.DefaultModel
+		}
+		return "model:" + model
+	}
+	return "ip:" + getClientIP(r)
+}
+
+// entryFor returns the buckets for a key, creating them on first use; callers must hold the mutex
+func (l *RateLimiter) entryFor(key string, now time.Time) *limiterEntry {
+	if now.Sub(l.lastSweep) > limiterIdleTimeout/2 {
+		for k, e := range l.entries {
+			if now.Sub(e.lastSeen) > limiterIdleTimeout {
+				delete(l.entries, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &limiterEntry{
+			requests: newTokenBucket(float64(l.config.Burst), l.config.RequestsPerSecond, now),
+		}
+		if tpm := l.config.PromptTokensPerMinute; tpm > 0 {
+			entry.promptTokens = newTokenBucket(float64(tpm), float64(tpm)/60, now)
+		}
+		if tpm := l.config.CompletionTokensPerMinute; tpm > 0 {
+			entry.completionTokens = newTokenBucket(float64(tpm), float64(tpm)/60, now)
+		}
+		l.entries[key] = entry
+	}
+	entry.lastSeen = now
+	return entry
+}
+
+// admit checks the token budgets and takes a request token; callers must hold the mutex.
+// A request is only admitted while the token buckets are not in debt from earlier usage.
+func (l *RateLimiter) admit(entry *limiterEntry, now time.Time) (time.Duration, bool) {
+	var wait time.Duration
+	for _, bucket := range []*tokenBucket{entry.promptTokens, entry.completionTokens} {
+		if bucket == nil {
+			continue
+		}
+		bucket.refill(now)
+		if w := bucket.wait(1); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return wait, false
+	}
+	return entry.requests.take(1, now)
+}
+
+// chargeUsage deducts the tokens used by a completed request; callers must hold the mutex
+func (l *RateLimiter) chargeUsage(entry *limiterEntry, usage *entities.Usage, now time.Time) {
+	if entry.promptTokens != nil {
+		entry.promptTokens.charge(float64(usage.PromptTokens), now)
+	}
+	if entry.completionTokens != nil {
+		entry.completionTokens.charge(float64(usage.CompletionTokens), now)
+	}
+}
+
+// setHeaders writes the X-RateLimit-* headers for the current bucket state; callers must hold the mutex
+func (l *RateLimiter) setHeaders(w http.ResponseWriter, entry *limiterEntry, now time.Time) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(l.config.Burst))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(entry.requests.remaining()))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(unixCeil(entry.requests.resetAt(now)), 10))
+
+	if entry.promptTokens != nil {
+		w.Header().Set("X-RateLimit-Limit-Prompt-Tokens", strconv.Itoa(l.config.PromptTokensPerMinute))
+		w.Header().Set("X-RateLimit-Remaining-Prompt-Tokens", strconv.Itoa(entry.promptTokens.remaining()))
+		w.Header().Set("X-RateLimit-Reset-Prompt-Tokens", strconv.FormatInt(unixCeil(entry.promptTokens.resetAt(now)), 10))
+	}
+	if entry.completionTokens != nil {
+		w.Header().Set("X-RateLimit-Limit-Completion-Tokens", strconv.Itoa(l.config.CompletionTokensPerMinute))
+		w.Header().Set("X-RateLimit-Remaining-Completion-Tokens", strconv.Itoa(entry.completionTokens.remaining()))
+		w.Header().Set("X-RateLimit-Reset-Completion-Tokens", strconv.FormatInt(unixCeil(entry.completionTokens.resetAt(now)), 10))
+	}
+}
+
+// unixCeil returns the Unix timestamp of t rounded up to the next whole second
+func unixCeil(t time.Time) int64 {
+	if t.Nanosecond() > 0 {
+		return t.Unix() + 1
+	}
+	return t.Unix()
+}
+
+// UsageRecorder collects the token usage reported by a handler for the current request
+type UsageRecorder struct {
+	usage *entities.Usage
+	mu    sync.Mutex
+}
+
+// Record adds token usage to the recorder
+func (u *UsageRecorder) Record(usage *entities.Usage) {
+	if u == nil || usage == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.usage == nil {
+		u.usage = &entities.Usage{}
+	}
+	u.usage.PromptTokens += usage.PromptTokens
+	u.usage.CompletionTokens += usage.CompletionTokens
+	u.usage.TotalTokens += usage.TotalTokens
+}
+
+// Usage returns the recorded usage, or nil when nothing was recorded
+func (u *UsageRecorder) Usage() *entities.Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.usage == nil {
+		return nil
+	}
+	usage := *u.usage
+	return &usage
+}
+
+// withUsageRecorder attaches a usage recorder to the request, reusing one set by an outer middleware
+func withUsageRecorder(r *http.Request) (*http.Request, *UsageRecorder) {
+	if recorder, ok := r.Context().Value(UsageRecorderKey).(*UsageRecorder); ok {
+		return r, recorder
+	}
+	recorder := &UsageRecorder{}
+	return r.WithContext(context.WithValue(r.Context(), UsageRecorderKey, recorder)), recorder
+}
+
+// RecordUsage reports the token usage of the current request to the rate limiter, if one is tracking tokens
+func RecordUsage(ctx context.Context, usage *entities.Usage) {
+	if recorder, ok := ctx.Value(UsageRecorderKey).(*UsageRecorder); ok {
+		recorder.Record(usage)
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(2, 1, now)
+
+	_, ok := bucket.take(1, now)
+	assert.True(t, ok)
+	_, ok = bucket.take(1, now)
+	assert.True(t, ok)
+
+	wait, ok := bucket.take(1, now)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	// Tokens refill at the configured rate without exceeding capacity
+	bucket.refill(now.Add(10 * time.Second))
+	assert.Equal(t, 2, bucket.remaining())
+
+	// Charging may push the balance into debt
+	bucket.charge(5, now.Add(10*time.Second))
+	assert.Equal(t, 0, bucket.remaining())
+	assert.Equal(t, 4*time.Second, bucket.wait(1))
+}
+
+func TestRateLimiter_HonoursBurst(t *testing.T) {
+	handler := NewRateLimiter(RateLimitConfig{RequestsPerSecond: 1, Burst: 3}).Middleware()(okHandler())
+
+	for i := 0; i < 3; i++ {
+		rec := serve(handler, httptest.NewRequest("GET", "/test", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(2-i), rec.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	rec := serve(handler, httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(3*time.Second).Unix(), reset, 1)
+}
+
+func TestRateLimiter_KeyedByIP(t *testing.T) {
+	handler := NewRateLimiter(RateLimitConfig{RequestsPerSecond: 1, Burst: 1}).Middleware()(okHandler())
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, http.StatusOK, serve(handler, req).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(handler, req).Code)
+
+	other := httptest.NewRequest("GET", "/test", nil)
+	other.RemoteAddr = "10.0.0.2:1234"
+	assert.Equal(t, http.StatusOK, serve(handler, other).Code)
+}
+
+func TestRateLimiter_KeyedByAPIKey(t *testing.T) {
+	handler := NewRateLimiter(RateLimitConfig{RequestsPerSecond: 1, Burst: 1, KeyBy: RateLimitByAPIKey}).Middleware()(okHandler())
+
+	withKey := func(id string) *http.Request {
+		req := httptest.NewRequest("GET", "/test", nil)
+		return req.WithContext(context.WithValue(req.Context(), APIKeyContextKey, &entities.APIKey{ID: id}))
+	}
+
+	assert.Equal(t, http.StatusOK, serve(handler, withKey("key_a")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(handler, withKey("key_a")).Code)
+	// Different keys from the same IP have separate budgets
+	assert.Equal(t, http.StatusOK, serve(handler, withKey("key_b")).Code)
+}
+
+func TestRateLimiter_KeyedByModel(t *testing.T) {
+	handler := NewRateLimiter(RateLimitConfig{
+		RequestsPerSecond: 1,
+		Burst:             1,
+		KeyBy:             RateLimitByModel,
+		DefaultModel:      "qwen3-coder-plus",
+	}).Middleware()(okHandler())
+
+	post := func(body string) *http.Request {
+		return httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+	}
+
+	assert.Equal(t, http.StatusOK, serve(handler, post(`{"model":"qwen3-coder-flash"}`)).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, post(`{"model":"qwen3-coder-plus"}`)).Code)
+	// A request without a model counts against the default model
+	assert.Equal(t, http.StatusTooManyRequests, serve(handler, post(`{}`)).Code)
+}
+
+func TestRateLimiter_TokensPerMinute(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{
+		RequestsPerSecond:         100,
+		Burst:                     100,
+		PromptTokensPerMinute:     600,
+		CompletionTokensPerMinute: 60,
+	})
+	handler := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RecordUsage(r.Context(), &entities.Usage{PromptTokens: 100, CompletionTokens: 120, TotalTokens: 220})
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := serve(handler, httptest.NewRequest("POST", "/test", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "600", rec.Header().Get("X-RateLimit-Limit-Prompt-Tokens"))
+	assert.Equal(t, "600", rec.Header().Get("X-RateLimit-Remaining-Prompt-Tokens"))
+	assert.Equal(t, "60", rec.Header().Get("X-RateLimit-Limit-Completion-Tokens"))
+
+	// The completion budget is now 60 tokens in debt, so the next request must wait about a minute
+	rec = serve(handler, httptest.NewRequest("POST", "/test", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining-Completion-Tokens"))
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.InDelta(t, 61, retryAfter, 1)
+}
+
+func TestRecordUsage_WithoutRecorder(t *testing.T) {
+	// Recording usage outside a token limited request is a no-op
+	assert.NotPanics(t, func() {
+		RecordUsage(context.Background(), &entities.Usage{PromptTokens: 1})
+	})
+}
+
+func TestUsageRecorder_Accumulates(t *testing.T) {
+	recorder := &UsageRecorder{}
+	assert.Nil(t, recorder.Usage())
+
+	recorder.Record(&entities.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3})
+	recorder.Record(&entities.Usage{PromptTokens: 4, CompletionTokens: 5, TotalTokens: 9})
+	recorder.Record(nil)
+
+	assert.Equal(t, &entities.Usage{PromptTokens: 5, CompletionTokens: 7, TotalTokens: 12}, recorder.Usage())
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultAccountName is the pool name of the account stored directly in the Qwen directory
+const DefaultAccountName = "default"
+
+// AccountDirectory describes a Qwen account whose credentials are stored on disk
+type AccountDirectory struct {
+	Name string
+	// Dir is the account's credential directory, relative to the working directory like QWEN_DIR
+	Dir string
+}
+
+// DiscoverAccounts lists the accounts that have stored credentials.
+// The credentials in qwenDir itself form the "default" account, and every
+// qwenDir/accounts/<name>/oauth_creds.json adds a named account.
+func DiscoverAccounts(qwenDir string) ([]AccountDirectory, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	var accounts []AccountDirectory
+	if hasCredentialsFile(filepath.Join(workDir, qwenDir)) {
+		accounts = append(accounts, AccountDirectory{Name: DefaultAccountName, Dir: qwenDir})
+	}
+
+	accountsDir := filepath.Join(qwenDir, "accounts")
+	entries, err := os.ReadDir(filepath.Join(workDir, accountsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return accounts, nil
+		}
+		return nil, fmt.Errorf("failed to read accounts directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != DefaultAccountName && hasCredentialsFile(filepath.Join(workDir, accountsDir, entry.Name())) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		accounts = append(accounts, AccountDirectory{Name: name, Dir: filepath.Join(accountsDir, name)})
+	}
+	return accounts, nil
+}
+
+// hasCredentialsFile reports whether a directory contains an OAuth credentials file
+func hasCredentialsFile(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, "oauth_creds.json"))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCredentialsFile(t *testing.T, dir string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "oauth_creds.json"), []byte(`{"access_token":"token"}`), 0600))
+}
+
+func TestDiscoverAccounts(t *testing.T) {
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+
+	writeCredentialsFile(t, filepath.Join(workDir, ".qwen"))
+	writeCredentialsFile(t, filepath.Join(workDir, ".qwen", "accounts", "work"))
+	writeCredentialsFile(t, filepath.Join(workDir, ".qwen", "accounts", "backup"))
+	// Account directories without credentials are ignored
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, ".qwen", "accounts", "empty"), 0700))
+
+	accounts, err := DiscoverAccounts(".qwen")
+	require.NoError(t, err)
+	assert.Equal(t, []AccountDirectory{
+		{Name: "default", Dir: ".qwen"},
+		{Name: "backup", Dir: filepath.Join(".qwen", "accounts", "backup")},
+		{Name: "work", Dir: filepath.Join(".qwen", "accounts", "work")},
+	}, accounts)
+}
+
+func TestDiscoverAccounts_NoAccounts(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	accounts, err := DiscoverAccounts(".qwen")
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+}
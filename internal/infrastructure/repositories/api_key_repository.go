@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// FileAPIKeyRepository implements APIKeyRepository using file storage.
+// All keys are stored together in a single JSON file readable only by the owner.
+type FileAPIKeyRepository struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewFileAPIKeyRepository creates a new file-based API key repository.
+// Keys are stored in api_keys.json inside the provided directory.
+func NewFileAPIKeyRepository(qwenDir string) interfaces.APIKeyRepository {
+	// Use current working directory as base path
+	workDir, err := os.Getwd()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to get current working directory: %v", err))
+	}
+	return &FileAPIKeyRepository{
+		filePath: filepath.Join(workDir, qwenDir, "api_keys.json"),
+	}
+}
+
+// List loads all API keys from file. A missing file means no keys have been created yet.
+func (r *FileAPIKeyRepository) List() ([]*entities.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.load()
+}
+
+// Save adds or replaces an API key and writes the whole key set back to file.
+func (r *FileAPIKeyRepository) Save(key *entities.APIKey) error {
+	if key == nil {
+		return fmt.Errorf("api key cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, existing := range keys {
+		if existing.ID == key.ID {
+			keys[i] = key
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		keys = append(keys, key)
+	}
+
+	dir := filepath.Dir(r.filePath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal api keys: %w", err)
+	}
+
+	return os.WriteFile(r.filePath, data, 0600)
+}
+
+// load reads the key file; callers must hold the mutex
+func (r *FileAPIKeyRepository) load() ([]*entities.APIKey, error) {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*entities.APIKey{}, nil
+		}
+		return nil, fmt.Errorf("failed to read api keys: %w", err)
+	}
+
+	var keys []*entities.APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys: %w", err)
+	}
+	return keys, nil
+}
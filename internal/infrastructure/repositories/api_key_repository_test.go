@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFileAPIKeyRepository(t *testing.T) {
+	repo := NewFileAPIKeyRepository(".qwen-test")
+	assert.NotNil(t, repo)
+}
+
+func TestFileAPIKeyRepository_List_Empty(t *testing.T) {
+	repo := &FileAPIKeyRepository{
+		filePath: filepath.Join(t.TempDir(), "api_keys.json"),
+	}
+
+	keys, err := repo.List()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestFileAPIKeyRepository_Save_List(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := &FileAPIKeyRepository{
+		filePath: filepath.Join(tempDir, ".qwen", "api_keys.json"),
+	}
+
+	key := &entities.APIKey{
+		ID:            "key_1",
+		Label:         "ci",
+		Hash:          "abc",
+		AllowedModels: []string{"qwen3-coder-plus"},
+		RateLimit:     60,
+		CreatedAt:     time.Unix(1700000000, 0).UTC(),
+	}
+	require.NoError(t, repo.Save(key))
+	require.NoError(t, repo.Save(&entities.APIKey{ID: "key_2", Label: "dev", Hash: "def"}))
+
+	info, err := os.Stat(repo.filePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Saving an existing ID replaces it instead of appending
+	revokedAt := time.Unix(1700000100, 0).UTC()
+	key.RevokedAt = &revokedAt
+	require.NoError(t, repo.Save(key))
+
+	keys, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "key_1", keys[0].ID)
+	assert.Equal(t, []string{"qwen3-coder-plus"}, keys[0].AllowedModels)
+	assert.True(t, keys[0].IsRevoked())
+	assert.Equal(t, "key_2", keys[1].ID)
+}
+
+func TestFileAPIKeyRepository_List_InvalidJSON(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "api_keys.json")
+	require.NoError(t, os.WriteFile(filePath, []byte("not json"), 0600))
+
+	repo := &FileAPIKeyRepository{filePath: filePath}
+	_, err := repo.List()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse api keys")
+}
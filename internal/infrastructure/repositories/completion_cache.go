@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// validCacheKey restricts cache keys to hex digests so that they are safe to use as file names
+var validCacheKey = regexp.MustCompile(`^[a-f0-9]{16,128}$`)
+
+// cacheFileExtension is the extension of cache entry files
+const cacheFileExtension = ".json"
+
+// memoryCacheEntry is an entry of the in-memory completion cache
+type memoryCacheEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryCompletionCache implements CompletionCache in memory with least recently used eviction.
+// Responses are stored serialized so that callers can never modify a cached entry.
+type MemoryCompletionCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+}
+
+// NewMemoryCompletionCache creates an in-memory completion cache.
+// maxEntries of 0 means unbounded, and a ttl of 0 means entries never expire.
+func NewMemoryCompletionCache(maxEntries int, ttl time.Duration) interfaces.CompletionCache {
+	return &MemoryCompletionCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Get retrieves a cached response and marks it as recently used
+func (c *MemoryCompletionCache) Get(key string) (*entities.ChatCompletionResponse, bool, error) {
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if isExpired(entry.expiresAt, c.now()) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return nil, false, nil
+	}
+	c.order.MoveToFront(element)
+	data := entry.data
+	c.mu.Unlock()
+
+	var response entities.ChatCompletionResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false, fmt.Errorf("failed to parse cached response: %w", err)
+	}
+	return &response, true, nil
+}
+
+// Set stores a response, evicting the least recently used entries beyond the size bound
+func (c *MemoryCompletionCache) Set(key string, response *entities.ChatCompletionResponse) error {
+	if response == nil {
+		return fmt.Errorf("response cannot be nil")
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryCacheEntry{key: key, data: data, expiresAt: expiryFor(c.ttl, c.now())}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(entry)
+	}
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+	return nil
+}
+
+// fileCacheEntry is the on-disk format of a cached response
+type fileCacheEntry struct {
+	ExpiresAt time.Time                        `json:"expires_at,omitempty"`
+	Response  *entities.ChatCompletionResponse `json:"response"`
+}
+
+// FileCompletionCache implements CompletionCache using one JSON file per entry.
+// Entries survive restarts; reading an entry refreshes its modification time,
+// and the least recently used files are removed beyond the size bound.
+type FileCompletionCache struct {
+	mu         sync.Mutex
+	dirPath    string
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+// NewFileCompletionCache creates a file-based completion cache.
+// Entries are stored in a "cache" subdirectory of the provided directory.
+// maxEntries of 0 means unbounded, and a ttl of 0 means entries never expire.
+func NewFileCompletionCache(qwenDir string, maxEntries int, ttl time.Duration) interfaces.CompletionCache {
+	// Use current working directory as base path
+	workDir, err := os.Getwd()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to get current working directory: %v", err))
+	}
+	return &FileCompletionCache{
+		dirPath:    filepath.Join(workDir, qwenDir, "cache"),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+	}
+}
+
+// Get retrieves a cached response from file, removing it when it has expired
+func (c *FileCompletionCache) Get(key string) (*entities.ChatCompletionResponse, bool, error) {
+	path, err := c.pathFor(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var entry fileCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		// A corrupt entry is treated as a miss and replaced on the next store
+		os.Remove(path)
+		return nil, false, nil
+	}
+	now := c.now()
+	if isExpired(entry.ExpiresAt, now) {
+		os.Remove(path)
+		return nil, false, nil
+	}
+
+	os.Chtimes(path, now, now)
+	return entry.Response, true, nil
+}
+
+// Set stores a response in a file, creating the cache directory if necessary
+func (c *FileCompletionCache) Set(key string, response *entities.ChatCompletionResponse) error {
+	if response == nil {
+		return fmt.Errorf("response cannot be nil")
+	}
+	path, err := c.pathFor(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fileCacheEntry{ExpiresAt: expiryFor(c.ttl, c.now()), Response: response})
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dirPath, 0700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", c.dirPath, err)
+	}
+	// Write to a temporary file first so that readers never see a partial entry
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return c.evict()
+}
+
+// evict removes the least recently used entries beyond the size bound; the caller must hold the lock
+func (c *FileCompletionCache) evict() error {
+	if c.maxEntries <= 0 {
+		return nil
+	}
+
+	dirEntries, err := os.ReadDir(c.dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to list cache entries: %w", err)
+	}
+
+	type cacheFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []cacheFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), cacheFileExtension) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{path: filepath.Join(c.dirPath, dirEntry.Name()), modTime: info.ModTime()})
+	}
+	if len(files) <= c.maxEntries {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, file := range files[:len(files)-c.maxEntries] {
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to evict cache entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// pathFor returns the file path for a cache key, rejecting keys that could escape the directory
+func (c *FileCompletionCache) pathFor(key string) (string, error) {
+	if !validCacheKey.MatchString(key) {
+		return "", fmt.Errorf("invalid cache key: %q", key)
+	}
+	return filepath.Join(c.dirPath, key+cacheFileExtension), nil
+}
+
+// expiryFor returns the expiry time of an entry stored now, or the zero time when entries never expire
+func expiryFor(ttl time.Duration, now time.Time) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// isExpired reports whether an entry with the given expiry time has expired
+func isExpired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cacheKeyA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	cacheKeyB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	cacheKeyC = "cccccccccccccccccccccccccccccccc"
+)
+
+func cachedResponse(id string) *entities.ChatCompletionResponse {
+	return &entities.ChatCompletionResponse{
+		ID:     id,
+		Object: "chat.completion",
+		Model:  "qwen3-coder-plus",
+		Choices: []entities.ChatCompletionChoice{
+			{Index: 0, Message: entities.ChatMessage{Role: "assistant", Content: "Hello"}, FinishReason: "stop"},
+		},
+		Usage: &entities.Usage{PromptTokens: 5, CompletionTokens: 1, TotalTokens: 6},
+	}
+}
+
+func TestMemoryCompletionCache_SetGet(t *testing.T) {
+	cache := NewMemoryCompletionCache(10, time.Hour)
+
+	_, found, err := cache.Get(cacheKeyA)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, cache.Set(cacheKeyA, cachedResponse("chatcmpl-a")))
+
+	response, found, err := cache.Get(cacheKeyA)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "chatcmpl-a", response.ID)
+	assert.Equal(t, "Hello", response.Choices[0].Message.Content)
+	assert.Equal(t, 6, response.Usage.TotalTokens)
+
+	// Modifying a returned response must not change the cached entry
+	response.ID = "modified"
+	response, _, _ = cache.Get(cacheKeyA)
+	assert.Equal(t, "chatcmpl-a", response.ID)
+}
+
+func TestMemoryCompletionCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCompletionCache(2, 0)
+
+	require.NoError(t, cache.Set(cacheKeyA, cachedResponse("a")))
+	require.NoError(t, cache.Set(cacheKeyB, cachedResponse("b")))
+	_, found, _ := cache.Get(cacheKeyA)
+	require.True(t, found)
+	require.NoError(t, cache.Set(cacheKeyC, cachedResponse("c")))
+
+	_, found, _ = cache.Get(cacheKeyA)
+	assert.True(t, found)
+	_, found, _ = cache.Get(cacheKeyB)
+	assert.False(t, found, "least recently used entry should be evicted")
+	_, found, _ = cache.Get(cacheKeyC)
+	assert.True(t, found)
+}
+
+func TestMemoryCompletionCache_Expiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := NewMemoryCompletionCache(0, time.Minute).(*MemoryCompletionCache)
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(cacheKeyA, cachedResponse("a")))
+
+	now = now.Add(59 * time.Second)
+	_, found, _ := cache.Get(cacheKeyA)
+	assert.True(t, found)
+
+	now = now.Add(time.Second)
+	_, found, _ = cache.Get(cacheKeyA)
+	assert.False(t, found)
+	assert.Empty(t, cache.entries)
+}
+
+func TestMemoryCompletionCache_SetNilResponse(t *testing.T) {
+	cache := NewMemoryCompletionCache(0, 0)
+	assert.Error(t, cache.Set(cacheKeyA, nil))
+}
+
+func newTestFileCompletionCache(t *testing.T, maxEntries int, ttl time.Duration) *FileCompletionCache {
+	return &FileCompletionCache{
+		dirPath:    filepath.Join(t.TempDir(), "cache"),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+	}
+}
+
+func TestNewFileCompletionCache(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cache := NewFileCompletionCache(".qwen-test", 10, time.Hour)
+	assert.NotNil(t, cache)
+}
+
+func TestFileCompletionCache_SetGet(t *testing.T) {
+	cache := newTestFileCompletionCache(t, 10, time.Hour)
+
+	_, found, err := cache.Get(cacheKeyA)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, cache.Set(cacheKeyA, cachedResponse("chatcmpl-a")))
+
+	info, err := os.Stat(filepath.Join(cache.dirPath, cacheKeyA+".json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// A new cache instance on the same directory sees the entry
+	reopened := &FileCompletionCache{dirPath: cache.dirPath, now: time.Now}
+	response, found, err := reopened.Get(cacheKeyA)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "chatcmpl-a", response.ID)
+	assert.Equal(t, "Hello", response.Choices[0].Message.Content)
+}
+
+func TestFileCompletionCache_Expiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := newTestFileCompletionCache(t, 0, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(cacheKeyA, cachedResponse("a")))
+
+	now = now.Add(time.Minute)
+	_, found, err := cache.Get(cacheKeyA)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = os.Stat(filepath.Join(cache.dirPath, cacheKeyA+".json"))
+	assert.True(t, os.IsNotExist(err), "expired entry should be removed")
+}
+
+func TestFileCompletionCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := newTestFileCompletionCache(t, 2, 0)
+	cache.now = func() time.Time { return now }
+
+	setAt := func(key string, at time.Time) {
+		require.NoError(t, cache.Set(key, cachedResponse(key)))
+		require.NoError(t, os.Chtimes(filepath.Join(cache.dirPath, key+".json"), at, at))
+	}
+	setAt(cacheKeyA, now.Add(-3*time.Minute))
+	setAt(cacheKeyB, now.Add(-2*time.Minute))
+
+	// Reading A makes it the most recently used entry
+	_, found, _ := cache.Get(cacheKeyA)
+	require.True(t, found)
+	require.NoError(t, cache.Set(cacheKeyC, cachedResponse(cacheKeyC)))
+
+	_, found, _ = cache.Get(cacheKeyA)
+	assert.True(t, found)
+	_, found, _ = cache.Get(cacheKeyB)
+	assert.False(t, found, "least recently used entry should be evicted")
+	_, found, _ = cache.Get(cacheKeyC)
+	assert.True(t, found)
+}
+
+func TestFileCompletionCache_CorruptEntry(t *testing.T) {
+	cache := newTestFileCompletionCache(t, 0, 0)
+	require.NoError(t, os.MkdirAll(cache.dirPath, 0700))
+	path := filepath.Join(cache.dirPath, cacheKeyA+".json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0600))
+
+	_, found, err := cache.Get(cacheKeyA)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "corrupt entry should be removed")
+}
+
+func TestFileCompletionCache_InvalidKey(t *testing.T) {
+	cache := newTestFileCompletionCache(t, 0, 0)
+
+	for _, key := range []string{"", "../../etc/passwd", "ABCDEF0123456789", "short"} {
+		_, _, err := cache.Get(key)
+		assert.Error(t, err, key)
+		assert.Error(t, cache.Set(key, cachedResponse("x")), key)
+	}
+}
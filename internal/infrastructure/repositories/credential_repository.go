@@ -51,24 +51,21 @@ func (r *FileCredentialRepository) Load() (*entities.Credentials, error) {
 	if err := json.Unmarshal(data, &creds); err != nil {
 		return nil, fmt.Errorf("failed to parse Qwen OAuth credentials: %w", err)
 	}
+	if isEncryptedCredentials(data) {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: the credentials file is encrypted. Please set CREDENTIAL_ENCRYPTION_KEY, CREDENTIAL_ENCRYPTION_KEY_FILE or CREDENTIAL_ENCRYPTION_PASSPHRASE")
+	}
 
 	return &creds, nil
 }
 
 // Save saves credentials to file.
-// It serializes credentials to JSON and writes them to the filesystem,
-// creating any necessary directories.
+// It serializes credentials to JSON and atomically replaces the file with them,
+// creating any necessary directories. The file is readable by the owner only.
 func (r *FileCredentialRepository) Save(credentials *entities.Credentials) error {
-	// Ensure directory exists
-	dir := filepath.Dir(r.filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
-	}
-
 	data, err := json.MarshalIndent(credentials, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal credentials: %w", err)
 	}
 
-	return os.WriteFile(r.filePath, data, 0644)
+	return writeFileAtomic(r.filePath, data)
 }
\ No newline at end of file
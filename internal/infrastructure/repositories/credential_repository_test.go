@@ -128,6 +128,41 @@ func TestFileCredentialRepository_Save_DirectoryCreation(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestFileCredentialRepository_Save_Permissions(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := &FileCredentialRepository{
+		filePath: filepath.Join(tempDir, "oauth_creds.json"),
+	}
+	// An existing world-readable file is replaced by one readable by the owner only
+	err := os.WriteFile(repo.filePath, []byte(`{"access_token":"old-token"}`), 0644)
+	assert.NoError(t, err)
+
+	err = repo.Save(&entities.Credentials{AccessToken: "test-token"})
+	assert.NoError(t, err)
+
+	info, err := os.Stat(repo.filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFileCredentialRepository_Load_EncryptedFile(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "oauth_creds.json")
+	encrypted := &EncryptedCredentialRepository{filePath: filePath, key: &CredentialKey{Key: make([]byte, 32)}}
+	err := encrypted.Save(&entities.Credentials{AccessToken: "test-token"})
+	assert.NoError(t, err)
+
+	repo := &FileCredentialRepository{filePath: filePath}
+	_, err = repo.Load()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CREDENTIAL_ENCRYPTION_KEY")
+}
+
 func TestFileCredentialRepository_Save_MarshalError(t *testing.T) {
 	// This test is to check what happens when JSON marshaling fails
 	// Since our Credentials struct is valid, we can't easily create a marshal error
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// Credential store backends
+const (
+	CredentialStoreFile   = "file"
+	CredentialStoreSQLite = "sqlite"
+	CredentialStoreVault  = "vault"
+)
+
+// NewCredentialStore creates the credential store configured by CREDENTIAL_STORE
+func NewCredentialStore(config *entities.Config) (interfaces.CredentialStore, error) {
+	switch config.CredentialStore {
+	case CredentialStoreSQLite:
+		path := config.CredentialSQLitePath
+		if path == "" {
+			path = filepath.Join(config.QWENDir, "credentials.db")
+		}
+		store, err := NewSQLiteCredentialStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case CredentialStoreVault:
+		return NewVaultCredentialStore(VaultConfig{
+			Address:   config.VaultAddress,
+			Token:     config.VaultToken,
+			Namespace: config.VaultNamespace,
+			Mount:     config.VaultKVMount,
+			Path:      config.VaultKVPath,
+		}), nil
+	case CredentialStoreFile, "":
+		key, err := LoadCredentialKey(config.CredentialEncryptionKey, config.CredentialEncryptionKeyFile, config.CredentialEncryptionPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileCredentialStore(config.QWENDir, key), nil
+	}
+	return nil, fmt.Errorf("unknown credential store: %s", config.CredentialStore)
+}
+
+// FileCredentialStore stores the credentials of every account in its own oauth_creds.json: the
+// default account in the Qwen directory and named accounts in qwenDir/accounts/<name>
+type FileCredentialStore struct {
+	qwenDir string
+	key     *CredentialKey
+}
+
+// NewFileCredentialStore creates a file credential store, which encrypts credentials when a key is given
+func NewFileCredentialStore(qwenDir string, key *CredentialKey) *FileCredentialStore {
+	return &FileCredentialStore{qwenDir: qwenDir, key: key}
+}
+
+// Repository returns the credential repository of an account
+func (s *FileCredentialStore) Repository(account string) interfaces.CredentialRepository {
+	return NewCredentialRepository(s.AccountDir(account), s.key)
+}
+
+// AccountDir returns the directory that holds the credentials of an account
+func (s *FileCredentialStore) AccountDir(account string) string {
+	if account == "" || account == DefaultAccountName {
+		return s.qwenDir
+	}
+	return filepath.Join(s.qwenDir, "accounts", account)
+}
+
+// Accounts lists the accounts with a credentials file
+func (s *FileCredentialStore) Accounts() ([]string, error) {
+	accounts, err := DiscoverAccounts(s.qwenDir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(accounts))
+	for i, account := range accounts {
+		names[i] = account.Name
+	}
+	return names, nil
+}
+
+// Close does nothing, files are not kept open
+func (s *FileCredentialStore) Close() error {
+	return nil
+}
+
+// sortAccounts sorts account names, with the default account first
+func sortAccounts(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == DefaultAccountName) != (names[j] == DefaultAccountName) {
+			return names[i] == DefaultAccountName
+		}
+		return names[i] < names[j]
+	})
+}
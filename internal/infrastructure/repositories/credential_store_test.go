@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCredentialStore(t *testing.T) {
+	store, err := NewCredentialStore(&entities.Config{CredentialStore: "file", QWENDir: ".qwen"})
+	require.NoError(t, err)
+	assert.IsType(t, &FileCredentialStore{}, store)
+	assert.IsType(t, &FileCredentialRepository{}, store.Repository(DefaultAccountName))
+
+	store, err = NewCredentialStore(&entities.Config{CredentialStore: "file", QWENDir: ".qwen", CredentialEncryptionPassphrase: "correct horse battery staple"})
+	require.NoError(t, err)
+	assert.IsType(t, &EncryptedCredentialRepository{}, store.Repository(DefaultAccountName))
+
+	_, err = NewCredentialStore(&entities.Config{CredentialStore: "file", CredentialEncryptionKey: "short"})
+	assert.Error(t, err)
+
+	store, err = NewCredentialStore(&entities.Config{CredentialStore: "vault", VaultAddress: "http://127.0.0.1:8200", VaultKVMount: "secret", VaultKVPath: "qwen-go-proxy"})
+	require.NoError(t, err)
+	assert.IsType(t, &VaultCredentialStore{}, store)
+
+	_, err = NewCredentialStore(&entities.Config{CredentialStore: "redis"})
+	assert.Error(t, err)
+}
+
+func TestFileCredentialStore(t *testing.T) {
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+	store := NewFileCredentialStore(".qwen", nil)
+
+	assert.Equal(t, ".qwen", store.AccountDir(DefaultAccountName))
+	assert.Equal(t, filepath.Join(".qwen", "accounts", "work"), store.AccountDir("work"))
+
+	accounts, err := store.Accounts()
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	require.NoError(t, store.Repository("work").Save(testCredentials()))
+	require.NoError(t, store.Repository(DefaultAccountName).Save(testCredentials()))
+	_, err = os.Stat(filepath.Join(workDir, ".qwen", "accounts", "work", "oauth_creds.json"))
+	require.NoError(t, err)
+
+	accounts, err = store.Accounts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultAccountName, "work"}, accounts)
+	assert.NoError(t, store.Close())
+}
+
+func TestSortAccounts(t *testing.T) {
+	names := []string{"work", "backup", DefaultAccountName, "archive"}
+	sortAccounts(names)
+	assert.Equal(t, []string{DefaultAccountName, "archive", "backup", "work"}, names)
+}
@@ -0,0 +1,337 @@
+package repositories
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+const (
+	// credentialKeySize is the size of the AES-256 key credentials are encrypted with
+	credentialKeySize = 32
+	// credentialSaltSize is the size of the random salt a passphrase is derived with
+	credentialSaltSize = 16
+	// credentialKDFIterations is the number of PBKDF2-HMAC-SHA256 iterations for new files
+	credentialKDFIterations = 600000
+
+	encryptedCredentialsVersion = 1
+	encryptedCredentialsCipher  = "AES-256-GCM"
+	encryptedCredentialsKDF     = "PBKDF2-HMAC-SHA256"
+)
+
+// CredentialKey is the secret credentials are encrypted with: a 256-bit key, or a passphrase
+// from which a key is derived with PBKDF2 and the random salt of every file
+type CredentialKey struct {
+	Key        []byte // Sensitive: never log this value
+	Passphrase string // Sensitive: never log this value
+}
+
+// LoadCredentialKey returns the credential encryption key given as a base64 or hex encoded key,
+// as a file that holds such a key or 32 raw bytes, or as a passphrase.
+// It returns nil when none is given, in which case credentials are stored in plaintext.
+func LoadCredentialKey(key, keyFile, passphrase string) (*CredentialKey, error) {
+	switch {
+	case key != "":
+		decoded, err := decodeCredentialKey([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential encryption key: %w", err)
+		}
+		return &CredentialKey{Key: decoded}, nil
+	case keyFile != "":
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credential encryption key file: %w", err)
+		}
+		if len(data) == credentialKeySize {
+			return &CredentialKey{Key: data}, nil
+		}
+		decoded, err := decodeCredentialKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential encryption key file %s: %w", keyFile, err)
+		}
+		return &CredentialKey{Key: decoded}, nil
+	case passphrase != "":
+		return &CredentialKey{Passphrase: passphrase}, nil
+	}
+	return nil, nil
+}
+
+// decodeCredentialKey decodes a 256-bit key from hex or base64
+func decodeCredentialKey(data []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(data))
+	if decoded, err := hex.DecodeString(text); err == nil && len(decoded) == credentialKeySize {
+		return decoded, nil
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(text); err == nil && len(decoded) == credentialKeySize {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("must be %d bytes, encoded as 64 hex characters or base64", credentialKeySize)
+}
+
+// encryptedCredentials is the JSON file credentials are encrypted into
+type encryptedCredentials struct {
+	Version    int    `json:"version"`
+	Cipher     string `json:"cipher"`
+	KDF        string `json:"kdf,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// isEncryptedCredentials reports whether a credentials file holds encrypted credentials
+func isEncryptedCredentials(data []byte) bool {
+	var envelope encryptedCredentials
+	return json.Unmarshal(data, &envelope) == nil && envelope.Ciphertext != nil
+}
+
+// additionalData binds the ciphertext to the header it is stored with
+func (e *encryptedCredentials) additionalData() []byte {
+	return fmt.Appendf(nil, "qwen-go-proxy credentials v%d %s %s %d", e.Version, e.Cipher, e.KDF, e.Iterations)
+}
+
+// EncryptedCredentialRepository implements CredentialRepository with credentials encrypted by
+// AES-256-GCM in the oauth_creds.json file. Files are replaced atomically and readable by the owner only.
+// A plaintext oauth_creds.json, as written by FileCredentialRepository or the Qwen CLI, is
+// encrypted in place the first time it is loaded.
+type EncryptedCredentialRepository struct {
+	filePath   string
+	key        *CredentialKey
+	iterations int
+
+	// mu guards the key derived from the passphrase and the salt it was derived with
+	mu          sync.Mutex
+	salt        []byte
+	derivedKey  []byte
+	derivedIter int
+}
+
+// NewEncryptedCredentialRepository creates a credential repository that encrypts the credentials
+// in qwenDir with the key
+func NewEncryptedCredentialRepository(qwenDir string, key *CredentialKey) interfaces.CredentialRepository {
+	if key == nil {
+		panic("key cannot be nil")
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to get current working directory: %v", err))
+	}
+	return &EncryptedCredentialRepository{
+		filePath:   filepath.Join(workDir, qwenDir, "oauth_creds.json"),
+		key:        key,
+		iterations: credentialKDFIterations,
+	}
+}
+
+// NewCredentialRepository creates the credential repository for qwenDir: an encrypted one when a key
+// is given, a plaintext one otherwise
+func NewCredentialRepository(qwenDir string, key *CredentialKey) interfaces.CredentialRepository {
+	if key == nil {
+		return NewFileCredentialRepository(qwenDir)
+	}
+	return NewEncryptedCredentialRepository(qwenDir, key)
+}
+
+// Load decrypts the stored credentials, encrypting them first when they are stored in plaintext
+func (r *EncryptedCredentialRepository) Load() (*entities.Credentials, error) {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: %w", err)
+	}
+
+	var envelope encryptedCredentials
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse Qwen OAuth credentials: %w", err)
+	}
+	if envelope.Ciphertext == nil {
+		return r.migrate(data)
+	}
+
+	plaintext, err := r.decrypt(&envelope)
+	if err != nil {
+		return nil, err
+	}
+	var creds entities.Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse Qwen OAuth credentials: %w", err)
+	}
+	return &creds, nil
+}
+
+// migrate encrypts plaintext credentials in place and returns them
+func (r *EncryptedCredentialRepository) migrate(data []byte) (*entities.Credentials, error) {
+	var creds entities.Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse Qwen OAuth credentials: %w", err)
+	}
+	if err := r.Save(&creds); err != nil {
+		return nil, fmt.Errorf("failed to encrypt plaintext Qwen OAuth credentials: %w", err)
+	}
+	return &creds, nil
+}
+
+// Save encrypts the credentials and atomically replaces the credentials file
+func (r *EncryptedCredentialRepository) Save(credentials *entities.Credentials) error {
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	envelope := &encryptedCredentials{
+		Version: encryptedCredentialsVersion,
+		Cipher:  encryptedCredentialsCipher,
+	}
+	key := r.key.Key
+	if key == nil {
+		envelope.KDF = encryptedCredentialsKDF
+		envelope.Iterations = r.iterations
+		envelope.Salt, key, err = r.encryptionKey()
+		if err != nil {
+			return err
+		}
+	}
+	aead, err := newCredentialCipher(key)
+	if err != nil {
+		return err
+	}
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(envelope.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, envelope.additionalData())
+
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted credentials: %w", err)
+	}
+	return writeFileAtomic(r.filePath, data)
+}
+
+// decrypt returns the plaintext of the encrypted credentials
+func (r *EncryptedCredentialRepository) decrypt(envelope *encryptedCredentials) ([]byte, error) {
+	if envelope.Version != encryptedCredentialsVersion || envelope.Cipher != encryptedCredentialsCipher {
+		return nil, fmt.Errorf("unsupported Qwen OAuth credentials encryption: version %d, cipher %s", envelope.Version, envelope.Cipher)
+	}
+
+	key := r.key.Key
+	switch {
+	case envelope.KDF == "" && key == nil:
+		return nil, fmt.Errorf("Qwen OAuth credentials are encrypted with a key, but a passphrase is configured")
+	case envelope.KDF != "" && key != nil:
+		return nil, fmt.Errorf("Qwen OAuth credentials are encrypted with a passphrase, but a key is configured")
+	case envelope.KDF != "":
+		if envelope.KDF != encryptedCredentialsKDF || envelope.Iterations <= 0 || len(envelope.Salt) == 0 {
+			return nil, fmt.Errorf("unsupported Qwen OAuth credentials key derivation: %s", envelope.KDF)
+		}
+		key = r.deriveKey(envelope.Salt, envelope.Iterations)
+	}
+
+	aead, err := newCredentialCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt Qwen OAuth credentials: invalid nonce")
+	}
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, envelope.additionalData())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt Qwen OAuth credentials: wrong key or corrupted file")
+	}
+	return plaintext, nil
+}
+
+// encryptionKey returns the salt and the key derived from the passphrase for a new file. The salt
+// of the last file loaded or saved is reused, so that the key is not derived again on every save.
+func (r *EncryptedCredentialRepository) encryptionKey() ([]byte, []byte, error) {
+	r.mu.Lock()
+	salt, derivedIter := r.salt, r.derivedIter
+	r.mu.Unlock()
+	if salt == nil || derivedIter != r.iterations {
+		salt = make([]byte, credentialSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+	return salt, r.deriveKey(salt, r.iterations), nil
+}
+
+// deriveKey derives the key for a salt from the passphrase, caching the key of the last salt
+func (r *EncryptedCredentialRepository) deriveKey(salt []byte, iterations int) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.derivedKey != nil && r.derivedIter == iterations && bytes.Equal(r.salt, salt) {
+		return r.derivedKey
+	}
+	key, err := pbkdf2.Key(sha256.New, r.key.Passphrase, salt, iterations, credentialKeySize)
+	if err != nil {
+		// Only possible for key lengths beyond what PBKDF2 can produce
+		panic(fmt.Sprintf("failed to derive credential encryption key: %v", err))
+	}
+	r.salt, r.derivedKey, r.derivedIter = salt, key, iterations
+	return key
+}
+
+// newCredentialCipher creates the AES-GCM cipher for a key
+func newCredentialCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// writeFileAtomic replaces a file with data readable by the owner only. The data is written to a
+// temporary file in the same directory and renamed over the file, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions of %s: %w", tmpPath, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCredentialKey(b byte) *CredentialKey {
+	key := make([]byte, credentialKeySize)
+	for i := range key {
+		key[i] = b
+	}
+	return &CredentialKey{Key: key}
+}
+
+func newTestEncryptedRepository(t *testing.T, filePath string, key *CredentialKey) *EncryptedCredentialRepository {
+	t.Helper()
+	// Few iterations keep passphrase tests fast
+	return &EncryptedCredentialRepository{filePath: filePath, key: key, iterations: 1000}
+}
+
+func testCredentials() *entities.Credentials {
+	return &entities.Credentials{
+		AccessToken:  "test-access-token",
+		TokenType:    "Bearer",
+		RefreshToken: "test-refresh-token",
+		ExpiryDate:   1234567890,
+		ResourceURL:  "https://api.example.com",
+	}
+}
+
+func TestNewEncryptedCredentialRepository(t *testing.T) {
+	assert.NotNil(t, NewEncryptedCredentialRepository(".qwen-test", testCredentialKey(1)))
+	assert.Panics(t, func() { NewEncryptedCredentialRepository(".qwen-test", nil) })
+}
+
+func TestNewCredentialRepository(t *testing.T) {
+	assert.IsType(t, &FileCredentialRepository{}, NewCredentialRepository(".qwen-test", nil))
+	assert.IsType(t, &EncryptedCredentialRepository{}, NewCredentialRepository(".qwen-test", testCredentialKey(1)))
+}
+
+func TestEncryptedCredentialRepository_Save_Load(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *CredentialKey
+	}{
+		{name: "key", key: testCredentialKey(1)},
+		{name: "passphrase", key: &CredentialKey{Passphrase: "correct horse battery staple"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), ".qwen-test", "oauth_creds.json")
+			repo := newTestEncryptedRepository(t, filePath, tt.key)
+			creds := testCredentials()
+
+			require.NoError(t, repo.Save(creds))
+
+			data, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "test-access-token")
+			assert.NotContains(t, string(data), "test-refresh-token")
+
+			info, err := os.Stat(filePath)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+			// A new repository, without the cached derived key, decrypts the file
+			loaded, err := newTestEncryptedRepository(t, filePath, tt.key).Load()
+			require.NoError(t, err)
+			assert.Equal(t, creds, loaded)
+		})
+	}
+}
+
+func TestEncryptedCredentialRepository_Save_NewNonce(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "oauth_creds.json")
+	repo := newTestEncryptedRepository(t, filePath, &CredentialKey{Passphrase: "correct horse battery staple"})
+
+	require.NoError(t, repo.Save(testCredentials()))
+	first, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(testCredentials()))
+	second, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	var a, b encryptedCredentials
+	require.NoError(t, json.Unmarshal(first, &a))
+	require.NoError(t, json.Unmarshal(second, &b))
+	assert.NotEqual(t, a.Nonce, b.Nonce)
+	assert.NotEqual(t, a.Ciphertext, b.Ciphertext)
+}
+
+func TestEncryptedCredentialRepository_Load_WrongKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		saveKey   *CredentialKey
+		loadKey   *CredentialKey
+		wantError string
+	}{
+		{name: "wrong key", saveKey: testCredentialKey(1), loadKey: testCredentialKey(2), wantError: "wrong key or corrupted file"},
+		{name: "wrong passphrase", saveKey: &CredentialKey{Passphrase: "correct horse battery staple"}, loadKey: &CredentialKey{Passphrase: "incorrect horse battery staple"}, wantError: "wrong key or corrupted file"},
+		{name: "key for passphrase", saveKey: &CredentialKey{Passphrase: "correct horse battery staple"}, loadKey: testCredentialKey(1), wantError: "encrypted with a passphrase"},
+		{name: "passphrase for key", saveKey: testCredentialKey(1), loadKey: &CredentialKey{Passphrase: "correct horse battery staple"}, wantError: "encrypted with a key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "oauth_creds.json")
+			require.NoError(t, newTestEncryptedRepository(t, filePath, tt.saveKey).Save(testCredentials()))
+
+			_, err := newTestEncryptedRepository(t, filePath, tt.loadKey).Load()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantError)
+		})
+	}
+}
+
+func TestEncryptedCredentialRepository_Load_TamperedHeader(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "oauth_creds.json")
+	key := &CredentialKey{Passphrase: "correct horse battery staple"}
+	require.NoError(t, newTestEncryptedRepository(t, filePath, key).Save(testCredentials()))
+
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	var envelope encryptedCredentials
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	envelope.Iterations = 1
+	data, err = json.Marshal(envelope)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filePath, data, 0600))
+
+	_, err = newTestEncryptedRepository(t, filePath, key).Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt Qwen OAuth credentials")
+}
+
+func TestEncryptedCredentialRepository_Load_MigratesPlaintext(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "oauth_creds.json")
+	creds := testCredentials()
+	require.NoError(t, (&FileCredentialRepository{filePath: filePath}).Save(creds))
+	require.NoError(t, os.Chmod(filePath, 0644))
+
+	repo := newTestEncryptedRepository(t, filePath, testCredentialKey(1))
+	loaded, err := repo.Load()
+	require.NoError(t, err)
+	assert.Equal(t, creds, loaded)
+
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.True(t, isEncryptedCredentials(data))
+	assert.NotContains(t, string(data), "test-access-token")
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err = repo.Load()
+	require.NoError(t, err)
+	assert.Equal(t, creds, loaded)
+}
+
+func TestEncryptedCredentialRepository_Load_Errors(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := newTestEncryptedRepository(t, filepath.Join(tempDir, "oauth_creds.json"), testCredentialKey(1))
+
+	_, err := repo.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read Qwen OAuth credentials")
+
+	require.NoError(t, os.WriteFile(repo.filePath, []byte("not json"), 0600))
+	_, err = repo.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse Qwen OAuth credentials")
+
+	require.NoError(t, os.WriteFile(repo.filePath, []byte(`{"version":2,"cipher":"AES-256-GCM","nonce":"","ciphertext":"AA=="}`), 0600))
+	_, err = repo.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported Qwen OAuth credentials encryption")
+}
+
+func TestLoadCredentialKey(t *testing.T) {
+	raw := testCredentialKey(7).Key
+	tempDir := t.TempDir()
+	rawFile := filepath.Join(tempDir, "raw.key")
+	require.NoError(t, os.WriteFile(rawFile, raw, 0600))
+	encodedFile := filepath.Join(tempDir, "encoded.key")
+	require.NoError(t, os.WriteFile(encodedFile, []byte(base64.StdEncoding.EncodeToString(raw)+"\n"), 0600))
+
+	tests := []struct {
+		name       string
+		key        string
+		keyFile    string
+		passphrase string
+		want       *CredentialKey
+		wantError  string
+	}{
+		{name: "none"},
+		{name: "hex key", key: hex.EncodeToString(raw), want: &CredentialKey{Key: raw}},
+		{name: "base64 key", key: base64.StdEncoding.EncodeToString(raw), want: &CredentialKey{Key: raw}},
+		{name: "raw base64url key", key: base64.RawURLEncoding.EncodeToString(raw), want: &CredentialKey{Key: raw}},
+		{name: "raw key file", keyFile: rawFile, want: &CredentialKey{Key: raw}},
+		{name: "encoded key file", keyFile: encodedFile, want: &CredentialKey{Key: raw}},
+		{name: "passphrase", passphrase: "correct horse battery staple", want: &CredentialKey{Passphrase: "correct horse battery staple"}},
+		{name: "short key", key: hex.EncodeToString(raw[:16]), wantError: "invalid credential encryption key"},
+		{name: "invalid key", key: strings.Repeat("z", 64), wantError: "invalid credential encryption key"},
+		{name: "missing key file", keyFile: filepath.Join(tempDir, "missing.key"), wantError: "failed to read credential encryption key file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadCredentialKey(tt.key, tt.keyFile, tt.passphrase)
+			if tt.wantError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, key)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+const (
+	// journalFileName is the file new journal entries are appended to
+	journalFileName = "journal.jsonl"
+
+	// journalRotatedPrefix and journalRotatedTimeFormat name rotated journal files,
+	// so that their names sort in the order they were rotated
+	journalRotatedPrefix     = "journal-"
+	journalRotatedTimeFormat = "20060102T150405.000000000"
+)
+
+// maxJournalLineSize bounds the size of a single journal entry when reading the journal
+const maxJournalLineSize = 64 << 20
+
+// FileJournal implements Journal as JSON lines appended to a file.
+// The file is rotated once it would grow beyond the size bound, and the oldest
+// rotated files are removed beyond the file bound.
+type FileJournal struct {
+	mu       sync.Mutex
+	dirPath  string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	now      func() time.Time
+}
+
+// NewFileJournal creates a file-based journal in the given directory.
+// maxSize is in bytes and maxFiles counts rotated files; 0 means unbounded for either.
+func NewFileJournal(dirPath string, maxSize int64, maxFiles int) interfaces.Journal {
+	return &FileJournal{
+		dirPath:  dirPath,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		now:      time.Now,
+	}
+}
+
+// Append writes an entry as a single line, rotating the journal file when it is full
+func (j *FileJournal) Append(entry *entities.JournalEntry) error {
+	if entry == nil {
+		return fmt.Errorf("entry cannot be nil")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.open(); err != nil {
+		return err
+	}
+	if j.maxSize > 0 && j.size > 0 && j.size+int64(len(data)) > j.maxSize {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.file.Write(data)
+	j.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// Search reads every journal file, oldest first, and returns the matching entries
+func (j *FileJournal) Search(query entities.JournalQuery) ([]*entities.JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	paths, err := j.files()
+	if err != nil {
+		return nil, err
+	}
+
+	contains := []byte(strings.ToLower(query.Contains))
+	var matches []*entities.JournalEntry
+	for _, path := range paths {
+		err := readJournalFile(path, func(line []byte) {
+			if len(contains) > 0 && !bytes.Contains(bytes.ToLower(line), contains) {
+				return
+			}
+			var entry entities.JournalEntry
+			if json.Unmarshal(line, &entry) != nil {
+				// Skip a partially written last line, e.g. after a crash
+				return
+			}
+			if query.Matches(&entry) {
+				matches = append(matches, &entry)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if query.Limit > 0 && len(matches) > query.Limit {
+		matches = matches[len(matches)-query.Limit:]
+	}
+	return matches, nil
+}
+
+// Close closes the journal file; a later Append opens it again
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// open opens the journal file for appending unless it is already open; the caller must hold the lock
+func (j *FileJournal) open() error {
+	if j.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(j.dirPath, 0700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", j.dirPath, err)
+	}
+	file, err := os.OpenFile(filepath.Join(j.dirPath, journalFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.file = file
+	j.size = info.Size()
+	return nil
+}
+
+// rotate renames the full journal file, removes the oldest rotated files beyond the bound
+// and opens a new journal file; the caller must hold the lock
+func (j *FileJournal) rotate() error {
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf("failed to close journal: %w", err)
+	}
+	j.file = nil
+
+	rotatedName := journalRotatedPrefix + j.now().UTC().Format(journalRotatedTimeFormat) + ".jsonl"
+	if err := os.Rename(filepath.Join(j.dirPath, journalFileName), filepath.Join(j.dirPath, rotatedName)); err != nil {
+		return fmt.Errorf("failed to rotate journal: %w", err)
+	}
+
+	if j.maxFiles > 0 {
+		rotated, err := j.rotatedFiles()
+		if err != nil {
+			return err
+		}
+		for len(rotated) > j.maxFiles {
+			if err := os.Remove(rotated[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove rotated journal: %w", err)
+			}
+			rotated = rotated[1:]
+		}
+	}
+	return j.open()
+}
+
+// files returns the rotated journal files, oldest first, followed by the current journal file
+func (j *FileJournal) files() ([]string, error) {
+	paths, err := j.rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	current := filepath.Join(j.dirPath, journalFileName)
+	if _, err := os.Stat(current); err == nil {
+		paths = append(paths, current)
+	}
+	return paths, nil
+}
+
+// rotatedFiles returns the rotated journal files, oldest first
+func (j *FileJournal) rotatedFiles() ([]string, error) {
+	dirEntries, err := os.ReadDir(j.dirPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal files: %w", err)
+	}
+
+	var paths []string
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !strings.HasPrefix(name, journalRotatedPrefix) || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		paths = append(paths, filepath.Join(j.dirPath, name))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readJournalFile calls fn for every non-empty line of a journal file
+func readJournalFile(path string, fn func(line []byte)) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Rotated away since the directory was listed
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJournalLineSize)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			fn(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read journal file %s: %w", path, err)
+	}
+	return nil
+}
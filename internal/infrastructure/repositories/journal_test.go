@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func journalEntry(requestID, model string, timestamp time.Time) *entities.JournalEntry {
+	return &entities.JournalEntry{
+		Timestamp: timestamp,
+		RequestID: requestID,
+		APIKeyID:  "key-1",
+		Model:     model,
+		Request: &entities.ChatCompletionRequest{
+			Model:    model,
+			Messages: []entities.ChatMessage{{Role: "user", Content: "Hello " + requestID}},
+		},
+		Response:  cachedResponse("chatcmpl-" + requestID),
+		LatencyMs: 42,
+	}
+}
+
+func TestFileJournal_AppendSearch(t *testing.T) {
+	dir := t.TempDir()
+	journal := NewFileJournal(dir, 0, 0)
+	defer journal.Close()
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, journal.Append(journalEntry("req-1", "qwen3-coder-plus", base)))
+	require.NoError(t, journal.Append(journalEntry("req-2", "qwen3-coder-flash", base.Add(time.Minute))))
+	require.NoError(t, journal.Append(journalEntry("req-3", "qwen3-coder-plus", base.Add(2*time.Minute))))
+
+	entries, err := journal.Search(entities.JournalQuery{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "req-1", entries[0].RequestID)
+	assert.Equal(t, "Hello req-1", entries[0].Request.Messages[0].Content)
+	assert.Equal(t, "Hello", entries[0].Response.Choices[0].Message.Content)
+	assert.Equal(t, int64(42), entries[0].LatencyMs)
+
+	entries, err = journal.Search(entities.JournalQuery{Model: "qwen3-coder-plus"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "req-3", entries[1].RequestID)
+
+	entries, err = journal.Search(entities.JournalQuery{Since: base.Add(time.Minute), Until: base.Add(2 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "req-2", entries[0].RequestID)
+
+	entries, err = journal.Search(entities.JournalQuery{Contains: "HELLO REQ-3"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "req-3", entries[0].RequestID)
+
+	entries, err = journal.Search(entities.JournalQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "req-2", entries[0].RequestID)
+}
+
+func TestFileJournal_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	journal := NewFileJournal(dir, 1, 2).(*FileJournal)
+	defer journal.Close()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	journal.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	// Every entry exceeds the size bound, so each append after the first rotates the journal
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, journal.Append(journalEntry("req-"+string(rune('0'+i)), "qwen3-coder-plus", now)))
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, dirEntry := range dirEntries {
+		names = append(names, dirEntry.Name())
+	}
+	assert.Len(t, names, 3)
+	assert.Contains(t, names, journalFileName)
+
+	// The oldest rotated file was removed
+	entries, err := journal.Search(entities.JournalQuery{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "req-2", entries[0].RequestID)
+	assert.Equal(t, "req-4", entries[2].RequestID)
+}
+
+func TestFileJournal_ReopensExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	journal := NewFileJournal(dir, 0, 0)
+	require.NoError(t, journal.Append(journalEntry("req-1", "qwen3-coder-plus", time.Now())))
+	require.NoError(t, journal.Close())
+
+	journal = NewFileJournal(dir, 0, 0)
+	defer journal.Close()
+	require.NoError(t, journal.Append(journalEntry("req-2", "qwen3-coder-plus", time.Now())))
+
+	entries, err := journal.Search(entities.JournalQuery{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestFileJournal_SkipsCorruptLines(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"request_id":"req-1","model":"m","timestamp":"2025-01-01T00:00:00Z"}` + "\n" + `{"request_id":"req-2","mod`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, journalFileName), []byte(content), 0600))
+
+	entries, err := NewFileJournal(dir, 0, 0).Search(entities.JournalQuery{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "req-1", entries[0].RequestID)
+}
+
+func TestFileJournal_SearchMissingDirectory(t *testing.T) {
+	entries, err := NewFileJournal(filepath.Join(t.TempDir(), "missing"), 0, 0).Search(entities.JournalQuery{RequestID: "req-1"})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestFileJournal_AppendNil(t *testing.T) {
+	err := NewFileJournal(t.TempDir(), 0, 0).Append(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be nil")
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// validResponseID restricts response IDs to characters that are safe to use as file names
+var validResponseID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// responseFileExtension is the extension of stored response files
+const responseFileExtension = ".json"
+
+// FileResponseRepository implements ResponseRepository using file storage.
+// Each response is stored as a separate JSON file named after its ID. Responses older than the
+// TTL are removed, as are the oldest responses beyond the entry bound.
+type FileResponseRepository struct {
+	mu         sync.Mutex
+	dirPath    string
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+// NewFileResponseRepository creates a new file-based response repository.
+// Responses are stored in a "responses" subdirectory of the provided directory.
+// maxEntries of 0 means unbounded, and a ttl of 0 means responses never expire.
+func NewFileResponseRepository(qwenDir string, maxEntries int, ttl time.Duration) interfaces.ResponseRepository {
+	// Use current working directory as base path
+	workDir, err := os.Getwd()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to get current working directory: %v", err))
+	}
+	return &FileResponseRepository{
+		dirPath:    filepath.Join(workDir, qwenDir, "responses"),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+	}
+}
+
+// Load loads a stored response from file.
+func (r *FileResponseRepository) Load(id string) (*entities.StoredResponse, error) {
+	path, err := r.pathFor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if info, err := os.Stat(path); err == nil && r.expired(info.ModTime()) {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to read stored response %s: %w", id, os.ErrNotExist)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored response %s: %w", id, err)
+	}
+
+	var response entities.StoredResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse stored response %s: %w", id, err)
+	}
+
+	return &response, nil
+}
+
+// Save saves a response to file, creating the storage directory if necessary,
+// and removes the responses that expired or exceed the entry bound.
+func (r *FileResponseRepository) Save(response *entities.StoredResponse) error {
+	if response == nil {
+		return fmt.Errorf("response cannot be nil")
+	}
+	path, err := r.pathFor(response.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.dirPath, 0700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", r.dirPath, err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write stored response %s: %w", response.ID, err)
+	}
+
+	return r.prune()
+}
+
+// prune removes expired responses and the oldest responses beyond the entry bound; the caller must hold the lock
+func (r *FileResponseRepository) prune() error {
+	if r.ttl <= 0 && r.maxEntries <= 0 {
+		return nil
+	}
+
+	dirEntries, err := os.ReadDir(r.dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to list stored responses: %w", err)
+	}
+
+	type responseFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []responseFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), responseFileExtension) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, responseFile{path: filepath.Join(r.dirPath, dirEntry.Name()), modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for i, file := range files {
+		if !r.expired(file.modTime) && (r.maxEntries <= 0 || len(files)-i <= r.maxEntries) {
+			break
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove stored response: %w", err)
+		}
+	}
+	return nil
+}
+
+// expired reports whether a response written at modTime has outlived the TTL
+func (r *FileResponseRepository) expired(modTime time.Time) bool {
+	return r.ttl > 0 && !r.now().Before(modTime.Add(r.ttl))
+}
+
+// pathFor returns the file path for a response ID, rejecting IDs that could escape the directory
+func (r *FileResponseRepository) pathFor(id string) (string, error) {
+	if !validResponseID.MatchString(id) {
+		return "", fmt.Errorf("invalid response ID: %q", id)
+	}
+	return filepath.Join(r.dirPath, id+responseFileExtension), nil
+}
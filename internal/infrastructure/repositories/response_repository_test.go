@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileResponseRepository(t *testing.T) {
+	repo := NewFileResponseRepository(".qwen-test", 100, time.Hour)
+	assert.NotNil(t, repo)
+}
+
+func TestFileResponseRepository_Save_Load_Success(t *testing.T) {
+	tempDir := t.TempDir()
+
+	repo := &FileResponseRepository{
+		dirPath: filepath.Join(tempDir, "responses"),
+	}
+
+	stored := &entities.StoredResponse{
+		ID:    "resp_abc123",
+		Model: "qwen3-coder-plus",
+		Messages: []entities.ChatMessage{
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "Hi there"},
+		},
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+	}
+
+	err := repo.Save(stored)
+	assert.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(tempDir, "responses", "resp_abc123.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := repo.Load("resp_abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, stored.ID, loaded.ID)
+	assert.Equal(t, stored.Model, loaded.Model)
+	assert.Len(t, loaded.Messages, 2)
+	assert.Equal(t, "Hi there", loaded.Messages[1].Content)
+	assert.True(t, stored.CreatedAt.Equal(loaded.CreatedAt))
+}
+
+func TestFileResponseRepository_Load_NotFound(t *testing.T) {
+	repo := &FileResponseRepository{
+		dirPath: filepath.Join(t.TempDir(), "responses"),
+	}
+
+	_, err := repo.Load("resp_missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read stored response")
+}
+
+func TestFileResponseRepository_InvalidID(t *testing.T) {
+	repo := &FileResponseRepository{
+		dirPath: filepath.Join(t.TempDir(), "responses"),
+	}
+
+	_, err := repo.Load("../oauth_creds")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid response ID")
+
+	err = repo.Save(&entities.StoredResponse{ID: "a/b"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid response ID")
+}
+
+func TestFileResponseRepository_Expiry(t *testing.T) {
+	now := time.Now()
+	repo := &FileResponseRepository{
+		dirPath: filepath.Join(t.TempDir(), "responses"),
+		ttl:     time.Hour,
+		now:     func() time.Time { return now },
+	}
+
+	assert.NoError(t, repo.Save(&entities.StoredResponse{ID: "resp_old"}))
+	_, err := repo.Load("resp_old")
+	assert.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, err = repo.Load("resp_old")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(repo.dirPath, "resp_old.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileResponseRepository_PrunesOnSave(t *testing.T) {
+	now := time.Now()
+	repo := &FileResponseRepository{
+		dirPath:    filepath.Join(t.TempDir(), "responses"),
+		maxEntries: 2,
+		ttl:        time.Hour,
+		now:        func() time.Time { return now },
+	}
+
+	// Files are aged by modification time, oldest first
+	for i, id := range []string{"resp_1", "resp_2", "resp_3"} {
+		assert.NoError(t, repo.Save(&entities.StoredResponse{ID: id}))
+		modTime := now.Add(time.Duration(i-3) * time.Minute)
+		assert.NoError(t, os.Chtimes(filepath.Join(repo.dirPath, id+".json"), modTime, modTime))
+	}
+	assert.NoError(t, repo.Save(&entities.StoredResponse{ID: "resp_4"}))
+
+	entries, err := os.ReadDir(repo.dirPath)
+	assert.NoError(t, err)
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"resp_3.json", "resp_4.json"}, ids)
+
+	// Expired responses are removed even below the entry bound
+	repo.maxEntries = 10
+	expired := now.Add(-2 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(repo.dirPath, "resp_3.json"), expired, expired))
+	assert.NoError(t, repo.Save(&entities.StoredResponse{ID: "resp_5"}))
+
+	entries, err = os.ReadDir(repo.dirPath)
+	assert.NoError(t, err)
+	ids = nil
+	for _, entry := range entries {
+		ids = append(ids, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"resp_4.json", "resp_5.json"}, ids)
+}
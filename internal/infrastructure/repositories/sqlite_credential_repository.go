@@ -0,0 +1,173 @@
+//go:build cgo
+
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+
+	// Registers the sqlite3 driver, which requires cgo
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteBusyTimeout is how long a write waits for another connection or process to release the database
+const sqliteBusyTimeout = 5 * time.Second
+
+const sqliteCredentialsSchema = `CREATE TABLE IF NOT EXISTS credentials (
+	account       TEXT PRIMARY KEY,
+	version       INTEGER NOT NULL,
+	access_token  TEXT NOT NULL,
+	token_type    TEXT NOT NULL,
+	refresh_token TEXT NOT NULL,
+	expiry_date   INTEGER NOT NULL,
+	resource_url  TEXT NOT NULL,
+	updated_at    INTEGER NOT NULL
+)`
+
+// SQLiteCredentialStore stores the credentials of every account as a row of a SQLite database,
+// which several proxy processes on one host can share. Every row has a version that is incremented
+// on every save, and saves compare-and-swap it, so that of two processes refreshing the same account
+// at once only the first one stores its tokens. SQLite serializes writers with a database lock,
+// which writes wait for up to the busy timeout.
+type SQLiteCredentialStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteCredentialStore opens the SQLite credential database at path, creating it readable by the owner only
+func NewSQLiteCredentialStore(path string) (*SQLiteCredentialStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory for credential database: %w", err)
+	}
+	// Create the file up front, so that SQLite and its journal files keep its permissions
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential database: %w", err)
+	}
+	file.Close()
+
+	params := url.Values{}
+	params.Set("_busy_timeout", fmt.Sprint(sqliteBusyTimeout.Milliseconds()))
+	params.Set("_journal_mode", "WAL")
+	params.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credential database: %w", err)
+	}
+	if _, err := db.Exec(sqliteCredentialsSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create credential database schema: %w", err)
+	}
+	return &SQLiteCredentialStore{db: db}, nil
+}
+
+// Repository returns the credential repository of an account
+func (s *SQLiteCredentialStore) Repository(account string) interfaces.CredentialRepository {
+	if account == "" {
+		account = DefaultAccountName
+	}
+	return &SQLiteCredentialRepository{db: s.db, account: account}
+}
+
+// Accounts lists the accounts with stored credentials
+func (s *SQLiteCredentialStore) Accounts() ([]string, error) {
+	rows, err := s.db.Query(`SELECT account FROM credentials`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	sortAccounts(names)
+	return names, nil
+}
+
+// Close closes the database
+func (s *SQLiteCredentialStore) Close() error {
+	return s.db.Close()
+}
+
+// SQLiteCredentialRepository implements CredentialRepository with the row of an account in a SQLite database
+type SQLiteCredentialRepository struct {
+	db      *sql.DB
+	account string
+}
+
+// Load loads the credentials of the account, with their version
+func (r *SQLiteCredentialRepository) Load() (*entities.Credentials, error) {
+	var creds entities.Credentials
+	err := r.db.QueryRow(`SELECT version, access_token, token_type, refresh_token, expiry_date, resource_url
+		FROM credentials WHERE account = ?`, r.account).
+		Scan(&creds.Version, &creds.AccessToken, &creds.TokenType, &creds.RefreshToken, &creds.ExpiryDate, &creds.ResourceURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: no credentials stored for account %s", r.account)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: %w", err)
+	}
+	return &creds, nil
+}
+
+// Save stores the credentials of the account. Credentials with a version replace the row only
+// while it still has that version, and entities.ErrCredentialConflict is returned otherwise.
+// On success the version of the credentials is set to the new version of the row.
+func (r *SQLiteCredentialRepository) Save(credentials *entities.Credentials) error {
+	now := time.Now().UnixMilli()
+	var row *sql.Row
+	if credentials.Version == 0 {
+		row = r.db.QueryRow(`INSERT INTO credentials
+			(account, version, access_token, token_type, refresh_token, expiry_date, resource_url, updated_at)
+			VALUES (?, 1, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (account) DO UPDATE SET
+				version = version + 1,
+				access_token = excluded.access_token,
+				token_type = excluded.token_type,
+				refresh_token = excluded.refresh_token,
+				expiry_date = excluded.expiry_date,
+				resource_url = excluded.resource_url,
+				updated_at = excluded.updated_at
+			RETURNING version`,
+			r.account, credentials.AccessToken, credentials.TokenType, credentials.RefreshToken,
+			credentials.ExpiryDate, credentials.ResourceURL, now)
+	} else {
+		row = r.db.QueryRow(`UPDATE credentials SET
+				version = version + 1,
+				access_token = ?,
+				token_type = ?,
+				refresh_token = ?,
+				expiry_date = ?,
+				resource_url = ?,
+				updated_at = ?
+			WHERE account = ? AND version = ?
+			RETURNING version`,
+			credentials.AccessToken, credentials.TokenType, credentials.RefreshToken,
+			credentials.ExpiryDate, credentials.ResourceURL, now, r.account, credentials.Version)
+	}
+
+	var version int64
+	if err := row.Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to save credentials of account %s at version %d: %w", r.account, credentials.Version, entities.ErrCredentialConflict)
+		}
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	credentials.Version = version
+	return nil
+}
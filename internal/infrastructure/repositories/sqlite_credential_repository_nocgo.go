@@ -0,0 +1,35 @@
+//go:build !cgo
+
+package repositories
+
+import (
+	"fmt"
+
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// errSQLiteWithoutCgo is returned by the SQLite credential store in builds without cgo
+var errSQLiteWithoutCgo = fmt.Errorf("the SQLite credential store is not available in this build, which was built without cgo (CGO_ENABLED=0)")
+
+// SQLiteCredentialStore is unavailable in builds without cgo, which the SQLite driver requires
+type SQLiteCredentialStore struct{}
+
+// NewSQLiteCredentialStore fails in builds without cgo
+func NewSQLiteCredentialStore(path string) (*SQLiteCredentialStore, error) {
+	return nil, errSQLiteWithoutCgo
+}
+
+// Repository is never called, the store cannot be created without cgo
+func (s *SQLiteCredentialStore) Repository(account string) interfaces.CredentialRepository {
+	panic("the SQLite credential store is not available without cgo")
+}
+
+// Accounts fails in builds without cgo
+func (s *SQLiteCredentialStore) Accounts() ([]string, error) {
+	return nil, errSQLiteWithoutCgo
+}
+
+// Close does nothing in builds without cgo
+func (s *SQLiteCredentialStore) Close() error {
+	return nil
+}
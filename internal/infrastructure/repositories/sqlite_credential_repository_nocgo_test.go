@@ -0,0 +1,23 @@
+//go:build !cgo
+
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLiteCredentialStore_WithoutCgo(t *testing.T) {
+	store, err := NewSQLiteCredentialStore(filepath.Join(t.TempDir(), "credentials.db"))
+	require.Error(t, err)
+	assert.Nil(t, store)
+	assert.Contains(t, err.Error(), "CGO_ENABLED=0")
+
+	_, err = NewCredentialStore(&entities.Config{CredentialStore: "sqlite", CredentialSQLitePath: filepath.Join(t.TempDir(), "credentials.db")})
+	assert.ErrorIs(t, err, errSQLiteWithoutCgo)
+}
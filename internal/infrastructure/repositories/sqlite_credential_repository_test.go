@@ -0,0 +1,144 @@
+//go:build cgo
+
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteStore(t *testing.T) (*SQLiteCredentialStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data", "credentials.db")
+	store, err := NewSQLiteCredentialStore(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store, path
+}
+
+func TestNewSQLiteCredentialStore(t *testing.T) {
+	_, path := newTestSQLiteStore(t)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestNewCredentialStore_SQLite(t *testing.T) {
+	store, err := NewCredentialStore(&entities.Config{CredentialStore: "sqlite", CredentialSQLitePath: filepath.Join(t.TempDir(), "credentials.db")})
+	require.NoError(t, err)
+	assert.IsType(t, &SQLiteCredentialStore{}, store)
+	require.NoError(t, store.Close())
+}
+
+func TestSQLiteCredentialRepository_Save_Load(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+	repo := store.Repository(DefaultAccountName)
+
+	_, err := repo.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no credentials stored for account default")
+
+	creds := testCredentials()
+	require.NoError(t, repo.Save(creds))
+	assert.Equal(t, int64(1), creds.Version)
+
+	loaded, err := repo.Load()
+	require.NoError(t, err)
+	assert.Equal(t, creds, loaded)
+
+	// Saving without a version replaces the credentials unconditionally
+	replaced := testCredentials()
+	replaced.AccessToken = "replaced-token"
+	require.NoError(t, repo.Save(replaced))
+	assert.Equal(t, int64(2), replaced.Version)
+
+	loaded, err = repo.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "replaced-token", loaded.AccessToken)
+}
+
+func TestSQLiteCredentialRepository_Save_Conflict(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+	require.NoError(t, store.Repository(DefaultAccountName).Save(testCredentials()))
+
+	// Two replicas load the same version and both refresh it
+	first, err := store.Repository(DefaultAccountName).Load()
+	require.NoError(t, err)
+	second, err := store.Repository(DefaultAccountName).Load()
+	require.NoError(t, err)
+
+	first.AccessToken = "first-token"
+	require.NoError(t, store.Repository(DefaultAccountName).Save(first))
+	assert.Equal(t, int64(2), first.Version)
+
+	second.AccessToken = "second-token"
+	err = store.Repository(DefaultAccountName).Save(second)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, entities.ErrCredentialConflict)
+
+	loaded, err := store.Repository(DefaultAccountName).Load()
+	require.NoError(t, err)
+	assert.Equal(t, "first-token", loaded.AccessToken)
+	assert.Equal(t, int64(2), loaded.Version)
+}
+
+func TestSQLiteCredentialRepository_Save_ConcurrentConflicts(t *testing.T) {
+	store, _ := newTestSQLiteStore(t)
+	creds := testCredentials()
+	require.NoError(t, store.Repository(DefaultAccountName).Save(creds))
+
+	// Of many writers replacing the same version, exactly one succeeds
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			update := *creds
+			errs[i] = store.Repository(DefaultAccountName).Save(&update)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, entities.ErrCredentialConflict)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestSQLiteCredentialStore_Accounts(t *testing.T) {
+	store, path := newTestSQLiteStore(t)
+
+	accounts, err := store.Accounts()
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	for _, name := range []string{"work", DefaultAccountName, "backup"} {
+		require.NoError(t, store.Repository(name).Save(testCredentials()))
+	}
+	accounts, err = store.Accounts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultAccountName, "backup", "work"}, accounts)
+
+	// Another process opening the same database shares the credentials
+	other, err := NewSQLiteCredentialStore(path)
+	require.NoError(t, err)
+	defer other.Close()
+	loaded, err := other.Repository("work").Load()
+	require.NoError(t, err)
+	assert.Equal(t, "test-access-token", loaded.AccessToken)
+}
@@ -0,0 +1,249 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// vaultRequestTimeout bounds every request to Vault
+const vaultRequestTimeout = 10 * time.Second
+
+// VaultConfig configures the Vault KV v2 secrets engine credentials are stored in
+type VaultConfig struct {
+	// Address is the Vault server URL, e.g. https://vault.example.com:8200
+	Address string
+	Token   string // Sensitive: never log this value
+	// Namespace is the Vault Enterprise namespace, empty for the root namespace
+	Namespace string
+	// Mount is the mount path of the KV v2 secrets engine
+	Mount string
+	// Path is the secret path under which every account is stored as <path>/<account>
+	Path string
+}
+
+// VaultCredentialStore stores the credentials of every account as a secret of a Vault KV v2
+// secrets engine, or of a server that implements its HTTP API, so that proxy replicas on several
+// hosts can share them. Saves use the check-and-set option on the secret version, so that of two
+// replicas refreshing the same account at once only the first one stores its tokens.
+type VaultCredentialStore struct {
+	httpClient *http.Client
+	config     VaultConfig
+}
+
+// vaultResponse is the body of Vault responses
+type vaultResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []string        `json:"errors"`
+}
+
+// vaultSecret is the data of a KV v2 read
+type vaultSecret struct {
+	Data     *entities.Credentials `json:"data"`
+	Metadata struct {
+		Version int64 `json:"version"`
+	} `json:"metadata"`
+}
+
+// vaultWriteRequest is the body of a KV v2 write
+type vaultWriteRequest struct {
+	Options *vaultWriteOptions    `json:"options,omitempty"`
+	Data    *entities.Credentials `json:"data"`
+}
+
+// vaultWriteOptions are the options of a KV v2 write
+type vaultWriteOptions struct {
+	CAS int64 `json:"cas"`
+}
+
+// NewVaultCredentialStore creates a credential store in the Vault KV v2 secrets engine
+func NewVaultCredentialStore(config VaultConfig) *VaultCredentialStore {
+	if config.Address == "" {
+		panic("vault address cannot be empty")
+	}
+	config.Address = strings.TrimRight(config.Address, "/")
+	config.Mount = strings.Trim(config.Mount, "/")
+	config.Path = strings.Trim(config.Path, "/")
+	return &VaultCredentialStore{
+		httpClient: &http.Client{
+			Timeout: vaultRequestTimeout,
+		},
+		config: config,
+	}
+}
+
+// Repository returns the credential repository of an account
+func (s *VaultCredentialStore) Repository(account string) interfaces.CredentialRepository {
+	if account == "" {
+		account = DefaultAccountName
+	}
+	return &VaultCredentialRepository{store: s, account: account}
+}
+
+// Accounts lists the accounts with stored credentials
+func (s *VaultCredentialStore) Accounts() ([]string, error) {
+	status, body, err := s.do("LIST", s.url("metadata", ""), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to list accounts: %s", vaultError(status, body))
+	}
+
+	var list struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(body.Data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse account list: %w", err)
+	}
+	names := make([]string, 0, len(list.Keys))
+	for _, key := range list.Keys {
+		// Keys ending in a slash are folders, not secrets
+		if !strings.HasSuffix(key, "/") {
+			names = append(names, key)
+		}
+	}
+	sortAccounts(names)
+	return names, nil
+}
+
+// Close does nothing, requests do not hold resources
+func (s *VaultCredentialStore) Close() error {
+	return nil
+}
+
+// url returns the URL of a KV v2 endpoint ("data" or "metadata") for an account, or for the path itself
+func (s *VaultCredentialStore) url(endpoint, account string) string {
+	path := s.config.Path
+	if account != "" {
+		path += "/" + url.PathEscape(account)
+	}
+	return fmt.Sprintf("%s/v1/%s/%s/%s", s.config.Address, s.config.Mount, endpoint, path)
+}
+
+// do sends a request to Vault and returns the status and parsed body of its response
+func (s *VaultCredentialStore) do(method, target string, payload any) (int, *vaultResponse, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vaultRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", s.config.Token)
+	req.Header.Set("X-Vault-Request", "true")
+	if s.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var parsed vaultResponse
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &parsed); err != nil && resp.StatusCode < 300 {
+			return 0, nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return resp.StatusCode, &parsed, nil
+}
+
+// vaultError describes a failed Vault response
+func vaultError(status int, body *vaultResponse) string {
+	if len(body.Errors) > 0 {
+		return fmt.Sprintf("vault returned status %d: %s", status, strings.Join(body.Errors, "; "))
+	}
+	return fmt.Sprintf("vault returned status %d", status)
+}
+
+// VaultCredentialRepository implements CredentialRepository with the secret of an account in Vault
+type VaultCredentialRepository struct {
+	store   *VaultCredentialStore
+	account string
+}
+
+// Load reads the latest version of the credentials of the account
+func (r *VaultCredentialRepository) Load() (*entities.Credentials, error) {
+	status, body, err := r.store.do(http.MethodGet, r.store.url("data", r.account), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: %w", err)
+	}
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: no credentials stored for account %s", r.account)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: %s", vaultError(status, body))
+	}
+
+	var secret vaultSecret
+	if err := json.Unmarshal(body.Data, &secret); err != nil {
+		return nil, fmt.Errorf("failed to parse Qwen OAuth credentials: %w", err)
+	}
+	// The latest version of a deleted secret has no data
+	if secret.Data == nil {
+		return nil, fmt.Errorf("failed to read Qwen OAuth credentials: no credentials stored for account %s", r.account)
+	}
+	secret.Data.Version = secret.Metadata.Version
+	return secret.Data, nil
+}
+
+// Save writes a new version of the credentials of the account. Credentials with a version are
+// written with that version as check-and-set, and entities.ErrCredentialConflict is returned when
+// another version was written in the meantime. On success the version of the credentials is set
+// to the version that was written.
+func (r *VaultCredentialRepository) Save(credentials *entities.Credentials) error {
+	request := &vaultWriteRequest{Data: credentials}
+	if credentials.Version != 0 {
+		request.Options = &vaultWriteOptions{CAS: credentials.Version}
+	}
+
+	status, body, err := r.store.do(http.MethodPost, r.store.url("data", r.account), request)
+	if err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	if status == http.StatusBadRequest && strings.Contains(strings.Join(body.Errors, " "), "check-and-set") {
+		return fmt.Errorf("failed to save credentials of account %s at version %d: %w", r.account, credentials.Version, entities.ErrCredentialConflict)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("failed to save credentials: %s", vaultError(status, body))
+	}
+
+	var metadata struct {
+		Version int64 `json:"version"`
+	}
+	if err := json.Unmarshal(body.Data, &metadata); err != nil {
+		return fmt.Errorf("failed to parse saved credentials version: %w", err)
+	}
+	credentials.Version = metadata.Version
+	return nil
+}
@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vaultStub is a local server that implements the parts of the Vault KV v2 API the store uses
+type vaultStub struct {
+	t         *testing.T
+	token     string
+	namespace string
+
+	mu      sync.Mutex
+	secrets map[string][]json.RawMessage // versions of every secret, by path
+}
+
+func newVaultStub(t *testing.T) (*vaultStub, *httptest.Server) {
+	stub := &vaultStub{t: t, token: "test-token", secrets: map[string][]json.RawMessage{}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (v *vaultStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != v.token {
+		v.respond(w, http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+	if r.Header.Get("X-Vault-Namespace") != v.namespace {
+		v.respond(w, http.StatusNotFound, map[string]any{"errors": []string{"no handler for route"}})
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch {
+	case r.Method == "LIST" && strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		prefix := strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/") + "/"
+		var keys []string
+		for path := range v.secrets {
+			if key, ok := strings.CutPrefix(path, prefix); ok {
+				if folder, _, nested := strings.Cut(key, "/"); nested {
+					key = folder + "/"
+				}
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			v.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		sort.Strings(keys)
+		v.respond(w, http.StatusOK, map[string]any{"data": map[string]any{"keys": keys}})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		versions := v.secrets[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")]
+		if len(versions) == 0 {
+			v.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		v.respond(w, http.StatusOK, map[string]any{"data": map[string]any{
+			"data":     versions[len(versions)-1],
+			"metadata": map[string]any{"version": len(versions)},
+		}})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		path := strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")
+		var request struct {
+			Options *struct {
+				CAS *int `json:"cas"`
+			} `json:"options"`
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(v.t, json.NewDecoder(r.Body).Decode(&request))
+		if request.Options != nil && request.Options.CAS != nil && *request.Options.CAS != len(v.secrets[path]) {
+			v.respond(w, http.StatusBadRequest, map[string]any{"errors": []string{"check-and-set parameter did not match the current version"}})
+			return
+		}
+		v.secrets[path] = append(v.secrets[path], request.Data)
+		v.respond(w, http.StatusOK, map[string]any{"data": map[string]any{"version": len(v.secrets[path])}})
+	default:
+		v.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+	}
+}
+
+func (v *vaultStub) respond(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func newTestVaultStore(server *httptest.Server) *VaultCredentialStore {
+	return NewVaultCredentialStore(VaultConfig{Address: server.URL + "/", Token: "test-token", Mount: "secret", Path: "/qwen-go-proxy/"})
+}
+
+func TestNewVaultCredentialStore(t *testing.T) {
+	assert.Panics(t, func() { NewVaultCredentialStore(VaultConfig{}) })
+}
+
+func TestVaultCredentialRepository_Save_Load(t *testing.T) {
+	stub, server := newVaultStub(t)
+	repo := newTestVaultStore(server).Repository(DefaultAccountName)
+
+	_, err := repo.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no credentials stored for account default")
+
+	creds := testCredentials()
+	require.NoError(t, repo.Save(creds))
+	assert.Equal(t, int64(1), creds.Version)
+	assert.Contains(t, stub.secrets, "qwen-go-proxy/default")
+
+	loaded, err := repo.Load()
+	require.NoError(t, err)
+	assert.Equal(t, creds, loaded)
+
+	// Saving without a version writes a new version unconditionally
+	replaced := testCredentials()
+	replaced.AccessToken = "replaced-token"
+	require.NoError(t, repo.Save(replaced))
+	assert.Equal(t, int64(2), replaced.Version)
+}
+
+func TestVaultCredentialRepository_Save_Conflict(t *testing.T) {
+	_, server := newVaultStub(t)
+	store := newTestVaultStore(server)
+	require.NoError(t, store.Repository(DefaultAccountName).Save(testCredentials()))
+
+	// Two replicas load the same version and both refresh it
+	first, err := store.Repository(DefaultAccountName).Load()
+	require.NoError(t, err)
+	second, err := store.Repository(DefaultAccountName).Load()
+	require.NoError(t, err)
+
+	first.AccessToken = "first-token"
+	require.NoError(t, store.Repository(DefaultAccountName).Save(first))
+	assert.Equal(t, int64(2), first.Version)
+
+	second.AccessToken = "second-token"
+	err = store.Repository(DefaultAccountName).Save(second)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, entities.ErrCredentialConflict)
+
+	loaded, err := store.Repository(DefaultAccountName).Load()
+	require.NoError(t, err)
+	assert.Equal(t, "first-token", loaded.AccessToken)
+	assert.Equal(t, int64(2), loaded.Version)
+}
+
+func TestVaultCredentialStore_Accounts(t *testing.T) {
+	stub, server := newVaultStub(t)
+	store := newTestVaultStore(server)
+
+	accounts, err := store.Accounts()
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	for _, name := range []string{"work", DefaultAccountName, "backup"} {
+		require.NoError(t, store.Repository(name).Save(testCredentials()))
+	}
+	stub.secrets["qwen-go-proxy/team/shared"] = []json.RawMessage{json.RawMessage(`{}`)}
+
+	accounts, err = store.Accounts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultAccountName, "backup", "work"}, accounts)
+}
+
+func TestVaultCredentialStore_Namespace(t *testing.T) {
+	stub, server := newVaultStub(t)
+	stub.namespace = "team-a"
+
+	_, err := newTestVaultStore(server).Repository(DefaultAccountName).Load()
+	require.Error(t, err)
+
+	store := NewVaultCredentialStore(VaultConfig{Address: server.URL, Token: "test-token", Namespace: "team-a", Mount: "secret", Path: "qwen-go-proxy"})
+	require.NoError(t, store.Repository(DefaultAccountName).Save(testCredentials()))
+}
+
+func TestVaultCredentialRepository_Errors(t *testing.T) {
+	_, server := newVaultStub(t)
+	store := NewVaultCredentialStore(VaultConfig{Address: server.URL, Token: "wrong-token", Mount: "secret", Path: "qwen-go-proxy"})
+
+	_, err := store.Repository(DefaultAccountName).Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vault returned status 403: permission denied")
+
+	err = store.Repository(DefaultAccountName).Save(testCredentials())
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, entities.ErrCredentialConflict)
+	assert.Contains(t, err.Error(), "permission denied")
+
+	_, err = store.Accounts()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to list accounts")
+
+	server.Close()
+	_, err = newTestVaultStore(server).Repository(DefaultAccountName).Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to send request")
+}
@@ -12,13 +12,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os/exec"
-	"runtime"
+	"net/url"
 	"strings"
 	"time"
 
 	"qwen-go-proxy/internal/domain/entities"
 	"qwen-go-proxy/internal/domain/interfaces"
+	"qwen-go-proxy/internal/infrastructure/tracing"
 
 	"golang.org/x/oauth2"
 )
@@ -99,8 +99,8 @@ func (s *OAuthService) RefreshToken(refreshToken, clientID string) (*entities.Cr
 	}, nil
 }
 
-// AuthenticateWithDeviceFlow performs OAuth2 device authorization flow with PKCE.
-func (s *OAuthService) AuthenticateWithDeviceFlow(clientID, scope string) (*entities.Credentials, error) {
+// StartDeviceFlow requests an OAuth2 device authorization with PKCE.
+func (s *OAuthService) StartDeviceFlow(ctx context.Context, clientID, scope string) (*entities.DeviceAuthorization, error) {
 	conf := &oauth2.Config{
 		ClientID: clientID,
 		Scopes:   []string{scope},
@@ -110,15 +110,13 @@ func (s *OAuthService) AuthenticateWithDeviceFlow(clientID, scope string) (*enti
 		},
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
 	codeVerifier, err := generateCodeVerifier()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate code verifier: %w", err)
 	}
 	codeChallenge := generateCodeChallenge(codeVerifier)
 
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, s.httpClient)
 	deviceAuthResponse, err := conf.DeviceAuth(ctx,
 		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
 		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
@@ -127,41 +125,50 @@ func (s *OAuthService) AuthenticateWithDeviceFlow(clientID, scope string) (*enti
 		return nil, fmt.Errorf("failed to start device auth flow: %w", err)
 	}
 
+	authorization := &entities.DeviceAuthorization{
+		DeviceCode:              deviceAuthResponse.DeviceCode,
+		UserCode:                deviceAuthResponse.UserCode,
+		VerificationURI:         deviceAuthResponse.VerificationURI,
+		VerificationURIComplete: deviceAuthResponse.VerificationURIComplete,
+		ExpiresAt:               deviceAuthResponse.Expiry,
+		Interval:                time.Duration(deviceAuthResponse.Interval) * time.Second,
+		CodeVerifier:            codeVerifier,
+	}
 	// Construct verification URL with user code and client parameter
-	var verificationURL string
-	if deviceAuthResponse.VerificationURIComplete != "" {
-		verificationURL = deviceAuthResponse.VerificationURIComplete
-	} else {
-		verificationURL = fmt.Sprintf("%s?user_code=%s&client=qwen-code", deviceAuthResponse.VerificationURI, deviceAuthResponse.UserCode)
+	if authorization.VerificationURIComplete == "" {
+		authorization.VerificationURIComplete = fmt.Sprintf("%s?user_code=%s&client=qwen-code", authorization.VerificationURI, authorization.UserCode)
 	}
+	return authorization, nil
+}
 
-	// Try to open the verification URI in the browser
-	if err := openBrowser(verificationURL); err != nil {
-		fmt.Printf("Failed to open browser automatically: %v. Please open the URL manually.\n", err)
+// PollDeviceToken asks once for the token of a device authorization.
+func (s *OAuthService) PollDeviceToken(ctx context.Context, clientID string, authorization *entities.DeviceAuthorization) (*entities.Credentials, error) {
+	data := url.Values{
+		"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+		"client_id":     {clientID},
+		"device_code":   {authorization.DeviceCode},
+		"code_verifier": {authorization.CodeVerifier},
 	}
 
-	fmt.Printf("\n=== Qwen OAuth Authentication ===\n")
-	fmt.Printf("If your browser didn't open, please go to: %s\n", verificationURL)
-	fmt.Printf("And enter this code: %s\n\n", deviceAuthResponse.UserCode)
-	fmt.Println("Waiting for authorization...")
-
-	token, err := conf.DeviceAccessToken(ctx, deviceAuthResponse, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get token: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	creds := &entities.Credentials{
-		AccessToken:  token.AccessToken,
-		TokenType:    token.TokenType,
-		RefreshToken: token.RefreshToken,
-		ExpiryDate:   token.Expiry.UnixMilli(),
-	}
-	if resourceURL, ok := token.Extra("resource_url").(string); ok {
-		creds.ResourceURL = resourceURL
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
+	defer resp.Body.Close()
 
-	fmt.Println("Authentication successful! Credentials obtained.")
-	return creds, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return parseDeviceTokenResponse(resp.StatusCode, body)
 }
 
 // AIService implements the AIService interface for AI API operations.
@@ -173,20 +180,26 @@ type AIService struct {
 // NewAIService creates a new AI service implementation.
 func NewAIService(config *entities.Config) interfaces.AIService {
 	return &AIService{
-		httpClient: &http.Client{
-			Timeout: 300 * time.Second, // DefaultHTTPTimeout
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
-		config: config,
+		httpClient: newUpstreamHTTPClient(),
+		config:     config,
+	}
+}
+
+// newUpstreamHTTPClient creates the HTTP client used for AI API calls.
+func newUpstreamHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 300 * time.Second, // DefaultHTTPTimeout
+		// Every upstream call is traced and carries the caller's traceparent
+		Transport: tracing.NewTransport(&http.Transport{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+		}),
 	}
 }
 
 // ChatCompletions makes a chat completion request to the AI API.
-func (s *AIService) ChatCompletions(req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+func (s *AIService) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
 	if req == nil {
 		return nil, fmt.Errorf("request cannot be nil")
 	}
@@ -203,7 +216,7 @@ func (s *AIService) ChatCompletions(req *entities.ChatCompletionRequest, credent
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", qwenURL, bytes.NewReader(bodyBytes))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", qwenURL, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -216,6 +229,55 @@ func (s *AIService) ChatCompletions(req *entities.ChatCompletionRequest, credent
 	return s.httpClient.Do(httpReq)
 }
 
+// Embeddings makes an embeddings request to the AI API.
+func (s *AIService) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	baseURL, err := s.GetBaseURL(credentials, s.config.APIBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base URL: %w", err)
+	}
+
+	bodyBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/embeddings", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if credentials != nil {
+		httpReq.Header.Set("Authorization", "Bearer "+credentials.AccessToken)
+	}
+
+	return s.httpClient.Do(httpReq)
+}
+
+// ListModels requests the model list from the AI API.
+func (s *AIService) ListModels(ctx context.Context, credentials *entities.Credentials) (*http.Response, error) {
+	baseURL, err := s.GetBaseURL(credentials, s.config.APIBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base URL: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	if credentials != nil {
+		httpReq.Header.Set("Authorization", "Bearer "+credentials.AccessToken)
+	}
+
+	return s.httpClient.Do(httpReq)
+}
+
 // GetBaseURL returns the base URL for API calls.
 func (s *AIService) GetBaseURL(credentials *entities.Credentials, defaultURL string) (string, error) {
 	baseURL := defaultURL
@@ -236,20 +298,48 @@ func (s *AIService) GetBaseURL(credentials *entities.Credentials, defaultURL str
 
 // Helper functions
 
-// openBrowser opens the default browser with the given URL.
-func openBrowser(url string) error {
-	var err error
-	switch runtime.GOOS {
-	case "linux":
-		err = exec.Command("xdg-open", url).Start()
-	case "windows":
-		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
-	case "darwin":
-		err = exec.Command("open", url).Start()
+// parseDeviceTokenResponse converts the response to a device token poll into credentials,
+// or into the errors that tell the caller to keep polling.
+func parseDeviceTokenResponse(statusCode int, body []byte) (*entities.Credentials, error) {
+	var tokenData struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		ExpiresIn    int    `json:"expires_in"`
+		ResourceURL  string `json:"resource_url,omitempty"`
+		Error        string `json:"error,omitempty"`
+		ErrorDesc    string `json:"error_description,omitempty"`
+	}
+	if err := json.Unmarshal(body, &tokenData); err != nil {
+		if statusCode != http.StatusOK {
+			return nil, fmt.Errorf("device token request failed with status %d: %s", statusCode, string(body))
+		}
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	switch tokenData.Error {
+	case "":
+	case "authorization_pending":
+		return nil, entities.ErrAuthorizationPending
+	case "slow_down":
+		return nil, entities.ErrSlowDown
 	default:
-		err = fmt.Errorf("unsupported platform")
+		return nil, fmt.Errorf("device token request failed: %s - %s", tokenData.Error, tokenData.ErrorDesc)
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("device token request failed with status %d: %s", statusCode, string(body))
+	}
+	if tokenData.AccessToken == "" {
+		return nil, fmt.Errorf("device token response has no access token")
 	}
-	return err
+
+	return &entities.Credentials{
+		AccessToken:  tokenData.AccessToken,
+		TokenType:    tokenData.TokenType,
+		RefreshToken: tokenData.RefreshToken,
+		ExpiryDate:   time.Now().UnixMilli() + int64(tokenData.ExpiresIn*1000),
+		ResourceURL:  tokenData.ResourceURL,
+	}, nil
 }
 
 // generateCodeVerifier generates a random code verifier for PKCE.
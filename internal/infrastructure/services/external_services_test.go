@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"qwen-go-proxy/internal/domain/entities"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewOAuthService(t *testing.T) {
@@ -301,7 +303,7 @@ func TestAIService_ChatCompletions(t *testing.T) {
 		AccessToken: "test-token",
 	}
 
-	resp, err := service.ChatCompletions(req, creds)
+	resp, err := service.ChatCompletions(context.Background(), req, creds)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
@@ -309,6 +311,47 @@ func TestAIService_ChatCompletions(t *testing.T) {
 	resp.Body.Close()
 }
 
+func TestAIService_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"id":"qwen3-coder-plus","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	service := NewAIService(&entities.Config{APIBaseURL: server.URL})
+
+	resp, err := service.ListModels(context.Background(), &entities.Credentials{AccessToken: "test-token"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestAIService_Embeddings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.5]}]}`))
+	}))
+	defer server.Close()
+
+	service := NewAIService(&entities.Config{APIBaseURL: server.URL})
+
+	resp, err := service.Embeddings(context.Background(), &entities.EmbeddingRequest{Model: "text-embedding-v3", Input: "Hello"}, &entities.Credentials{AccessToken: "test-token"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	_, err = service.Embeddings(context.Background(), nil, nil)
+	assert.Error(t, err)
+}
+
 func TestAIService_ChatCompletions_InvalidRequest(t *testing.T) {
 	// Create a mock server that won't be called
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -323,7 +366,7 @@ func TestAIService_ChatCompletions_InvalidRequest(t *testing.T) {
 	service := NewAIService(config)
 
 	// Test with nil request
-	_, err := service.ChatCompletions(nil, &entities.Credentials{})
+	_, err := service.ChatCompletions(context.Background(), nil, &entities.Credentials{})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "request cannot be nil")
 }
@@ -360,7 +403,7 @@ func TestAIService_ChatCompletions_InvalidCredentials(t *testing.T) {
 	}
 
 	// Test with invalid credentials (nil)
-	resp, err := service.ChatCompletions(req, nil)
+	resp, err := service.ChatCompletions(context.Background(), req, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
@@ -390,10 +433,40 @@ func TestGenerateCodeChallenge(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestOAuthService_AuthenticateWithDeviceFlow(t *testing.T) {
-	// Since this method involves user interaction and external API calls,
-	// we can't easily test it directly. We'll focus on testing the error paths.
+func TestOAuthService_StartDeviceFlow(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/oauth2/device/code", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "test-client", r.Form.Get("client_id"))
+		assert.Equal(t, "test-scope", r.Form.Get("scope"))
+		assert.Equal(t, "S256", r.Form.Get("code_challenge_method"))
+		assert.NotEmpty(t, r.Form.Get("code_challenge"))
 
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://chat.qwen.ai/authorize",
+			"expires_in":       600,
+			"interval":         2,
+		})
+	}))
+	defer server.Close()
+
+	service := NewOAuthService(server.URL)
+
+	authorization, err := service.StartDeviceFlow(context.Background(), "test-client", "test-scope")
+	require.NoError(t, err)
+	assert.Equal(t, "device-code", authorization.DeviceCode)
+	assert.Equal(t, "ABCD-EFGH", authorization.UserCode)
+	assert.Equal(t, "https://chat.qwen.ai/authorize", authorization.VerificationURI)
+	assert.Equal(t, "https://chat.qwen.ai/authorize?user_code=ABCD-EFGH&client=qwen-code", authorization.VerificationURIComplete)
+	assert.Equal(t, 2*time.Second, authorization.Interval)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), authorization.ExpiresAt, time.Minute)
+	assert.NotEmpty(t, authorization.CodeVerifier)
+}
+
+func TestOAuthService_StartDeviceFlow_Error(t *testing.T) {
 	// Create an OAuthService with a non-existent base URL to trigger an error
 	service := &OAuthService{
 		httpClient: &http.Client{
@@ -404,6 +477,68 @@ func TestOAuthService_AuthenticateWithDeviceFlow(t *testing.T) {
 		tokenURL:      "http://non-existent-url-for-test/token",
 	}
 
-	_, err := service.AuthenticateWithDeviceFlow("test-client", "test-scope")
+	_, err := service.StartDeviceFlow(context.Background(), "test-client", "test-scope")
 	assert.Error(t, err)
 }
+
+func TestOAuthService_PollDeviceToken(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/oauth2/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:device_code", r.Form.Get("grant_type"))
+		assert.Equal(t, "test-client", r.Form.Get("client_id"))
+		assert.Equal(t, "device-code", r.Form.Get("device_code"))
+		assert.Equal(t, "verifier", r.Form.Get("code_verifier"))
+
+		polls++
+		switch polls {
+		case 1:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"slow_down"}`))
+		case 3:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"access_denied","error_description":"The user denied the request"}`))
+		default:
+			w.Write([]byte(`{"access_token":"access","token_type":"Bearer","refresh_token":"refresh","expires_in":3600,"resource_url":"portal.qwen.ai"}`))
+		}
+	}))
+	defer server.Close()
+
+	service := NewOAuthService(server.URL)
+	authorization := &entities.DeviceAuthorization{DeviceCode: "device-code", CodeVerifier: "verifier"}
+
+	_, err := service.PollDeviceToken(context.Background(), "test-client", authorization)
+	assert.ErrorIs(t, err, entities.ErrAuthorizationPending)
+
+	_, err = service.PollDeviceToken(context.Background(), "test-client", authorization)
+	assert.ErrorIs(t, err, entities.ErrSlowDown)
+
+	_, err = service.PollDeviceToken(context.Background(), "test-client", authorization)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "access_denied")
+
+	creds, err := service.PollDeviceToken(context.Background(), "test-client", authorization)
+	require.NoError(t, err)
+	assert.Equal(t, "access", creds.AccessToken)
+	assert.Equal(t, "refresh", creds.RefreshToken)
+	assert.Equal(t, "portal.qwen.ai", creds.ResourceURL)
+	assert.Greater(t, creds.ExpiryDate, time.Now().UnixMilli())
+}
+
+func TestOAuthService_PollDeviceToken_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("bad gateway"))
+	}))
+	defer server.Close()
+
+	service := NewOAuthService(server.URL)
+
+	_, err := service.PollDeviceToken(context.Background(), "test-client", &entities.DeviceAuthorization{DeviceCode: "device-code"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 502: bad gateway")
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// OpenAICompatibleService implements the AIService interface for OpenAI-compatible servers
+// such as llama.cpp or vLLM. Requests are authenticated with the provider's static bearer key,
+// if any; the Qwen credentials passed by callers are ignored.
+type OpenAICompatibleService struct {
+	httpClient *http.Client
+	provider   entities.ProviderConfig
+}
+
+// NewOpenAICompatibleService creates an AI service for an OpenAI-compatible provider.
+// The provider's base URL includes the API version prefix, e.g. http://localhost:8000/v1.
+func NewOpenAICompatibleService(provider entities.ProviderConfig) interfaces.AIService {
+	return &OpenAICompatibleService{
+		httpClient: newUpstreamHTTPClient(),
+		provider:   provider,
+	}
+}
+
+// ChatCompletions makes a chat completion request to the provider.
+func (s *OpenAICompatibleService) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	baseURL, err := s.GetBaseURL(nil, s.provider.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base URL: %w", err)
+	}
+
+	bodyBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	s.setAuthorization(httpReq)
+
+	return s.httpClient.Do(httpReq)
+}
+
+// Embeddings makes an embeddings request to the provider.
+func (s *OpenAICompatibleService) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	baseURL, err := s.GetBaseURL(nil, s.provider.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base URL: %w", err)
+	}
+
+	bodyBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/embeddings", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	s.setAuthorization(httpReq)
+
+	return s.httpClient.Do(httpReq)
+}
+
+// ListModels requests the model list from the provider.
+func (s *OpenAICompatibleService) ListModels(ctx context.Context, credentials *entities.Credentials) (*http.Response, error) {
+	baseURL, err := s.GetBaseURL(nil, s.provider.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base URL: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	s.setAuthorization(httpReq)
+
+	return s.httpClient.Do(httpReq)
+}
+
+// GetBaseURL returns the provider's base URL; credentials never override it.
+func (s *OpenAICompatibleService) GetBaseURL(credentials *entities.Credentials, defaultURL string) (string, error) {
+	baseURL := strings.TrimSuffix(defaultURL, "/")
+	if baseURL == "" {
+		return "", fmt.Errorf("base URL cannot be empty")
+	}
+	return baseURL, nil
+}
+
+// setAuthorization adds the provider's bearer key to a request.
+func (s *OpenAICompatibleService) setAuthorization(httpReq *http.Request) {
+	if s.provider.AuthType == entities.ProviderAuthBearer && s.provider.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+s.provider.APIKey)
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAICompatibleService_ChatCompletions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-local", r.Header.Get("Authorization"), "the provider key is used instead of the Qwen token")
+
+		var req entities.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "llama-3.1-8b", req.Model)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := NewOpenAICompatibleService(entities.ProviderConfig{
+		Name:     "vllm",
+		BaseURL:  server.URL + "/v1/",
+		AuthType: entities.ProviderAuthBearer,
+		APIKey:   "sk-local",
+	})
+
+	resp, err := service.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "llama-3.1-8b"}, &entities.Credentials{AccessToken: "qwen-token"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestOpenAICompatibleService_ListModels_NoAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	}))
+	defer server.Close()
+
+	service := NewOpenAICompatibleService(entities.ProviderConfig{Name: "stub", BaseURL: server.URL, AuthType: entities.ProviderAuthNone})
+
+	resp, err := service.ListModels(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestOpenAICompatibleService_NilRequest(t *testing.T) {
+	service := NewOpenAICompatibleService(entities.ProviderConfig{Name: "stub", BaseURL: "http://localhost", AuthType: entities.ProviderAuthNone})
+
+	_, err := service.ChatCompletions(context.Background(), nil, nil)
+	assert.EqualError(t, err, "request cannot be nil")
+}
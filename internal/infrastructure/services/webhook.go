@@ -0,0 +1,71 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// WebhookNotifier implements the DeviceFlowNotifier interface by posting the outcome of
+// device authorization flows as JSON to a webhook URL.
+type WebhookNotifier struct {
+	httpClient *http.Client
+	url        string
+}
+
+// webhookPayload is the JSON body posted to the webhook
+type webhookPayload struct {
+	Event      string                     `json:"event"`
+	DeviceFlow *entities.DeviceFlowStatus `json:"device_flow"`
+}
+
+// NewWebhookNotifier creates a device flow notifier that posts to the URL.
+// With an empty URL notifications are dropped.
+func NewWebhookNotifier(url string) interfaces.DeviceFlowNotifier {
+	return &WebhookNotifier{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		url: url,
+	}
+}
+
+// NotifyDeviceFlow posts a device_flow.succeeded or device_flow.failed event with the flow status.
+func (n *WebhookNotifier) NotifyDeviceFlow(ctx context.Context, status *entities.DeviceFlowStatus) error {
+	if n.url == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(&webhookPayload{
+		Event:      "device_flow." + status.State,
+		DeviceFlow: status,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
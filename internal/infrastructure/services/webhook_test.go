@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier_NotifyDeviceFlow(t *testing.T) {
+	var payload struct {
+		Event      string                    `json:"event"`
+		DeviceFlow entities.DeviceFlowStatus `json:"device_flow"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	err := notifier.NotifyDeviceFlow(context.Background(), &entities.DeviceFlowStatus{
+		State:    entities.DeviceFlowSucceeded,
+		UserCode: "ABCD-EFGH",
+		Polls:    3,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "device_flow.succeeded", payload.Event)
+	assert.Equal(t, "ABCD-EFGH", payload.DeviceFlow.UserCode)
+	assert.Equal(t, 3, payload.DeviceFlow.Polls)
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	err := notifier.NotifyDeviceFlow(context.Background(), &entities.DeviceFlowStatus{State: entities.DeviceFlowFailed})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 500")
+}
+
+func TestWebhookNotifier_Disabled(t *testing.T) {
+	notifier := NewWebhookNotifier("")
+	assert.NoError(t, notifier.NotifyDeviceFlow(context.Background(), &entities.DeviceFlowStatus{State: entities.DeviceFlowFailed}))
+}
@@ -0,0 +1,286 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxWordLength bounds the bytes merged at once, since merging is quadratic in the word length
+	maxWordLength = 256
+	// maxCachedWords bounds the cache of encoded words, which is cleared when it is full
+	maxCachedWords = 65536
+)
+
+// qwenSpecialTokens are the special tokens that follow the vocabulary of Qwen tiktoken files
+var qwenSpecialTokens = []string{"<|endoftext|>", "<|im_start|>", "<|im_end|>"}
+
+// BPE is a byte-level byte pair encoding tokenizer compatible with the Qwen models.
+// Text is split into words by the Qwen pre-tokenizer, and the bytes of every word are merged
+// pairwise by merge priority until no known pair is left. Special tokens such as <|im_end|>
+// are recognised in the text and encoded as single tokens.
+type BPE struct {
+	vocab    map[string]int
+	rank     func(left, right string) (int, bool)
+	specials map[string]int
+
+	mu    sync.Mutex
+	cache map[string][]int
+}
+
+// LoadBPE loads a tokenizer from a Hugging Face tokenizer.json file, or from a tiktoken
+// file with one base64 encoded token and its rank per line, such as qwen.tiktoken
+func LoadBPE(path string) (*BPE, error) {
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return loadHuggingFaceBPE(path)
+	}
+	return loadTiktokenBPE(path)
+}
+
+// loadHuggingFaceBPE loads the vocabulary, merges and added tokens of a tokenizer.json file
+func loadHuggingFaceBPE(path string) (*BPE, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer file: %w", err)
+	}
+	var file struct {
+		AddedTokens []struct {
+			ID      int    `json:"id"`
+			Content string `json:"content"`
+		} `json:"added_tokens"`
+		Model struct {
+			Type   string            `json:"type"`
+			Vocab  map[string]int    `json:"vocab"`
+			Merges []json.RawMessage `json:"merges"`
+		} `json:"model"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tokenizer file: %w", err)
+	}
+	if file.Model.Type != "" && file.Model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported tokenizer model %q, only BPE is supported", file.Model.Type)
+	}
+	if len(file.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("tokenizer file has no vocabulary")
+	}
+
+	decoder := byteDecoder()
+	vocab := make(map[string]int, len(file.Model.Vocab))
+	for token, id := range file.Model.Vocab {
+		vocab[decodeByteLevel(token, decoder)] = id
+	}
+	merges := make(map[[2]string]int, len(file.Model.Merges))
+	for i, raw := range file.Model.Merges {
+		// Merges are "left right" strings, or [left, right] pairs in newer files
+		var pair []string
+		var merge string
+		if err := json.Unmarshal(raw, &merge); err == nil {
+			pair = strings.SplitN(merge, " ", 2)
+		} else if err := json.Unmarshal(raw, &pair); err != nil {
+			return nil, fmt.Errorf("invalid merge %d: %s", i, raw)
+		}
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid merge %d: %s", i, raw)
+		}
+		merges[[2]string{decodeByteLevel(pair[0], decoder), decodeByteLevel(pair[1], decoder)}] = i
+	}
+
+	specials := make(map[string]int, len(file.AddedTokens))
+	for _, token := range file.AddedTokens {
+		specials[token.Content] = token.ID
+	}
+	return newBPE(vocab, func(left, right string) (int, bool) {
+		rank, ok := merges[[2]string{left, right}]
+		return rank, ok
+	}, specials), nil
+}
+
+// loadTiktokenBPE loads a tiktoken file, where the rank of a token is both its ID and the
+// priority of merging into it
+func loadTiktokenBPE(path string) (*BPE, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer file: %w", err)
+	}
+	defer file.Close()
+
+	vocab := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid tokenizer line %d", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid token on tokenizer line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on tokenizer line %d: %w", line, err)
+		}
+		vocab[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer file: %w", err)
+	}
+	if len(vocab) == 0 {
+		return nil, fmt.Errorf("tokenizer file has no vocabulary")
+	}
+
+	specials := make(map[string]int, len(qwenSpecialTokens))
+	for i, token := range qwenSpecialTokens {
+		specials[token] = len(vocab) + i
+	}
+	return newBPE(vocab, func(left, right string) (int, bool) {
+		rank, ok := vocab[left+right]
+		return rank, ok
+	}, specials), nil
+}
+
+// newBPE creates a tokenizer from its vocabulary, merge priorities and special tokens
+func newBPE(vocab map[string]int, rank func(left, right string) (int, bool), specials map[string]int) *BPE {
+	return &BPE{
+		vocab:    vocab,
+		rank:     rank,
+		specials: specials,
+		cache:    make(map[string][]int),
+	}
+}
+
+// CountTokens returns the number of tokens the text encodes to
+func (t *BPE) CountTokens(text string) int {
+	tokens, _ := t.Encode(text)
+	return len(tokens)
+}
+
+// Encode returns the token IDs of the text
+func (t *BPE) Encode(text string) ([]int, bool) {
+	tokens := []int{}
+	for len(text) > 0 {
+		start, special := t.nextSpecial(text)
+		for _, word := range splitWords(text[:start]) {
+			tokens = append(tokens, t.encodeWord(word)...)
+		}
+		if special == "" {
+			break
+		}
+		tokens = append(tokens, t.specials[special])
+		text = text[start+len(special):]
+	}
+	return tokens, true
+}
+
+// nextSpecial returns the position of the first special token in the text and the token,
+// or the length of the text and an empty token when there is none
+func (t *BPE) nextSpecial(text string) (int, string) {
+	start, found := len(text), ""
+	if !strings.Contains(text, "<|") {
+		return start, found
+	}
+	for special := range t.specials {
+		i := strings.Index(text, special)
+		if i >= 0 && (i < start || i == start && len(special) > len(found)) {
+			start, found = i, special
+		}
+	}
+	return start, found
+}
+
+// encodeWord returns the token IDs of a single word, caching the result
+func (t *BPE) encodeWord(word string) []int {
+	if id, ok := t.vocab[word]; ok {
+		return []int{id}
+	}
+	t.mu.Lock()
+	cached, ok := t.cache[word]
+	t.mu.Unlock()
+	if ok {
+		return cached
+	}
+
+	var tokens []int
+	for chunk := word; len(chunk) > 0; {
+		n := min(len(chunk), maxWordLength)
+		for _, part := range t.merge(chunk[:n]) {
+			if id, ok := t.vocab[part]; ok {
+				tokens = append(tokens, id)
+				continue
+			}
+			// A vocabulary without every single byte still counts the missing bytes
+			for i := range len(part) {
+				tokens = append(tokens, t.vocab[part[i:i+1]])
+			}
+		}
+		chunk = chunk[n:]
+	}
+
+	t.mu.Lock()
+	if len(t.cache) >= maxCachedWords {
+		clear(t.cache)
+	}
+	t.cache[word] = tokens
+	t.mu.Unlock()
+	return tokens
+}
+
+// merge splits a word into bytes and merges the adjacent pair with the highest priority
+// until no pair can be merged
+func (t *BPE) merge(word string) []string {
+	parts := make([]string, len(word))
+	for i := range len(word) {
+		parts[i] = word[i : i+1]
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := t.rank(parts[i], parts[i+1]); ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return parts
+}
+
+// byteDecoder returns the inverse of the byte-level alphabet of GPT-2 style tokenizers, which
+// maps printable bytes to themselves and every other byte to a rune from 256 upwards
+func byteDecoder() map[rune]byte {
+	decoder := make(map[rune]byte, 256)
+	next := rune(256)
+	for b := 0; b < 256; b++ {
+		if b >= '!' && b <= '~' || b >= 0xA1 && b <= 0xAC || b >= 0xAE && b <= 0xFF {
+			decoder[rune(b)] = byte(b)
+			continue
+		}
+		decoder[next] = byte(b)
+		next++
+	}
+	return decoder
+}
+
+// decodeByteLevel converts a token of the byte-level alphabet back to its bytes
+func decodeByteLevel(token string, decoder map[rune]byte) string {
+	var decoded strings.Builder
+	for _, r := range token {
+		if b, ok := decoder[r]; ok {
+			decoded.WriteByte(b)
+		} else {
+			decoded.WriteRune(r)
+		}
+	}
+	return decoded.String()
+}
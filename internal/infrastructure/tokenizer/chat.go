@@ -0,0 +1,78 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+const (
+	// tokensPerMessage covers the chat template around every message besides its role:
+	// <|im_start|>, the line break after the role, <|im_end|> and the line break after it
+	tokensPerMessage = 4
+	// tokensPerReply covers the assistant header that primes the reply
+	tokensPerReply = 3
+)
+
+// CountPromptTokens returns the number of prompt tokens of a chat completion request,
+// including the chat template around its messages and the tool definitions
+func CountPromptTokens(tokenizer interfaces.Tokenizer, req *entities.ChatCompletionRequest) int {
+	tokens := tokensPerReply
+	for _, message := range req.Messages {
+		tokens += CountMessageTokens(tokenizer, message)
+	}
+	if len(req.Tools) > 0 {
+		if tools, err := json.Marshal(req.Tools); err == nil {
+			tokens += tokenizer.CountTokens(string(tools))
+		}
+	}
+	return tokens
+}
+
+// CountMessageTokens returns the number of tokens of a single chat message
+func CountMessageTokens(tokenizer interfaces.Tokenizer, message entities.ChatMessage) int {
+	tokens := tokensPerMessage + tokenizer.CountTokens(message.Role)
+	tokens += tokenizer.CountTokens(MessageText(message.Content))
+	tokens += tokenizer.CountTokens(message.ReasoningContent)
+	for _, toolCall := range message.ToolCalls {
+		tokens += tokenizer.CountTokens(toolCall.Function.Name) + tokenizer.CountTokens(toolCall.Function.Arguments)
+	}
+	return tokens
+}
+
+// CountCompletionTokens returns the number of tokens generated in a chat completion response
+func CountCompletionTokens(tokenizer interfaces.Tokenizer, response *entities.ChatCompletionResponse) int {
+	tokens := 0
+	for _, choice := range response.Choices {
+		tokens += CountMessageTokens(tokenizer, choice.Message) - tokensPerMessage - tokenizer.CountTokens(choice.Message.Role)
+	}
+	return tokens
+}
+
+// MessageText returns the text of message content given as a string or as content blocks.
+// Content that is not text, such as images, is left out.
+func MessageText(content any) string {
+	switch content := content.(type) {
+	case string:
+		return content
+	case []entities.ContentBlock:
+		var text strings.Builder
+		for _, block := range content {
+			text.WriteString(block.Text)
+		}
+		return text.String()
+	case []any:
+		var text strings.Builder
+		for _, block := range content {
+			if block, ok := block.(map[string]any); ok {
+				if value, ok := block["text"].(string); ok {
+					text.WriteString(value)
+				}
+			}
+		}
+		return text.String()
+	}
+	return ""
+}
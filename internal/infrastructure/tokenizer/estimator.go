@@ -0,0 +1,65 @@
+package tokenizer
+
+import (
+	"unicode"
+
+	"qwen-go-proxy/internal/domain/interfaces"
+)
+
+// New returns the BPE tokenizer loaded from the vocabulary file at path, or an estimator
+// when no path is given
+func New(path string) (interfaces.Tokenizer, error) {
+	if path == "" {
+		return NewEstimator(), nil
+	}
+	return LoadBPE(path)
+}
+
+// charsPerToken is the average number of Latin letters or digits in a token
+const charsPerToken = 4
+
+// Estimator approximates token counts without a vocabulary.
+// Runs of letters and digits count one token per four characters, CJK characters count one
+// token each and every other non-space character counts as a token of its own.
+type Estimator struct{}
+
+// NewEstimator creates a token count estimator
+func NewEstimator() *Estimator {
+	return &Estimator{}
+}
+
+// CountTokens returns the estimated number of tokens of the text
+func (e *Estimator) CountTokens(text string) int {
+	tokens := 0
+	run := 0
+	flush := func() {
+		tokens += (run + charsPerToken - 1) / charsPerToken
+		run = 0
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			run++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Encode reports that the estimator does not know token IDs
+func (e *Estimator) Encode(text string) ([]int, bool) {
+	return nil, false
+}
+
+// isCJK reports whether the rune is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,104 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splitWords splits text into the pieces that byte pair encoding is applied to, following the
+// pre-tokenizer pattern of the Qwen tokenizers:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// The pattern uses a lookahead, which the regexp package does not support, so it is matched by hand.
+func splitWords(text string) []string {
+	var words []string
+	for len(text) > 0 {
+		n := matchWord(text)
+		words = append(words, text[:n])
+		text = text[n:]
+	}
+	return words
+}
+
+// matchWord returns the length in bytes of the first word of a non-empty text
+func matchWord(text string) int {
+	r, size := utf8.DecodeRuneInString(text)
+
+	// (?i:'s|'t|'re|'ve|'m|'ll|'d)
+	if r == '\'' {
+		lower := strings.ToLower(text[size:min(len(text), size+2)])
+		for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+			if strings.HasPrefix(lower, suffix) {
+				return size + len(suffix)
+			}
+		}
+	}
+
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	if unicode.IsLetter(r) {
+		return size + spanOf(text[size:], unicode.IsLetter)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if letters := spanOf(text[size:], unicode.IsLetter); letters > 0 {
+			return size + letters
+		}
+	}
+
+	// \p{N}
+	if unicode.IsNumber(r) {
+		return size
+	}
+
+	// ?[^\s\p{L}\p{N}]+[\r\n]*
+	start := 0
+	if r == ' ' {
+		start = size
+	}
+	if symbols := spanOf(text[start:], isSymbol); symbols > 0 {
+		end := start + symbols
+		return end + spanOf(text[end:], isNewline)
+	}
+
+	// \s*[\r\n]+ matches the whitespace up to and including its last line break
+	spaces := spanOf(text, unicode.IsSpace)
+	if lastBreak := strings.LastIndexAny(text[:spaces], "\r\n"); lastBreak >= 0 {
+		return lastBreak + 1
+	}
+
+	// \s+(?!\S) leaves the last space to the word that follows it
+	if spaces < len(text) {
+		_, lastSize := utf8.DecodeLastRuneInString(text[:spaces])
+		if spaces > lastSize {
+			return spaces - lastSize
+		}
+	}
+
+	// \s+
+	if spaces > 0 {
+		return spaces
+	}
+	// Invalid UTF-8 and other leftovers become a word of their own
+	return size
+}
+
+// spanOf returns the length in bytes of the prefix of text whose runes all satisfy f
+func spanOf(text string, f func(rune) bool) int {
+	for i, r := range text {
+		if !f(r) {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// isSymbol reports whether the rune is neither whitespace, a letter nor a number
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isNewline reports whether the rune is a line break
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
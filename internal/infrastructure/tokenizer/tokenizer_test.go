@@ -0,0 +1,165 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimator_CountTokens(t *testing.T) {
+	estimator := NewEstimator()
+
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"short words", "Hi there", 3},
+		{"long word", "internationalization", 5},
+		{"punctuation", "a, b!", 4},
+		{"chinese", "你好世界", 4},
+		{"mixed", "hello 世界", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, estimator.CountTokens(tt.text))
+		})
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	assert.Equal(t, "plain", MessageText("plain"))
+	assert.Equal(t, "ab", MessageText([]entities.ContentBlock{{Type: "text", Text: "a"}, {Type: "image_url"}, {Type: "text", Text: "b"}}))
+	assert.Equal(t, "ab", MessageText([]any{
+		map[string]any{"type": "text", "text": "a"},
+		map[string]any{"type": "image_url", "image_url": map[string]any{"url": "https://example.com/cat.png"}},
+		map[string]any{"type": "text", "text": "b"},
+	}))
+	assert.Empty(t, MessageText(nil))
+}
+
+func TestCountPromptTokens(t *testing.T) {
+	estimator := NewEstimator()
+	req := &entities.ChatCompletionRequest{
+		Messages: []entities.ChatMessage{
+			{Role: "system", Content: "Be brief"},
+			{Role: "user", Content: "Hello"},
+		},
+	}
+
+	// Every message adds the chat template and its role to its content
+	want := tokensPerReply + (tokensPerMessage + 2 + 3) + (tokensPerMessage + 1 + 2)
+	assert.Equal(t, want, CountPromptTokens(estimator, req))
+
+	req.Tools = []entities.Tool{{Type: "function", Function: entities.Function{Name: "lookup"}}}
+	assert.Greater(t, CountPromptTokens(estimator, req), want)
+}
+
+func TestCountCompletionTokens(t *testing.T) {
+	response := &entities.ChatCompletionResponse{
+		Choices: []entities.ChatCompletionChoice{{
+			Message: entities.ChatMessage{
+				Role:      "assistant",
+				Content:   "Hello",
+				ToolCalls: []entities.ToolCall{{Function: entities.Function{Name: "lookup", Arguments: "{}"}}},
+			},
+		}},
+	}
+
+	assert.Equal(t, 2+2+2, CountCompletionTokens(NewEstimator(), response))
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello world", []string{"Hello", " world"}},
+		{"I'm fine, THEY'LL see", []string{"I", "'m", " fine", ",", " THEY", "'LL", " see"}},
+		{"x = 123;", []string{"x", " =", " ", "1", "2", "3", ";"}},
+		{"a  \n\n  b", []string{"a", "  \n\n", " ", " b"}},
+		{"hi!!\n", []string{"hi", "!!\n"}},
+		{"trailing  ", []string{"trailing", "  "}},
+		{"你好，世界", []string{"你好", "，世界"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitWords(tt.text))
+		})
+	}
+}
+
+func TestLoadBPE_HuggingFace(t *testing.T) {
+	// "Ġ" is the byte-level form of a space
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"added_tokens": [{"id": 10, "content": "<|im_end|>", "special": true}],
+		"model": {
+			"type": "BPE",
+			"vocab": {"h": 0, "e": 1, "l": 2, "o": 3, "Ġ": 4, "he": 5, "ll": 6, "hell": 7, "hello": 8, "Ġh": 9},
+			"merges": ["h e", "l l", ["he", "ll"], "hell o", "Ġ h"]
+		}
+	}`), 0600))
+
+	bpe, err := LoadBPE(path)
+	require.NoError(t, err)
+
+	// Merging "h e" takes priority over "Ġ h", so the space stays a token of its own
+	tokens, exact := bpe.Encode("hello hello<|im_end|>")
+	assert.True(t, exact)
+	assert.Equal(t, []int{8, 4, 8, 10}, tokens)
+	assert.Equal(t, 4, bpe.CountTokens("hello hello<|im_end|>"))
+	assert.Equal(t, 0, bpe.CountTokens(""))
+}
+
+func TestLoadBPE_Tiktoken(t *testing.T) {
+	var vocab strings.Builder
+	for rank, token := range []string{"a", "b", " ", "ab", " ab"} {
+		fmt.Fprintf(&vocab, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	path := filepath.Join(t.TempDir(), "qwen.tiktoken")
+	require.NoError(t, os.WriteFile(path, []byte(vocab.String()), 0600))
+
+	bpe, err := LoadBPE(path)
+	require.NoError(t, err)
+
+	tokens, _ := bpe.Encode("ab ab")
+	assert.Equal(t, []int{3, 4}, tokens)
+	tokens, _ = bpe.Encode("ba<|im_start|>")
+	// The special tokens follow the vocabulary
+	assert.Equal(t, []int{1, 0, 6}, tokens)
+}
+
+func TestLoadBPE_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadBPE(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+
+	unigram := filepath.Join(dir, "unigram.json")
+	require.NoError(t, os.WriteFile(unigram, []byte(`{"model": {"type": "Unigram", "vocab": {"a": 0}}}`), 0600))
+	_, err = LoadBPE(unigram)
+	assert.ErrorContains(t, err, "only BPE")
+
+	broken := filepath.Join(dir, "broken.tiktoken")
+	require.NoError(t, os.WriteFile(broken, []byte("YQ== zero\n"), 0600))
+	_, err = LoadBPE(broken)
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestNew(t *testing.T) {
+	tokenizer, err := New("")
+	require.NoError(t, err)
+	tokens, exact := tokenizer.Encode("hello")
+	assert.False(t, exact)
+	assert.Nil(t, tokens)
+}
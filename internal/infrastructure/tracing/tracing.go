@@ -0,0 +1,149 @@
+// Package tracing configures OpenTelemetry tracing with W3C trace context propagation.
+// Spans are started through the global tracer provider, so code that creates spans works
+// unchanged whether tracing is exported or disabled.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported span exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// tracerName identifies the instrumentation scope of spans created by the proxy
+const tracerName = "qwen-go-proxy"
+
+// Span attribute keys, following the OpenTelemetry GenAI semantic conventions where one exists
+const (
+	AttrModel            = attribute.Key("gen_ai.request.model")
+	AttrStream           = attribute.Key("gen_ai.request.stream")
+	AttrPromptTokens     = attribute.Key("gen_ai.usage.input_tokens")
+	AttrCompletionTokens = attribute.Key("gen_ai.usage.output_tokens")
+)
+
+// Config holds the tracing configuration
+type Config struct {
+	// Exporter is one of none, stdout or otlp; empty means none
+	Exporter string
+	// OTLPEndpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318
+	OTLPEndpoint string
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+	// Writer receives spans from the stdout exporter, defaulting to os.Stdout
+	Writer io.Writer
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// The propagator is installed even when no exporter is configured, so incoming trace
+// context is still forwarded upstream. The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		writer := cfg.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(writer))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = tracerName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used for all spans created by the proxy
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start starts an internal span as a child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
+
+// RecordError records err on the span and marks the span as failed
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Extract returns ctx with the trace context carried by the request headers
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject writes the trace context of ctx into the outgoing request headers
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// UsageAttributes returns the span attributes describing token usage
+func UsageAttributes(usage *entities.Usage) []attribute.KeyValue {
+	if usage == nil {
+		return nil
+	}
+	return []attribute.KeyValue{
+		AttrPromptTokens.Int(usage.PromptTokens),
+		AttrCompletionTokens.Int(usage.CompletionTokens),
+	}
+}
+
+// SetUsage adds token usage attributes to the span in ctx
+func SetUsage(ctx context.Context, usage *entities.Usage) {
+	trace.SpanFromContext(ctx).SetAttributes(UsageAttributes(usage)...)
+}
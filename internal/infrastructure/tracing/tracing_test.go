@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// useRecorder installs a tracer provider that records finished spans for the duration of the test
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return recorder
+}
+
+func TestSetup_StdoutExporter(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, ServiceName: "proxy-test", Writer: &out})
+	require.NoError(t, err)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := Start(context.Background(), "test-span", AttrModel.String("qwen3-coder-plus"))
+	End(span, errors.New("upstream failed"))
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, out.String(), `"Name":"test-span"`)
+	assert.Contains(t, out.String(), "qwen3-coder-plus")
+	assert.Contains(t, out.String(), "proxy-test")
+	assert.Contains(t, out.String(), "upstream failed")
+}
+
+func TestSetup_NoExporter(t *testing.T) {
+	for _, exporter := range []string{"", ExporterNone} {
+		shutdown, err := Setup(context.Background(), Config{Exporter: exporter})
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "jaeger"})
+	assert.EqualError(t, err, "unknown trace exporter: jaeger")
+}
+
+func TestInjectExtract(t *testing.T) {
+	_, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := Extract(context.Background(), header)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.SpanContextFromContext(ctx).TraceID().String())
+
+	// The incoming trace context is forwarded even when tracing is not exported
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", outgoing.Get("traceparent"))
+}
+
+func TestUsageAttributes(t *testing.T) {
+	assert.Nil(t, UsageAttributes(nil))
+
+	recorder := useRecorder(t)
+	ctx, span := Start(context.Background(), "usage")
+	SetUsage(ctx, &entities.Usage{PromptTokens: 3, CompletionTokens: 5})
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Attributes(), AttrPromptTokens.Int(3))
+	assert.Contains(t, spans[0].Attributes(), AttrCompletionTokens.Int(5))
+}
@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTransport_CreatesClientSpanAndPropagates(t *testing.T) {
+	recorder := useRecorder(t)
+	_, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, parent := Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/v1/chat/completions", nil)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: NewTransport(nil)}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	parent.End()
+
+	// The caller's request is left untouched
+	assert.Empty(t, req.Header.Get("traceparent"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	clientSpan := spans[0]
+	assert.Equal(t, "POST /v1/chat/completions", clientSpan.Name())
+	assert.Equal(t, trace.SpanKindClient, clientSpan.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), clientSpan.Parent().SpanID())
+	assert.Contains(t, clientSpan.Attributes(), attribute.Int("http.response.status_code", http.StatusTooManyRequests))
+	assert.Equal(t, codes.Error, clientSpan.Status().Code)
+
+	// Upstream sees the client span as the parent
+	assert.Contains(t, traceparent, clientSpan.SpanContext().TraceID().String())
+	assert.Contains(t, traceparent, clientSpan.SpanContext().SpanID().String())
+}
@@ -52,14 +52,134 @@ func (v *ConfigValidator) ValidateConfig(config *entities.Config) error {
 		return fmt.Errorf("QWEN_DIR cannot be empty")
 	}
 
+	// Validate credential store backend
+	validCredentialStores := []string{"file", "sqlite", "vault"}
+	if config.CredentialStore != "" && !contains(validCredentialStores, config.CredentialStore) {
+		return fmt.Errorf("CREDENTIAL_STORE must be one of: %v, got: %s", validCredentialStores, config.CredentialStore)
+	}
+
+	if config.CredentialStore == "vault" {
+		if config.VaultAddress == "" || config.VaultToken == "" {
+			return fmt.Errorf("VAULT_ADDR and VAULT_TOKEN are required when CREDENTIAL_STORE is vault")
+		}
+		if vaultURL, err := url.Parse(config.VaultAddress); err != nil || (vaultURL.Scheme != "http" && vaultURL.Scheme != "https") || vaultURL.Host == "" {
+			return fmt.Errorf("VAULT_ADDR must be an http or https URL")
+		}
+		if config.VaultKVMount == "" || config.VaultKVPath == "" {
+			return fmt.Errorf("VAULT_KV_MOUNT and VAULT_KV_PATH cannot be empty")
+		}
+	}
+
+	// Only one source of the credential encryption key may be configured
+	encryptionKeySources := 0
+	for _, source := range []string{config.CredentialEncryptionKey, config.CredentialEncryptionKeyFile, config.CredentialEncryptionPassphrase} {
+		if source != "" {
+			encryptionKeySources++
+		}
+	}
+	if encryptionKeySources > 1 {
+		return fmt.Errorf("only one of CREDENTIAL_ENCRYPTION_KEY, CREDENTIAL_ENCRYPTION_KEY_FILE and CREDENTIAL_ENCRYPTION_PASSPHRASE can be set")
+	}
+	if encryptionKeySources > 0 && config.CredentialStore != "" && config.CredentialStore != "file" {
+		return fmt.Errorf("credential encryption is only supported with CREDENTIAL_STORE=file")
+	}
+
+	// A short passphrase would make the encrypted credentials easy to brute force
+	if config.CredentialEncryptionPassphrase != "" && len(config.CredentialEncryptionPassphrase) < 16 {
+		return fmt.Errorf("CREDENTIAL_ENCRYPTION_PASSPHRASE must be at least 16 characters long")
+	}
+
 	if config.TokenRefreshBuffer < 0 {
 		return fmt.Errorf("TOKEN_REFRESH_BUFFER must be non-negative")
 	}
 
+	if config.TokenRefreshJitter < 0 || config.TokenRefreshMaxBackoff < 0 {
+		return fmt.Errorf("TOKEN_REFRESH_JITTER and TOKEN_REFRESH_MAX_BACKOFF must be non-negative")
+	}
+
 	if config.ShutdownTimeout < 0 {
 		return fmt.Errorf("SHUTDOWN_TIMEOUT must be non-negative")
 	}
 
+	if config.AccountCooldown < 0 {
+		return fmt.Errorf("ACCOUNT_COOLDOWN must be non-negative")
+	}
+
+	if config.RetryMaxAttempts < 0 {
+		return fmt.Errorf("RETRY_MAX_ATTEMPTS must be non-negative")
+	}
+
+	if config.RetryInitialBackoff < 0 || config.RetryMaxBackoff < 0 {
+		return fmt.Errorf("RETRY_INITIAL_BACKOFF and RETRY_MAX_BACKOFF must be non-negative")
+	}
+
+	// Validate tracing exporter (empty disables tracing)
+	validTracingExporters := []string{"none", "stdout", "otlp"}
+	if config.TracingExporter != "" && !contains(validTracingExporters, config.TracingExporter) {
+		return fmt.Errorf("TRACING_EXPORTER must be one of: %v, got: %s", validTracingExporters, config.TracingExporter)
+	}
+	if config.TracingExporter == "otlp" {
+		endpoint, err := url.Parse(config.TracingOTLPEndpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("TRACING_OTLP_ENDPOINT must be an http or https URL when TRACING_EXPORTER is otlp")
+		}
+	}
+
+	if config.AuthWebhookURL != "" {
+		webhook, err := url.Parse(config.AuthWebhookURL)
+		if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			return fmt.Errorf("AUTH_WEBHOOK_URL must be an http or https URL")
+		}
+	}
+
+	if config.ModelDiscoveryTTL < 0 {
+		return fmt.Errorf("MODEL_DISCOVERY_TTL must be non-negative")
+	}
+
+	// Validate response cache backend (empty disables the cache)
+	validCacheBackends := []string{"none", "memory", "disk"}
+	if config.CacheBackend != "" && !contains(validCacheBackends, config.CacheBackend) {
+		return fmt.Errorf("CACHE_BACKEND must be one of: %v, got: %s", validCacheBackends, config.CacheBackend)
+	}
+
+	if config.CacheTTL < 0 {
+		return fmt.Errorf("CACHE_TTL must be non-negative")
+	}
+
+	if config.CacheMaxEntries < 0 {
+		return fmt.Errorf("CACHE_MAX_ENTRIES must be non-negative")
+	}
+
+	if config.ResponseStoreTTL < 0 || config.ResponseStoreMaxEntries < 0 {
+		return fmt.Errorf("RESPONSE_STORE_TTL and RESPONSE_STORE_MAX_ENTRIES must be non-negative")
+	}
+
+	// Validate journal redaction (empty keeps message content)
+	validRedactions := []string{entities.JournalRedactionNone, entities.JournalRedactionRedact, entities.JournalRedactionHash}
+	if config.JournalRedaction != "" && !contains(validRedactions, config.JournalRedaction) {
+		return fmt.Errorf("JOURNAL_REDACTION must be one of: %v, got: %s", validRedactions, config.JournalRedaction)
+	}
+
+	if config.JournalMaxSizeMB < 0 || config.JournalMaxFiles < 0 {
+		return fmt.Errorf("JOURNAL_MAX_SIZE_MB and JOURNAL_MAX_FILES must be non-negative")
+	}
+
+	// Validate context overflow policy (empty disables the preflight)
+	validOverflows := []string{entities.ContextOverflowOff, entities.ContextOverflowReject, entities.ContextOverflowTrim}
+	if config.ContextOverflow != "" && !contains(validOverflows, config.ContextOverflow) {
+		return fmt.Errorf("CONTEXT_OVERFLOW must be one of: %v, got: %s", validOverflows, config.ContextOverflow)
+	}
+	validStrategies := []string{entities.ContextStrategyDropOldest, entities.ContextStrategyKeepToolPairs, entities.ContextStrategySummarize}
+	if config.ContextStrategy != "" && !contains(validStrategies, config.ContextStrategy) {
+		return fmt.Errorf("CONTEXT_STRATEGY must be one of: %v, got: %s", validStrategies, config.ContextStrategy)
+	}
+
+	// Validate credential pool strategy (empty falls back to round_robin)
+	validPoolStrategies := []string{"round_robin", "least_recently_throttled"}
+	if config.CredentialPoolStrategy != "" && !contains(validPoolStrategies, config.CredentialPoolStrategy) {
+		return fmt.Errorf("CREDENTIAL_POOL_STRATEGY must be one of: %v, got: %s", validPoolStrategies, config.CredentialPoolStrategy)
+	}
+
 	if config.RateLimitRequestsPerSecond <= 0 {
 		return fmt.Errorf("RATE_LIMIT_REQUESTS_PER_SECOND must be positive")
 	}
@@ -68,6 +188,21 @@ func (v *ConfigValidator) ValidateConfig(config *entities.Config) error {
 		return fmt.Errorf("RATE_LIMIT_BURST must be positive")
 	}
 
+	// Validate rate limit key (empty falls back to ip)
+	validRateLimitKeys := []string{"ip", "api_key", "model"}
+	if config.RateLimitKey != "" && !contains(validRateLimitKeys, config.RateLimitKey) {
+		return fmt.Errorf("RATE_LIMIT_KEY must be one of: %v, got: %s", validRateLimitKeys, config.RateLimitKey)
+	}
+
+	if config.RateLimitPromptTPM < 0 || config.RateLimitCompletionTPM < 0 {
+		return fmt.Errorf("RATE_LIMIT_PROMPT_TPM and RATE_LIMIT_COMPLETION_TPM must be non-negative")
+	}
+
+	// A short admin key would make the key management endpoints easy to brute force
+	if config.AdminAPIKey != "" && len(config.AdminAPIKey) < 16 {
+		return fmt.Errorf("ADMIN_API_KEY must be at least 16 characters long")
+	}
+
 	if config.LogLevel == "" {
 		return fmt.Errorf("LOG_LEVEL cannot be empty")
 	}
@@ -145,7 +280,7 @@ func (v *RequestValidator) ValidateChatCompletionRequest(req *entities.ChatCompl
 		return fmt.Errorf("max_tokens must be non-negative")
 	}
 
-	if req.Temperature < 0 || req.Temperature > 2 {
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
 		return fmt.Errorf("temperature must be between 0 and 2")
 	}
 
@@ -157,12 +157,53 @@ func TestConfigValidator_ValidateConfig_EmptyQWENDir(t *testing.T) {
 	assert.Contains(t, err.Error(), "QWEN_DIR cannot be empty")
 }
 
+func TestConfigValidator_ValidateConfig_InvalidCredentialPoolStrategy(t *testing.T) {
+	config := &entities.Config{
+		ServerPort:                 8080,
+		QWENOAuthBaseURL:           "https://oauth.example.com",
+		QWENOAuthClientID:          "test-client-id",
+		QWENOAuthDeviceAuthURL:     "https://oauth.example.com/device",
+		APIBaseURL:                 "https://api.example.com",
+		QWENDir:                    ".qwen",
+		RateLimitRequestsPerSecond: 10,
+		RateLimitBurst:             20,
+		LogLevel:                   "info",
+		CredentialPoolStrategy:     "random",
+	}
+
+	validator := NewConfigValidator()
+	err := validator.ValidateConfig(config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CREDENTIAL_POOL_STRATEGY must be one of")
+}
+
+func TestConfigValidator_ValidateConfig_ShortAdminAPIKey(t *testing.T) {
+	config := &entities.Config{
+		ServerPort:                 8080,
+		QWENOAuthBaseURL:           "https://oauth.example.com",
+		QWENOAuthClientID:          "test-client-id",
+		QWENOAuthDeviceAuthURL:     "https://oauth.example.com/device",
+		APIBaseURL:                 "https://api.example.com",
+		QWENDir:                    ".qwen",
+		RateLimitRequestsPerSecond: 10,
+		RateLimitBurst:             20,
+		LogLevel:                   "info",
+		AdminAPIKey:                "short",
+	}
+
+	validator := NewConfigValidator()
+	err := validator.ValidateConfig(config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ADMIN_API_KEY must be at least 16 characters long")
+}
+
 func TestNewRequestValidator(t *testing.T) {
 	validator := NewRequestValidator()
 	assert.NotNil(t, validator)
 }
 
 func TestRequestValidator_ValidateChatCompletionRequest_Valid(t *testing.T) {
+	temperature := 0.5
 	req := &entities.ChatCompletionRequest{
 		Model: "test-model",
 		Messages: []entities.ChatMessage{
@@ -172,7 +213,7 @@ func TestRequestValidator_ValidateChatCompletionRequest_Valid(t *testing.T) {
 			},
 		},
 		MaxTokens:   100,
-		Temperature: 0.5,
+		Temperature: &temperature,
 		TopP:        0.9,
 	}
 
@@ -236,6 +277,7 @@ func TestRequestValidator_ValidateChatCompletionRequest_InvalidMaxTokens(t *test
 }
 
 func TestRequestValidator_ValidateChatCompletionRequest_InvalidTemperature(t *testing.T) {
+	temperature := 3.0 // Invalid - too high
 	req := &entities.ChatCompletionRequest{
 		Model: "test-model",
 		Messages: []entities.ChatMessage{
@@ -244,7 +286,7 @@ func TestRequestValidator_ValidateChatCompletionRequest_InvalidTemperature(t *te
 				Content: "Hello",
 			},
 		},
-		Temperature: 3.0, // Invalid - too high
+		Temperature: &temperature,
 	}
 
 	validator := NewRequestValidator()
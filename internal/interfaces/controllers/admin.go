@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/usecases/apikey"
+)
+
+// Constants for admin API responses
+const (
+	// ErrorTypeNotFound Error types
+	ErrorTypeNotFound = "not_found_error"
+
+	// StatusCreated HTTP status codes for admin responses
+	StatusCreated  = http.StatusCreated
+	StatusNotFound = http.StatusNotFound
+)
+
+// AdminController handles administrative requests such as API key management
+type AdminController struct {
+	*APIController
+	apiKeyUseCase apikey.APIKeyUseCaseInterface
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(apiKeyUseCase apikey.APIKeyUseCaseInterface, logger logging.LoggerInterface) *AdminController {
+	if apiKeyUseCase == nil {
+		panic("apiKeyUseCase cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	return &AdminController{
+		APIController: &APIController{logger: logger},
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+// CreateAPIKeyHandler creates a new proxy API key. The plaintext key is only returned in this response.
+func (ctrl *AdminController) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req entities.APIKeyCreateRequest
+	if !ctrl.validateJSONRequest(w, r, &req) {
+		return
+	}
+
+	key, rawKey, err := ctrl.apiKeyUseCase.CreateKey(&req)
+	if err != nil {
+		ctrl.sendValidationError(w, r, err.Error())
+		return
+	}
+
+	ctrl.logger.Info("API key created via admin API", "request_id", middleware.GetRequestID(r.Context()), "key_id", key.ID)
+
+	response := key.Sanitize()
+	response["key"] = rawKey
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListAPIKeysHandler lists all proxy API keys without their hashes
+func (ctrl *AdminController) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := ctrl.apiKeyUseCase.ListKeys()
+	if err != nil {
+		ctrl.sendInternalError(w, r, err)
+		return
+	}
+
+	data := make([]map[string]any, len(keys))
+	for i, key := range keys {
+		data[i] = key.Sanitize()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": ObjectList,
+		"data":   data,
+	})
+}
+
+// RevokeAPIKeyHandler revokes a proxy API key by ID
+func (ctrl *AdminController) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	keyID := chi.URLParam(r, "key_id")
+
+	key, err := ctrl.apiKeyUseCase.RevokeKey(keyID)
+	if err != nil {
+		if errors.Is(err, apikey.ErrAPIKeyNotFound) {
+			ctrl.sendErrorResponse(w, r, StatusNotFound, ErrorTypeNotFound, "API key '"+keyID+"' not found")
+			return
+		}
+		ctrl.sendInternalError(w, r, err)
+		return
+	}
+
+	ctrl.logger.Info("API key revoked via admin API", "request_id", middleware.GetRequestID(r.Context()), "key_id", key.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(key.Sanitize())
+}
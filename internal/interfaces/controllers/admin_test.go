@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/apikey"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNewAdminController_Panics(t *testing.T) {
+	logger := &logging.Logger{Logger: logging.NewLogger("info")}
+
+	assert.PanicsWithValue(t, "apiKeyUseCase cannot be nil", func() {
+		NewAdminController(nil, logger)
+	})
+}
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKeys := mocks.NewMockAPIKeyUseCaseInterface(ctrl)
+	controller := NewAdminController(mockKeys, &logging.Logger{Logger: logging.NewLogger("info")})
+
+	mockKeys.EXPECT().CreateKey(gomock.Any()).DoAndReturn(func(req *entities.APIKeyCreateRequest) (*entities.APIKey, string, error) {
+		assert.Equal(t, "ci", req.Label)
+		assert.Equal(t, []string{"qwen3-coder-plus"}, req.AllowedModels)
+		assert.Equal(t, 60, req.RateLimit)
+		require.NotNil(t, req.ExpiresAt)
+		return &entities.APIKey{ID: "key_1", Label: req.Label, Hash: "secret-hash", Prefix: "sk-qwen-abcd", CreatedAt: time.Now()}, "sk-qwen-abcdef", nil
+	})
+
+	body := `{"label":"ci","allowed_models":["qwen3-coder-plus"],"rate_limit":60,"expires_at":"2099-01-01T00:00:00Z"}`
+	req := httptest.NewRequest("POST", "/admin/keys", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.CreateAPIKeyHandler(rec, req)
+
+	assert.Equal(t, 201, rec.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "key_1", response["id"])
+	assert.Equal(t, "sk-qwen-abcdef", response["key"])
+	assert.NotContains(t, rec.Body.String(), "secret-hash")
+}
+
+func TestCreateAPIKeyHandler_ValidationError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKeys := mocks.NewMockAPIKeyUseCaseInterface(ctrl)
+	controller := NewAdminController(mockKeys, &logging.Logger{Logger: logging.NewLogger("info")})
+
+	mockKeys.EXPECT().CreateKey(gomock.Any()).Return(nil, "", errors.New("label is required"))
+
+	req := httptest.NewRequest("POST", "/admin/keys", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+
+	controller.CreateAPIKeyHandler(rec, req)
+
+	assert.Equal(t, 400, rec.Code)
+	assert.Contains(t, rec.Body.String(), "label is required")
+}
+
+func TestListAPIKeysHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKeys := mocks.NewMockAPIKeyUseCaseInterface(ctrl)
+	controller := NewAdminController(mockKeys, &logging.Logger{Logger: logging.NewLogger("info")})
+
+	mockKeys.EXPECT().ListKeys().Return([]*entities.APIKey{
+		{ID: "key_1", Label: "ci", Hash: "secret-hash"},
+		{ID: "key_2", Label: "dev", Hash: "other-hash"},
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/admin/keys", nil)
+	rec := httptest.NewRecorder()
+
+	controller.ListAPIKeysHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	var response struct {
+		Object string                   `json:"object"`
+		Data   []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "list", response.Object)
+	assert.Len(t, response.Data, 2)
+	assert.NotContains(t, rec.Body.String(), "secret-hash")
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockKeys := mocks.NewMockAPIKeyUseCaseInterface(ctrl)
+	controller := NewAdminController(mockKeys, &logging.Logger{Logger: logging.NewLogger("info")})
+
+	revokedAt := time.Now()
+	mockKeys.EXPECT().RevokeKey("key_1").Return(&entities.APIKey{ID: "key_1", RevokedAt: &revokedAt}, nil)
+	mockKeys.EXPECT().RevokeKey("key_2").Return(nil, apikey.ErrAPIKeyNotFound)
+
+	for id, status := range map[string]int{"key_1": 200, "key_2": 404} {
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("key_id", id)
+		req := httptest.NewRequest("DELETE", "/admin/keys/"+id, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+		rec := httptest.NewRecorder()
+
+		controller.RevokeAPIKeyHandler(rec, req)
+
+		assert.Equal(t, status, rec.Code)
+		assert.Contains(t, rec.Body.String(), id)
+	}
+}
@@ -0,0 +1,601 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/infrastructure/tracing"
+	"qwen-go-proxy/internal/usecases/auth"
+	"qwen-go-proxy/internal/usecases/models"
+	"qwen-go-proxy/internal/usecases/proxy"
+	"qwen-go-proxy/internal/usecases/streaming"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Constants for the Anthropic Messages API
+const (
+	// AnthropicObjectMessage Anthropic object types
+	AnthropicObjectMessage = "message"
+	AnthropicObjectError   = "error"
+
+	// AnthropicErrorTypeAPI Anthropic error types
+	AnthropicErrorTypeAPI            = "api_error"
+	AnthropicErrorTypeTimeout        = "timeout_error"
+	AnthropicErrorTypeNotFound       = "not_found_error"
+	AnthropicErrorTypeInvalidRequest = "invalid_request_error"
+	AnthropicErrorTypeAuthentication = "authentication_error"
+	AnthropicErrorTypeRateLimit      = "rate_limit_error"
+	AnthropicErrorTypeOverloaded     = "overloaded_error"
+
+	// AnthropicStopEndTurn Anthropic stop reasons
+	AnthropicStopEndTurn   = "end_turn"
+	AnthropicStopMaxTokens = "max_tokens"
+	AnthropicStopToolUse   = "tool_use"
+
+	// AnthropicBlockText Anthropic content block types
+	AnthropicBlockText       = "text"
+	AnthropicBlockImage      = "image"
+	AnthropicBlockToolUse    = "tool_use"
+	AnthropicBlockToolResult = "tool_result"
+
+	// ErrMsgMissingMessages Error messages
+	ErrMsgMissingMessages = "messages: at least one message is required"
+)
+
+// MessagesHandler handles Anthropic Messages API requests by translating them onto chat completions
+func (ctrl *APIController) MessagesHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.MessagesHandler")
+	defer span.End()
+	ctrl.logger.Debug("Anthropic messages request received")
+
+	var req entities.AnthropicMessagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ctrl.logger.Error("JSON binding failed", "request_id", middleware.GetRequestID(r.Context()), "error", err)
+		ctrl.sendAnthropicError(w, r, StatusBadRequest, ErrorTypeInvalidRequest, ErrMsgInvalidJSON)
+		return
+	}
+	if len(req.Messages) == 0 {
+		ctrl.sendAnthropicError(w, r, StatusBadRequest, ErrorTypeInvalidRequest, ErrMsgMissingMessages)
+		return
+	}
+
+	chatReq, err := buildChatRequestFromMessages(&req)
+	if err != nil {
+		ctrl.sendAnthropicError(w, r, StatusBadRequest, ErrorTypeInvalidRequest, err.Error())
+		return
+	}
+
+	ctrl.logger.Info("Processing messages request", "model", req.Model, "stream", req.Stream, "messages", len(chatReq.Messages))
+	span.SetAttributes(tracing.AttrModel.String(req.Model), tracing.AttrStream.Bool(req.Stream))
+
+	if req.Stream {
+		ctrl.streamMessages(w, r, chatReq)
+		return
+	}
+
+	response, err := ctrl.proxyUseCase.ChatCompletions(r.Context(), chatReq)
+	if err != nil {
+		ctrl.sendAnthropicInternalError(w, r, err)
+		return
+	}
+	if len(response.Choices) == 0 {
+		ctrl.sendAnthropicError(w, r, StatusInternalServerError, AnthropicErrorTypeAPI, ErrMsgUnexpectedFormat)
+		return
+	}
+
+	messagesResponse := buildMessagesResponse(response)
+	ctrl.logger.Info("Messages response sent", "id", messagesResponse.ID, "usage", response.Usage)
+	middleware.RecordUsage(r.Context(), response.Usage)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(messagesResponse)
+}
+
+// streamMessages streams a chat completion back to the client as Anthropic SSE events
+func (ctrl *APIController) streamMessages(w http.ResponseWriter, r *http.Request, chatReq *entities.ChatCompletionRequest) {
+	ctrl.logger.Debug("Streaming messages initiated", "model", chatReq.Model)
+
+	// Upstream usage only arrives at the end of the stream, so message_start announces an estimate
+	streamWriter := newAnthropicStreamWriter(w, ctrl.logger, tokenizer.CountPromptTokens(ctrl.tokenizer, chatReq))
+	err := ctrl.proxyUseCase.StreamChatCompletions(r.Context(), chatReq, streamWriter)
+	middleware.RecordUsage(r.Context(), &streamWriter.usage)
+	tracing.SetUsage(r.Context(), &streamWriter.usage)
+	if err != nil {
+		if !streamWriter.wroteHeader {
+			// Nothing has been sent yet, so a regular JSON error can still be returned
+			ctrl.sendAnthropicInternalError(w, r, err)
+			return
+		}
+		if r.Context().Err() != nil {
+			ctrl.logger.Info("Client disconnected during streaming", "request_id", middleware.GetRequestID(r.Context()))
+			return
+		}
+		ctrl.logger.Error("Streaming messages failed", "error", err)
+		streamWriter.emitError(AnthropicErrorTypeAPI, ErrMsgInternalError)
+		return
+	}
+
+	streamWriter.finish()
+	ctrl.logger.Debug("Streaming messages completed successfully")
+}
+
+// sendAnthropicError sends an error response in Anthropic format
+func (ctrl *APIController) sendAnthropicError(w http.ResponseWriter, r *http.Request, statusCode int, errorType, message string) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctrl.logger.Error("API error response", "request_id", requestID, "status", statusCode, "type", errorType, "message", message)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	errorResponse := map[string]interface{}{
+		"type": AnthropicObjectError,
+		"error": map[string]interface{}{
+			"type":    errorType,
+			"message": message,
+		},
+	}
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// sendAnthropicInternalError reports a failed upstream request in Anthropic error format.
+// Nothing is written when the client disconnected, and an expired deadline is reported as a timeout.
+func (ctrl *APIController) sendAnthropicInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := middleware.GetRequestID(r.Context())
+	tracing.RecordError(trace.SpanFromContext(r.Context()), err)
+	var notFound *models.ModelNotFoundError
+	var tooLong *proxy.ContextLengthExceededError
+	var authRequired *auth.AuthenticationRequiredError
+	var upstream *proxy.UpstreamError
+	switch {
+	case errors.Is(err, context.Canceled):
+		ctrl.logger.Info("Client disconnected, upstream request cancelled", "request_id", requestID)
+	case errors.Is(err, context.DeadlineExceeded):
+		ctrl.logger.Warn("Request deadline exceeded", "request_id", requestID, "error", err)
+		ctrl.sendAnthropicError(w, r, StatusGatewayTimeout, AnthropicErrorTypeTimeout, ErrMsgTimeout)
+	case errors.As(err, &notFound):
+		ctrl.sendAnthropicError(w, r, http.StatusNotFound, AnthropicErrorTypeNotFound, fmt.Sprintf(ErrMsgModelNotFound, notFound.Model))
+	case errors.As(err, &tooLong):
+		ctrl.sendAnthropicError(w, r, http.StatusBadRequest, AnthropicErrorTypeInvalidRequest, tooLong.Error())
+	case errors.As(err, &authRequired):
+		ctrl.logger.Warn("Request rejected, authentication required", "request_id", requestID, "auth_state", authRequired.State, "error", authRequired.Err)
+		ctrl.sendAnthropicError(w, r, http.StatusUnauthorized, AnthropicErrorTypeAuthentication, authRequired.Error())
+	case errors.As(err, &upstream):
+		status := upstreamStatus(upstream)
+		errorType := AnthropicErrorTypeAPI
+		switch {
+		case status == http.StatusTooManyRequests:
+			errorType = AnthropicErrorTypeRateLimit
+		case status == http.StatusServiceUnavailable || status == 529:
+			errorType = AnthropicErrorTypeOverloaded
+		case status < http.StatusInternalServerError:
+			errorType = AnthropicErrorTypeInvalidRequest
+		}
+		setRetryAfter(w, upstream.RetryAfter)
+		ctrl.sendAnthropicError(w, r, status, errorType, upstream.Detail())
+	default:
+		ctrl.logger.Error("Internal server error", "request_id", requestID, "error", err)
+		ctrl.sendAnthropicError(w, r, StatusInternalServerError, AnthropicErrorTypeAPI, ErrMsgInternalError)
+	}
+}
+
+// buildChatRequestFromMessages converts an Anthropic Messages request to chat completion format
+func buildChatRequestFromMessages(req *entities.AnthropicMessagesRequest) (*entities.ChatCompletionRequest, error) {
+	chatReq := &entities.ChatCompletionRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Stream:      req.Stream,
+		Temperature: req.Temperature,
+	}
+	if req.TopP != nil {
+		chatReq.TopP = *req.TopP
+	}
+	if len(req.StopSequences) > 0 {
+		chatReq.Stop = req.StopSequences
+	}
+	if req.Metadata != nil {
+		chatReq.User = req.Metadata.UserID
+	}
+	if req.Stream {
+		// Usage is needed for the final message_delta event
+		chatReq.StreamOptions = &entities.StreamOptions{IncludeUsage: true}
+	}
+
+	systemBlocks, err := decodeAnthropicContent(req.System)
+	if err != nil {
+		return nil, fmt.Errorf("system: %w", err)
+	}
+	if systemText := joinAnthropicText(systemBlocks); systemText != "" {
+		chatReq.Messages = append(chatReq.Messages, entities.ChatMessage{Role: "system", Content: systemText})
+	}
+
+	for i, message := range req.Messages {
+		converted, err := convertAnthropicMessage(message)
+		if err != nil {
+			return nil, fmt.Errorf("messages.%d: %w", i, err)
+		}
+		chatReq.Messages = append(chatReq.Messages, converted...)
+	}
+
+	for _, tool := range req.Tools {
+		chatReq.Tools = append(chatReq.Tools, entities.Tool{
+			Type: "function",
+			Function: entities.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if req.ToolChoice != nil {
+		switch req.ToolChoice.Type {
+		case "auto", "none":
+			chatReq.ToolChoice = req.ToolChoice.Type
+		case "any":
+			chatReq.ToolChoice = "required"
+		case "tool":
+			chatReq.ToolChoice = entities.ToolChoice{Type: "function", Function: entities.Function{Name: req.ToolChoice.Name}}
+		}
+	}
+
+	return chatReq, nil
+}
+
+// convertAnthropicMessage converts one Anthropic message into one or more chat messages.
+// Tool results become separate "tool" messages placed ahead of any remaining user content.
+func convertAnthropicMessage(message entities.AnthropicMessage) ([]entities.ChatMessage, error) {
+	blocks, err := decodeAnthropicContent(message.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	switch message.Role {
+	case "user":
+		var messages []entities.ChatMessage
+		var parts []entities.ContentBlock
+		hasImage := false
+		for _, block := range blocks {
+			switch block.Type {
+			case AnthropicBlockText:
+				parts = append(parts, entities.ContentBlock{Type: ContentTypeText, Text: block.Text})
+			case AnthropicBlockImage:
+				if block.Source == nil {
+					return nil, fmt.Errorf("image block is missing source")
+				}
+				imageURL := block.Source.URL
+				if block.Source.Type == "base64" {
+					imageURL = fmt.Sprintf("data:%s;base64,%s", block.Source.MediaType, block.Source.Data)
+				}
+				parts = append(parts, entities.ContentBlock{Type: "image_url", ImageURL: &entities.ImageURL{URL: imageURL}})
+				hasImage = true
+			case AnthropicBlockToolResult:
+				resultBlocks, err := decodeAnthropicContent(block.Content)
+				if err != nil {
+					return nil, fmt.Errorf("tool_result: %w", err)
+				}
+				content := joinAnthropicText(resultBlocks)
+				if block.IsError {
+					content = "Error: " + content
+				}
+				messages = append(messages, entities.ChatMessage{Role: "tool", Content: content, ToolCallID: block.ToolUseID})
+			}
+		}
+		if len(parts) > 0 {
+			if hasImage {
+				messages = append(messages, entities.ChatMessage{Role: "user", Content: parts})
+			} else {
+				messages = append(messages, entities.ChatMessage{Role: "user", Content: joinContentText(parts)})
+			}
+		}
+		return messages, nil
+
+	case "assistant":
+		assistant := entities.ChatMessage{Role: "assistant"}
+		var text strings.Builder
+		for _, block := range blocks {
+			switch block.Type {
+			case AnthropicBlockText:
+				text.WriteString(block.Text)
+			case AnthropicBlockToolUse:
+				arguments := "{}"
+				if len(block.Input) > 0 {
+					arguments = string(block.Input)
+				}
+				assistant.ToolCalls = append(assistant.ToolCalls, entities.ToolCall{
+					ID:       block.ID,
+					Type:     "function",
+					Function: entities.Function{Name: block.Name, Arguments: arguments},
+				})
+			}
+		}
+		if text.Len() > 0 || len(assistant.ToolCalls) == 0 {
+			assistant.Content = text.String()
+		}
+		return []entities.ChatMessage{assistant}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported role: %s", message.Role)
+	}
+}
+
+// decodeAnthropicContent normalizes Anthropic content (string or block array) into content blocks
+func decodeAnthropicContent(content any) ([]entities.AnthropicContentBlock, error) {
+	switch c := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []entities.AnthropicContentBlock{{Type: AnthropicBlockText, Text: c}}, nil
+	default:
+		raw, err := json.Marshal(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content: %w", err)
+		}
+		var blocks []entities.AnthropicContentBlock
+		if err := json.Unmarshal(raw, &blocks); err != nil {
+			return nil, fmt.Errorf("content must be a string or an array of content blocks")
+		}
+		return blocks, nil
+	}
+}
+
+// joinAnthropicText concatenates the text of all text blocks
+func joinAnthropicText(blocks []entities.AnthropicContentBlock) string {
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == AnthropicBlockText {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// joinContentText concatenates the text of all text content blocks
+func joinContentText(parts []entities.ContentBlock) string {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// buildMessagesResponse converts a chat completion response to Anthropic Messages format
+func buildMessagesResponse(response *entities.ChatCompletionResponse) *entities.AnthropicMessagesResponse {
+	choice := response.Choices[0]
+
+	content := []entities.AnthropicContentBlock{}
+	if text := extractTextContent(choice.Message.Content); text != "" {
+		content = append(content, entities.AnthropicContentBlock{Type: AnthropicBlockText, Text: text})
+	}
+	for _, toolCall := range choice.Message.ToolCalls {
+		content = append(content, entities.AnthropicContentBlock{
+			Type:  AnthropicBlockToolUse,
+			ID:    toolCall.ID,
+			Name:  toolCall.Function.Name,
+			Input: toolInput(toolCall.Function.Arguments),
+		})
+	}
+
+	finishReason := choice.FinishReason
+	if finishReason == "" && len(choice.Message.ToolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	stopReason := anthropicStopReason(finishReason)
+
+	messagesResponse := &entities.AnthropicMessagesResponse{
+		ID:         anthropicMessageID(response.ID),
+		Type:       AnthropicObjectMessage,
+		Role:       "assistant",
+		Model:      response.Model,
+		Content:    content,
+		StopReason: &stopReason,
+	}
+	if response.Usage != nil {
+		messagesResponse.Usage = entities.AnthropicUsage{
+			InputTokens:  response.Usage.PromptTokens,
+			OutputTokens: response.Usage.CompletionTokens,
+		}
+	}
+	return messagesResponse
+}
+
+// anthropicStopReason maps an OpenAI finish_reason onto an Anthropic stop_reason
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return AnthropicStopMaxTokens
+	case "tool_calls", "function_call":
+		return AnthropicStopToolUse
+	default:
+		return AnthropicStopEndTurn
+	}
+}
+
+// anthropicMessageID derives an Anthropic-style message ID from an upstream completion ID
+func anthropicMessageID(id string) string {
+	if strings.HasPrefix(id, "msg_") {
+		return id
+	}
+	return "msg_" + strings.TrimPrefix(id, "chatcmpl-")
+}
+
+// toolInput converts tool call arguments into a JSON object suitable for a tool_use block
+func toolInput(arguments string) json.RawMessage {
+	if arguments == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// anthropicStreamWriter re-emits the OpenAI SSE stream written by the proxy use case
+// to the client as Anthropic Messages stream events.
+type anthropicStreamWriter struct {
+	*chunkStreamWriter
+
+	started  bool
+	finished bool
+
+	nextIndex   int
+	openIndex   int
+	openType    string
+	toolBlocks  map[int]int
+	toolIndexer streaming.ToolCallIndexer
+
+	stopReason  string
+	usage       entities.Usage
+	inputTokens int
+}
+
+// newAnthropicStreamWriter creates a new Anthropic stream writer around the client response writer.
+// inputTokens is announced in message_start unless the first chunk reports the prompt tokens itself.
+func newAnthropicStreamWriter(w http.ResponseWriter, logger logging.LoggerInterface, inputTokens int) *anthropicStreamWriter {
+	sw := &anthropicStreamWriter{
+		openIndex:   -1,
+		toolBlocks:  make(map[int]int),
+		inputTokens: inputTokens,
+	}
+	sw.chunkStreamWriter = newChunkStreamWriter(w, logger, sw.handleChunk, sw.finish)
+	return sw
+}
+
+// handleChunk translates a single chat completion chunk into Anthropic events
+func (sw *anthropicStreamWriter) handleChunk(chunk *entities.ChatCompletionResponse) {
+	if chunk.Usage != nil {
+		sw.usage = *chunk.Usage
+	}
+	sw.start(chunk.ID, chunk.Model)
+	if len(chunk.Choices) == 0 {
+		return
+	}
+
+	choice := chunk.Choices[0]
+	if text := extractTextContent(choice.Delta.Content); text != "" {
+		if sw.openType != AnthropicBlockText {
+			sw.openBlock(map[string]interface{}{"type": AnthropicBlockText, "text": ""})
+		}
+		sw.emit("content_block_delta", map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": sw.openIndex,
+			"delta": map[string]interface{}{"type": "text_delta", "text": text},
+		})
+	}
+
+	for _, toolCall := range choice.Delta.ToolCalls {
+		toolIndex := sw.toolIndexer.Index(toolCall)
+		blockIndex, known := sw.toolBlocks[toolIndex]
+		if !known {
+			blockIndex = sw.openBlock(map[string]interface{}{
+				"type":  AnthropicBlockToolUse,
+				"id":    toolCall.ID,
+				"name":  toolCall.Function.Name,
+				"input": map[string]interface{}{},
+			})
+			sw.toolBlocks[toolIndex] = blockIndex
+		}
+		if toolCall.Function.Arguments != "" {
+			sw.emit("content_block_delta", map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": blockIndex,
+				"delta": map[string]interface{}{"type": "input_json_delta", "partial_json": toolCall.Function.Arguments},
+			})
+		}
+	}
+
+	if choice.FinishReason != "" {
+		sw.stopReason = anthropicStopReason(choice.FinishReason)
+	}
+}
+
+// start emits message_start once, before any other event
+func (sw *anthropicStreamWriter) start(id, model string) {
+	if sw.started {
+		return
+	}
+	sw.started = true
+	inputTokens := sw.usage.PromptTokens
+	if inputTokens == 0 {
+		inputTokens = sw.inputTokens
+	}
+	sw.emit("message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id":            anthropicMessageID(id),
+			"type":          AnthropicObjectMessage,
+			"role":          "assistant",
+			"model":         model,
+			"content":       []interface{}{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         entities.AnthropicUsage{InputTokens: inputTokens},
+		},
+	})
+}
+
+// openBlock closes the current content block and starts a new one, returning its index
+func (sw *anthropicStreamWriter) openBlock(contentBlock map[string]interface{}) int {
+	sw.closeBlock()
+	sw.openIndex = sw.nextIndex
+	sw.openType, _ = contentBlock["type"].(string)
+	sw.nextIndex++
+	sw.emit("content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         sw.openIndex,
+		"content_block": contentBlock,
+	})
+	return sw.openIndex
+}
+
+// closeBlock emits content_block_stop for the currently open block, if any
+func (sw *anthropicStreamWriter) closeBlock() {
+	if sw.openIndex < 0 {
+		return
+	}
+	sw.emit("content_block_stop", map[string]interface{}{
+		"type":  "content_block_stop",
+		"index": sw.openIndex,
+	})
+	sw.openIndex = -1
+	sw.openType = ""
+}
+
+// finish closes any open block and emits message_delta and message_stop exactly once
+func (sw *anthropicStreamWriter) finish() {
+	if sw.finished {
+		return
+	}
+	if !sw.wroteHeader {
+		sw.WriteHeader(StatusOK)
+	}
+	sw.start("", "")
+	sw.closeBlock()
+
+	stopReason := sw.stopReason
+	if stopReason == "" {
+		stopReason = AnthropicStopEndTurn
+	}
+	sw.emit("message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
+		"usage": entities.AnthropicUsage{InputTokens: sw.usage.PromptTokens, OutputTokens: sw.usage.CompletionTokens},
+	})
+	sw.emit("message_stop", map[string]interface{}{"type": "message_stop"})
+	sw.finished = true
+}
+
+// emitError emits an Anthropic error event on an already-started stream
+func (sw *anthropicStreamWriter) emitError(errorType, message string) {
+	sw.emit("error", map[string]interface{}{
+		"type":  AnthropicObjectError,
+		"error": map[string]interface{}{"type": errorType, "message": message},
+	})
+	sw.finished = true
+}
@@ -0,0 +1,470 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/auth"
+	"qwen-go-proxy/internal/usecases/models"
+	"qwen-go-proxy/internal/usecases/proxy"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBuildChatRequestFromMessages(t *testing.T) {
+	body := `{
+		"model": "qwen3-coder-plus",
+		"max_tokens": 512,
+		"system": [{"type": "text", "text": "You are helpful."}],
+		"temperature": 0.2,
+		"stop_sequences": ["END"],
+		"tools": [{"name": "get_weather", "description": "Get weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any"},
+		"messages": [
+			{"role": "user", "content": "What is the weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "Sunny"}]},
+				{"type": "text", "text": "Thanks"}
+			]}
+		]
+	}`
+
+	var req entities.AnthropicMessagesRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+
+	chatReq, err := buildChatRequestFromMessages(&req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "qwen3-coder-plus", chatReq.Model)
+	assert.Equal(t, 512, chatReq.MaxTokens)
+	assert.Equal(t, 0.2, *chatReq.Temperature)
+	assert.Equal(t, []string{"END"}, chatReq.Stop)
+	assert.Equal(t, "required", chatReq.ToolChoice)
+	require.Len(t, chatReq.Tools, 1)
+	assert.Equal(t, "get_weather", chatReq.Tools[0].Function.Name)
+
+	require.Len(t, chatReq.Messages, 5)
+	assert.Equal(t, entities.ChatMessage{Role: "system", Content: "You are helpful."}, chatReq.Messages[0])
+	assert.Equal(t, entities.ChatMessage{Role: "user", Content: "What is the weather in Paris?"}, chatReq.Messages[1])
+
+	assistant := chatReq.Messages[2]
+	assert.Equal(t, "assistant", assistant.Role)
+	assert.Equal(t, "Let me check.", assistant.Content)
+	require.Len(t, assistant.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", assistant.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", assistant.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city": "Paris"}`, assistant.ToolCalls[0].Function.Arguments)
+
+	assert.Equal(t, entities.ChatMessage{Role: "tool", Content: "Sunny", ToolCallID: "toolu_1"}, chatReq.Messages[3])
+	assert.Equal(t, entities.ChatMessage{Role: "user", Content: "Thanks"}, chatReq.Messages[4])
+}
+
+func TestBuildChatRequestFromMessages_Image(t *testing.T) {
+	req := &entities.AnthropicMessagesRequest{
+		Messages: []entities.AnthropicMessage{
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "Describe this"},
+				map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "aGVsbG8="}},
+			}},
+		},
+	}
+
+	chatReq, err := buildChatRequestFromMessages(req)
+	require.NoError(t, err)
+	require.Len(t, chatReq.Messages, 1)
+
+	parts, ok := chatReq.Messages[0].Content.([]entities.ContentBlock)
+	require.True(t, ok)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "Describe this", parts[0].Text)
+	assert.Equal(t, "data:image/png;base64,aGVsbG8=", parts[1].ImageURL.URL)
+}
+
+func TestBuildChatRequestFromMessages_InvalidRole(t *testing.T) {
+	req := &entities.AnthropicMessagesRequest{
+		Messages: []entities.AnthropicMessage{{Role: "system", Content: "nope"}},
+	}
+
+	_, err := buildChatRequestFromMessages(req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported role")
+}
+
+func TestBuildMessagesResponse(t *testing.T) {
+	response := &entities.ChatCompletionResponse{
+		ID:    "chatcmpl-123",
+		Model: "qwen3-coder-plus",
+		Choices: []entities.ChatCompletionChoice{
+			{
+				Message: entities.ChatMessage{
+					Role:    "assistant",
+					Content: "Checking",
+					ToolCalls: []entities.ToolCall{
+						{ID: "call_1", Type: "function", Function: entities.Function{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+					},
+				},
+				FinishReason: "tool_calls",
+			},
+		},
+		Usage: &entities.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+
+	result := buildMessagesResponse(response)
+
+	assert.Equal(t, "msg_123", result.ID)
+	assert.Equal(t, AnthropicObjectMessage, result.Type)
+	assert.Equal(t, "assistant", result.Role)
+	assert.Equal(t, AnthropicStopToolUse, *result.StopReason)
+	assert.Equal(t, entities.AnthropicUsage{InputTokens: 10, OutputTokens: 5}, result.Usage)
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, "Checking", result.Content[0].Text)
+	assert.Equal(t, AnthropicBlockToolUse, result.Content[1].Type)
+	assert.JSONEq(t, `{"city":"Paris"}`, string(result.Content[1].Input))
+}
+
+func TestAnthropicStopReason(t *testing.T) {
+	assert.Equal(t, AnthropicStopEndTurn, anthropicStopReason("stop"))
+	assert.Equal(t, AnthropicStopMaxTokens, anthropicStopReason("length"))
+	assert.Equal(t, AnthropicStopToolUse, anthropicStopReason("tool_calls"))
+	assert.Equal(t, AnthropicStopEndTurn, anthropicStopReason(""))
+}
+
+func TestMessagesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+		assert.Equal(t, "system", req.Messages[0].Role)
+		return &entities.ChatCompletionResponse{
+			ID:    "chatcmpl-abc",
+			Model: "qwen3-coder-plus",
+			Choices: []entities.ChatCompletionChoice{
+				{Message: entities.ChatMessage{Role: "assistant", Content: "Hello!"}, FinishReason: "stop"},
+			},
+		}, nil
+	})
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "system": "Be brief", "messages": [{"role": "user", "content": "Hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	var result entities.AnthropicMessagesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "msg_abc", result.ID)
+	assert.Equal(t, "Hello!", result.Content[0].Text)
+	assert.Equal(t, AnthropicStopEndTurn, *result.StopReason)
+}
+
+func TestMessagesHandler_InvalidJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader("{invalid"))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 400, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"type":"error"`)
+	assert.Contains(t, rec.Body.String(), ErrorTypeInvalidRequest)
+}
+
+func TestMessagesHandler_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "messages": [{"role": "user", "content": "Hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 500, rec.Code)
+	assert.Contains(t, rec.Body.String(), AnthropicErrorTypeAPI)
+}
+
+func TestMessagesHandler_ModelNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, &models.ModelNotFoundError{Model: "claude-unknown"})
+
+	body := `{"model": "claude-unknown", "max_tokens": 100, "messages": [{"role": "user", "content": "Hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 404, rec.Code)
+	assert.Contains(t, rec.Body.String(), AnthropicErrorTypeNotFound)
+	assert.Contains(t, rec.Body.String(), "claude-unknown")
+}
+
+func TestMessagesHandler_AuthenticationRequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, &auth.AuthenticationRequiredError{State: entities.AuthStateUnauthenticated})
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "messages": [{"role": "user", "content": "Hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 401, rec.Code)
+	assert.Contains(t, rec.Body.String(), AnthropicErrorTypeAuthentication)
+	assert.Contains(t, rec.Body.String(), "GET /auth")
+}
+
+func TestMessagesHandler_UpstreamRateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	upstream := &proxy.UpstreamError{StatusCode: 429, Message: `{"error":{"message":"Rate limit exceeded"}}`, RetryAfter: 30 * time.Second}
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, upstream)
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "messages": [{"role": "user", "content": "Hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 429, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), AnthropicErrorTypeRateLimit)
+	assert.Contains(t, rec.Body.String(), "Rate limit exceeded")
+}
+
+func TestMessagesHandler_Streaming(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	upstream := strings.Join([]string{
+		`data: {"id":"chatcmpl-s1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`data: {"id":"chatcmpl-s1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		`data: {"id":"chatcmpl-s1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}]}}]}`,
+		`data: {"id":"chatcmpl-s1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"q\":1}"}}]},"finish_reason":"tool_calls"}]}`,
+		`data: {"id":"chatcmpl-s1","model":"qwen3-coder-plus","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`,
+		`data: [DONE]`,
+	}, "\n\n") + "\n\n"
+
+	var sent *entities.ChatCompletionRequest
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req *entities.ChatCompletionRequest, w http.ResponseWriter) error {
+		sent = req
+		assert.True(t, req.Stream)
+		assert.True(t, req.StreamOptions.IncludeUsage)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		// Split the write to exercise partial line buffering
+		w.Write([]byte(upstream[:40]))
+		w.Write([]byte(upstream[40:]))
+		return nil
+	})
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	events := parseSSEEvents(t, rec.Body.String())
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.name
+	}
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, names)
+
+	assert.Equal(t, "msg_s1", events[0].data["message"].(map[string]interface{})["id"])
+	assert.Equal(t, "Hel", events[2].data["delta"].(map[string]interface{})["text"])
+	assert.Equal(t, "lookup", events[5].data["content_block"].(map[string]interface{})["name"])
+	assert.Equal(t, `{"q":1}`, events[6].data["delta"].(map[string]interface{})["partial_json"])
+
+	// The upstream reports usage only at the end, so message_start announces the local estimate
+	estimate := tokenizer.CountPromptTokens(tokenizer.NewEstimator(), sent)
+	assert.Positive(t, estimate)
+	startUsage := events[0].data["message"].(map[string]interface{})["usage"].(map[string]interface{})
+	assert.Equal(t, float64(estimate), startUsage["input_tokens"])
+
+	messageDelta := events[8].data
+	assert.Equal(t, AnthropicStopToolUse, messageDelta["delta"].(map[string]interface{})["stop_reason"])
+	assert.Equal(t, float64(7), messageDelta["usage"].(map[string]interface{})["input_tokens"])
+	assert.Equal(t, float64(3), messageDelta["usage"].(map[string]interface{})["output_tokens"])
+}
+
+func TestMessagesHandler_StreamingUpstreamInputTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	upstream := strings.Join([]string{
+		`data: {"id":"chatcmpl-s2","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}],"usage":{"prompt_tokens":42,"completion_tokens":0,"total_tokens":42}}`,
+		`data: {"id":"chatcmpl-s2","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":42,"completion_tokens":1,"total_tokens":43}}`,
+		`data: [DONE]`,
+	}, "\n\n") + "\n\n"
+
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ *entities.ChatCompletionRequest, w http.ResponseWriter) error {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(upstream))
+		return nil
+	})
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`
+	rec := httptest.NewRecorder()
+	controller.MessagesHandler(rec, httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body)))
+
+	// Prompt tokens reported with the first chunk are announced instead of the estimate
+	events := parseSSEEvents(t, rec.Body.String())
+	require.NotEmpty(t, events)
+	assert.Equal(t, "message_start", events[0].name)
+	startUsage := events[0].data["message"].(map[string]interface{})["usage"].(map[string]interface{})
+	assert.Equal(t, float64(42), startUsage["input_tokens"])
+}
+
+func TestMessagesHandler_StreamingToolCallsWithoutIndex(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	// Argument fragments without an index continue the tool call they follow
+	upstream := strings.Join([]string{
+		`data: {"id":"chatcmpl-s2","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":"}}]}}]}`,
+		`data: {"id":"chatcmpl-s2","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"tool_calls":[{"function":{"arguments":"1}"}}]},"finish_reason":"tool_calls"}]}`,
+		`data: [DONE]`,
+	}, "\n\n") + "\n\n"
+
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req *entities.ChatCompletionRequest, w http.ResponseWriter) error {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(upstream))
+		return nil
+	})
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`
+	rec := httptest.NewRecorder()
+	controller.MessagesHandler(rec, httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body)))
+
+	events := parseSSEEvents(t, rec.Body.String())
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.name
+	}
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, names)
+	assert.Equal(t, `{"q":`, events[2].data["delta"].(map[string]interface{})["partial_json"])
+	assert.Equal(t, `1}`, events[3].data["delta"].(map[string]interface{})["partial_json"])
+	assert.Equal(t, float64(0), events[3].data["index"])
+}
+
+func TestMessagesHandler_StreamingErrorBeforeHeaders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError)
+
+	body := `{"model": "qwen3-coder-plus", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.MessagesHandler(rec, req)
+
+	assert.Equal(t, 500, rec.Code)
+	assert.Contains(t, rec.Body.String(), AnthropicErrorTypeAPI)
+}
+
+type sseEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// parseSSEEvents parses named SSE events from a response body
+func parseSSEEvents(t *testing.T, body string) []sseEvent {
+	var events []sseEvent
+	for _, frame := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event sseEvent
+		for _, line := range strings.Split(frame, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data))
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
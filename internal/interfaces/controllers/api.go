@@ -1,14 +1,27 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
 	"qwen-go-proxy/internal/infrastructure/logging"
 	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tracing"
+	"qwen-go-proxy/internal/usecases/auth"
+	"qwen-go-proxy/internal/usecases/models"
 	"qwen-go-proxy/internal/usecases/proxy"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Constants for API responses and error handling
@@ -21,6 +34,9 @@ const (
 	ErrorTypeInvalidRequest = "invalid_request_error"
 	ErrorTypeInternal       = "internal_error"
 	ErrorTypeAuthentication = "authentication_error"
+	ErrorTypeTimeout        = "timeout_error"
+	ErrorTypeRateLimit      = "rate_limit_error"
+	ErrorTypeUpstream       = "upstream_error"
 
 	// ErrMsgInvalidJSON Error messages
 	ErrMsgInvalidJSON      = "Invalid JSON"
@@ -28,10 +44,16 @@ const (
 	ErrMsgUnexpectedFormat = "Unexpected response format"
 	ErrMsgAuthFailed       = "Authentication failed"
 	ErrMsgInternalError    = "An internal error occurred"
+	ErrMsgTimeout          = "The request timed out before the upstream API responded"
+	ErrMsgModelNotFound    = "The model `%s` does not exist or you do not have access to it."
+
+	// ErrCodeModelNotFound Error codes
+	ErrCodeModelNotFound         = "model_not_found"
+	ErrCodeContextLengthExceeded = "context_length_exceeded"
 
 	// MsgUserAuthenticated Response messages
 	MsgUserAuthenticated   = "User is authenticated"
-	MsgAuthInitiated       = "Device authentication initiated. Please complete the authentication process in your browser."
+	MsgAuthInitiated       = "Device authentication initiated. Open the verification URL and enter the user code to complete it."
 	MsgHealthy             = "healthy"
 	MsgAuthStatusInitiated = "authentication_initiated"
 
@@ -42,24 +64,31 @@ const (
 	StatusOK                  = http.StatusOK
 	StatusBadRequest          = http.StatusBadRequest
 	StatusInternalServerError = http.StatusInternalServerError
+	StatusGatewayTimeout      = http.StatusGatewayTimeout
 )
 
 // APIController handles API requests
 type APIController struct {
 	proxyUseCase proxy.ProxyUseCaseInterface
+	tokenizer    interfaces.Tokenizer
 	logger       logging.LoggerInterface
 }
 
-// NewAPIController creates a new API controller
-func NewAPIController(proxyUseCase proxy.ProxyUseCaseInterface, logger logging.LoggerInterface) *APIController {
+// NewAPIController creates a new API controller.
+// The tokenizer estimates the input tokens announced at the start of Anthropic streams.
+func NewAPIController(proxyUseCase proxy.ProxyUseCaseInterface, tokenizer interfaces.Tokenizer, logger logging.LoggerInterface) *APIController {
 	if proxyUseCase == nil {
 		panic("proxyUseCase cannot be nil")
 	}
+	if tokenizer == nil {
+		panic("tokenizer cannot be nil")
+	}
 	if logger == nil {
 		panic("logger cannot be nil")
 	}
 	return &APIController{
 		proxyUseCase: proxyUseCase,
+		tokenizer:    tokenizer,
 		logger:       logger,
 	}
 }
@@ -96,10 +125,144 @@ func (ctrl *APIController) sendInternalError(w http.ResponseWriter, r *http.Requ
 	if requestID == "" {
 		requestID = "unknown"
 	}
+	tracing.RecordError(trace.SpanFromContext(r.Context()), err)
+	if ctrl.handleContextError(w, r, err) {
+		return
+	}
+	var notFound *models.ModelNotFoundError
+	if errors.As(err, &notFound) {
+		ctrl.sendModelNotFoundError(w, r, notFound.Model)
+		return
+	}
+	var tooLong *proxy.ContextLengthExceededError
+	if errors.As(err, &tooLong) {
+		ctrl.sendContextLengthError(w, r, tooLong)
+		return
+	}
+	var authRequired *auth.AuthenticationRequiredError
+	if errors.As(err, &authRequired) {
+		ctrl.sendAuthenticationError(w, r, authRequired)
+		return
+	}
+	var upstream *proxy.UpstreamError
+	if errors.As(err, &upstream) {
+		ctrl.sendUpstreamError(w, r, upstream)
+		return
+	}
 	ctrl.logger.Error("Internal server error", "request_id", requestID, "error", err)
 	ctrl.sendErrorResponse(w, r, StatusInternalServerError, ErrorTypeInternal, ErrMsgInternalError)
 }
 
+// sendModelNotFoundError sends the OpenAI error for a model that does not exist
+func (ctrl *APIController) sendModelNotFoundError(w http.ResponseWriter, r *http.Request, model string) {
+	ctrl.logger.Warn("Unknown model requested", "request_id", middleware.GetRequestID(r.Context()), "model", model)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+
+	errorResponse := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": fmt.Sprintf(ErrMsgModelNotFound, model),
+			"type":    ErrorTypeInvalidRequest,
+			"param":   "model",
+			"code":    ErrCodeModelNotFound,
+		},
+	}
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// sendContextLengthError sends the OpenAI error for a request that exceeds the context window of its model
+func (ctrl *APIController) sendContextLengthError(w http.ResponseWriter, r *http.Request, err *proxy.ContextLengthExceededError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+
+	errorResponse := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": err.Error(),
+			"type":    ErrorTypeInvalidRequest,
+			"param":   "messages",
+			"code":    ErrCodeContextLengthExceeded,
+		},
+	}
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// sendAuthenticationError sends the OpenAI error for a request made while no usable credentials are available.
+// The code is the authentication state and the message names the action that restores authentication.
+func (ctrl *APIController) sendAuthenticationError(w http.ResponseWriter, r *http.Request, err *auth.AuthenticationRequiredError) {
+	ctrl.logger.Warn("Request rejected, authentication required",
+		"request_id", middleware.GetRequestID(r.Context()),
+		"auth_state", err.State,
+		"error", err.Err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+
+	errorResponse := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": err.Error(),
+			"type":    ErrorTypeAuthentication,
+			"param":   nil,
+			"code":    err.State,
+		},
+	}
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// sendUpstreamError passes an upstream error response through to the client in OpenAI format,
+// keeping its status, message and Retry-After header
+func (ctrl *APIController) sendUpstreamError(w http.ResponseWriter, r *http.Request, err *proxy.UpstreamError) {
+	status := upstreamStatus(err)
+	errorType := ErrorTypeUpstream
+	switch {
+	case status == http.StatusTooManyRequests:
+		errorType = ErrorTypeRateLimit
+	case status < http.StatusInternalServerError:
+		errorType = ErrorTypeInvalidRequest
+	}
+	setRetryAfter(w, err.RetryAfter)
+	ctrl.sendErrorResponse(w, r, status, errorType, err.Detail())
+}
+
+// upstreamStatus returns the status to report to clients for an upstream error.
+// Exhausted quotas are reported as 429 whatever status the upstream used. The upstream rejecting
+// the proxy's own credentials is not the client's fault, so 401 and 403 are reported as a bad
+// gateway, as are statuses that are not errors.
+func upstreamStatus(err *proxy.UpstreamError) int {
+	switch {
+	case err.QuotaExceeded():
+		return http.StatusTooManyRequests
+	case err.StatusCode == http.StatusUnauthorized, err.StatusCode == http.StatusForbidden:
+		return http.StatusBadGateway
+	case err.StatusCode < http.StatusBadRequest, err.StatusCode > 599:
+		return http.StatusBadGateway
+	}
+	return err.StatusCode
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds when there is a wait to report
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+}
+
+// handleContextError handles errors caused by the request context ending and reports whether err was one.
+// A cancelled context means the client went away, so nothing is written; an expired deadline is a 504.
+func (ctrl *APIController) handleContextError(w http.ResponseWriter, r *http.Request, err error) bool {
+	requestID := middleware.GetRequestID(r.Context())
+	switch {
+	case errors.Is(err, context.Canceled):
+		ctrl.logger.Info("Client disconnected, upstream request cancelled", "request_id", requestID)
+		return true
+	case errors.Is(err, context.DeadlineExceeded):
+		ctrl.logger.Warn("Request deadline exceeded", "request_id", requestID, "error", err)
+		ctrl.sendErrorResponse(w, r, StatusGatewayTimeout, ErrorTypeTimeout, ErrMsgTimeout)
+		return true
+	}
+	return false
+}
+
 // validateJSONRequest validates and binds JSON request
 func (ctrl *APIController) validateJSONRequest(w http.ResponseWriter, r *http.Request, target interface{}) bool {
 	if err := json.NewDecoder(r.Body).Decode(target); err != nil {
@@ -131,8 +294,10 @@ func (ctrl *APIController) OpenAIHealthHandler(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(response)
 }
 
-// AuthenticateHandler checks authentication status and initiates device auth if needed
+// AuthenticateHandler checks authentication status and starts device auth if needed, without waiting for it
 func (ctrl *APIController) AuthenticateHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.AuthenticateHandler")
+	defer span.End()
 	requestID := middleware.GetRequestID(r.Context())
 	if requestID == "" {
 		requestID = "unknown"
@@ -155,9 +320,9 @@ func (ctrl *APIController) AuthenticateHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// User is not authenticated, initiate device authentication
+	// User is not authenticated, start device authentication or join the flow in progress
 	ctrl.logger.Info("User not authenticated, initiating device authentication", "request_id", requestID)
-	err = ctrl.proxyUseCase.AuthenticateManually()
+	status, err := ctrl.proxyUseCase.StartDeviceFlow(r.Context())
 	if err != nil {
 		ctrl.logger.Error("Authentication initiation failed", "request_id", requestID, "error", err)
 		w.Header().Set("Content-Type", "application/json")
@@ -177,51 +342,89 @@ func (ctrl *APIController) AuthenticateHandler(w http.ResponseWriter, r *http.Re
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(StatusOK)
 	response := map[string]interface{}{
-		"authenticated": false,
-		"message":       MsgAuthInitiated,
-		"status":        MsgAuthStatusInitiated,
+		"authenticated":    false,
+		"message":          MsgAuthInitiated,
+		"status":           MsgAuthStatusInitiated,
+		"user_code":        status.UserCode,
+		"verification_url": status.VerificationURIComplete,
+		"device_flow":      status,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// AuthStatusHandler reports whether the proxy is authenticated and the progress of the device flow
+func (ctrl *APIController) AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.AuthStatusHandler")
+	defer span.End()
+	requestID := middleware.GetRequestID(r.Context())
+	if requestID == "" {
+		requestID = "unknown"
+	}
+	ctrl.logger.Debug("Authentication status requested", "request_id", requestID)
+
+	credentials, err := ctrl.proxyUseCase.CheckAuthentication()
+	response := map[string]interface{}{
+		"authenticated": err == nil && credentials != nil,
+		"state":         ctrl.proxyUseCase.AuthState(),
+	}
+	if status := ctrl.proxyUseCase.DeviceFlowStatus(); status != nil {
+		response["device_flow"] = status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 // OpenAIModelsHandler returns models in OpenAI-compatible format
 func (ctrl *APIController) OpenAIModelsHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.OpenAIModelsHandler")
+	defer span.End()
 	requestID := middleware.GetRequestID(r.Context())
 	if requestID == "" {
 		requestID = "unknown"
 	}
 	ctrl.logger.Debug("Models list requested", "request_id", requestID)
 
-	models, err := ctrl.proxyUseCase.GetModels()
+	modelList, err := ctrl.proxyUseCase.GetModels(r.Context())
 	if err != nil {
 		ctrl.sendInternalError(w, r, err)
 		return
 	}
-	ctrl.logger.Info("Retrieved models", "request_id", requestID, "count", len(models))
-
-	// Convert to OpenAI format
-	openAIModels := make([]map[string]interface{}, len(models))
-	for i, model := range models {
-		openAIModels[i] = map[string]interface{}{
-			"id":         model.ID,
-			"object":     model.Object,
-			"created":    model.Created,
-			"owned_by":   model.OwnedBy,
-			"permission": model.Permission,
-		}
-	}
+	ctrl.logger.Info("Retrieved models", "request_id", requestID, "count", len(modelList))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(StatusOK)
 	response := map[string]interface{}{
 		"object": ObjectList,
-		"data":   openAIModels,
+		"data":   modelList,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// OpenAIModelHandler returns a single model by ID or alias in OpenAI-compatible format
+func (ctrl *APIController) OpenAIModelHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.OpenAIModelHandler")
+	defer span.End()
+	// Model IDs may contain slashes, so the route captures the rest of the path
+	modelID := chi.URLParam(r, "*")
+	ctrl.logger.Debug("Model requested", "request_id", middleware.GetRequestID(r.Context()), "model", modelID)
+
+	model, err := ctrl.proxyUseCase.GetModel(r.Context(), modelID)
+	if err != nil {
+		ctrl.sendInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(model)
+}
+
 // OpenAICompletionsHandler handles OpenAI-style completions (non-chat)
 func (ctrl *APIController) OpenAICompletionsHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.OpenAICompletionsHandler")
+	defer span.End()
 	ctrl.logger.Debug("OpenAI completions request received")
 
 	var body map[string]interface{}
@@ -269,7 +472,7 @@ func (ctrl *APIController) copyCompletionParameters(body map[string]interface{},
 		chatReq.MaxTokens = int(maxTokens)
 	}
 	if temperature, ok := extractFloat64(body["temperature"]); ok {
-		chatReq.Temperature = temperature
+		chatReq.Temperature = &temperature
 	}
 	if topP, ok := extractFloat64(body["top_p"]); ok {
 		chatReq.TopP = topP
@@ -278,7 +481,7 @@ func (ctrl *APIController) copyCompletionParameters(body map[string]interface{},
 
 // handleNonStreamingCompletion handles non-streaming completion responses
 func (ctrl *APIController) handleNonStreamingCompletion(w http.ResponseWriter, r *http.Request, chatReq *entities.ChatCompletionRequest) {
-	response, err := ctrl.proxyUseCase.ChatCompletions(chatReq)
+	response, err := ctrl.proxyUseCase.ChatCompletions(r.Context(), chatReq)
 	if err != nil {
 		ctrl.sendInternalError(w, r, err)
 		return
@@ -291,6 +494,7 @@ func (ctrl *APIController) handleNonStreamingCompletion(w http.ResponseWriter, r
 
 	completionResponse := ctrl.buildCompletionResponse(response)
 	ctrl.logger.Info("Completion response sent", "id", response.ID, "usage", response.Usage)
+	middleware.RecordUsage(r.Context(), response.Usage)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(StatusOK)
@@ -318,6 +522,8 @@ func (ctrl *APIController) buildCompletionResponse(response *entities.ChatComple
 
 // ChatCompletionsHandler handles chat completion requests
 func (ctrl *APIController) ChatCompletionsHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.ChatCompletionsHandler")
+	defer span.End()
 	ctrl.logger.Debug("Chat completions request received")
 
 	var req entities.ChatCompletionRequest
@@ -326,6 +532,7 @@ func (ctrl *APIController) ChatCompletionsHandler(w http.ResponseWriter, r *http
 	}
 
 	ctrl.logger.Info("Processing chat completion", "model", req.Model, "stream", req.Stream, "messages", len(req.Messages))
+	span.SetAttributes(tracing.AttrModel.String(req.Model), tracing.AttrStream.Bool(req.Stream))
 
 	if req.Stream {
 		ctrl.StreamChatCompletionsHandler(w, r, &req)
@@ -337,13 +544,14 @@ func (ctrl *APIController) ChatCompletionsHandler(w http.ResponseWriter, r *http
 
 // handleNonStreamingChatCompletion handles non-streaming chat completion responses
 func (ctrl *APIController) handleNonStreamingChatCompletion(w http.ResponseWriter, r *http.Request, req *entities.ChatCompletionRequest) {
-	response, err := ctrl.proxyUseCase.ChatCompletions(req)
+	response, err := ctrl.proxyUseCase.ChatCompletions(r.Context(), req)
 	if err != nil {
 		ctrl.sendInternalError(w, r, err)
 		return
 	}
 
 	ctrl.logger.Info("Chat completion response sent", "id", response.ID, "usage", response.Usage)
+	middleware.RecordUsage(r.Context(), response.Usage)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(StatusOK)
@@ -354,8 +562,32 @@ func (ctrl *APIController) handleNonStreamingChatCompletion(w http.ResponseWrite
 func (ctrl *APIController) StreamChatCompletionsHandler(w http.ResponseWriter, r *http.Request, req *entities.ChatCompletionRequest) {
 	ctrl.logger.Debug("Streaming chat completion initiated", "model", req.Model)
 
-	err := ctrl.proxyUseCase.StreamChatCompletions(req, w)
+	usageWriter := newUsageTrackingWriter(w)
+	err := ctrl.proxyUseCase.StreamChatCompletions(r.Context(), req, usageWriter)
+	middleware.RecordUsage(r.Context(), usageWriter.usage)
+	tracing.SetUsage(r.Context(), usageWriter.usage)
 	if err != nil {
+		if r.Context().Err() != nil {
+			ctrl.logger.Info("Client disconnected during streaming", "request_id", middleware.GetRequestID(r.Context()))
+			return
+		}
+		// Unknown models, over-long requests and missing credentials are rejected before anything
+		// is streamed, so a JSON error can still be sent
+		var notFound *models.ModelNotFoundError
+		if errors.As(err, &notFound) {
+			ctrl.sendModelNotFoundError(w, r, notFound.Model)
+			return
+		}
+		var tooLong *proxy.ContextLengthExceededError
+		if errors.As(err, &tooLong) {
+			ctrl.sendContextLengthError(w, r, tooLong)
+			return
+		}
+		var authRequired *auth.AuthenticationRequiredError
+		if errors.As(err, &authRequired) {
+			ctrl.sendAuthenticationError(w, r, authRequired)
+			return
+		}
 		// For streaming, we can't send JSON error after headers are set
 		// The error would have been logged in the use case
 		ctrl.logger.Error("Streaming chat completion failed", "error", err)
@@ -365,6 +597,12 @@ func (ctrl *APIController) StreamChatCompletionsHandler(w http.ResponseWriter, r
 	ctrl.logger.Debug("Streaming chat completion completed successfully")
 }
 
+// startHandlerSpan starts a span for a controller handler and returns the request carrying it
+func startHandlerSpan(r *http.Request, name string) (*http.Request, trace.Span) {
+	ctx, span := tracing.Start(r.Context(), name)
+	return r.WithContext(ctx), span
+}
+
 // extractFloat64 safely extracts a float64 value from interface{}
 func extractFloat64(value interface{}) (float64, bool) {
 	if f, ok := value.(float64); ok {
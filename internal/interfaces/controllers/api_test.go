@@ -1,15 +1,28 @@
 package controllers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"qwen-go-proxy/internal/domain/entities"
 	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
 	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/auth"
+	"qwen-go-proxy/internal/usecases/models"
+	"qwen-go-proxy/internal/usecases/proxy"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -21,7 +34,7 @@ func TestStreamChatCompletionsHandler(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Test successful streaming
 	req := &entities.ChatCompletionRequest{
@@ -36,7 +49,7 @@ func TestStreamChatCompletionsHandler(t *testing.T) {
 	}
 
 	// Mock the StreamChatCompletions call to return no error
-	mockProxy.EXPECT().StreamChatCompletions(req, gomock.Any()).Return(nil).Times(1)
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).Return(nil).Times(1)
 
 	// Create a request to the handler
 	httpReq := httptest.NewRequest("POST", "/chat/completions", nil)
@@ -50,6 +63,37 @@ func TestStreamChatCompletionsHandler(t *testing.T) {
 	// The important part is that the mock expectation was met.
 }
 
+func TestStreamChatCompletionsHandler_RecordsUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	req := &entities.ChatCompletionRequest{Model: "test-model", Stream: true}
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).DoAndReturn(func(_ context.Context, _ *entities.ChatCompletionRequest, w http.ResponseWriter) error {
+		w.Write([]byte("data: {\"id\":\"chatcmpl-1\",\"choices\":[]}\n\n"))
+		w.Write([]byte("data: {\"id\":\"chatcmpl-1\",\"choices\":[],\"usage\":{\"prompt_tokens\":7,"))
+		w.Write([]byte("\"completion_tokens\":3,\"total_tokens\":10}}\n\ndata: [DONE]\n\n"))
+		return nil
+	})
+
+	// The rate limiter installs a usage recorder that handlers report token usage to
+	limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{RequestsPerSecond: 10, Burst: 10, PromptTokensPerMinute: 10})
+	var recorded *entities.Usage
+	handler := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		controller.StreamChatCompletionsHandler(w, r, req)
+		recorded = r.Context().Value(middleware.UsageRecorderKey).(*middleware.UsageRecorder).Usage()
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+
+	// The stream reaches the client unchanged
+	assert.Contains(t, rec.Body.String(), "data: [DONE]")
+	assert.Equal(t, &entities.Usage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10}, recorded)
+}
+
 func TestStreamChatCompletionsHandler_Error(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -57,7 +101,7 @@ func TestStreamChatCompletionsHandler_Error(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := &entities.ChatCompletionRequest{
 		Model: "test-model",
@@ -71,7 +115,7 @@ func TestStreamChatCompletionsHandler_Error(t *testing.T) {
 	}
 
 	// Mock the StreamChatCompletions call to return an error
-	mockProxy.EXPECT().StreamChatCompletions(req, gomock.Any()).Return(assert.AnError).Times(1)
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).Return(assert.AnError).Times(1)
 
 	// Create a request to the handler
 	httpReq := httptest.NewRequest("POST", "/chat/completions", nil)
@@ -195,7 +239,7 @@ func TestCopyCompletionParameters(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Create a request body with various parameters
 	body := map[string]interface{}{
@@ -213,7 +257,7 @@ func TestCopyCompletionParameters(t *testing.T) {
 
 	// Check that the parameters were copied correctly
 	assert.Equal(t, 100, chatReq.MaxTokens)
-	assert.Equal(t, 0.7, chatReq.Temperature)
+	assert.Equal(t, 0.7, *chatReq.Temperature)
 	assert.Equal(t, 0.9, chatReq.TopP)
 }
 
@@ -224,7 +268,7 @@ func TestBuildChatRequestFromCompletion(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	body := map[string]interface{}{
 		"max_tokens":  200.0,
@@ -242,7 +286,7 @@ func TestBuildChatRequestFromCompletion(t *testing.T) {
 	assert.Equal(t, "user", chatReq.Messages[0].Role)
 	assert.Equal(t, prompt, chatReq.Messages[0].Content)
 	assert.Equal(t, 200, chatReq.MaxTokens)
-	assert.Equal(t, 0.5, chatReq.Temperature)
+	assert.Equal(t, 0.5, *chatReq.Temperature)
 	assert.Equal(t, false, chatReq.Stream)
 }
 
@@ -253,7 +297,7 @@ func TestBuildCompletionResponse(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Create a sample chat completion response
 	chatResponse := &entities.ChatCompletionResponse{
@@ -307,7 +351,7 @@ func TestSendErrorResponse(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := httptest.NewRequest("GET", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -326,7 +370,7 @@ func TestSendValidationError(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := httptest.NewRequest("GET", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -345,7 +389,7 @@ func TestSendInternalError(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := httptest.NewRequest("GET", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -357,6 +401,25 @@ func TestSendInternalError(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "An internal error occurred")
 }
 
+func TestSendInternalError_ContextErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	// An expired deadline is reported as a gateway timeout
+	rec := httptest.NewRecorder()
+	controller.sendInternalError(rec, httptest.NewRequest("GET", "/test", nil), fmt.Errorf("API request failed: %w", context.DeadlineExceeded))
+	assert.Equal(t, 504, rec.Code)
+	assert.Contains(t, rec.Body.String(), "timeout_error")
+
+	// Nothing is written for a client that already disconnected
+	rec = httptest.NewRecorder()
+	controller.sendInternalError(rec, httptest.NewRequest("GET", "/test", nil), fmt.Errorf("API request failed: %w", context.Canceled))
+	assert.Empty(t, rec.Body.String())
+}
+
 func TestValidateJSONRequest(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -364,7 +427,7 @@ func TestValidateJSONRequest(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Test valid JSON
 	req := httptest.NewRequest("POST", "/test", strings.NewReader(`{"valid": "json"}`))
@@ -385,7 +448,7 @@ func TestValidateJSONRequest_Invalid(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Test invalid JSON
 	req := httptest.NewRequest("POST", "/test", strings.NewReader(`{"invalid": json}`))
@@ -406,7 +469,7 @@ func TestOpenAIHealthHandler(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	rec := httptest.NewRecorder()
@@ -429,7 +492,7 @@ func TestAuthenticateHandler_UserAuthenticated(t *testing.T) {
 		ResourceURL: "https://api.example.com",
 	}, nil)
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := httptest.NewRequest("GET", "/auth", nil)
 	rec := httptest.NewRecorder()
@@ -441,6 +504,93 @@ func TestAuthenticateHandler_UserAuthenticated(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "true")
 }
 
+func TestAuthenticateHandler_StartsDeviceFlow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	mockProxy.EXPECT().CheckAuthentication().Return(nil, errors.New("no credentials available"))
+	mockProxy.EXPECT().StartDeviceFlow(gomock.Any()).Return(&entities.DeviceFlowStatus{
+		State:                   entities.DeviceFlowPending,
+		UserCode:                "ABCD-EFGH",
+		VerificationURI:         "https://chat.qwen.ai/authorize",
+		VerificationURIComplete: "https://chat.qwen.ai/authorize?user_code=ABCD-EFGH",
+	}, nil)
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	req := httptest.NewRequest("GET", "/auth", nil)
+	rec := httptest.NewRecorder()
+
+	controller.AuthenticateHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, false, response["authenticated"])
+	assert.Equal(t, "ABCD-EFGH", response["user_code"])
+	assert.Equal(t, "https://chat.qwen.ai/authorize?user_code=ABCD-EFGH", response["verification_url"])
+	assert.Equal(t, entities.DeviceFlowPending, response["device_flow"].(map[string]interface{})["state"])
+}
+
+func TestAuthenticateHandler_DeviceFlowError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	mockProxy.EXPECT().CheckAuthentication().Return(nil, errors.New("no credentials available"))
+	mockProxy.EXPECT().StartDeviceFlow(gomock.Any()).Return(nil, errors.New("device authentication failed"))
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	req := httptest.NewRequest("GET", "/auth", nil)
+	rec := httptest.NewRecorder()
+
+	controller.AuthenticateHandler(rec, req)
+
+	assert.Equal(t, 500, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrMsgAuthFailed)
+}
+
+func TestAuthStatusHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	mockProxy.EXPECT().CheckAuthentication().Return(nil, errors.New("no credentials available"))
+	mockProxy.EXPECT().AuthState().Return(entities.AuthStateAuthenticating)
+	mockProxy.EXPECT().DeviceFlowStatus().Return(&entities.DeviceFlowStatus{
+		State:    entities.DeviceFlowPending,
+		UserCode: "ABCD-EFGH",
+		Polls:    4,
+	})
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	req := httptest.NewRequest("GET", "/auth/status", nil)
+	rec := httptest.NewRecorder()
+
+	controller.AuthStatusHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	var response struct {
+		Authenticated bool                      `json:"authenticated"`
+		State         string                    `json:"state"`
+		DeviceFlow    entities.DeviceFlowStatus `json:"device_flow"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.False(t, response.Authenticated)
+	assert.Equal(t, entities.AuthStateAuthenticating, response.State)
+	assert.Equal(t, entities.DeviceFlowPending, response.DeviceFlow.State)
+	assert.Equal(t, 4, response.DeviceFlow.Polls)
+}
+
 func TestOpenAIModelsHandler(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -457,9 +607,9 @@ func TestOpenAIModelsHandler(t *testing.T) {
 			OwnedBy: "test",
 		},
 	}
-	mockProxy.EXPECT().GetModels().Return(expectedModels, nil)
+	mockProxy.EXPECT().GetModels(gomock.Any()).Return(expectedModels, nil)
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := httptest.NewRequest("GET", "/models", nil)
 	rec := httptest.NewRecorder()
@@ -479,9 +629,9 @@ func TestOpenAIModelsHandler_Error(t *testing.T) {
 	logger := logging.NewLogger("info")
 
 	// Mock the GetModels to return an error
-	mockProxy.EXPECT().GetModels().Return(nil, assert.AnError)
+	mockProxy.EXPECT().GetModels(gomock.Any()).Return(nil, assert.AnError)
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	req := httptest.NewRequest("GET", "/models", nil)
 	rec := httptest.NewRecorder()
@@ -493,6 +643,235 @@ func TestOpenAIModelsHandler_Error(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), ErrMsgInternalError)
 }
 
+func TestOpenAIModelHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	contextWindow := 131072
+	mockProxy.EXPECT().GetModel(gomock.Any(), "qwen/coder").Return(&entities.ModelInfo{
+		ID:            "qwen3-coder-plus",
+		Object:        "model",
+		OwnedBy:       "qwen",
+		Aliases:       []string{"qwen/coder"},
+		ContextWindow: contextWindow,
+	}, nil)
+	mockProxy.EXPECT().GetModel(gomock.Any(), "gpt-4").Return(nil, &models.ModelNotFoundError{Model: "gpt-4"})
+
+	router := chi.NewRouter()
+	router.Get("/v1/models/*", controller.OpenAIModelHandler)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/models/qwen/coder", nil))
+	assert.Equal(t, 200, rec.Code)
+	var model map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &model))
+	assert.Equal(t, "qwen3-coder-plus", model["id"])
+	assert.Equal(t, float64(contextWindow), model["context_window"])
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/models/gpt-4", nil))
+	assert.Equal(t, 404, rec.Code)
+	var errorResponse struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Param   string `json:"param"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, ErrCodeModelNotFound, errorResponse.Error.Code)
+	assert.Equal(t, ErrorTypeInvalidRequest, errorResponse.Error.Type)
+	assert.Equal(t, "model", errorResponse.Error.Param)
+	assert.Contains(t, errorResponse.Error.Message, "gpt-4")
+}
+
+func TestChatCompletionsHandler_ModelNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	notFound := &models.ModelNotFoundError{Model: "gpt-4"}
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, notFound)
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).Return(notFound)
+
+	for _, body := range []string{
+		`{"model": "gpt-4", "messages": [{"role": "user", "content": "Hi"}]}`,
+		`{"model": "gpt-4", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`,
+	} {
+		rec := httptest.NewRecorder()
+		controller.ChatCompletionsHandler(rec, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+
+		assert.Equal(t, 404, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), ErrCodeModelNotFound)
+	}
+}
+
+func TestChatCompletionsHandler_ContextLengthExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	tooLong := &proxy.ContextLengthExceededError{Model: "qwen3-coder-plus", ContextWindow: 100, PromptTokens: 90, CompletionTokens: 20}
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, tooLong)
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).Return(tooLong)
+
+	for _, body := range []string{
+		`{"model": "qwen3-coder-plus", "messages": [{"role": "user", "content": "Hi"}]}`,
+		`{"model": "qwen3-coder-plus", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`,
+	} {
+		rec := httptest.NewRecorder()
+		controller.ChatCompletionsHandler(rec, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+
+		assert.Equal(t, 400, rec.Code)
+		var errorResponse struct {
+			Error struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+				Param   string `json:"param"`
+				Code    string `json:"code"`
+			} `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+		assert.Equal(t, ErrCodeContextLengthExceeded, errorResponse.Error.Code)
+		assert.Equal(t, ErrorTypeInvalidRequest, errorResponse.Error.Type)
+		assert.Equal(t, "messages", errorResponse.Error.Param)
+		assert.Contains(t, errorResponse.Error.Message, "maximum context length is 100 tokens")
+		assert.Contains(t, errorResponse.Error.Message, "(90 in the messages, 20 in the completion)")
+	}
+}
+
+func TestChatCompletionsHandler_AuthenticationRequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	authRequired := fmt.Errorf("authentication failed: %w", &auth.AuthenticationRequiredError{
+		State: entities.AuthStateRefreshFailed,
+		Err:   errors.New("invalid_grant"),
+	})
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, authRequired)
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).Return(authRequired)
+
+	for _, body := range []string{
+		`{"model": "qwen3-coder-plus", "messages": [{"role": "user", "content": "Hi"}]}`,
+		`{"model": "qwen3-coder-plus", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`,
+	} {
+		rec := httptest.NewRecorder()
+		controller.ChatCompletionsHandler(rec, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+
+		assert.Equal(t, 401, rec.Code)
+		var errorResponse struct {
+			Error struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+				Code    string `json:"code"`
+			} `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+		assert.Equal(t, ErrorTypeAuthentication, errorResponse.Error.Type)
+		assert.Equal(t, entities.AuthStateRefreshFailed, errorResponse.Error.Code)
+		assert.Contains(t, errorResponse.Error.Message, "invalid_grant")
+		assert.Contains(t, errorResponse.Error.Message, "GET /auth")
+	}
+}
+
+func TestChatCompletionsHandler_UpstreamError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            *proxy.UpstreamError
+		wantStatus     int
+		wantType       string
+		wantMessage    string
+		wantRetryAfter string
+	}{
+		{
+			name:           "rate limited",
+			err:            &proxy.UpstreamError{StatusCode: 429, Message: `{"error":{"message":"Rate limit exceeded"}}`, RetryAfter: 1500 * time.Millisecond},
+			wantStatus:     429,
+			wantType:       ErrorTypeRateLimit,
+			wantMessage:    "Rate limit exceeded",
+			wantRetryAfter: "2",
+		},
+		{
+			name:        "bad request",
+			err:         &proxy.UpstreamError{StatusCode: 400, Message: "invalid tools"},
+			wantStatus:  400,
+			wantType:    ErrorTypeInvalidRequest,
+			wantMessage: "invalid tools",
+		},
+		{
+			name:        "server error",
+			err:         &proxy.UpstreamError{StatusCode: 503, Message: ""},
+			wantStatus:  503,
+			wantType:    ErrorTypeUpstream,
+			wantMessage: "Service Unavailable",
+		},
+		{
+			name:        "quota exceeded",
+			err:         &proxy.UpstreamError{StatusCode: 403, Message: `{"error":"Free allocated quota exceeded."}`},
+			wantStatus:  429,
+			wantType:    ErrorTypeRateLimit,
+			wantMessage: "Free allocated quota exceeded.",
+		},
+		{
+			name:        "proxy credentials rejected",
+			err:         &proxy.UpstreamError{StatusCode: 401, Message: `{"error":{"message":"invalid access token"}}`},
+			wantStatus:  502,
+			wantType:    ErrorTypeUpstream,
+			wantMessage: "invalid access token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+			logger := logging.NewLogger("info")
+
+			controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+			mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("chat completion failed: %w", tt.err))
+
+			body := `{"model": "qwen3-coder-plus", "messages": [{"role": "user", "content": "Hi"}]}`
+			rec := httptest.NewRecorder()
+			controller.ChatCompletionsHandler(rec, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantRetryAfter, rec.Header().Get("Retry-After"))
+			var errorResponse struct {
+				Error struct {
+					Message string `json:"message"`
+					Type    string `json:"type"`
+				} `json:"error"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+			assert.Equal(t, tt.wantType, errorResponse.Error.Type)
+			assert.Equal(t, tt.wantMessage, errorResponse.Error.Message)
+		})
+	}
+}
+
 func TestOpenAICompletionsHandler(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -500,7 +879,7 @@ func TestOpenAICompletionsHandler(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Valid request body
 	jsonBody := `{
@@ -531,7 +910,7 @@ func TestOpenAICompletionsHandler(t *testing.T) {
 		},
 	}
 
-	mockProxy.EXPECT().ChatCompletions(gomock.Any()).Return(expectedResponse, nil)
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(expectedResponse, nil)
 
 	req := httptest.NewRequest("POST", "/completions", strings.NewReader(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
@@ -550,7 +929,7 @@ func TestOpenAICompletionsHandler_InvalidJSON(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Invalid JSON request body
 	invalidJSON := `{"invalid": json}`
@@ -573,7 +952,7 @@ func TestOpenAICompletionsHandler_MissingPrompt(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Valid JSON but missing prompt
 	jsonBody := `{"model": "test-model"}`
@@ -596,7 +975,7 @@ func TestHandleNonStreamingCompletion(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Create a chat completion request
 	chatReq := &entities.ChatCompletionRequest{
@@ -633,7 +1012,7 @@ func TestHandleNonStreamingCompletion(t *testing.T) {
 		},
 	}
 
-	mockProxy.EXPECT().ChatCompletions(chatReq).Return(expectedResponse, nil)
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), chatReq).Return(expectedResponse, nil)
 
 	req := httptest.NewRequest("POST", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -651,7 +1030,7 @@ func TestHandleNonStreamingCompletion_Error(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Create a chat completion request
 	chatReq := &entities.ChatCompletionRequest{
@@ -666,7 +1045,7 @@ func TestHandleNonStreamingCompletion_Error(t *testing.T) {
 	}
 
 	// Mock the ChatCompletions call to return an error
-	mockProxy.EXPECT().ChatCompletions(chatReq).Return(nil, assert.AnError)
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), chatReq).Return(nil, assert.AnError)
 
 	req := httptest.NewRequest("POST", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -685,7 +1064,7 @@ func TestHandleNonStreamingCompletion_EmptyChoices(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Create a chat completion request
 	chatReq := &entities.ChatCompletionRequest{
@@ -713,7 +1092,7 @@ func TestHandleNonStreamingCompletion_EmptyChoices(t *testing.T) {
 		},
 	}
 
-	mockProxy.EXPECT().ChatCompletions(chatReq).Return(expectedResponse, nil)
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), chatReq).Return(expectedResponse, nil)
 
 	req := httptest.NewRequest("POST", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -733,7 +1112,7 @@ func TestChatCompletionsHandler(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Valid request body
 	jsonBody := `{
@@ -770,7 +1149,7 @@ func TestChatCompletionsHandler(t *testing.T) {
 		},
 	}
 
-	mockProxy.EXPECT().ChatCompletions(gomock.Any()).Return(expectedResponse, nil)
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(expectedResponse, nil)
 
 	req := httptest.NewRequest("POST", "/chat/completions", strings.NewReader(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
@@ -789,7 +1168,7 @@ func TestChatCompletionsHandler_InvalidJSON(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Invalid JSON request body
 	invalidJSON := `{"invalid": json}`
@@ -812,7 +1191,7 @@ func TestHandleNonStreamingChatCompletion(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Create a chat completion request
 	req := &entities.ChatCompletionRequest{
@@ -849,7 +1228,7 @@ func TestHandleNonStreamingChatCompletion(t *testing.T) {
 		},
 	}
 
-	mockProxy.EXPECT().ChatCompletions(req).Return(expectedResponse, nil)
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), req).Return(expectedResponse, nil)
 
 	httpReq := httptest.NewRequest("POST", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -867,7 +1246,7 @@ func TestHandleNonStreamingChatCompletion_Error(t *testing.T) {
 	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
 	logger := logging.NewLogger("info")
 
-	controller := NewAPIController(mockProxy, &logging.Logger{Logger: logger})
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
 
 	// Create a chat completion request
 	req := &entities.ChatCompletionRequest{
@@ -882,7 +1261,7 @@ func TestHandleNonStreamingChatCompletion_Error(t *testing.T) {
 	}
 
 	// Mock the ChatCompletions call to return an error
-	mockProxy.EXPECT().ChatCompletions(req).Return(nil, assert.AnError)
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), req).Return(nil, assert.AnError)
 
 	httpReq := httptest.NewRequest("POST", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tracing"
+)
+
+const (
+	// ErrMsgMissingModel Error messages
+	ErrMsgMissingModel          = "model is required"
+	ErrMsgInvalidEmbeddingInput = "input must be a non-empty string, an array of strings, an array of tokens or an array of token arrays"
+	ErrMsgInvalidEncodingFormat = "encoding_format must be one of: float, base64, got: %s"
+	ErrMsgInvalidDimensions     = "dimensions must be a positive integer"
+)
+
+// EmbeddingsHandler handles OpenAI embeddings requests
+func (ctrl *APIController) EmbeddingsHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "APIController.EmbeddingsHandler")
+	defer span.End()
+	ctrl.logger.Debug("Embeddings request received")
+
+	var req entities.EmbeddingRequest
+	if !ctrl.validateJSONRequest(w, r, &req) {
+		return
+	}
+	switch {
+	case req.Model == "":
+		ctrl.sendValidationError(w, r, ErrMsgMissingModel)
+		return
+	case req.Input == nil:
+		ctrl.sendValidationError(w, r, ErrMsgMissingInput)
+		return
+	case !validEmbeddingInput(req.Input):
+		ctrl.sendValidationError(w, r, ErrMsgInvalidEmbeddingInput)
+		return
+	case req.EncodingFormat != "" && req.EncodingFormat != entities.EncodingFormatFloat && req.EncodingFormat != entities.EncodingFormatBase64:
+		ctrl.sendValidationError(w, r, fmt.Sprintf(ErrMsgInvalidEncodingFormat, req.EncodingFormat))
+		return
+	case req.Dimensions < 0:
+		ctrl.sendValidationError(w, r, ErrMsgInvalidDimensions)
+		return
+	}
+	span.SetAttributes(tracing.AttrModel.String(req.Model))
+
+	response, err := ctrl.proxyUseCase.Embeddings(r.Context(), &req)
+	if err != nil {
+		ctrl.sendInternalError(w, r, err)
+		return
+	}
+
+	ctrl.logger.Info("Embeddings response sent", "model", response.Model, "embeddings", len(response.Data), "usage", response.Usage)
+	middleware.RecordUsage(r.Context(), response.Usage)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// validEmbeddingInput reports whether an embeddings input is a non-empty string, or a non-empty
+// array of non-empty strings, of token IDs or of non-empty token ID arrays
+func validEmbeddingInput(input any) bool {
+	switch input := input.(type) {
+	case string:
+		return input != ""
+	case []any:
+		if len(input) == 0 {
+			return false
+		}
+		for _, item := range input {
+			switch item := item.(type) {
+			case string:
+				if item == "" {
+					return false
+				}
+			case float64:
+			case []any:
+				if len(item) == 0 {
+					return false
+				}
+				for _, token := range item {
+					if _, ok := token.(float64); !ok {
+						return false
+					}
+				}
+			default:
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
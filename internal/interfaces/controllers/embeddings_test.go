@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/proxy"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEmbeddingsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	mockProxy.EXPECT().Embeddings(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *entities.EmbeddingRequest) (*entities.EmbeddingResponse, error) {
+			assert.Equal(t, "text-embedding-v3", req.Model)
+			assert.Equal(t, []any{"a", "b"}, req.Input)
+			assert.Equal(t, entities.EncodingFormatBase64, req.EncodingFormat)
+			assert.Equal(t, 256, req.Dimensions)
+			return &entities.EmbeddingResponse{
+				Object: "list",
+				Model:  "text-embedding-v3",
+				Data:   []entities.Embedding{{Object: "embedding", Index: 0, Embedding: "AAAAPw=="}},
+				Usage:  &entities.Usage{PromptTokens: 2, TotalTokens: 2},
+			}, nil
+		})
+
+	rec := httptest.NewRecorder()
+	body := `{"model": "text-embedding-v3", "input": ["a", "b"], "encoding_format": "base64", "dimensions": 256}`
+	controller.EmbeddingsHandler(rec, httptest.NewRequest("POST", "/v1/embeddings", strings.NewReader(body)))
+
+	assert.Equal(t, 200, rec.Code)
+	var response entities.EmbeddingResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "list", response.Object)
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "AAAAPw==", response.Data[0].Embedding)
+	assert.Equal(t, 2, response.Usage.TotalTokens)
+}
+
+func TestEmbeddingsHandler_InvalidRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	controller := NewAPIController(mocks.NewMockProxyUseCaseInterface(ctrl), tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	for name, test := range map[string]struct {
+		body    string
+		message string
+	}{
+		"missing model":   {`{"input": "Hello"}`, ErrMsgMissingModel},
+		"missing input":   {`{"model": "text-embedding-v3"}`, ErrMsgMissingInput},
+		"empty string":    {`{"model": "text-embedding-v3", "input": ""}`, ErrMsgInvalidEmbeddingInput},
+		"empty array":     {`{"model": "text-embedding-v3", "input": []}`, ErrMsgInvalidEmbeddingInput},
+		"mixed array":     {`{"model": "text-embedding-v3", "input": ["a", {"text": "b"}]}`, ErrMsgInvalidEmbeddingInput},
+		"bad token array": {`{"model": "text-embedding-v3", "input": [[1, "b"]]}`, ErrMsgInvalidEmbeddingInput},
+		"encoding format": {`{"model": "text-embedding-v3", "input": "a", "encoding_format": "int8"}`, "encoding_format must be one of"},
+		"dimensions":      {`{"model": "text-embedding-v3", "input": "a", "dimensions": -1}`, ErrMsgInvalidDimensions},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			controller.EmbeddingsHandler(rec, httptest.NewRequest("POST", "/v1/embeddings", strings.NewReader(test.body)))
+
+			assert.Equal(t, 400, rec.Code)
+			assert.Contains(t, rec.Body.String(), test.message)
+		})
+	}
+}
+
+func TestEmbeddingsHandler_UpstreamError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewAPIController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	mockProxy.EXPECT().Embeddings(gomock.Any(), gomock.Any()).Return(nil, &proxy.UpstreamError{StatusCode: 400, Message: "bad"})
+
+	rec := httptest.NewRecorder()
+	controller.EmbeddingsHandler(rec, httptest.NewRequest("POST", "/v1/embeddings", strings.NewReader(`{"model": "m", "input": [1, 2, 3]}`)))
+
+	assert.Equal(t, 400, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrorTypeInvalidRequest)
+	assert.Contains(t, rec.Body.String(), "bad")
+}
+
+func TestValidEmbeddingInput(t *testing.T) {
+	assert.True(t, validEmbeddingInput("Hello"))
+	assert.True(t, validEmbeddingInput([]any{"a", "b"}))
+	assert.True(t, validEmbeddingInput([]any{float64(1), float64(2)}))
+	assert.True(t, validEmbeddingInput([]any{[]any{float64(1)}, []any{float64(2)}}))
+	assert.False(t, validEmbeddingInput(nil))
+	assert.False(t, validEmbeddingInput(float64(1)))
+	assert.False(t, validEmbeddingInput([]any{[]any{}}))
+}
@@ -0,0 +1,688 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tracing"
+	"qwen-go-proxy/internal/usecases/proxy"
+	"qwen-go-proxy/internal/usecases/streaming"
+)
+
+// Constants for the OpenAI Responses API
+const (
+	// ObjectResponse Responses API object types
+	ObjectResponse = "response"
+
+	// ResponseStatusInProgress Responses API statuses
+	ResponseStatusInProgress = "in_progress"
+	ResponseStatusCompleted  = "completed"
+	ResponseStatusIncomplete = "incomplete"
+	ResponseStatusFailed     = "failed"
+
+	// ResponseItemMessage Responses API item and content types
+	ResponseItemMessage            = "message"
+	ResponseItemFunctionCall       = "function_call"
+	ResponseItemFunctionCallOutput = "function_call_output"
+	ResponseItemReasoning          = "reasoning"
+	ResponsePartInputText          = "input_text"
+	ResponsePartInputImage         = "input_image"
+	ResponsePartOutputText         = "output_text"
+
+	// ErrMsgMissingInput Error messages
+	ErrMsgMissingInput = "input is required"
+)
+
+// ResponsesController handles OpenAI Responses API requests.
+// Responses are stored locally so that clients can chain requests with previous_response_id.
+type ResponsesController struct {
+	*APIController
+	responseRepo interfaces.ResponseRepository
+}
+
+// NewResponsesController creates a new Responses API controller
+func NewResponsesController(proxyUseCase proxy.ProxyUseCaseInterface, responseRepo interfaces.ResponseRepository, tokenizer interfaces.Tokenizer, logger logging.LoggerInterface) *ResponsesController {
+	if responseRepo == nil {
+		panic("responseRepo cannot be nil")
+	}
+	return &ResponsesController{
+		APIController: NewAPIController(proxyUseCase, tokenizer, logger),
+		responseRepo:  responseRepo,
+	}
+}
+
+// ResponsesHandler handles Responses API requests by translating them onto chat completions
+func (ctrl *ResponsesController) ResponsesHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "ResponsesController.ResponsesHandler")
+	defer span.End()
+	ctrl.logger.Debug("Responses request received")
+
+	var req entities.ResponsesRequest
+	if !ctrl.validateJSONRequest(w, r, &req) {
+		return
+	}
+	if req.Input == nil {
+		ctrl.sendValidationError(w, r, ErrMsgMissingInput)
+		return
+	}
+
+	var history []entities.ChatMessage
+	if req.PreviousResponseID != "" {
+		previous, err := ctrl.responseRepo.Load(req.PreviousResponseID)
+		if err != nil {
+			ctrl.logger.Warn("Previous response lookup failed", "request_id", middleware.GetRequestID(r.Context()), "previous_response_id", req.PreviousResponseID, "error", err)
+			ctrl.sendValidationError(w, r, fmt.Sprintf("Previous response with id '%s' not found.", req.PreviousResponseID))
+			return
+		}
+		history = previous.Messages
+	}
+
+	input, err := convertResponsesInput(req.Input)
+	if err != nil {
+		ctrl.sendValidationError(w, r, err.Error())
+		return
+	}
+
+	conversation := make([]entities.ChatMessage, 0, len(history)+len(input))
+	conversation = append(conversation, history...)
+	conversation = append(conversation, input...)
+
+	chatReq, err := buildChatRequestFromResponses(&req, conversation)
+	if err != nil {
+		ctrl.sendValidationError(w, r, err.Error())
+		return
+	}
+
+	responseID := newItemID("resp_")
+	ctrl.logger.Info("Processing responses request", "response_id", responseID, "model", req.Model, "stream", req.Stream, "messages", len(chatReq.Messages))
+
+	if req.Stream {
+		ctrl.streamResponses(w, r, &req, chatReq, responseID, conversation)
+		return
+	}
+
+	response, err := ctrl.proxyUseCase.ChatCompletions(r.Context(), chatReq)
+	if err != nil {
+		ctrl.sendInternalError(w, r, err)
+		return
+	}
+	if len(response.Choices) == 0 {
+		ctrl.sendErrorResponse(w, r, StatusInternalServerError, ErrorTypeInternal, ErrMsgUnexpectedFormat)
+		return
+	}
+
+	result := buildResponseObject(responseID, &req, response)
+	ctrl.storeResponse(&req, result, conversation)
+	ctrl.logger.Info("Responses response sent", "response_id", responseID, "usage", response.Usage)
+	middleware.RecordUsage(r.Context(), response.Usage)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetResponseHandler returns a previously stored response
+func (ctrl *ResponsesController) GetResponseHandler(w http.ResponseWriter, r *http.Request) {
+	responseID := chi.URLParam(r, "response_id")
+	ctrl.logger.Debug("Stored response requested", "response_id", responseID)
+
+	stored, err := ctrl.responseRepo.Load(responseID)
+	if err != nil {
+		ctrl.sendErrorResponse(w, r, http.StatusNotFound, ErrorTypeInvalidRequest, fmt.Sprintf("Response with id '%s' not found.", responseID))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(stored.Response)
+}
+
+// streamResponses streams a chat completion back to the client as Responses API events
+func (ctrl *ResponsesController) streamResponses(w http.ResponseWriter, r *http.Request, req *entities.ResponsesRequest, chatReq *entities.ChatCompletionRequest, responseID string, conversation []entities.ChatMessage) {
+	ctrl.logger.Debug("Streaming responses initiated", "response_id", responseID, "model", chatReq.Model)
+
+	streamWriter := newResponsesStreamWriter(w, ctrl.logger, newResponseSkeleton(responseID, req, chatReq.Model))
+	streamWriter.onComplete = func(result *entities.ResponseObject) {
+		ctrl.storeResponse(req, result, conversation)
+	}
+
+	err := ctrl.proxyUseCase.StreamChatCompletions(r.Context(), chatReq, streamWriter)
+	middleware.RecordUsage(r.Context(), streamWriter.usage)
+	tracing.SetUsage(r.Context(), streamWriter.usage)
+	if err != nil {
+		if !streamWriter.wroteHeader {
+			ctrl.sendInternalError(w, r, err)
+			return
+		}
+		if r.Context().Err() != nil {
+			ctrl.logger.Info("Client disconnected during streaming", "request_id", middleware.GetRequestID(r.Context()), "response_id", responseID)
+			return
+		}
+		ctrl.logger.Error("Streaming responses failed", "response_id", responseID, "error", err)
+		streamWriter.fail(ErrMsgInternalError)
+		return
+	}
+
+	streamWriter.finish()
+	ctrl.logger.Debug("Streaming responses completed successfully", "response_id", responseID)
+}
+
+// storeResponse persists a completed response unless the client opted out with store=false
+func (ctrl *ResponsesController) storeResponse(req *entities.ResponsesRequest, result *entities.ResponseObject, conversation []entities.ChatMessage) {
+	if req.Store != nil && !*req.Store {
+		return
+	}
+
+	messages := make([]entities.ChatMessage, 0, len(conversation)+1)
+	messages = append(messages, conversation...)
+	messages = append(messages, assistantMessageFromOutput(result.Output))
+
+	stored := &entities.StoredResponse{
+		ID:                 result.ID,
+		Model:              result.Model,
+		PreviousResponseID: req.PreviousResponseID,
+		Messages:           messages,
+		Response:           *result,
+		CreatedAt:          time.Now(),
+	}
+	if err := ctrl.responseRepo.Save(stored); err != nil {
+		ctrl.logger.Warn("Failed to store response", "response_id", result.ID, "error", err)
+	}
+}
+
+// buildChatRequestFromResponses converts a Responses request and its resolved conversation to chat completion format
+func buildChatRequestFromResponses(req *entities.ResponsesRequest, conversation []entities.ChatMessage) (*entities.ChatCompletionRequest, error) {
+	chatReq := &entities.ChatCompletionRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxOutputTokens,
+		Stream:      req.Stream,
+		User:        req.User,
+		Temperature: req.Temperature,
+	}
+	if req.TopP != nil {
+		chatReq.TopP = *req.TopP
+	}
+	if req.Stream {
+		// Usage is needed for the final response.completed event
+		chatReq.StreamOptions = &entities.StreamOptions{IncludeUsage: true}
+	}
+
+	// Instructions are not carried over from previous responses, so they always come first
+	if req.Instructions != "" {
+		chatReq.Messages = append(chatReq.Messages, entities.ChatMessage{Role: "system", Content: req.Instructions})
+	}
+	chatReq.Messages = append(chatReq.Messages, conversation...)
+
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			return nil, fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+		chatReq.Tools = append(chatReq.Tools, entities.Tool{
+			Type: "function",
+			Function: entities.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	switch choice := req.ToolChoice.(type) {
+	case string:
+		chatReq.ToolChoice = choice
+	case map[string]interface{}:
+		if name, ok := choice["name"].(string); ok {
+			chatReq.ToolChoice = entities.ToolChoice{Type: "function", Function: entities.Function{Name: name}}
+		}
+	}
+
+	return chatReq, nil
+}
+
+// convertResponsesInput converts Responses API input (string or item list) into chat messages
+func convertResponsesInput(input any) ([]entities.ChatMessage, error) {
+	if text, ok := input.(string); ok {
+		return []entities.ChatMessage{{Role: "user", Content: text}}, nil
+	}
+
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	var items []entities.ResponseInputItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("input must be a string or an array of input items")
+	}
+
+	var messages []entities.ChatMessage
+	for i, item := range items {
+		switch item.Type {
+		case ResponseItemFunctionCall:
+			toolCall := entities.ToolCall{
+				ID:       item.CallID,
+				Type:     "function",
+				Function: entities.Function{Name: item.Name, Arguments: item.Arguments},
+			}
+			// Consecutive function calls belong to the same assistant turn
+			if last := len(messages) - 1; last >= 0 && messages[last].Role == "assistant" {
+				messages[last].ToolCalls = append(messages[last].ToolCalls, toolCall)
+				continue
+			}
+			messages = append(messages, entities.ChatMessage{Role: "assistant", ToolCalls: []entities.ToolCall{toolCall}})
+		case ResponseItemFunctionCallOutput:
+			messages = append(messages, entities.ChatMessage{Role: "tool", Content: item.Output, ToolCallID: item.CallID})
+		case ResponseItemReasoning:
+			// Reasoning items are not forwarded upstream
+		case "", ResponseItemMessage:
+			message, err := convertResponsesMessage(item)
+			if err != nil {
+				return nil, fmt.Errorf("input.%d: %w", i, err)
+			}
+			messages = append(messages, message)
+		default:
+			return nil, fmt.Errorf("input.%d: unsupported input item type: %s", i, item.Type)
+		}
+	}
+	return messages, nil
+}
+
+// convertResponsesMessage converts a Responses API message item into a chat message
+func convertResponsesMessage(item entities.ResponseInputItem) (entities.ChatMessage, error) {
+	role := item.Role
+	switch role {
+	case "developer":
+		role = "system"
+	case "user", "assistant", "system":
+	default:
+		return entities.ChatMessage{}, fmt.Errorf("unsupported role: %s", item.Role)
+	}
+
+	if text, ok := item.Content.(string); ok {
+		return entities.ChatMessage{Role: role, Content: text}, nil
+	}
+
+	raw, err := json.Marshal(item.Content)
+	if err != nil {
+		return entities.ChatMessage{}, fmt.Errorf("invalid content: %w", err)
+	}
+	var parts []entities.ResponseContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return entities.ChatMessage{}, fmt.Errorf("content must be a string or an array of content parts")
+	}
+
+	var blocks []entities.ContentBlock
+	hasImage := false
+	for _, part := range parts {
+		switch part.Type {
+		case ResponsePartInputText, ResponsePartOutputText:
+			blocks = append(blocks, entities.ContentBlock{Type: ContentTypeText, Text: part.Text})
+		case ResponsePartInputImage:
+			blocks = append(blocks, entities.ContentBlock{Type: "image_url", ImageURL: &entities.ImageURL{URL: part.ImageURL}})
+			hasImage = true
+		default:
+			return entities.ChatMessage{}, fmt.Errorf("unsupported content type: %s", part.Type)
+		}
+	}
+	if hasImage {
+		return entities.ChatMessage{Role: role, Content: blocks}, nil
+	}
+	return entities.ChatMessage{Role: role, Content: joinContentText(blocks)}, nil
+}
+
+// newResponseSkeleton creates an in-progress response object for a request
+func newResponseSkeleton(responseID string, req *entities.ResponsesRequest, model string) *entities.ResponseObject {
+	return &entities.ResponseObject{
+		ID:                 responseID,
+		Object:             ObjectResponse,
+		CreatedAt:          time.Now().Unix(),
+		Status:             ResponseStatusInProgress,
+		Model:              model,
+		Output:             []entities.ResponseOutputItem{},
+		Instructions:       req.Instructions,
+		PreviousResponseID: req.PreviousResponseID,
+		Metadata:           req.Metadata,
+	}
+}
+
+// buildResponseObject converts a chat completion response to a Responses API response
+func buildResponseObject(responseID string, req *entities.ResponsesRequest, response *entities.ChatCompletionResponse) *entities.ResponseObject {
+	result := newResponseSkeleton(responseID, req, response.Model)
+	choice := response.Choices[0]
+
+	if text := extractTextContent(choice.Message.Content); text != "" {
+		result.Output = append(result.Output, newMessageItem(text, ResponseStatusCompleted))
+		result.OutputText = text
+	}
+	for _, toolCall := range choice.Message.ToolCalls {
+		result.Output = append(result.Output, entities.ResponseOutputItem{
+			Type:      ResponseItemFunctionCall,
+			ID:        newItemID("fc_"),
+			Status:    ResponseStatusCompleted,
+			CallID:    toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
+	}
+
+	result.Status = responseStatus(choice.FinishReason)
+	result.Usage = responseUsage(response.Usage)
+	return result
+}
+
+// newMessageItem creates an assistant message output item with a single output_text part
+func newMessageItem(text, status string) entities.ResponseOutputItem {
+	return entities.ResponseOutputItem{
+		Type:    ResponseItemMessage,
+		ID:      newItemID("msg_"),
+		Status:  status,
+		Role:    "assistant",
+		Content: []entities.ResponseContentPart{{Type: ResponsePartOutputText, Text: text, Annotations: []any{}}},
+	}
+}
+
+// responseStatus maps an OpenAI finish_reason onto a Responses API status
+func responseStatus(finishReason string) string {
+	if finishReason == "length" {
+		return ResponseStatusIncomplete
+	}
+	return ResponseStatusCompleted
+}
+
+// responseUsage converts chat completion usage to Responses API usage
+func responseUsage(usage *entities.Usage) *entities.ResponseUsage {
+	if usage == nil {
+		return nil
+	}
+	return &entities.ResponseUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+}
+
+// assistantMessageFromOutput rebuilds the assistant chat message represented by response output items
+func assistantMessageFromOutput(output []entities.ResponseOutputItem) entities.ChatMessage {
+	message := entities.ChatMessage{Role: "assistant"}
+	var text strings.Builder
+	for _, item := range output {
+		switch item.Type {
+		case ResponseItemMessage:
+			for _, part := range item.Content {
+				text.WriteString(part.Text)
+			}
+		case ResponseItemFunctionCall:
+			message.ToolCalls = append(message.ToolCalls, entities.ToolCall{
+				ID:       item.CallID,
+				Type:     "function",
+				Function: entities.Function{Name: item.Name, Arguments: item.Arguments},
+			})
+		}
+	}
+	if text.Len() > 0 || len(message.ToolCalls) == 0 {
+		message.Content = text.String()
+	}
+	return message
+}
+
+// newItemID generates a random identifier with the given prefix
+func newItemID(prefix string) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		// Fallback to timestamp-based ID if crypto rand fails
+		return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + hex.EncodeToString(b)
+}
+
+// streamOutputItem tracks an output item while it is being streamed
+type streamOutputItem struct {
+	item entities.ResponseOutputItem
+	data strings.Builder // text for messages, arguments for function calls
+	done bool
+}
+
+// responsesStreamWriter re-emits the OpenAI SSE stream written by the proxy use case
+// to the client as Responses API stream events.
+type responsesStreamWriter struct {
+	*chunkStreamWriter
+
+	response   *entities.ResponseObject
+	onComplete func(result *entities.ResponseObject)
+
+	sequence int
+	started  bool
+	finished bool
+
+	items       []*streamOutputItem
+	textItem    *streamOutputItem
+	toolItems   map[int]*streamOutputItem
+	toolIndexer streaming.ToolCallIndexer
+
+	finishReason string
+	usage        *entities.Usage
+}
+
+// newResponsesStreamWriter creates a new Responses stream writer around the client response writer
+func newResponsesStreamWriter(w http.ResponseWriter, logger logging.LoggerInterface, response *entities.ResponseObject) *responsesStreamWriter {
+	sw := &responsesStreamWriter{
+		response:  response,
+		toolItems: make(map[int]*streamOutputItem),
+	}
+	sw.chunkStreamWriter = newChunkStreamWriter(w, logger, sw.handleChunk, sw.finish)
+	return sw
+}
+
+// handleChunk translates a single chat completion chunk into Responses API events
+func (sw *responsesStreamWriter) handleChunk(chunk *entities.ChatCompletionResponse) {
+	if chunk.Model != "" {
+		sw.response.Model = chunk.Model
+	}
+	sw.start()
+	if chunk.Usage != nil {
+		sw.usage = chunk.Usage
+	}
+	if len(chunk.Choices) == 0 {
+		return
+	}
+
+	choice := chunk.Choices[0]
+	if text := extractTextContent(choice.Delta.Content); text != "" {
+		if sw.textItem == nil {
+			sw.openTextItem()
+		}
+		sw.textItem.data.WriteString(text)
+		sw.send("response.output_text.delta", map[string]interface{}{
+			"item_id":       sw.textItem.item.ID,
+			"output_index":  sw.outputIndex(sw.textItem),
+			"content_index": 0,
+			"delta":         text,
+		})
+	}
+
+	for _, toolCall := range choice.Delta.ToolCalls {
+		toolIndex := sw.toolIndexer.Index(toolCall)
+		item, known := sw.toolItems[toolIndex]
+		if !known {
+			sw.closeTextItem()
+			item = sw.addItem(entities.ResponseOutputItem{
+				Type:   ResponseItemFunctionCall,
+				ID:     newItemID("fc_"),
+				Status: ResponseStatusInProgress,
+				CallID: toolCall.ID,
+				Name:   toolCall.Function.Name,
+			})
+			sw.toolItems[toolIndex] = item
+		}
+		if toolCall.Function.Arguments != "" {
+			item.data.WriteString(toolCall.Function.Arguments)
+			sw.send("response.function_call_arguments.delta", map[string]interface{}{
+				"item_id":      item.item.ID,
+				"output_index": sw.outputIndex(item),
+				"delta":        toolCall.Function.Arguments,
+			})
+		}
+	}
+
+	if choice.FinishReason != "" {
+		sw.finishReason = choice.FinishReason
+	}
+}
+
+// start emits response.created and response.in_progress once, before any other event
+func (sw *responsesStreamWriter) start() {
+	if sw.started {
+		return
+	}
+	sw.started = true
+	sw.send("response.created", map[string]interface{}{"response": sw.response})
+	sw.send("response.in_progress", map[string]interface{}{"response": sw.response})
+}
+
+// addItem appends a new output item and emits response.output_item.added
+func (sw *responsesStreamWriter) addItem(item entities.ResponseOutputItem) *streamOutputItem {
+	streamItem := &streamOutputItem{item: item}
+	sw.items = append(sw.items, streamItem)
+	sw.send("response.output_item.added", map[string]interface{}{
+		"output_index": sw.outputIndex(streamItem),
+		"item":         item,
+	})
+	return streamItem
+}
+
+// openTextItem starts a new assistant message item with an empty output_text part
+func (sw *responsesStreamWriter) openTextItem() {
+	item := newMessageItem("", ResponseStatusInProgress)
+	item.Content = []entities.ResponseContentPart{}
+	sw.textItem = sw.addItem(item)
+	sw.send("response.content_part.added", map[string]interface{}{
+		"item_id":       sw.textItem.item.ID,
+		"output_index":  sw.outputIndex(sw.textItem),
+		"content_index": 0,
+		"part":          entities.ResponseContentPart{Type: ResponsePartOutputText, Annotations: []any{}},
+	})
+}
+
+// closeTextItem completes the current message item, if any
+func (sw *responsesStreamWriter) closeTextItem() {
+	if sw.textItem == nil {
+		return
+	}
+	item := sw.textItem
+	sw.textItem = nil
+
+	text := item.data.String()
+	part := entities.ResponseContentPart{Type: ResponsePartOutputText, Text: text, Annotations: []any{}}
+	outputIndex := sw.outputIndex(item)
+	sw.send("response.output_text.done", map[string]interface{}{
+		"item_id":       item.item.ID,
+		"output_index":  outputIndex,
+		"content_index": 0,
+		"text":          text,
+	})
+	sw.send("response.content_part.done", map[string]interface{}{
+		"item_id":       item.item.ID,
+		"output_index":  outputIndex,
+		"content_index": 0,
+		"part":          part,
+	})
+	item.item.Content = []entities.ResponseContentPart{part}
+	sw.completeItem(item)
+}
+
+// completeItem marks an item as completed and emits response.output_item.done
+func (sw *responsesStreamWriter) completeItem(item *streamOutputItem) {
+	item.item.Status = ResponseStatusCompleted
+	item.done = true
+	sw.send("response.output_item.done", map[string]interface{}{
+		"output_index": sw.outputIndex(item),
+		"item":         item.item,
+	})
+}
+
+// finish completes all open items and emits the final response event exactly once
+func (sw *responsesStreamWriter) finish() {
+	if sw.finished {
+		return
+	}
+	if !sw.wroteHeader {
+		sw.WriteHeader(StatusOK)
+	}
+	sw.start()
+	sw.closeTextItem()
+
+	for _, item := range sw.items {
+		if item.done || item.item.Type != ResponseItemFunctionCall {
+			continue
+		}
+		item.item.Arguments = item.data.String()
+		sw.send("response.function_call_arguments.done", map[string]interface{}{
+			"item_id":      item.item.ID,
+			"output_index": sw.outputIndex(item),
+			"arguments":    item.item.Arguments,
+		})
+		sw.completeItem(item)
+	}
+
+	for _, item := range sw.items {
+		sw.response.Output = append(sw.response.Output, item.item)
+		if item.item.Type == ResponseItemMessage {
+			sw.response.OutputText += item.data.String()
+		}
+	}
+	sw.response.Status = responseStatus(sw.finishReason)
+	sw.response.Usage = responseUsage(sw.usage)
+	sw.finished = true
+
+	if sw.onComplete != nil {
+		sw.onComplete(sw.response)
+	}
+
+	event := "response.completed"
+	if sw.response.Status == ResponseStatusIncomplete {
+		event = "response.incomplete"
+	}
+	sw.send(event, map[string]interface{}{"response": sw.response})
+}
+
+// fail emits response.failed on an already-started stream
+func (sw *responsesStreamWriter) fail(message string) {
+	if sw.finished {
+		return
+	}
+	sw.start()
+	sw.response.Status = ResponseStatusFailed
+	sw.finished = true
+	sw.send("response.failed", map[string]interface{}{
+		"response": sw.response,
+		"error":    map[string]interface{}{"code": "server_error", "message": message},
+	})
+}
+
+// outputIndex returns the position of an item in the response output
+func (sw *responsesStreamWriter) outputIndex(item *streamOutputItem) int {
+	for i, candidate := range sw.items {
+		if candidate == item {
+			return i
+		}
+	}
+	return -1
+}
+
+// send emits a Responses API event, adding the type and sequence number to the payload
+func (sw *responsesStreamWriter) send(event string, payload map[string]interface{}) {
+	payload["type"] = event
+	payload["sequence_number"] = sw.sequence
+	sw.sequence++
+	sw.emit(event, payload)
+}
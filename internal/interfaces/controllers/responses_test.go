@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestConvertResponsesInput_String(t *testing.T) {
+	messages, err := convertResponsesInput("Hello")
+	require.NoError(t, err)
+	assert.Equal(t, []entities.ChatMessage{{Role: "user", Content: "Hello"}}, messages)
+}
+
+func TestConvertResponsesInput_Items(t *testing.T) {
+	var input interface{}
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role": "developer", "content": "Be terse"},
+		{"type": "message", "role": "user", "content": [{"type": "input_text", "text": "Weather?"}]},
+		{"type": "function_call", "call_id": "call_1", "name": "weather", "arguments": "{}"},
+		{"type": "function_call", "call_id": "call_2", "name": "time", "arguments": "{}"},
+		{"type": "function_call_output", "call_id": "call_1", "output": "Sunny"},
+		{"type": "reasoning", "summary": []}
+	]`), &input))
+
+	messages, err := convertResponsesInput(input)
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+
+	assert.Equal(t, entities.ChatMessage{Role: "system", Content: "Be terse"}, messages[0])
+	assert.Equal(t, entities.ChatMessage{Role: "user", Content: "Weather?"}, messages[1])
+	assert.Equal(t, "assistant", messages[2].Role)
+	require.Len(t, messages[2].ToolCalls, 2)
+	assert.Equal(t, "call_2", messages[2].ToolCalls[1].ID)
+	assert.Equal(t, entities.ChatMessage{Role: "tool", Content: "Sunny", ToolCallID: "call_1"}, messages[3])
+}
+
+func TestConvertResponsesInput_UnsupportedItem(t *testing.T) {
+	_, err := convertResponsesInput([]interface{}{map[string]interface{}{"type": "item_reference", "id": "x"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported input item type")
+}
+
+func TestBuildChatRequestFromResponses(t *testing.T) {
+	temperature := 0.5
+	req := &entities.ResponsesRequest{
+		Model:           "qwen3-coder-plus",
+		Instructions:    "You are a bot",
+		MaxOutputTokens: 256,
+		Temperature:     &temperature,
+		Tools:           []entities.ResponseTool{{Type: "function", Name: "weather", Parameters: map[string]interface{}{"type": "object"}}},
+		ToolChoice:      map[string]interface{}{"type": "function", "name": "weather"},
+	}
+	conversation := []entities.ChatMessage{{Role: "user", Content: "Hi"}}
+
+	chatReq, err := buildChatRequestFromResponses(req, conversation)
+	require.NoError(t, err)
+
+	assert.Equal(t, 256, chatReq.MaxTokens)
+	assert.Equal(t, 0.5, *chatReq.Temperature)
+	require.Len(t, chatReq.Messages, 2)
+	assert.Equal(t, entities.ChatMessage{Role: "system", Content: "You are a bot"}, chatReq.Messages[0])
+	require.Len(t, chatReq.Tools, 1)
+	assert.Equal(t, "weather", chatReq.Tools[0].Function.Name)
+	assert.Equal(t, entities.ToolChoice{Type: "function", Function: entities.Function{Name: "weather"}}, chatReq.ToolChoice)
+
+	req.Tools = []entities.ResponseTool{{Type: "web_search"}}
+	_, err = buildChatRequestFromResponses(req, conversation)
+	assert.Error(t, err)
+}
+
+func TestResponsesHandler_ChainsPreviousResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	mockRepo := mocks.NewMockResponseRepository(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewResponsesController(mockProxy, mockRepo, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockRepo.EXPECT().Load("resp_prev").Return(&entities.StoredResponse{
+		ID: "resp_prev",
+		Messages: []entities.ChatMessage{
+			{Role: "user", Content: "My name is Ada"},
+			{Role: "assistant", Content: "Nice to meet you, Ada"},
+		},
+	}, nil)
+
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+		require.Len(t, req.Messages, 4)
+		assert.Equal(t, "system", req.Messages[0].Role)
+		assert.Equal(t, "My name is Ada", req.Messages[1].Content)
+		assert.Equal(t, "What is my name?", req.Messages[3].Content)
+		return &entities.ChatCompletionResponse{
+			ID:    "chatcmpl-1",
+			Model: "qwen3-coder-plus",
+			Choices: []entities.ChatCompletionChoice{
+				{Message: entities.ChatMessage{Role: "assistant", Content: "Ada"}, FinishReason: "stop"},
+			},
+			Usage: &entities.Usage{PromptTokens: 20, CompletionTokens: 1, TotalTokens: 21},
+		}, nil
+	})
+
+	var stored *entities.StoredResponse
+	mockRepo.EXPECT().Save(gomock.Any()).DoAndReturn(func(response *entities.StoredResponse) error {
+		stored = response
+		return nil
+	})
+
+	body := `{"model": "qwen3-coder-plus", "instructions": "Be brief", "previous_response_id": "resp_prev", "input": "What is my name?"}`
+	req := httptest.NewRequest("POST", "/v1/responses", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.ResponsesHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	var result entities.ResponseObject
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, strings.HasPrefix(result.ID, "resp_"))
+	assert.Equal(t, ObjectResponse, result.Object)
+	assert.Equal(t, ResponseStatusCompleted, result.Status)
+	assert.Equal(t, "resp_prev", result.PreviousResponseID)
+	assert.Equal(t, "Ada", result.OutputText)
+	require.Len(t, result.Output, 1)
+	assert.Equal(t, ResponsePartOutputText, result.Output[0].Content[0].Type)
+	assert.Equal(t, 21, result.Usage.TotalTokens)
+
+	require.NotNil(t, stored)
+	assert.Equal(t, result.ID, stored.ID)
+	require.Len(t, stored.Messages, 4)
+	assert.Equal(t, entities.ChatMessage{Role: "assistant", Content: "Ada"}, stored.Messages[3])
+}
+
+func TestResponsesHandler_PreviousResponseNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	mockRepo := mocks.NewMockResponseRepository(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewResponsesController(mockProxy, mockRepo, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockRepo.EXPECT().Load("resp_missing").Return(nil, assert.AnError)
+
+	body := `{"previous_response_id": "resp_missing", "input": "Hi"}`
+	req := httptest.NewRequest("POST", "/v1/responses", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.ResponsesHandler(rec, req)
+
+	assert.Equal(t, 400, rec.Code)
+	assert.Contains(t, rec.Body.String(), "resp_missing")
+	assert.Contains(t, rec.Body.String(), ErrorTypeInvalidRequest)
+}
+
+func TestResponsesHandler_StoreDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	mockRepo := mocks.NewMockResponseRepository(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewResponsesController(mockProxy, mockRepo, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockProxy.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(&entities.ChatCompletionResponse{
+		Choices: []entities.ChatCompletionChoice{{Message: entities.ChatMessage{Role: "assistant", Content: "ok"}}},
+	}, nil)
+	mockRepo.EXPECT().Save(gomock.Any()).Times(0)
+
+	body := `{"input": "Hi", "store": false}`
+	req := httptest.NewRequest("POST", "/v1/responses", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.ResponsesHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+}
+
+func TestResponsesHandler_Streaming(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	mockRepo := mocks.NewMockResponseRepository(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewResponsesController(mockProxy, mockRepo, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	upstream := strings.Join([]string{
+		`data: {"id":"c1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+		`data: {"id":"c1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		`data: {"id":"c1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_9","function":{"name":"lookup","arguments":"{\"a\""}}]}}]}`,
+		`data: {"id":"c1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":":1}"}}]},"finish_reason":"tool_calls"}]}`,
+		`data: {"id":"c1","model":"qwen3-coder-plus","choices":[],"usage":{"prompt_tokens":4,"completion_tokens":2,"total_tokens":6}}`,
+		`data: [DONE]`,
+	}, "\n\n") + "\n\n"
+
+	mockProxy.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req *entities.ChatCompletionRequest, w http.ResponseWriter) error {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(upstream))
+		return nil
+	})
+
+	var stored *entities.StoredResponse
+	mockRepo.EXPECT().Save(gomock.Any()).DoAndReturn(func(response *entities.StoredResponse) error {
+		stored = response
+		return nil
+	})
+
+	body := `{"input": "Hi", "stream": true}`
+	req := httptest.NewRequest("POST", "/v1/responses", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	controller.ResponsesHandler(rec, req)
+
+	assert.Equal(t, 200, rec.Code)
+	events := parseSSEEvents(t, rec.Body.String())
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.name
+		assert.Equal(t, float64(i), event.data["sequence_number"])
+	}
+	assert.Equal(t, []string{
+		"response.created", "response.in_progress",
+		"response.output_item.added", "response.content_part.added",
+		"response.output_text.delta", "response.output_text.delta",
+		"response.output_text.done", "response.content_part.done", "response.output_item.done",
+		"response.output_item.added", "response.function_call_arguments.delta", "response.function_call_arguments.delta",
+		"response.function_call_arguments.done", "response.output_item.done",
+		"response.completed",
+	}, names)
+
+	assert.Equal(t, "lo", events[5].data["delta"])
+	assert.Equal(t, `{"a":1}`, events[12].data["arguments"])
+
+	completed := events[14].data["response"].(map[string]interface{})
+	assert.Equal(t, ResponseStatusCompleted, completed["status"])
+	assert.Len(t, completed["output"], 2)
+	assert.Equal(t, float64(6), completed["usage"].(map[string]interface{})["total_tokens"])
+
+	require.NotNil(t, stored)
+	require.Len(t, stored.Messages, 2)
+	assert.Equal(t, "Hello", stored.Messages[1].Content)
+	require.Len(t, stored.Messages[1].ToolCalls, 1)
+	assert.Equal(t, `{"a":1}`, stored.Messages[1].ToolCalls[0].Function.Arguments)
+}
+
+func TestGetResponseHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	mockRepo := mocks.NewMockResponseRepository(ctrl)
+	logger := logging.NewLogger("info")
+
+	controller := NewResponsesController(mockProxy, mockRepo, tokenizer.NewEstimator(), &logging.Logger{Logger: logger})
+
+	mockRepo.EXPECT().Load("resp_1").Return(&entities.StoredResponse{
+		ID:       "resp_1",
+		Response: entities.ResponseObject{ID: "resp_1", Object: ObjectResponse, Status: ResponseStatusCompleted},
+	}, nil)
+	mockRepo.EXPECT().Load("resp_2").Return(nil, assert.AnError)
+
+	for id, status := range map[string]int{"resp_1": 200, "resp_2": 404} {
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("response_id", id)
+		req := httptest.NewRequest("GET", "/v1/responses/"+id, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+		rec := httptest.NewRecorder()
+
+		controller.GetResponseHandler(rec, req)
+
+		assert.Equal(t, status, rec.Code)
+		assert.Contains(t, rec.Body.String(), id)
+	}
+}
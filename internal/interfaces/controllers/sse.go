@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+)
+
+// chunkStreamWriter is an http.ResponseWriter that receives the OpenAI SSE stream written by the
+// proxy use case and hands every decoded chunk to a callback. It is the base for translating
+// chat completion streams into other API formats.
+type chunkStreamWriter struct {
+	w      http.ResponseWriter
+	logger logging.LoggerInterface
+
+	pending     []byte
+	wroteHeader bool
+
+	onChunk func(chunk *entities.ChatCompletionResponse)
+	onDone  func()
+}
+
+// newChunkStreamWriter creates a new chunk stream writer around the client response writer
+func newChunkStreamWriter(w http.ResponseWriter, logger logging.LoggerInterface, onChunk func(*entities.ChatCompletionResponse), onDone func()) *chunkStreamWriter {
+	return &chunkStreamWriter{
+		w:       w,
+		logger:  logger,
+		onChunk: onChunk,
+		onDone:  onDone,
+	}
+}
+
+// Header returns the client response headers
+func (cw *chunkStreamWriter) Header() http.Header {
+	return cw.w.Header()
+}
+
+// WriteHeader writes SSE response headers, replacing any copied from the upstream response
+func (cw *chunkStreamWriter) WriteHeader(statusCode int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.w.Header().Del("Content-Length")
+	cw.w.Header().Set("Content-Type", "text/event-stream")
+	cw.w.Header().Set("Cache-Control", "no-cache")
+	cw.w.WriteHeader(statusCode)
+}
+
+// Write consumes OpenAI SSE bytes and decodes every complete line
+func (cw *chunkStreamWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(StatusOK)
+	}
+	cw.pending = append(cw.pending, b...)
+	for {
+		idx := bytes.IndexByte(cw.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		line := strings.TrimRight(string(cw.pending[:idx]), "\r")
+		cw.pending = cw.pending[idx+1:]
+		cw.handleLine(line)
+	}
+	return len(b), nil
+}
+
+// Flush implements the http.Flusher interface
+func (cw *chunkStreamWriter) Flush() {
+	if flusher, ok := cw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// handleLine decodes a single OpenAI SSE line
+func (cw *chunkStreamWriter) handleLine(line string) {
+	if !strings.HasPrefix(line, "data: ") {
+		return
+	}
+	data := strings.TrimPrefix(line, "data: ")
+	if data == "[DONE]" {
+		cw.onDone()
+		return
+	}
+
+	var chunk entities.ChatCompletionResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		cw.logger.Debug("Skipping malformed stream chunk", "error", err)
+		return
+	}
+	cw.onChunk(&chunk)
+}
+
+// emit writes a single named SSE event to the client
+func (cw *chunkStreamWriter) emit(event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		cw.logger.Error("Failed to encode stream event", "event", event, "error", err)
+		return
+	}
+	fmt.Fprintf(cw.w, "event: %s\ndata: %s\n\n", event, data)
+	cw.Flush()
+}
+
+// usageTrackingWriter passes an OpenAI SSE stream through to the client unchanged while
+// picking up the usage reported in the final chunk, when upstream includes one.
+type usageTrackingWriter struct {
+	http.ResponseWriter
+	pending []byte
+	usage   *entities.Usage
+}
+
+// newUsageTrackingWriter creates a new usage tracking writer around the client response writer
+func newUsageTrackingWriter(w http.ResponseWriter) *usageTrackingWriter {
+	return &usageTrackingWriter{ResponseWriter: w}
+}
+
+// Write forwards bytes to the client and inspects every complete line for usage
+func (uw *usageTrackingWriter) Write(b []byte) (int, error) {
+	n, err := uw.ResponseWriter.Write(b)
+	uw.pending = append(uw.pending, b[:n]...)
+	for {
+		idx := bytes.IndexByte(uw.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		line := uw.pending[:idx]
+		uw.pending = uw.pending[idx+1:]
+		if bytes.HasPrefix(line, []byte("data: ")) && bytes.Contains(line, []byte(`"usage"`)) {
+			var chunk entities.ChatCompletionResponse
+			if json.Unmarshal(bytes.TrimPrefix(line, []byte("data: ")), &chunk) == nil && chunk.Usage != nil {
+				uw.usage = chunk.Usage
+			}
+		}
+	}
+	return n, err
+}
+
+// Flush implements the http.Flusher interface
+func (uw *usageTrackingWriter) Flush() {
+	if flusher, ok := uw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/usecases/proxy"
+)
+
+// ErrMsgMissingTokenizeInput Error messages
+const ErrMsgMissingTokenizeInput = "prompt or messages is required"
+
+// TokenizeController counts tokens with the tokenizer used for the context window preflight,
+// so that clients can check the size of a request before sending it
+type TokenizeController struct {
+	*APIController
+}
+
+// NewTokenizeController creates a new tokenize controller
+func NewTokenizeController(proxyUseCase proxy.ProxyUseCaseInterface, tokenizer interfaces.Tokenizer, logger logging.LoggerInterface) *TokenizeController {
+	return &TokenizeController{
+		APIController: NewAPIController(proxyUseCase, tokenizer, logger),
+	}
+}
+
+// TokenizeHandler counts the tokens of a prompt or of chat messages.
+// When a model is given, its context window is reported as max_model_len.
+func (ctrl *TokenizeController) TokenizeHandler(w http.ResponseWriter, r *http.Request) {
+	r, span := startHandlerSpan(r, "TokenizeController.TokenizeHandler")
+	defer span.End()
+
+	var req entities.TokenizeRequest
+	if !ctrl.validateJSONRequest(w, r, &req) {
+		return
+	}
+	if req.Prompt == "" && len(req.Messages) == 0 {
+		ctrl.sendValidationError(w, r, ErrMsgMissingTokenizeInput)
+		return
+	}
+
+	response := &entities.TokenizeResponse{}
+	if req.Model != "" {
+		model, err := ctrl.proxyUseCase.GetModel(r.Context(), req.Model)
+		if err != nil {
+			ctrl.sendInternalError(w, r, err)
+			return
+		}
+		response.Model = model.ID
+		response.MaxModelLen = model.ContextWindow
+	}
+
+	// Messages take precedence over a prompt, and are counted the way the preflight counts them
+	tokens, exact := ctrl.tokenizer.Encode(req.Prompt)
+	response.Estimated = !exact
+	switch {
+	case len(req.Messages) > 0:
+		response.Count = tokenizer.CountPromptTokens(ctrl.tokenizer, &entities.ChatCompletionRequest{Messages: req.Messages, Tools: req.Tools})
+	case exact:
+		response.Count = len(tokens)
+		response.Tokens = tokens
+	default:
+		response.Count = ctrl.tokenizer.CountTokens(req.Prompt)
+	}
+	ctrl.logger.Debug("Tokens counted", "request_id", middleware.GetRequestID(r.Context()), "model", response.Model, "count", response.Count)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
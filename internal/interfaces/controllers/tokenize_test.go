@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTokenizeHandler_Estimated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewTokenizeController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	mockProxy.EXPECT().GetModel(gomock.Any(), "qwen/coder").Return(&entities.ModelInfo{ID: "qwen3-coder-plus", ContextWindow: 131072}, nil)
+
+	rec := httptest.NewRecorder()
+	controller.TokenizeHandler(rec, httptest.NewRequest("POST", "/v1/tokenize", strings.NewReader(`{"model": "qwen/coder", "prompt": "Hello there"}`)))
+
+	assert.Equal(t, 200, rec.Code)
+	var response entities.TokenizeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "qwen3-coder-plus", response.Model)
+	assert.Equal(t, 131072, response.MaxModelLen)
+	assert.Equal(t, 4, response.Count)
+	assert.True(t, response.Estimated)
+	assert.Empty(t, response.Tokens)
+}
+
+func TestTokenizeHandler_Vocabulary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"added_tokens": [{"id": 4, "content": "<|im_end|>"}],
+		"model": {"type": "BPE", "vocab": {"h": 0, "i": 1, "Ġ": 2, "hi": 3}, "merges": ["h i"]}
+	}`), 0600))
+	bpe, err := tokenizer.LoadBPE(path)
+	require.NoError(t, err)
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewTokenizeController(mockProxy, bpe, &logging.Logger{Logger: logging.NewLogger("info")})
+
+	rec := httptest.NewRecorder()
+	controller.TokenizeHandler(rec, httptest.NewRequest("POST", "/v1/tokenize", strings.NewReader(`{"prompt": "hi hi<|im_end|>"}`)))
+
+	assert.Equal(t, 200, rec.Code)
+	var response entities.TokenizeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []int{3, 2, 3, 4}, response.Tokens)
+	assert.Equal(t, 4, response.Count)
+	assert.False(t, response.Estimated)
+
+	// Messages are counted with the chat template around them
+	rec = httptest.NewRecorder()
+	controller.TokenizeHandler(rec, httptest.NewRequest("POST", "/v1/tokenize", strings.NewReader(`{"messages": [{"role": "user", "content": "hi"}]}`)))
+
+	assert.Equal(t, 200, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Greater(t, response.Count, 1)
+	assert.False(t, response.Estimated)
+}
+
+func TestTokenizeHandler_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProxy := mocks.NewMockProxyUseCaseInterface(ctrl)
+	controller := NewTokenizeController(mockProxy, tokenizer.NewEstimator(), &logging.Logger{Logger: logging.NewLogger("info")})
+
+	mockProxy.EXPECT().GetModel(gomock.Any(), "gpt-4").Return(nil, &models.ModelNotFoundError{Model: "gpt-4"})
+
+	rec := httptest.NewRecorder()
+	controller.TokenizeHandler(rec, httptest.NewRequest("POST", "/v1/tokenize", strings.NewReader(`{"model": "qwen3-coder-plus"}`)))
+	assert.Equal(t, 400, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrMsgMissingTokenizeInput)
+
+	rec = httptest.NewRecorder()
+	controller.TokenizeHandler(rec, httptest.NewRequest("POST", "/v1/tokenize", strings.NewReader(`{"model": "gpt-4", "prompt": "Hi"}`)))
+	assert.Equal(t, 404, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrCodeModelNotFound)
+}
+
+func TestNewTokenizeController_NilTokenizer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	assert.Panics(t, func() {
+		NewTokenizeController(mocks.NewMockProxyUseCaseInterface(ctrl), nil, &logging.Logger{Logger: logging.NewLogger("info")})
+	})
+}
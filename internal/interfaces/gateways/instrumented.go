@@ -0,0 +1,73 @@
+package gateways
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/metrics"
+)
+
+// InstrumentedQwenAPIGateway wraps a QwenAPIGateway and records upstream latency and, for
+// streaming requests, the time until the first byte of the stream is read.
+type InstrumentedQwenAPIGateway struct {
+	QwenAPIGateway
+}
+
+// NewInstrumentedQwenAPIGateway creates a gateway that reports upstream metrics
+func NewInstrumentedQwenAPIGateway(gateway QwenAPIGateway) *InstrumentedQwenAPIGateway {
+	if gateway == nil {
+		panic("gateway cannot be nil")
+	}
+	return &InstrumentedQwenAPIGateway{QwenAPIGateway: gateway}
+}
+
+// ChatCompletions sends the request and records its latency by model and status.
+// Failed requests without a response are recorded with status 0.
+func (g *InstrumentedQwenAPIGateway) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+	start := time.Now()
+	resp, err := g.QwenAPIGateway.ChatCompletions(ctx, req, credentials)
+	if err != nil {
+		metrics.ObserveUpstream(req.Model, 0, time.Since(start))
+		return nil, err
+	}
+	metrics.ObserveUpstream(req.Model, resp.StatusCode, time.Since(start))
+
+	if req.Stream && resp.StatusCode == http.StatusOK {
+		resp.Body = &firstByteReader{ReadCloser: resp.Body, onFirstByte: func() {
+			metrics.ObserveTimeToFirstToken(req.Model, time.Since(start))
+		}}
+	}
+	return resp, nil
+}
+
+// Embeddings sends the request and records its latency by model and status
+func (g *InstrumentedQwenAPIGateway) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	start := time.Now()
+	resp, err := g.QwenAPIGateway.Embeddings(ctx, req, credentials)
+	if err != nil {
+		metrics.ObserveUpstream(req.Model, 0, time.Since(start))
+		return nil, err
+	}
+	metrics.ObserveUpstream(req.Model, resp.StatusCode, time.Since(start))
+	return resp, nil
+}
+
+// firstByteReader calls onFirstByte once, when the first byte of the body is read
+type firstByteReader struct {
+	io.ReadCloser
+	onFirstByte func()
+	once        sync.Once
+}
+
+// Read reads from the wrapped body and reports the first byte
+func (r *firstByteReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.once.Do(r.onFirstByte)
+	}
+	return n, err
+}
@@ -0,0 +1,53 @@
+package gateways
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInstrumentedQwenAPIGateway_Panics(t *testing.T) {
+	assert.PanicsWithValue(t, "gateway cannot be nil", func() {
+		NewInstrumentedQwenAPIGateway(nil)
+	})
+}
+
+func TestInstrumentedGateway_PassesThrough(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusOK, "data: [DONE]\n\n", nil),
+		fail(errors.New("no such host")),
+	}}
+	gateway := NewInstrumentedQwenAPIGateway(stub)
+
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Stream: true}, nil)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "data: [DONE]\n\n", string(body))
+	assert.NoError(t, resp.Body.Close())
+
+	_, err = gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "qwen3-coder-plus"}, nil)
+	assert.EqualError(t, err, "no such host")
+}
+
+func TestFirstByteReader(t *testing.T) {
+	calls := 0
+	stub := &stubGateway{responses: []func() (*http.Response, error){respond(http.StatusOK, "abc", nil)}}
+	resp, _ := stub.ChatCompletions(context.Background(), nil, nil)
+	reader := &firstByteReader{ReadCloser: resp.Body, onFirstByte: func() { calls++ }}
+
+	buf := make([]byte, 1)
+	for {
+		if _, err := reader.Read(buf); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,107 @@
+package gateways
+
+import (
+	"context"
+	"net/http"
+
+	"qwen-go-proxy/internal/domain/entities"
+)
+
+// Provider is a named upstream together with the gateway used to reach it
+type Provider struct {
+	Config  entities.ProviderConfig
+	Gateway QwenAPIGateway
+}
+
+// ProviderRouter is implemented by gateways that send some models to providers which do not use
+// the proxy's Qwen credentials, so that callers can skip Qwen authentication for those models
+type ProviderRouter interface {
+	RequiresQwenAuth(model string) bool
+}
+
+// ProviderRegistry is a QwenAPIGateway that routes every request to the provider serving its model.
+// Models that no provider declares go to the default provider, the first Qwen OAuth provider,
+// which also serves the model list.
+type ProviderRegistry struct {
+	providers       []*Provider
+	byModel         map[string]*Provider
+	defaultProvider *Provider
+}
+
+// NewProviderRegistry creates a registry of the given providers.
+// Falls back to the first provider as default when none uses Qwen OAuth.
+func NewProviderRegistry(providers []Provider) *ProviderRegistry {
+	if len(providers) == 0 {
+		panic("providers cannot be empty")
+	}
+
+	registry := &ProviderRegistry{byModel: make(map[string]*Provider)}
+	for i := range providers {
+		provider := &providers[i]
+		if provider.Gateway == nil {
+			panic("gateway of provider " + provider.Config.Name + " cannot be nil")
+		}
+		registry.providers = append(registry.providers, provider)
+		for _, model := range provider.Config.Models {
+			if _, exists := registry.byModel[model]; !exists {
+				registry.byModel[model] = provider
+			}
+		}
+		if registry.defaultProvider == nil && provider.Config.AuthType == entities.ProviderAuthQwenOAuth {
+			registry.defaultProvider = provider
+		}
+	}
+	if registry.defaultProvider == nil {
+		registry.defaultProvider = registry.providers[0]
+	}
+	return registry
+}
+
+// Lookup returns the provider that serves a model
+func (r *ProviderRegistry) Lookup(model string) *Provider {
+	if provider, ok := r.byModel[model]; ok {
+		return provider
+	}
+	return r.defaultProvider
+}
+
+// RequiresQwenAuth reports whether requests for a model are sent with Qwen credentials
+func (r *ProviderRegistry) RequiresQwenAuth(model string) bool {
+	return r.Lookup(model).Config.AuthType == entities.ProviderAuthQwenOAuth
+}
+
+// ChatCompletions sends the request to the provider serving its model.
+// Qwen credentials are only passed on to Qwen OAuth providers.
+func (r *ProviderRegistry) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+	provider := r.defaultProvider
+	if req != nil {
+		provider = r.Lookup(req.Model)
+	}
+	if provider.Config.AuthType != entities.ProviderAuthQwenOAuth {
+		credentials = nil
+	}
+	return provider.Gateway.ChatCompletions(ctx, req, credentials)
+}
+
+// Embeddings sends the request to the provider serving its model.
+// Qwen credentials are only passed on to Qwen OAuth providers.
+func (r *ProviderRegistry) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	provider := r.defaultProvider
+	if req != nil {
+		provider = r.Lookup(req.Model)
+	}
+	if provider.Config.AuthType != entities.ProviderAuthQwenOAuth {
+		credentials = nil
+	}
+	return provider.Gateway.Embeddings(ctx, req, credentials)
+}
+
+// ListModels requests the model list from the default provider
+func (r *ProviderRegistry) ListModels(ctx context.Context, credentials *entities.Credentials) (*http.Response, error) {
+	return r.defaultProvider.Gateway.ListModels(ctx, credentials)
+}
+
+// GetBaseURL returns the base URL of the default provider
+func (r *ProviderRegistry) GetBaseURL(credentials *entities.Credentials, defaultURL string) (string, error) {
+	return r.defaultProvider.Gateway.GetBaseURL(credentials, defaultURL)
+}
@@ -0,0 +1,106 @@
+package gateways
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingGateway records the credentials of every request it receives
+type recordingGateway struct {
+	QwenAPIGateway
+	name        string
+	credentials []*entities.Credentials
+}
+
+func (g *recordingGateway) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+	g.credentials = append(g.credentials, credentials)
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Provider": {g.name}}}, nil
+}
+
+func (g *recordingGateway) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	g.credentials = append(g.credentials, credentials)
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Provider": {g.name}}}, nil
+}
+
+func (g *recordingGateway) ListModels(ctx context.Context, credentials *entities.Credentials) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Provider": {g.name}}}, nil
+}
+
+func newTestRegistry() (*ProviderRegistry, *recordingGateway, *recordingGateway) {
+	local := &recordingGateway{name: "local"}
+	qwen := &recordingGateway{name: "qwen"}
+	registry := NewProviderRegistry([]Provider{
+		{Config: entities.ProviderConfig{Name: "local", AuthType: entities.ProviderAuthNone, Models: []string{"llama-3.1-8b"}}, Gateway: local},
+		{Config: entities.ProviderConfig{Name: "qwen", AuthType: entities.ProviderAuthQwenOAuth}, Gateway: qwen},
+	})
+	return registry, local, qwen
+}
+
+func TestNewProviderRegistry_Panics(t *testing.T) {
+	assert.PanicsWithValue(t, "providers cannot be empty", func() { NewProviderRegistry(nil) })
+	assert.PanicsWithValue(t, "gateway of provider local cannot be nil", func() {
+		NewProviderRegistry([]Provider{{Config: entities.ProviderConfig{Name: "local"}}})
+	})
+}
+
+func TestProviderRegistry_RoutesByModel(t *testing.T) {
+	registry, local, qwen := newTestRegistry()
+	credentials := &entities.Credentials{AccessToken: "qwen-token"}
+
+	resp, err := registry.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "llama-3.1-8b"}, credentials)
+	require.NoError(t, err)
+	assert.Equal(t, "local", resp.Header.Get("X-Provider"))
+	assert.Equal(t, []*entities.Credentials{nil}, local.credentials, "Qwen credentials are not sent to other providers")
+
+	resp, err = registry.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "qwen3-coder-plus"}, credentials)
+	require.NoError(t, err)
+	assert.Equal(t, "qwen", resp.Header.Get("X-Provider"), "undeclared models go to the Qwen provider")
+	assert.Equal(t, []*entities.Credentials{credentials}, qwen.credentials)
+}
+
+func TestProviderRegistry_RoutesEmbeddingsByModel(t *testing.T) {
+	registry, local, qwen := newTestRegistry()
+	credentials := &entities.Credentials{AccessToken: "qwen-token"}
+
+	resp, err := registry.Embeddings(context.Background(), &entities.EmbeddingRequest{Model: "llama-3.1-8b"}, credentials)
+	require.NoError(t, err)
+	assert.Equal(t, "local", resp.Header.Get("X-Provider"))
+	assert.Equal(t, []*entities.Credentials{nil}, local.credentials)
+
+	resp, err = registry.Embeddings(context.Background(), &entities.EmbeddingRequest{Model: "text-embedding-v3"}, credentials)
+	require.NoError(t, err)
+	assert.Equal(t, "qwen", resp.Header.Get("X-Provider"))
+	assert.Equal(t, []*entities.Credentials{credentials}, qwen.credentials)
+}
+
+func TestProviderRegistry_RequiresQwenAuth(t *testing.T) {
+	registry, _, _ := newTestRegistry()
+
+	assert.False(t, registry.RequiresQwenAuth("llama-3.1-8b"))
+	assert.True(t, registry.RequiresQwenAuth("qwen3-coder-plus"))
+	assert.Equal(t, "local", registry.Lookup("llama-3.1-8b").Config.Name)
+}
+
+func TestProviderRegistry_ListModelsUsesDefaultProvider(t *testing.T) {
+	registry, _, _ := newTestRegistry()
+
+	resp, err := registry.ListModels(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "qwen", resp.Header.Get("X-Provider"))
+}
+
+func TestProviderRegistry_DefaultsToFirstProvider(t *testing.T) {
+	local := &recordingGateway{name: "local"}
+	registry := NewProviderRegistry([]Provider{
+		{Config: entities.ProviderConfig{Name: "local", AuthType: entities.ProviderAuthNone, Models: []string{"llama-3.1-8b"}}, Gateway: local},
+	})
+
+	assert.Equal(t, "local", registry.Lookup("unknown").Config.Name)
+	assert.False(t, registry.RequiresQwenAuth("unknown"))
+}
@@ -1,6 +1,7 @@
 package gateways
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -70,7 +71,7 @@ func TestQwenAPIGatewayImpl_ChatCompletions(t *testing.T) {
 	}
 
 	// Call the ChatCompletions method
-	httpResp, err := gateway.ChatCompletions(req, credentials)
+	httpResp, err := gateway.ChatCompletions(context.Background(), req, credentials)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
 
@@ -83,6 +84,32 @@ func TestQwenAPIGatewayImpl_ChatCompletions(t *testing.T) {
 	httpResp.Body.Close()
 }
 
+func TestQwenAPIGatewayImpl_ChatCompletions_ContextCancelled(t *testing.T) {
+	// The upstream server blocks until the proxy gives up on the request
+	requestCancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a closed connection once the request body has been read
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+		close(requestCancelled)
+	}))
+	defer server.Close()
+
+	gateway := NewQwenAPIGateway(&entities.Config{APIBaseURL: server.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	resp, err := gateway.ChatCompletions(ctx, &entities.ChatCompletionRequest{Model: "test-model"}, &entities.Credentials{AccessToken: "test-token"})
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case <-requestCancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not cancelled")
+	}
+}
 func TestQwenAPIGatewayImpl_ChatCompletions_Error(t *testing.T) {
 	// Create a Qwen API gateway with an invalid URL
 	config := &entities.Config{
@@ -107,12 +134,60 @@ func TestQwenAPIGatewayImpl_ChatCompletions_Error(t *testing.T) {
 	}
 
 	// Call the ChatCompletions method - should return an error
-	_, err := gateway.ChatCompletions(req, credentials)
+	_, err := gateway.ChatCompletions(context.Background(), req, credentials)
 	assert.Error(t, err)
 	// The error should be a network error since the URL is invalid
 	assert.Contains(t, err.Error(), "invalid-url-that-does-not-exist")
 }
 
+func TestQwenAPIGatewayImpl_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"id":"qwen3-coder-plus","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	gateway := NewQwenAPIGateway(&entities.Config{APIBaseURL: server.URL})
+
+	httpResp, err := gateway.ListModels(context.Background(), &entities.Credentials{AccessToken: "test-token"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	body, err := io.ReadAll(httpResp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "qwen3-coder-plus")
+	httpResp.Body.Close()
+}
+
+func TestQwenAPIGatewayImpl_Embeddings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		var req entities.EmbeddingRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-v3", req.Model)
+		assert.Equal(t, 512, req.Dimensions)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.5]}]}`))
+	}))
+	defer server.Close()
+
+	gateway := NewQwenAPIGateway(&entities.Config{APIBaseURL: server.URL})
+
+	req := &entities.EmbeddingRequest{Model: "text-embedding-v3", Input: "Hello", Dimensions: 512}
+	httpResp, err := gateway.Embeddings(context.Background(), req, &entities.Credentials{AccessToken: "test-token"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	httpResp.Body.Close()
+}
+
 func TestQwenAPIGatewayImpl_GetBaseURL(t *testing.T) {
 	// Create a Qwen API gateway
 	config := &entities.Config{
@@ -337,6 +412,55 @@ func TestOAuthGatewayImpl_RefreshToken_ErrorInResponse(t *testing.T) {
 	assert.Contains(t, err.Error(), "The request is missing a required parameter")
 }
 
+func TestOAuthGatewayImpl_DeviceFlow(t *testing.T) {
+	var codeChallenge string
+	approved := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/oauth2/device/code":
+			codeChallenge = r.Form.Get("code_challenge")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"device_code":               "device-code",
+				"user_code":                 "ABCD-EFGH",
+				"verification_uri":          "https://chat.qwen.ai/authorize",
+				"verification_uri_complete": "https://chat.qwen.ai/authorize?user_code=ABCD-EFGH",
+				"expires_in":                600,
+			})
+		case "/api/v1/oauth2/token":
+			// The verifier sent with the poll must match the challenge sent with the authorization
+			verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			assert.Equal(t, codeChallenge, base64.RawURLEncoding.EncodeToString(verifier[:]))
+			if !approved {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"access","token_type":"Bearer","refresh_token":"refresh","expires_in":3600}`))
+		}
+	}))
+	defer server.Close()
+
+	gateway := NewOAuthGateway(server.URL)
+
+	authorization, err := gateway.StartDeviceFlow(context.Background(), "test-client-id", "test-scope")
+	assert.NoError(t, err)
+	assert.Equal(t, "ABCD-EFGH", authorization.UserCode)
+	assert.Equal(t, "https://chat.qwen.ai/authorize?user_code=ABCD-EFGH", authorization.VerificationURIComplete)
+
+	_, err = gateway.PollDeviceToken(context.Background(), "test-client-id", authorization)
+	assert.ErrorIs(t, err, entities.ErrAuthorizationPending)
+
+	approved = true
+	creds, err := gateway.PollDeviceToken(context.Background(), "test-client-id", authorization)
+	assert.NoError(t, err)
+	assert.Equal(t, "access", creds.AccessToken)
+	assert.Equal(t, "refresh", creds.RefreshToken)
+}
+
 func TestGenerateCodeVerifier(t *testing.T) {
 	// Test that generateCodeVerifier creates a valid code verifier
 	verifier, err := generateCodeVerifier()
@@ -0,0 +1,214 @@
+package gateways
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+)
+
+// RetryPolicy configures how failed upstream requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first; 1 or less disables retries
+	MaxAttempts int
+	// InitialBackoff is the base delay, doubled on every retry and capped at MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// FailOverRateLimits hands 429 responses back at once instead of retrying them on the same
+	// credentials, so that a credential pool can fail over to another account
+	FailOverRateLimits bool
+}
+
+// RetryingQwenAPIGateway wraps a QwenAPIGateway and retries requests that fail with 429, 5xx or a
+// connection reset. Upstream Retry-After hints are honoured as long as they do not exceed MaxBackoff;
+// longer waits are returned to the caller so that it can fail over to another account instead.
+type RetryingQwenAPIGateway struct {
+	QwenAPIGateway
+	policy RetryPolicy
+	logger logging.LoggerInterface
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryingQwenAPIGateway creates a gateway that retries transient upstream failures
+func NewRetryingQwenAPIGateway(gateway QwenAPIGateway, policy RetryPolicy, logger logging.LoggerInterface) *RetryingQwenAPIGateway {
+	if gateway == nil {
+		panic("gateway cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &RetryingQwenAPIGateway{
+		QwenAPIGateway: gateway,
+		policy:         policy,
+		logger:         logger,
+		sleep:          sleepContext,
+	}
+}
+
+// ChatCompletions sends the request, retrying transient failures according to the retry policy.
+// Streaming responses are only retried until their first byte arrives, so nothing is ever
+// retried after part of a stream could have been forwarded to the client. Retries stop as soon
+// as ctx is done or when the next delay would run past its deadline.
+func (g *RetryingQwenAPIGateway) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+	return g.retry(ctx, req.Model, req.Stream, func() (*http.Response, error) {
+		return g.QwenAPIGateway.ChatCompletions(ctx, req, credentials)
+	})
+}
+
+// Embeddings sends the request, retrying transient failures according to the retry policy
+func (g *RetryingQwenAPIGateway) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	return g.retry(ctx, req.Model, false, func() (*http.Response, error) {
+		return g.QwenAPIGateway.Embeddings(ctx, req, credentials)
+	})
+}
+
+// retry sends a request until it succeeds, fails permanently or the attempts are used up
+func (g *RetryingQwenAPIGateway) retry(ctx context.Context, model string, stream bool, send func() (*http.Response, error)) (*http.Response, error) {
+	requestID := middleware.GetRequestID(ctx)
+	for attempt := 1; ; attempt++ {
+		g.logger.Debug("Sending upstream request", "request_id", requestID, "attempt", attempt, "max_attempts", g.policy.MaxAttempts, "model", model)
+
+		resp, err := send()
+		if err == nil && resp.StatusCode == http.StatusOK && stream {
+			err = awaitFirstByte(resp)
+		}
+
+		retryable, delay := g.shouldRetry(ctx, resp, err, attempt)
+		if !retryable {
+			if err != nil {
+				g.logger.Warn("Upstream request failed", "request_id", requestID, "attempt", attempt, "error", err)
+				closeBody(resp)
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				g.logger.Warn("Upstream request failed", "request_id", requestID, "attempt", attempt, "status", resp.StatusCode)
+			}
+			return resp, nil
+		}
+
+		if err != nil {
+			g.logger.Warn("Upstream request failed, retrying", "request_id", requestID, "attempt", attempt, "error", err, "delay", delay)
+		} else {
+			g.logger.Warn("Upstream request failed, retrying", "request_id", requestID, "attempt", attempt, "status", resp.StatusCode, "delay", delay)
+		}
+		closeBody(resp)
+		if err := g.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry decides whether an attempt is retried and how long to wait before the next one
+func (g *RetryingQwenAPIGateway) shouldRetry(ctx context.Context, resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if attempt >= g.policy.MaxAttempts || ctx.Err() != nil {
+		return false, 0
+	}
+
+	var delay time.Duration
+	switch {
+	case err != nil:
+		if !isConnectionReset(err) {
+			return false, 0
+		}
+		delay = g.backoff(attempt)
+	case resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError:
+		return false, 0
+	case resp.StatusCode == http.StatusTooManyRequests && g.policy.FailOverRateLimits:
+		return false, 0
+	default:
+		delay = g.backoff(attempt)
+		if retryAfter := ParseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			if g.policy.MaxBackoff > 0 && retryAfter > g.policy.MaxBackoff {
+				return false, 0
+			}
+			delay = retryAfter
+		}
+	}
+
+	// There is no point in waiting for an attempt that the deadline would cut short
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return false, 0
+	}
+	return true, delay
+}
+
+// backoff returns a full jitter exponential backoff delay for the given attempt
+func (g *RetryingQwenAPIGateway) backoff(attempt int) time.Duration {
+	if g.policy.InitialBackoff <= 0 {
+		return 0
+	}
+	ceiling := g.policy.InitialBackoff << (attempt - 1)
+	if ceiling <= 0 || (g.policy.MaxBackoff > 0 && ceiling > g.policy.MaxBackoff) {
+		ceiling = g.policy.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// isConnectionReset reports whether an error means the upstream connection was dropped
+func isConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// awaitFirstByte blocks until the response body produces its first byte, so that a connection
+// dropped before the stream starts can still be retried. The body is replaced with a buffered
+// reader that replays the byte.
+func awaitFirstByte(resp *http.Response) error {
+	reader := bufio.NewReader(resp.Body)
+	if _, err := reader.Peek(1); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{reader, resp.Body}
+	return nil
+}
+
+// closeBody closes a response body if the response is not nil
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
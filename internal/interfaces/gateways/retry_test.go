@@ -0,0 +1,286 @@
+package gateways
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGateway returns a scripted sequence of responses
+type stubGateway struct {
+	QwenAPIGateway
+	calls     int
+	responses []func() (*http.Response, error)
+}
+
+func (s *stubGateway) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+	next := s.responses[s.calls]
+	s.calls++
+	return next()
+}
+
+func (s *stubGateway) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	next := s.responses[s.calls]
+	s.calls++
+	return next()
+}
+
+func respond(status int, body string, header http.Header) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+}
+
+func fail(err error) func() (*http.Response, error) {
+	return func() (*http.Response, error) { return nil, err }
+}
+
+// failingReader fails on the first read, like a connection dropped before the stream started
+type failingReader struct{ err error }
+
+func (f failingReader) Read([]byte) (int, error) { return 0, f.err }
+
+func newRetryingGateway(stub *stubGateway, policy RetryPolicy) (*RetryingQwenAPIGateway, *[]time.Duration) {
+	gateway := NewRetryingQwenAPIGateway(stub, policy, &logging.Logger{Logger: logging.NewLogger("error")})
+	var delays []time.Duration
+	gateway.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return gateway, &delays
+}
+
+func TestNewRetryingQwenAPIGateway_Panics(t *testing.T) {
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+
+	assert.PanicsWithValue(t, "gateway cannot be nil", func() {
+		NewRetryingQwenAPIGateway(nil, RetryPolicy{}, logger)
+	})
+	assert.PanicsWithValue(t, "logger cannot be nil", func() {
+		NewRetryingQwenAPIGateway(&stubGateway{}, RetryPolicy{}, nil)
+	})
+}
+
+func TestRetryingGateway_RetriesServerErrors(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusBadGateway, "bad gateway", nil),
+		fail(&wrappedError{syscall.ECONNRESET}),
+		respond(http.StatusOK, "ok", nil),
+	}}
+	gateway, delays := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, stub.calls)
+
+	// Jittered backoff never exceeds the exponential ceiling for the attempt
+	require.Len(t, *delays, 2)
+	assert.LessOrEqual(t, (*delays)[0], 100*time.Millisecond)
+	assert.LessOrEqual(t, (*delays)[1], 200*time.Millisecond)
+}
+
+func TestRetryingGateway_RetriesEmbeddings(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusServiceUnavailable, "unavailable", nil),
+		respond(http.StatusOK, "ok", nil),
+	}}
+	gateway, delays := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+
+	resp, err := gateway.Embeddings(context.Background(), &entities.EmbeddingRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, stub.calls)
+	assert.Len(t, *delays, 1)
+}
+
+func TestRetryingGateway_HonoursRetryAfter(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusTooManyRequests, "slow down", http.Header{"Retry-After": []string{"2"}}),
+		respond(http.StatusOK, "ok", nil),
+	}}
+	gateway, delays := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second})
+
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{2 * time.Second}, *delays)
+}
+
+func TestRetryingGateway_RetryAfterBeyondMaxBackoff(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusTooManyRequests, "quota exceeded", http.Header{"Retry-After": []string{"3600"}}),
+	}}
+	gateway, delays := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second})
+
+	// The throttled response is handed back so that the caller can fail over
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "quota exceeded", string(body))
+	assert.Empty(t, *delays)
+}
+
+func TestRetryingGateway_FailOverRateLimits(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusTooManyRequests, "slow down", http.Header{"Retry-After": []string{"1"}}),
+	}}
+	gateway, delays := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second, FailOverRateLimits: true})
+
+	// Rate limits are left to the credential pool, other failures are still retried
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 1, stub.calls)
+	assert.Empty(t, *delays)
+
+	stub = &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusBadGateway, "bad gateway", nil),
+		respond(http.StatusOK, "ok", nil),
+	}}
+	gateway, _ = newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3, FailOverRateLimits: true})
+
+	resp, err = gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, stub.calls)
+}
+
+func TestRetryingGateway_DoesNotRetryClientErrors(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusBadRequest, "bad request", nil),
+	}}
+	gateway, _ := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3})
+
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, 1, stub.calls)
+
+	stub = &stubGateway{responses: []func() (*http.Response, error){
+		fail(errors.New("no such host")),
+	}}
+	gateway, _ = newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3})
+
+	_, err = gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	assert.EqualError(t, err, "no such host")
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestRetryingGateway_GivesUpAfterMaxAttempts(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusServiceUnavailable, "unavailable", nil),
+		respond(http.StatusServiceUnavailable, "still unavailable", nil),
+	}}
+	gateway, _ := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 2})
+
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "still unavailable", string(body))
+	assert.Equal(t, 2, stub.calls)
+}
+
+func TestRetryingGateway_StreamRetriedBeforeFirstByte(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(failingReader{syscall.ECONNRESET})}, nil
+		},
+		respond(http.StatusOK, "data: [DONE]\n\n", nil),
+	}}
+	gateway, _ := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 2})
+
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Stream: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stub.calls)
+
+	// The peeked byte is replayed to the streaming processor
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "data: [DONE]\n\n", string(body))
+}
+
+func TestRetryingGateway_NonStreamBodyNotPeeked(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(failingReader{syscall.ECONNRESET})}, nil
+		},
+	}}
+	gateway, _ := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 2})
+
+	resp, err := gateway.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestRetryingGateway_StopsWhenContextDone(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusServiceUnavailable, "unavailable", nil),
+	}}
+	gateway, delays := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3})
+
+	// A disconnected client is never retried on its behalf
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp, err := gateway.ChatCompletions(ctx, &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, stub.calls)
+	assert.Empty(t, *delays)
+}
+
+func TestRetryingGateway_DelayBeyondDeadline(t *testing.T) {
+	stub := &stubGateway{responses: []func() (*http.Response, error){
+		respond(http.StatusTooManyRequests, "slow down", http.Header{"Retry-After": []string{"5"}}),
+	}}
+	gateway, delays := newRetryingGateway(stub, RetryPolicy{MaxAttempts: 3, MaxBackoff: 10 * time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := gateway.ChatCompletions(ctx, &entities.ChatCompletionRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Empty(t, *delays)
+}
+
+func TestSleepContext(t *testing.T) {
+	assert.NoError(t, sleepContext(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sleepContext(ctx, time.Hour), context.Canceled)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(""))
+	assert.Equal(t, 5*time.Second, ParseRetryAfter("5"))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("invalid"))
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	retryAfter := ParseRetryAfter(date)
+	assert.Greater(t, retryAfter, 50*time.Second)
+	assert.LessOrEqual(t, retryAfter, time.Minute)
+}
+
+// wrappedError mimics the *url.Error returned by http.Client for a reset connection
+type wrappedError struct{ err error }
+
+func (w *wrappedError) Error() string { return "Post \"https://upstream\": " + w.err.Error() }
+func (w *wrappedError) Unwrap() error { return w.err }
@@ -10,8 +10,10 @@
 package mocks
 
 import (
+	context "context"
 	entities "qwen-go-proxy/internal/domain/entities"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,18 +42,18 @@ func (m *MockAuthUseCaseInterface) EXPECT() *MockAuthUseCaseInterfaceMockRecorde
 	return m.recorder
 }
 
-// AuthenticateManually mocks base method.
-func (m *MockAuthUseCaseInterface) AuthenticateManually() error {
+// AuthState mocks base method.
+func (m *MockAuthUseCaseInterface) AuthState() string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AuthenticateManually")
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "AuthState")
+	ret0, _ := ret[0].(string)
 	return ret0
 }
 
-// AuthenticateManually indicates an expected call of AuthenticateManually.
-func (mr *MockAuthUseCaseInterfaceMockRecorder) AuthenticateManually() *gomock.Call {
+// AuthState indicates an expected call of AuthState.
+func (mr *MockAuthUseCaseInterfaceMockRecorder) AuthState() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateManually", reflect.TypeOf((*MockAuthUseCaseInterface)(nil).AuthenticateManually))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthState", reflect.TypeOf((*MockAuthUseCaseInterface)(nil).AuthState))
 }
 
 // CheckAuthentication mocks base method.
@@ -69,17 +71,184 @@ func (mr *MockAuthUseCaseInterfaceMockRecorder) CheckAuthentication() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAuthentication", reflect.TypeOf((*MockAuthUseCaseInterface)(nil).CheckAuthentication))
 }
 
+// DeviceFlowStatus mocks base method.
+func (m *MockAuthUseCaseInterface) DeviceFlowStatus() *entities.DeviceFlowStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeviceFlowStatus")
+	ret0, _ := ret[0].(*entities.DeviceFlowStatus)
+	return ret0
+}
+
+// DeviceFlowStatus indicates an expected call of DeviceFlowStatus.
+func (mr *MockAuthUseCaseInterfaceMockRecorder) DeviceFlowStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeviceFlowStatus", reflect.TypeOf((*MockAuthUseCaseInterface)(nil).DeviceFlowStatus))
+}
+
+// EnsureAuthenticated mocks base method.
+func (m *MockAuthUseCaseInterface) EnsureAuthenticated(ctx context.Context) (*entities.Credentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureAuthenticated", ctx)
+	ret0, _ := ret[0].(*entities.Credentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnsureAuthenticated indicates an expected call of EnsureAuthenticated.
+func (mr *MockAuthUseCaseInterfaceMockRecorder) EnsureAuthenticated(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureAuthenticated", reflect.TypeOf((*MockAuthUseCaseInterface)(nil).EnsureAuthenticated), ctx)
+}
+
+// StartDeviceFlow mocks base method.
+func (m *MockAuthUseCaseInterface) StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartDeviceFlow", ctx)
+	ret0, _ := ret[0].(*entities.DeviceFlowStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDeviceFlow indicates an expected call of StartDeviceFlow.
+func (mr *MockAuthUseCaseInterfaceMockRecorder) StartDeviceFlow(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDeviceFlow", reflect.TypeOf((*MockAuthUseCaseInterface)(nil).StartDeviceFlow), ctx)
+}
+
+// MockCredentialPoolInterface is a mock of CredentialPoolInterface interface.
+type MockCredentialPoolInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockCredentialPoolInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockCredentialPoolInterfaceMockRecorder is the mock recorder for MockCredentialPoolInterface.
+type MockCredentialPoolInterfaceMockRecorder struct {
+	mock *MockCredentialPoolInterface
+}
+
+// NewMockCredentialPoolInterface creates a new mock instance.
+func NewMockCredentialPoolInterface(ctrl *gomock.Controller) *MockCredentialPoolInterface {
+	mock := &MockCredentialPoolInterface{ctrl: ctrl}
+	mock.recorder = &MockCredentialPoolInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCredentialPoolInterface) EXPECT() *MockCredentialPoolInterfaceMockRecorder {
+	return m.recorder
+}
+
+// AccountStatuses mocks base method.
+func (m *MockCredentialPoolInterface) AccountStatuses() []entities.AccountStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AccountStatuses")
+	ret0, _ := ret[0].([]entities.AccountStatus)
+	return ret0
+}
+
+// AccountStatuses indicates an expected call of AccountStatuses.
+func (mr *MockCredentialPoolInterfaceMockRecorder) AccountStatuses() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccountStatuses", reflect.TypeOf((*MockCredentialPoolInterface)(nil).AccountStatuses))
+}
+
+// AcquireAccount mocks base method.
+func (m *MockCredentialPoolInterface) AcquireAccount(ctx context.Context, exclude map[string]bool) (*entities.AccountCredentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireAccount", ctx, exclude)
+	ret0, _ := ret[0].(*entities.AccountCredentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireAccount indicates an expected call of AcquireAccount.
+func (mr *MockCredentialPoolInterfaceMockRecorder) AcquireAccount(ctx, exclude any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireAccount", reflect.TypeOf((*MockCredentialPoolInterface)(nil).AcquireAccount), ctx, exclude)
+}
+
+// AuthState mocks base method.
+func (m *MockCredentialPoolInterface) AuthState() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthState")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// AuthState indicates an expected call of AuthState.
+func (mr *MockCredentialPoolInterfaceMockRecorder) AuthState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthState", reflect.TypeOf((*MockCredentialPoolInterface)(nil).AuthState))
+}
+
+// CheckAuthentication mocks base method.
+func (m *MockCredentialPoolInterface) CheckAuthentication() (*entities.Credentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckAuthentication")
+	ret0, _ := ret[0].(*entities.Credentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckAuthentication indicates an expected call of CheckAuthentication.
+func (mr *MockCredentialPoolInterfaceMockRecorder) CheckAuthentication() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAuthentication", reflect.TypeOf((*MockCredentialPoolInterface)(nil).CheckAuthentication))
+}
+
+// DeviceFlowStatus mocks base method.
+func (m *MockCredentialPoolInterface) DeviceFlowStatus() *entities.DeviceFlowStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeviceFlowStatus")
+	ret0, _ := ret[0].(*entities.DeviceFlowStatus)
+	return ret0
+}
+
+// DeviceFlowStatus indicates an expected call of DeviceFlowStatus.
+func (mr *MockCredentialPoolInterfaceMockRecorder) DeviceFlowStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeviceFlowStatus", reflect.TypeOf((*MockCredentialPoolInterface)(nil).DeviceFlowStatus))
+}
+
 // EnsureAuthenticated mocks base method.
-func (m *MockAuthUseCaseInterface) EnsureAuthenticated() (*entities.Credentials, error) {
+func (m *MockCredentialPoolInterface) EnsureAuthenticated(ctx context.Context) (*entities.Credentials, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "EnsureAuthenticated")
+	ret := m.ctrl.Call(m, "EnsureAuthenticated", ctx)
 	ret0, _ := ret[0].(*entities.Credentials)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // EnsureAuthenticated indicates an expected call of EnsureAuthenticated.
-func (mr *MockAuthUseCaseInterfaceMockRecorder) EnsureAuthenticated() *gomock.Call {
+func (mr *MockCredentialPoolInterfaceMockRecorder) EnsureAuthenticated(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureAuthenticated", reflect.TypeOf((*MockCredentialPoolInterface)(nil).EnsureAuthenticated), ctx)
+}
+
+// ReportThrottled mocks base method.
+func (m *MockCredentialPoolInterface) ReportThrottled(name string, retryAfter time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReportThrottled", name, retryAfter)
+}
+
+// ReportThrottled indicates an expected call of ReportThrottled.
+func (mr *MockCredentialPoolInterfaceMockRecorder) ReportThrottled(name, retryAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportThrottled", reflect.TypeOf((*MockCredentialPoolInterface)(nil).ReportThrottled), name, retryAfter)
+}
+
+// StartDeviceFlow mocks base method.
+func (m *MockCredentialPoolInterface) StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartDeviceFlow", ctx)
+	ret0, _ := ret[0].(*entities.DeviceFlowStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDeviceFlow indicates an expected call of StartDeviceFlow.
+func (mr *MockCredentialPoolInterfaceMockRecorder) StartDeviceFlow(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureAuthenticated", reflect.TypeOf((*MockAuthUseCaseInterface)(nil).EnsureAuthenticated))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDeviceFlow", reflect.TypeOf((*MockCredentialPoolInterface)(nil).StartDeviceFlow), ctx)
 }
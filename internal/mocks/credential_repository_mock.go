@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	http "net/http"
 	entities "qwen-go-proxy/internal/domain/entities"
 	reflect "reflect"
@@ -70,6 +71,166 @@ func (mr *MockCredentialRepositoryMockRecorder) Save(credentials any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockCredentialRepository)(nil).Save), credentials)
 }
 
+// MockResponseRepository is a mock of ResponseRepository interface.
+type MockResponseRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockResponseRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockResponseRepositoryMockRecorder is the mock recorder for MockResponseRepository.
+type MockResponseRepositoryMockRecorder struct {
+	mock *MockResponseRepository
+}
+
+// NewMockResponseRepository creates a new mock instance.
+func NewMockResponseRepository(ctrl *gomock.Controller) *MockResponseRepository {
+	mock := &MockResponseRepository{ctrl: ctrl}
+	mock.recorder = &MockResponseRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResponseRepository) EXPECT() *MockResponseRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Load mocks base method.
+func (m *MockResponseRepository) Load(id string) (*entities.StoredResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", id)
+	ret0, _ := ret[0].(*entities.StoredResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockResponseRepositoryMockRecorder) Load(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockResponseRepository)(nil).Load), id)
+}
+
+// Save mocks base method.
+func (m *MockResponseRepository) Save(response *entities.StoredResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockResponseRepositoryMockRecorder) Save(response any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockResponseRepository)(nil).Save), response)
+}
+
+// MockAPIKeyRepository is a mock of APIKeyRepository interface.
+type MockAPIKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockAPIKeyRepositoryMockRecorder is the mock recorder for MockAPIKeyRepository.
+type MockAPIKeyRepositoryMockRecorder struct {
+	mock *MockAPIKeyRepository
+}
+
+// NewMockAPIKeyRepository creates a new mock instance.
+func NewMockAPIKeyRepository(ctrl *gomock.Controller) *MockAPIKeyRepository {
+	mock := &MockAPIKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyRepository) EXPECT() *MockAPIKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockAPIKeyRepository) List() ([]*entities.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAPIKeyRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAPIKeyRepository)(nil).List))
+}
+
+// Save mocks base method.
+func (m *MockAPIKeyRepository) Save(key *entities.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockAPIKeyRepositoryMockRecorder) Save(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockAPIKeyRepository)(nil).Save), key)
+}
+
+// MockCompletionCache is a mock of CompletionCache interface.
+type MockCompletionCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockCompletionCacheMockRecorder
+	isgomock struct{}
+}
+
+// MockCompletionCacheMockRecorder is the mock recorder for MockCompletionCache.
+type MockCompletionCacheMockRecorder struct {
+	mock *MockCompletionCache
+}
+
+// NewMockCompletionCache creates a new mock instance.
+func NewMockCompletionCache(ctrl *gomock.Controller) *MockCompletionCache {
+	mock := &MockCompletionCache{ctrl: ctrl}
+	mock.recorder = &MockCompletionCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCompletionCache) EXPECT() *MockCompletionCacheMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockCompletionCache) Get(key string) (*entities.ChatCompletionResponse, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", key)
+	ret0, _ := ret[0].(*entities.ChatCompletionResponse)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockCompletionCacheMockRecorder) Get(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCompletionCache)(nil).Get), key)
+}
+
+// Set mocks base method.
+func (m *MockCompletionCache) Set(key string, response *entities.ChatCompletionResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", key, response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockCompletionCacheMockRecorder) Set(key, response any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCompletionCache)(nil).Set), key, response)
+}
+
 // MockOAuthService is a mock of OAuthService interface.
 type MockOAuthService struct {
 	ctrl     *gomock.Controller
@@ -94,19 +255,19 @@ func (m *MockOAuthService) EXPECT() *MockOAuthServiceMockRecorder {
 	return m.recorder
 }
 
-// AuthenticateWithDeviceFlow mocks base method.
-func (m *MockOAuthService) AuthenticateWithDeviceFlow(clientID, scope string) (*entities.Credentials, error) {
+// PollDeviceToken mocks base method.
+func (m *MockOAuthService) PollDeviceToken(ctx context.Context, clientID string, authorization *entities.DeviceAuthorization) (*entities.Credentials, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AuthenticateWithDeviceFlow", clientID, scope)
+	ret := m.ctrl.Call(m, "PollDeviceToken", ctx, clientID, authorization)
 	ret0, _ := ret[0].(*entities.Credentials)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AuthenticateWithDeviceFlow indicates an expected call of AuthenticateWithDeviceFlow.
-func (mr *MockOAuthServiceMockRecorder) AuthenticateWithDeviceFlow(clientID, scope any) *gomock.Call {
+// PollDeviceToken indicates an expected call of PollDeviceToken.
+func (mr *MockOAuthServiceMockRecorder) PollDeviceToken(ctx, clientID, authorization any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateWithDeviceFlow", reflect.TypeOf((*MockOAuthService)(nil).AuthenticateWithDeviceFlow), clientID, scope)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PollDeviceToken", reflect.TypeOf((*MockOAuthService)(nil).PollDeviceToken), ctx, clientID, authorization)
 }
 
 // RefreshToken mocks base method.
@@ -124,6 +285,59 @@ func (mr *MockOAuthServiceMockRecorder) RefreshToken(refreshToken, clientID any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockOAuthService)(nil).RefreshToken), refreshToken, clientID)
 }
 
+// StartDeviceFlow mocks base method.
+func (m *MockOAuthService) StartDeviceFlow(ctx context.Context, clientID, scope string) (*entities.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartDeviceFlow", ctx, clientID, scope)
+	ret0, _ := ret[0].(*entities.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDeviceFlow indicates an expected call of StartDeviceFlow.
+func (mr *MockOAuthServiceMockRecorder) StartDeviceFlow(ctx, clientID, scope any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDeviceFlow", reflect.TypeOf((*MockOAuthService)(nil).StartDeviceFlow), ctx, clientID, scope)
+}
+
+// MockDeviceFlowNotifier is a mock of DeviceFlowNotifier interface.
+type MockDeviceFlowNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeviceFlowNotifierMockRecorder
+	isgomock struct{}
+}
+
+// MockDeviceFlowNotifierMockRecorder is the mock recorder for MockDeviceFlowNotifier.
+type MockDeviceFlowNotifierMockRecorder struct {
+	mock *MockDeviceFlowNotifier
+}
+
+// NewMockDeviceFlowNotifier creates a new mock instance.
+func NewMockDeviceFlowNotifier(ctrl *gomock.Controller) *MockDeviceFlowNotifier {
+	mock := &MockDeviceFlowNotifier{ctrl: ctrl}
+	mock.recorder = &MockDeviceFlowNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeviceFlowNotifier) EXPECT() *MockDeviceFlowNotifierMockRecorder {
+	return m.recorder
+}
+
+// NotifyDeviceFlow mocks base method.
+func (m *MockDeviceFlowNotifier) NotifyDeviceFlow(ctx context.Context, status *entities.DeviceFlowStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NotifyDeviceFlow", ctx, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// NotifyDeviceFlow indicates an expected call of NotifyDeviceFlow.
+func (mr *MockDeviceFlowNotifierMockRecorder) NotifyDeviceFlow(ctx, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyDeviceFlow", reflect.TypeOf((*MockDeviceFlowNotifier)(nil).NotifyDeviceFlow), ctx, status)
+}
+
 // MockAIService is a mock of AIService interface.
 type MockAIService struct {
 	ctrl     *gomock.Controller
@@ -149,18 +363,33 @@ func (m *MockAIService) EXPECT() *MockAIServiceMockRecorder {
 }
 
 // ChatCompletions mocks base method.
-func (m *MockAIService) ChatCompletions(req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+func (m *MockAIService) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChatCompletions", req, credentials)
+	ret := m.ctrl.Call(m, "ChatCompletions", ctx, req, credentials)
 	ret0, _ := ret[0].(*http.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ChatCompletions indicates an expected call of ChatCompletions.
-func (mr *MockAIServiceMockRecorder) ChatCompletions(req, credentials any) *gomock.Call {
+func (mr *MockAIServiceMockRecorder) ChatCompletions(ctx, req, credentials any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChatCompletions", reflect.TypeOf((*MockAIService)(nil).ChatCompletions), ctx, req, credentials)
+}
+
+// Embeddings mocks base method.
+func (m *MockAIService) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Embeddings", ctx, req, credentials)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Embeddings indicates an expected call of Embeddings.
+func (mr *MockAIServiceMockRecorder) Embeddings(ctx, req, credentials any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChatCompletions", reflect.TypeOf((*MockAIService)(nil).ChatCompletions), req, credentials)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Embeddings", reflect.TypeOf((*MockAIService)(nil).Embeddings), ctx, req, credentials)
 }
 
 // GetBaseURL mocks base method.
@@ -178,6 +407,21 @@ func (mr *MockAIServiceMockRecorder) GetBaseURL(credentials, defaultURL any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBaseURL", reflect.TypeOf((*MockAIService)(nil).GetBaseURL), credentials, defaultURL)
 }
 
+// ListModels mocks base method.
+func (m *MockAIService) ListModels(ctx context.Context, credentials *entities.Credentials) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListModels", ctx, credentials)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListModels indicates an expected call of ListModels.
+func (mr *MockAIServiceMockRecorder) ListModels(ctx, credentials any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListModels", reflect.TypeOf((*MockAIService)(nil).ListModels), ctx, credentials)
+}
+
 // MockStreamingService is a mock of StreamingService interface.
 type MockStreamingService struct {
 	ctrl     *gomock.Controller
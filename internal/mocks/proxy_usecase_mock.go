@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	http "net/http"
 	entities "qwen-go-proxy/internal/domain/entities"
 	reflect "reflect"
@@ -41,33 +42,33 @@ func (m *MockProxyUseCaseInterface) EXPECT() *MockProxyUseCaseInterfaceMockRecor
 	return m.recorder
 }
 
-// AuthenticateManually mocks base method.
-func (m *MockProxyUseCaseInterface) AuthenticateManually() error {
+// AuthState mocks base method.
+func (m *MockProxyUseCaseInterface) AuthState() string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AuthenticateManually")
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "AuthState")
+	ret0, _ := ret[0].(string)
 	return ret0
 }
 
-// AuthenticateManually indicates an expected call of AuthenticateManually.
-func (mr *MockProxyUseCaseInterfaceMockRecorder) AuthenticateManually() *gomock.Call {
+// AuthState indicates an expected call of AuthState.
+func (mr *MockProxyUseCaseInterfaceMockRecorder) AuthState() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateManually", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).AuthenticateManually))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthState", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).AuthState))
 }
 
 // ChatCompletions mocks base method.
-func (m *MockProxyUseCaseInterface) ChatCompletions(req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+func (m *MockProxyUseCaseInterface) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChatCompletions", req)
+	ret := m.ctrl.Call(m, "ChatCompletions", ctx, req)
 	ret0, _ := ret[0].(*entities.ChatCompletionResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ChatCompletions indicates an expected call of ChatCompletions.
-func (mr *MockProxyUseCaseInterfaceMockRecorder) ChatCompletions(req any) *gomock.Call {
+func (mr *MockProxyUseCaseInterfaceMockRecorder) ChatCompletions(ctx, req any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChatCompletions", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).ChatCompletions), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChatCompletions", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).ChatCompletions), ctx, req)
 }
 
 // CheckAuthentication mocks base method.
@@ -85,31 +86,90 @@ func (mr *MockProxyUseCaseInterfaceMockRecorder) CheckAuthentication() *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAuthentication", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).CheckAuthentication))
 }
 
+// DeviceFlowStatus mocks base method.
+func (m *MockProxyUseCaseInterface) DeviceFlowStatus() *entities.DeviceFlowStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeviceFlowStatus")
+	ret0, _ := ret[0].(*entities.DeviceFlowStatus)
+	return ret0
+}
+
+// DeviceFlowStatus indicates an expected call of DeviceFlowStatus.
+func (mr *MockProxyUseCaseInterfaceMockRecorder) DeviceFlowStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeviceFlowStatus", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).DeviceFlowStatus))
+}
+
+// Embeddings mocks base method.
+func (m *MockProxyUseCaseInterface) Embeddings(ctx context.Context, req *entities.EmbeddingRequest) (*entities.EmbeddingResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Embeddings", ctx, req)
+	ret0, _ := ret[0].(*entities.EmbeddingResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Embeddings indicates an expected call of Embeddings.
+func (mr *MockProxyUseCaseInterfaceMockRecorder) Embeddings(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Embeddings", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).Embeddings), ctx, req)
+}
+
+// GetModel mocks base method.
+func (m *MockProxyUseCaseInterface) GetModel(ctx context.Context, id string) (*entities.ModelInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetModel", ctx, id)
+	ret0, _ := ret[0].(*entities.ModelInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetModel indicates an expected call of GetModel.
+func (mr *MockProxyUseCaseInterfaceMockRecorder) GetModel(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModel", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).GetModel), ctx, id)
+}
+
 // GetModels mocks base method.
-func (m *MockProxyUseCaseInterface) GetModels() ([]*entities.ModelInfo, error) {
+func (m *MockProxyUseCaseInterface) GetModels(ctx context.Context) ([]*entities.ModelInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetModels")
+	ret := m.ctrl.Call(m, "GetModels", ctx)
 	ret0, _ := ret[0].([]*entities.ModelInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetModels indicates an expected call of GetModels.
-func (mr *MockProxyUseCaseInterfaceMockRecorder) GetModels() *gomock.Call {
+func (mr *MockProxyUseCaseInterfaceMockRecorder) GetModels(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModels", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).GetModels), ctx)
+}
+
+// StartDeviceFlow mocks base method.
+func (m *MockProxyUseCaseInterface) StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartDeviceFlow", ctx)
+	ret0, _ := ret[0].(*entities.DeviceFlowStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDeviceFlow indicates an expected call of StartDeviceFlow.
+func (mr *MockProxyUseCaseInterfaceMockRecorder) StartDeviceFlow(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModels", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).GetModels))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDeviceFlow", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).StartDeviceFlow), ctx)
 }
 
 // StreamChatCompletions mocks base method.
-func (m *MockProxyUseCaseInterface) StreamChatCompletions(req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+func (m *MockProxyUseCaseInterface) StreamChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "StreamChatCompletions", req, writer)
+	ret := m.ctrl.Call(m, "StreamChatCompletions", ctx, req, writer)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // StreamChatCompletions indicates an expected call of StreamChatCompletions.
-func (mr *MockProxyUseCaseInterfaceMockRecorder) StreamChatCompletions(req, writer any) *gomock.Call {
+func (mr *MockProxyUseCaseInterfaceMockRecorder) StreamChatCompletions(ctx, req, writer any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamChatCompletions", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).StreamChatCompletions), req, writer)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamChatCompletions", reflect.TypeOf((*MockProxyUseCaseInterface)(nil).StreamChatCompletions), ctx, req, writer)
 }
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	http "net/http"
 	entities "qwen-go-proxy/internal/domain/entities"
 	reflect "reflect"
@@ -42,18 +43,33 @@ func (m *MockQwenAPIGateway) EXPECT() *MockQwenAPIGatewayMockRecorder {
 }
 
 // ChatCompletions mocks base method.
-func (m *MockQwenAPIGateway) ChatCompletions(req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
+func (m *MockQwenAPIGateway) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, credentials *entities.Credentials) (*http.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChatCompletions", req, credentials)
+	ret := m.ctrl.Call(m, "ChatCompletions", ctx, req, credentials)
 	ret0, _ := ret[0].(*http.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ChatCompletions indicates an expected call of ChatCompletions.
-func (mr *MockQwenAPIGatewayMockRecorder) ChatCompletions(req, credentials any) *gomock.Call {
+func (mr *MockQwenAPIGatewayMockRecorder) ChatCompletions(ctx, req, credentials any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChatCompletions", reflect.TypeOf((*MockQwenAPIGateway)(nil).ChatCompletions), req, credentials)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChatCompletions", reflect.TypeOf((*MockQwenAPIGateway)(nil).ChatCompletions), ctx, req, credentials)
+}
+
+// Embeddings mocks base method.
+func (m *MockQwenAPIGateway) Embeddings(ctx context.Context, req *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Embeddings", ctx, req, credentials)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Embeddings indicates an expected call of Embeddings.
+func (mr *MockQwenAPIGatewayMockRecorder) Embeddings(ctx, req, credentials any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Embeddings", reflect.TypeOf((*MockQwenAPIGateway)(nil).Embeddings), ctx, req, credentials)
 }
 
 // GetBaseURL mocks base method.
@@ -70,3 +86,87 @@ func (mr *MockQwenAPIGatewayMockRecorder) GetBaseURL(credentials, defaultURL any
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBaseURL", reflect.TypeOf((*MockQwenAPIGateway)(nil).GetBaseURL), credentials, defaultURL)
 }
+
+// ListModels mocks base method.
+func (m *MockQwenAPIGateway) ListModels(ctx context.Context, credentials *entities.Credentials) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListModels", ctx, credentials)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListModels indicates an expected call of ListModels.
+func (mr *MockQwenAPIGatewayMockRecorder) ListModels(ctx, credentials any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListModels", reflect.TypeOf((*MockQwenAPIGateway)(nil).ListModels), ctx, credentials)
+}
+
+// MockOAuthGateway is a mock of OAuthGateway interface.
+type MockOAuthGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthGatewayMockRecorder
+	isgomock struct{}
+}
+
+// MockOAuthGatewayMockRecorder is the mock recorder for MockOAuthGateway.
+type MockOAuthGatewayMockRecorder struct {
+	mock *MockOAuthGateway
+}
+
+// NewMockOAuthGateway creates a new mock instance.
+func NewMockOAuthGateway(ctrl *gomock.Controller) *MockOAuthGateway {
+	mock := &MockOAuthGateway{ctrl: ctrl}
+	mock.recorder = &MockOAuthGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthGateway) EXPECT() *MockOAuthGatewayMockRecorder {
+	return m.recorder
+}
+
+// PollDeviceToken mocks base method.
+func (m *MockOAuthGateway) PollDeviceToken(ctx context.Context, clientID string, authorization *entities.DeviceAuthorization) (*entities.Credentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PollDeviceToken", ctx, clientID, authorization)
+	ret0, _ := ret[0].(*entities.Credentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PollDeviceToken indicates an expected call of PollDeviceToken.
+func (mr *MockOAuthGatewayMockRecorder) PollDeviceToken(ctx, clientID, authorization any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PollDeviceToken", reflect.TypeOf((*MockOAuthGateway)(nil).PollDeviceToken), ctx, clientID, authorization)
+}
+
+// RefreshToken mocks base method.
+func (m *MockOAuthGateway) RefreshToken(refreshToken, clientID string) (*entities.Credentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", refreshToken, clientID)
+	ret0, _ := ret[0].(*entities.Credentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockOAuthGatewayMockRecorder) RefreshToken(refreshToken, clientID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockOAuthGateway)(nil).RefreshToken), refreshToken, clientID)
+}
+
+// StartDeviceFlow mocks base method.
+func (m *MockOAuthGateway) StartDeviceFlow(ctx context.Context, clientID, scope string) (*entities.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartDeviceFlow", ctx, clientID, scope)
+	ret0, _ := ret[0].(*entities.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDeviceFlow indicates an expected call of StartDeviceFlow.
+func (mr *MockOAuthGatewayMockRecorder) StartDeviceFlow(ctx, clientID, scope any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDeviceFlow", reflect.TypeOf((*MockOAuthGateway)(nil).StartDeviceFlow), ctx, clientID, scope)
+}
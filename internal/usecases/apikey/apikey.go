@@ -0,0 +1,217 @@
+// Package apikey implements inbound proxy API key management and verification.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+	"qwen-go-proxy/internal/infrastructure/logging"
+)
+
+const (
+	// KeyPrefix is prepended to every generated API key so keys are easy to recognise
+	KeyPrefix = "sk-qwen-"
+
+	// displayPrefixLength is the number of key characters kept for identification
+	displayPrefixLength = len(KeyPrefix) + 4
+)
+
+// API key verification errors
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyExpired  = errors.New("api key has expired")
+	ErrAPIKeyRevoked  = errors.New("api key has been revoked")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKeyUseCaseInterface defines the interface for API key operations
+type APIKeyUseCaseInterface interface {
+	CreateKey(req *entities.APIKeyCreateRequest) (*entities.APIKey, string, error)
+	ListKeys() ([]*entities.APIKey, error)
+	RevokeKey(id string) (*entities.APIKey, error)
+	Authenticate(rawKey string) (*entities.APIKey, error)
+}
+
+// APIKeyUseCase manages inbound API keys.
+// Keys are cached in memory by hash after the first load so that verification does not hit the disk.
+type APIKeyUseCase struct {
+	repo   interfaces.APIKeyRepository
+	logger logging.LoggerInterface
+	byHash map[string]*entities.APIKey
+	mu     sync.RWMutex
+}
+
+// NewAPIKeyUseCase creates a new API key use case
+func NewAPIKeyUseCase(repo interfaces.APIKeyRepository, logger logging.LoggerInterface) *APIKeyUseCase {
+	if repo == nil {
+		panic("repo cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	return &APIKeyUseCase{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateKey generates a new API key with the given policy.
+// The plaintext key is returned once and cannot be recovered afterwards.
+func (uc *APIKeyUseCase) CreateKey(req *entities.APIKeyCreateRequest) (*entities.APIKey, string, error) {
+	if req == nil {
+		return nil, "", fmt.Errorf("request cannot be nil")
+	}
+	if strings.TrimSpace(req.Label) == "" {
+		return nil, "", fmt.Errorf("label is required")
+	}
+	if req.RateLimit < 0 {
+		return nil, "", fmt.Errorf("rate_limit must be non-negative")
+	}
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, "", fmt.Errorf("expires_at must be in the future")
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key id: %w", err)
+	}
+	rawKey := KeyPrefix + secret
+
+	key := &entities.APIKey{
+		ID:            "key_" + id,
+		Label:         req.Label,
+		Hash:          hashKey(rawKey),
+		Prefix:        rawKey[:displayPrefixLength],
+		AllowedModels: req.AllowedModels,
+		ExpiresAt:     req.ExpiresAt,
+		RateLimit:     req.RateLimit,
+		CreatedAt:     now,
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if err := uc.loadLocked(); err != nil {
+		return nil, "", err
+	}
+	if err := uc.repo.Save(key); err != nil {
+		return nil, "", fmt.Errorf("failed to save api key: %w", err)
+	}
+	uc.byHash[key.Hash] = key
+
+	uc.logger.Info("API key created", "key_id", key.ID, "label", key.Label)
+	return key, rawKey, nil
+}
+
+// ListKeys returns all API keys, including revoked and expired ones
+func (uc *APIKeyUseCase) ListKeys() ([]*entities.APIKey, error) {
+	return uc.repo.List()
+}
+
+// RevokeKey revokes an API key so that it can no longer be used
+func (uc *APIKeyUseCase) RevokeKey(id string) (*entities.APIKey, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if err := uc.loadLocked(); err != nil {
+		return nil, err
+	}
+
+	for _, key := range uc.byHash {
+		if key.ID != id {
+			continue
+		}
+		if key.IsRevoked() {
+			return key, nil
+		}
+		revoked := *key
+		now := time.Now().UTC()
+		revoked.RevokedAt = &now
+		if err := uc.repo.Save(&revoked); err != nil {
+			return nil, fmt.Errorf("failed to save api key: %w", err)
+		}
+		uc.byHash[key.Hash] = &revoked
+
+		uc.logger.Info("API key revoked", "key_id", id, "label", key.Label)
+		return &revoked, nil
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+// Authenticate verifies a plaintext API key and returns its policy
+func (uc *APIKeyUseCase) Authenticate(rawKey string) (*entities.APIKey, error) {
+	if rawKey == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	hash := hashKey(rawKey)
+
+	uc.mu.RLock()
+	loaded := uc.byHash != nil
+	key := uc.byHash[hash]
+	uc.mu.RUnlock()
+
+	if !loaded {
+		uc.mu.Lock()
+		err := uc.loadLocked()
+		key = uc.byHash[hash]
+		uc.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.IsRevoked() {
+		return nil, ErrAPIKeyRevoked
+	}
+	if key.IsExpired(time.Now()) {
+		return nil, ErrAPIKeyExpired
+	}
+	return key, nil
+}
+
+// loadLocked populates the in-memory key cache on first use; callers must hold the write lock
+func (uc *APIKeyUseCase) loadLocked() error {
+	if uc.byHash != nil {
+		return nil
+	}
+	keys, err := uc.repo.List()
+	if err != nil {
+		return fmt.Errorf("failed to load api keys: %w", err)
+	}
+	uc.byHash = make(map[string]*entities.APIKey, len(keys))
+	for _, key := range keys {
+		uc.byHash[key.Hash] = key
+	}
+	return nil
+}
+
+// hashKey returns the hex-encoded SHA-256 hash of a plaintext key
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,138 @@
+package apikey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestUseCase(t *testing.T) (*APIKeyUseCase, *mocks.MockAPIKeyRepository) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockAPIKeyRepository(ctrl)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+	return NewAPIKeyUseCase(repo, logger), repo
+}
+
+func TestNewAPIKeyUseCase_Panics(t *testing.T) {
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	assert.PanicsWithValue(t, "repo cannot be nil", func() {
+		NewAPIKeyUseCase(nil, logger)
+	})
+	assert.PanicsWithValue(t, "logger cannot be nil", func() {
+		NewAPIKeyUseCase(mocks.NewMockAPIKeyRepository(gomock.NewController(t)), nil)
+	})
+}
+
+func TestAPIKeyUseCase_CreateKey_Authenticate(t *testing.T) {
+	uc, repo := newTestUseCase(t)
+
+	var saved *entities.APIKey
+	repo.EXPECT().List().Return([]*entities.APIKey{}, nil)
+	repo.EXPECT().Save(gomock.Any()).DoAndReturn(func(key *entities.APIKey) error {
+		saved = key
+		return nil
+	})
+
+	key, rawKey, err := uc.CreateKey(&entities.APIKeyCreateRequest{
+		Label:         "ci",
+		AllowedModels: []string{"qwen3-coder-plus"},
+		RateLimit:     30,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(rawKey, KeyPrefix))
+	assert.True(t, strings.HasPrefix(rawKey, key.Prefix))
+	assert.NotContains(t, key.Hash, rawKey)
+	assert.Equal(t, hashKey(rawKey), saved.Hash)
+	assert.Equal(t, "ci", key.Label)
+	assert.Equal(t, 30, key.RateLimit)
+
+	authenticated, err := uc.Authenticate(rawKey)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+
+	_, err = uc.Authenticate(rawKey + "x")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyUseCase_CreateKey_Validation(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+
+	_, _, err := uc.CreateKey(&entities.APIKeyCreateRequest{})
+	assert.EqualError(t, err, "label is required")
+
+	_, _, err = uc.CreateKey(&entities.APIKeyCreateRequest{Label: "x", RateLimit: -1})
+	assert.EqualError(t, err, "rate_limit must be non-negative")
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = uc.CreateKey(&entities.APIKeyCreateRequest{Label: "x", ExpiresAt: &past})
+	assert.EqualError(t, err, "expires_at must be in the future")
+}
+
+func TestAPIKeyUseCase_Authenticate_LoadsFromRepository(t *testing.T) {
+	uc, repo := newTestUseCase(t)
+
+	expired := time.Now().Add(-time.Minute)
+	revoked := time.Now().Add(-time.Hour)
+	repo.EXPECT().List().Return([]*entities.APIKey{
+		{ID: "key_valid", Hash: hashKey("sk-qwen-valid")},
+		{ID: "key_expired", Hash: hashKey("sk-qwen-expired"), ExpiresAt: &expired},
+		{ID: "key_revoked", Hash: hashKey("sk-qwen-revoked"), RevokedAt: &revoked},
+	}, nil).Times(1)
+
+	key, err := uc.Authenticate("sk-qwen-valid")
+	require.NoError(t, err)
+	assert.Equal(t, "key_valid", key.ID)
+
+	_, err = uc.Authenticate("sk-qwen-expired")
+	assert.ErrorIs(t, err, ErrAPIKeyExpired)
+
+	_, err = uc.Authenticate("sk-qwen-revoked")
+	assert.ErrorIs(t, err, ErrAPIKeyRevoked)
+
+	_, err = uc.Authenticate("")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyUseCase_Authenticate_RepositoryError(t *testing.T) {
+	uc, repo := newTestUseCase(t)
+
+	repo.EXPECT().List().Return(nil, errors.New("disk error"))
+
+	_, err := uc.Authenticate("sk-qwen-valid")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load api keys")
+}
+
+func TestAPIKeyUseCase_RevokeKey(t *testing.T) {
+	uc, repo := newTestUseCase(t)
+
+	repo.EXPECT().List().Return([]*entities.APIKey{
+		{ID: "key_1", Hash: hashKey("sk-qwen-one")},
+	}, nil)
+	repo.EXPECT().Save(gomock.Any()).DoAndReturn(func(key *entities.APIKey) error {
+		assert.Equal(t, "key_1", key.ID)
+		assert.NotNil(t, key.RevokedAt)
+		return nil
+	})
+
+	key, err := uc.RevokeKey("key_1")
+	require.NoError(t, err)
+	assert.True(t, key.IsRevoked())
+
+	_, err = uc.Authenticate("sk-qwen-one")
+	assert.ErrorIs(t, err, ErrAPIKeyRevoked)
+
+	_, err = uc.RevokeKey("key_missing")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
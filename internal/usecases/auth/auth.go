@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -8,6 +10,8 @@ import (
 	"qwen-go-proxy/internal/domain/entities"
 	"qwen-go-proxy/internal/domain/interfaces"
 	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/metrics"
+	"qwen-go-proxy/internal/infrastructure/tracing"
 )
 
 // AuthUseCase defines the authentication use case
@@ -15,13 +19,59 @@ type AuthUseCase struct {
 	config         *entities.Config
 	oauthService   interfaces.OAuthService
 	credentialRepo interfaces.CredentialRepository
+	notifier       interfaces.DeviceFlowNotifier
 	logger         *logging.Logger
 	tokenMutex     sync.RWMutex
 	refreshMutex   sync.Mutex
+	// flowMutex guards the device flow and the outcome of the last token refresh
+	flowMutex     sync.Mutex
+	flow          *deviceFlow
+	flowStart     *deviceFlowStart
+	refreshErr    error
+	lastRefreshAt time.Time
+}
+
+// AuthenticationRequiredError is returned when no usable credentials are available.
+// Device flow is never started on the request path, so the error names the action that restores authentication.
+type AuthenticationRequiredError struct {
+	// State is the entities.AuthState the account is in
+	State string
+	// Account names the pooled account that needs authentication, empty outside a credential pool
+	Account string
+	// DeviceFlowAccount reports whether GET /auth authenticates the pooled account
+	DeviceFlowAccount bool
+	Err               error
+}
+
+// Error implements the error interface with a message that names the recovery action
+func (e *AuthenticationRequiredError) Error() string {
+	account := ""
+	action := "GET /auth or `qwen-go-proxy auth`"
+	if e.Account != "" {
+		account = fmt.Sprintf(" of account %q", e.Account)
+		action = fmt.Sprintf("`qwen-go-proxy auth --account %s`", e.Account)
+		if e.DeviceFlowAccount {
+			action = "GET /auth or " + action
+		}
+	}
+
+	switch e.State {
+	case entities.AuthStateAuthenticating:
+		return "Qwen device authentication is in progress. Approve it at the verification URL returned by GET /auth, and follow it with GET /auth/status."
+	case entities.AuthStateRefreshFailed:
+		return fmt.Sprintf("Refreshing the Qwen OAuth token%s failed (%v). Re-authenticate with %s.", account, e.Err, action)
+	default:
+		return fmt.Sprintf("No Qwen OAuth credentials%s are available. Authenticate with %s.", account, action)
+	}
+}
+
+// Unwrap returns the error that made authentication unavailable
+func (e *AuthenticationRequiredError) Unwrap() error {
+	return e.Err
 }
 
 // NewAuthUseCase creates a new authentication use case
-func NewAuthUseCase(config *entities.Config, oauthService interfaces.OAuthService, credentialRepo interfaces.CredentialRepository, logger *logging.Logger) *AuthUseCase {
+func NewAuthUseCase(config *entities.Config, oauthService interfaces.OAuthService, credentialRepo interfaces.CredentialRepository, notifier interfaces.DeviceFlowNotifier, logger *logging.Logger) *AuthUseCase {
 	if config == nil {
 		panic("config cannot be nil")
 	}
@@ -31,6 +81,9 @@ func NewAuthUseCase(config *entities.Config, oauthService interfaces.OAuthServic
 	if credentialRepo == nil {
 		panic("credentialRepo cannot be nil")
 	}
+	if notifier == nil {
+		panic("notifier cannot be nil")
+	}
 	if logger == nil {
 		panic("logger cannot be nil")
 	}
@@ -38,27 +91,40 @@ func NewAuthUseCase(config *entities.Config, oauthService interfaces.OAuthServic
 		config:         config,
 		oauthService:   oauthService,
 		credentialRepo: credentialRepo,
+		notifier:       notifier,
 		logger:         logger,
 	}
 }
 
-// EnsureAuthenticated ensures valid credentials are available, performing device auth if needed
-func (uc *AuthUseCase) EnsureAuthenticated() (*entities.Credentials, error) {
+// EnsureAuthenticated ensures valid credentials are available, refreshing them when they are about to expire.
+// It never starts device flow: without usable credentials it fails fast with an *AuthenticationRequiredError.
+func (uc *AuthUseCase) EnsureAuthenticated(ctx context.Context) (*entities.Credentials, error) {
+	ctx, span := tracing.Start(ctx, "AuthUseCase.EnsureAuthenticated")
+	credentials, err := uc.ensureAuthenticated(ctx)
+	tracing.End(span, err)
+	return credentials, err
+}
+
+// ensureAuthenticated loads the stored credentials and refreshes them when they are about to expire
+func (uc *AuthUseCase) ensureAuthenticated(ctx context.Context) (*entities.Credentials, error) {
+	return uc.refreshWithin(ctx, uc.config.TokenRefreshBuffer)
+}
+
+// refreshWithin loads the stored credentials and refreshes them when they expire within the buffer
+func (uc *AuthUseCase) refreshWithin(ctx context.Context, buffer time.Duration) (*entities.Credentials, error) {
 	uc.tokenMutex.RLock()
 	credentials, err := uc.credentialRepo.Load()
 	uc.tokenMutex.RUnlock()
 
 	if err != nil {
-		// No credentials found, perform device authentication
-		uc.logger.Info("No credentials found, initiating device authentication")
-		return uc.authenticateWithDeviceFlow()
+		return nil, uc.authenticationRequired(entities.AuthStateUnauthenticated, err)
 	}
 
 	uc.tokenMutex.RLock()
 	// Check if token is expired or close to expiring
 	now := time.Now().UnixMilli()
 	timeUntilExpiry := credentials.ExpiryDate - now
-	bufferInMillis := uc.config.TokenRefreshBuffer.Milliseconds()
+	bufferInMillis := buffer.Milliseconds()
 	isExpired := timeUntilExpiry < bufferInMillis
 	uc.tokenMutex.RUnlock()
 
@@ -81,10 +147,22 @@ func (uc *AuthUseCase) EnsureAuthenticated() (*entities.Credentials, error) {
 
 		if isStillExpired {
 			uc.logger.Info("Qwen token expired or close to expiring, refreshing")
-			newCredentials, err := uc.refreshAccessToken(credentials)
+			newCredentials, err := uc.refreshAccessToken(ctx, credentials)
 			if err != nil {
-				uc.logger.Warn("Failed to refresh token, falling back to device authentication", "error", err)
-				return uc.authenticateWithDeviceFlow()
+				if stored := uc.refreshedElsewhere(credentials, bufferInMillis); stored != nil {
+					uc.logger.Info("Token refresh failed after another writer refreshed the token, using the stored credentials", "error", err)
+					newCredentials, err = stored, nil
+				}
+			}
+			uc.flowMutex.Lock()
+			uc.refreshErr = err
+			if err == nil {
+				uc.lastRefreshAt = time.Now()
+			}
+			uc.flowMutex.Unlock()
+			if err != nil {
+				uc.logger.Warn("Failed to refresh token, device authentication is required", "error", err)
+				return nil, uc.authenticationRequired(entities.AuthStateRefreshFailed, err)
 			}
 			return newCredentials, nil
 		} else {
@@ -99,63 +177,136 @@ func (uc *AuthUseCase) EnsureAuthenticated() (*entities.Credentials, error) {
 }
 
 // refreshAccessToken refreshes the access token
-func (uc *AuthUseCase) refreshAccessToken(credentials *entities.Credentials) (*entities.Credentials, error) {
+func (uc *AuthUseCase) refreshAccessToken(ctx context.Context, credentials *entities.Credentials) (newCredentials *entities.Credentials, err error) {
+	_, span := tracing.Start(ctx, "AuthUseCase.RefreshToken")
+	defer func() { tracing.End(span, err) }()
+
 	if credentials.RefreshToken == "" {
 		return nil, fmt.Errorf("no refresh token available in credentials")
 	}
 
-	newCredentials, err := uc.oauthService.RefreshToken(credentials.RefreshToken, uc.config.QWENOAuthClientID)
+	newCredentials, err = uc.oauthService.RefreshToken(credentials.RefreshToken, uc.config.QWENOAuthClientID)
+	metrics.ObserveTokenRefresh(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
 
-	// Keep existing resource URL
+	// Keep existing resource URL, and replace only the version that was refreshed
 	newCredentials.ResourceURL = credentials.ResourceURL
+	newCredentials.Version = credentials.Version
 
 	uc.tokenMutex.Lock()
 	defer uc.tokenMutex.Unlock()
 
 	if err := uc.credentialRepo.Save(newCredentials); err != nil {
+		if errors.Is(err, entities.ErrCredentialConflict) {
+			// Another replica sharing the credential store refreshed the token first, and its
+			// credentials are the ones every replica uses from now on
+			if stored, loadErr := uc.credentialRepo.Load(); loadErr == nil {
+				uc.logger.Info("Token was refreshed by another writer, using the stored credentials")
+				return stored, nil
+			}
+		}
 		return nil, fmt.Errorf("failed to save refreshed credentials: %w", err)
 	}
 
 	return newCredentials, nil
 }
 
-// authenticateWithDeviceFlow performs OAuth2 device authorization flow
-func (uc *AuthUseCase) authenticateWithDeviceFlow() (*entities.Credentials, error) {
-	credentials, err := uc.oauthService.AuthenticateWithDeviceFlow(uc.config.QWENOAuthClientID, uc.config.QWENOAuthScope)
+// refreshedElsewhere reloads the credentials after a failed refresh and returns them when another
+// writer sharing the credential store replaced the refreshed ones in the meantime: when the version
+// has moved on, or, for stores without versions, when the stored token differs and is not within the
+// buffer. Using a refresh token invalidates it, so a replica that loses the race to refresh fails
+// upstream rather than with entities.ErrCredentialConflict. Otherwise it returns nil.
+func (uc *AuthUseCase) refreshedElsewhere(refreshed *entities.Credentials, bufferInMillis int64) *entities.Credentials {
+	uc.tokenMutex.RLock()
+	stored, err := uc.credentialRepo.Load()
+	uc.tokenMutex.RUnlock()
 	if err != nil {
-		return nil, fmt.Errorf("device authentication failed: %w", err)
+		return nil
 	}
 
-	// Save the credentials
-	uc.tokenMutex.Lock()
-	defer uc.tokenMutex.Unlock()
+	if stored.Version != refreshed.Version {
+		return stored
+	}
+	changed := stored.AccessToken != refreshed.AccessToken || stored.RefreshToken != refreshed.RefreshToken
+	if changed && stored.ExpiryDate-time.Now().UnixMilli() >= bufferInMillis {
+		return stored
+	}
+	return nil
+}
 
-	if err := uc.credentialRepo.Save(credentials); err != nil {
-		return nil, fmt.Errorf("failed to save credentials: %w", err)
+// authenticationRequired returns the error for an account without usable credentials, which is
+// authenticating rather than in the given state while a device flow is pending
+func (uc *AuthUseCase) authenticationRequired(state string, err error) error {
+	uc.flowMutex.Lock()
+	defer uc.flowMutex.Unlock()
+	if uc.authenticating() {
+		state = entities.AuthStateAuthenticating
 	}
+	return &AuthenticationRequiredError{State: state, Err: err}
+}
 
-	uc.logger.Info("Device authentication successful, credentials saved")
-	return credentials, nil
+// AuthState returns the authentication state: authenticating while a device flow is pending,
+// unauthenticated without stored credentials, refresh_failed when the last token refresh failed
+// and the stored token is within the refresh buffer, and valid otherwise. A background refresh
+// that fails ahead of the buffer leaves the account valid until its token comes within it.
+func (uc *AuthUseCase) AuthState() string {
+	uc.flowMutex.Lock()
+	pending := uc.authenticating()
+	refreshFailed := uc.refreshErr != nil
+	uc.flowMutex.Unlock()
+	if pending {
+		return entities.AuthStateAuthenticating
+	}
+
+	uc.tokenMutex.RLock()
+	credentials, err := uc.credentialRepo.Load()
+	uc.tokenMutex.RUnlock()
+	switch {
+	case err != nil:
+		return entities.AuthStateUnauthenticated
+	case refreshFailed && credentials.ExpiryDate-time.Now().UnixMilli() < uc.config.TokenRefreshBuffer.Milliseconds():
+		return entities.AuthStateRefreshFailed
+	}
+	return entities.AuthStateValid
 }
 
-// AuthenticateManually triggers the OAuth2 device flow authentication manually
-func (uc *AuthUseCase) AuthenticateManually() error {
-	uc.logger.Info("Manual authentication requested")
-	_, err := uc.authenticateWithDeviceFlow()
-	return err
+// RefreshStatus returns the time of the last successful token refresh and the error of the last
+// refresh when it failed
+func (uc *AuthUseCase) RefreshStatus() entities.TokenRefreshStatus {
+	uc.flowMutex.Lock()
+	defer uc.flowMutex.Unlock()
+	var status entities.TokenRefreshStatus
+	if !uc.lastRefreshAt.IsZero() {
+		lastRefreshAt := uc.lastRefreshAt
+		status.LastRefreshAt = &lastRefreshAt
+	}
+	if uc.refreshErr != nil {
+		status.LastError = uc.refreshErr.Error()
+	}
+	return status
 }
 
-// CheckAuthentication checks if authentication is available without performing device flow
+// CheckAuthentication checks if authentication is available without performing device flow.
+// Credentials that are about to expire are still refreshed.
 func (uc *AuthUseCase) CheckAuthentication() (*entities.Credentials, error) {
-	return uc.EnsureAuthenticated()
+	return uc.EnsureAuthenticated(context.Background())
 }
 
 // AuthUseCaseInterface defines the interface for authentication operations
 type AuthUseCaseInterface interface {
-	EnsureAuthenticated() (*entities.Credentials, error)
-	AuthenticateManually() error
+	EnsureAuthenticated(ctx context.Context) (*entities.Credentials, error)
+	StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error)
+	DeviceFlowStatus() *entities.DeviceFlowStatus
 	CheckAuthentication() (*entities.Credentials, error)
+	AuthState() string
+}
+
+// CredentialPoolInterface defines the interface for rotating requests across several accounts
+type CredentialPoolInterface interface {
+	AuthUseCaseInterface
+	AcquireAccount(ctx context.Context, exclude map[string]bool) (*entities.AccountCredentials, error)
+	ReportThrottled(name string, retryAfter time.Duration)
+	AccountStatuses() []entities.AccountStatus
 }
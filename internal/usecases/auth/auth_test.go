@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"qwen-go-proxy/internal/mocks"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -35,10 +37,10 @@ func TestNewAuthUseCase(t *testing.T) {
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 	repo := &mockCredentialRepository{}
 
-	NewAuthUseCase(config, oauthService, repo, logger)
+	NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 }
 
-func TestAuthUseCase_EnsureAuthenticated_NoCredentials_DeviceFlowSuccess(t *testing.T) {
+func TestAuthUseCase_EnsureAuthenticated_NoCredentials(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -48,20 +50,8 @@ func TestAuthUseCase_EnsureAuthenticated_NoCredentials_DeviceFlowSuccess(t *test
 		TokenRefreshBuffer: 5 * time.Minute,
 	}
 
-	testCreds := &entities.Credentials{
-		AccessToken:  "device-flow-token",
-		TokenType:    "Bearer",
-		RefreshToken: "device-refresh-token",
-		ExpiryDate:   time.Now().Add(time.Hour).UnixMilli(),
-		ResourceURL:  "https://api.example.com",
-	}
-
+	// Device flow is never started from the request path, so the OAuth service is not called
 	oauthService := mocks.NewMockOAuthService(ctrl)
-	oauthService.EXPECT().
-		AuthenticateWithDeviceFlow("test-client-id", "test-scope").
-		Return(testCreds, nil).
-		Times(1)
-
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
 	// Mock repo that returns error on load
@@ -69,22 +59,21 @@ func TestAuthUseCase_EnsureAuthenticated_NoCredentials_DeviceFlowSuccess(t *test
 		loadError: fmt.Errorf("file not found"),
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	result, err := useCase.EnsureAuthenticated()
+	result, err := useCase.EnsureAuthenticated(context.Background())
 
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
-	}
-	if result == nil {
-		t.Fatal("Expected credentials")
-	}
-	if result.AccessToken != testCreds.AccessToken {
-		t.Errorf("Expected access token %s, got %s", testCreds.AccessToken, result.AccessToken)
-	}
+	assert.Nil(t, result)
+	var authRequired *AuthenticationRequiredError
+	require.ErrorAs(t, err, &authRequired)
+	assert.Equal(t, entities.AuthStateUnauthenticated, authRequired.State)
+	assert.Contains(t, err.Error(), "GET /auth")
+	assert.ErrorIs(t, err, repo.loadError)
+	assert.Nil(t, useCase.DeviceFlowStatus())
+	assert.Equal(t, entities.AuthStateUnauthenticated, useCase.AuthState())
 }
 
-func TestAuthUseCase_EnsureAuthenticated_NoCredentials_DeviceFlowFailure(t *testing.T) {
+func TestAuthUseCase_EnsureAuthenticated_NoCredentials_DeviceFlowPending(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -95,10 +84,14 @@ func TestAuthUseCase_EnsureAuthenticated_NoCredentials_DeviceFlowFailure(t *test
 	}
 
 	oauthService := mocks.NewMockOAuthService(ctrl)
-	oauthService.EXPECT().
-		AuthenticateWithDeviceFlow("test-client-id", "test-scope").
-		Return(nil, errors.New("device flow failed")).
-		Times(1)
+	authorization := &entities.DeviceAuthorization{
+		DeviceCode: "device-code",
+		UserCode:   "ABCD-EFGH",
+		ExpiresAt:  time.Now().Add(time.Minute),
+		Interval:   time.Millisecond,
+	}
+	oauthService.EXPECT().StartDeviceFlow(gomock.Any(), "test-client-id", "test-scope").Return(authorization, nil).Times(1)
+	oauthService.EXPECT().PollDeviceToken(gomock.Any(), "test-client-id", authorization).Return(nil, entities.ErrAuthorizationPending).AnyTimes()
 
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
@@ -106,19 +99,20 @@ func TestAuthUseCase_EnsureAuthenticated_NoCredentials_DeviceFlowFailure(t *test
 		loadError: fmt.Errorf("file not found"),
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	result, err := useCase.EnsureAuthenticated()
+	_, err := useCase.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
 
-	if result != nil {
-		t.Error("Expected nil result on failure")
-	}
-	if err == nil {
-		t.Error("Expected error")
-	}
-	if !strings.Contains(err.Error(), "device authentication failed") {
-		t.Errorf("Expected device auth error, got %v", err)
-	}
+	// Requests fail fast while the user has not approved the flow
+	result, err := useCase.EnsureAuthenticated(context.Background())
+
+	assert.Nil(t, result)
+	var authRequired *AuthenticationRequiredError
+	require.ErrorAs(t, err, &authRequired)
+	assert.Equal(t, entities.AuthStateAuthenticating, authRequired.State)
+	assert.Contains(t, err.Error(), "GET /auth/status")
+	assert.Equal(t, entities.AuthStateAuthenticating, useCase.AuthState())
 }
 
 func TestAuthUseCase_EnsureAuthenticated_ValidCredentials(t *testing.T) {
@@ -144,9 +138,9 @@ func TestAuthUseCase_EnsureAuthenticated_ValidCredentials(t *testing.T) {
 		loadCredentials: validCreds,
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	result, err := useCase.EnsureAuthenticated()
+	result, err := useCase.EnsureAuthenticated(context.Background())
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -197,9 +191,9 @@ func TestAuthUseCase_EnsureAuthenticated_ExpiredCredentials_RefreshSuccess(t *te
 		loadCredentials: expiredCreds,
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	result, err := useCase.EnsureAuthenticated()
+	result, err := useCase.EnsureAuthenticated(context.Background())
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -215,7 +209,7 @@ func TestAuthUseCase_EnsureAuthenticated_ExpiredCredentials_RefreshSuccess(t *te
 	}
 }
 
-func TestAuthUseCase_EnsureAuthenticated_ExpiredCredentials_RefreshFailure_FallbackToDeviceFlow(t *testing.T) {
+func TestAuthUseCase_EnsureAuthenticated_ExpiredCredentials_RefreshFailure(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -246,10 +240,6 @@ func TestAuthUseCase_EnsureAuthenticated_ExpiredCredentials_RefreshFailure_Fallb
 		RefreshToken("invalid-refresh", "test-client-id").
 		Return(nil, errors.New("refresh failed")).
 		Times(1)
-	oauthService.EXPECT().
-		AuthenticateWithDeviceFlow("test-client-id", "test-scope").
-		Return(deviceFlowCreds, nil).
-		Times(1)
 
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
@@ -257,18 +247,98 @@ func TestAuthUseCase_EnsureAuthenticated_ExpiredCredentials_RefreshFailure_Fallb
 		loadCredentials: expiredCreds,
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	result, err := useCase.EnsureAuthenticated()
+	result, err := useCase.EnsureAuthenticated(context.Background())
 
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
-	}
-	if result == nil {
-		t.Fatal("Expected credentials")
-	}
-	if result.AccessToken != deviceFlowCreds.AccessToken {
-		t.Errorf("Expected device flow access token %s, got %s", deviceFlowCreds.AccessToken, result.AccessToken)
+	assert.Nil(t, result)
+	var authRequired *AuthenticationRequiredError
+	require.ErrorAs(t, err, &authRequired)
+	assert.Equal(t, entities.AuthStateRefreshFailed, authRequired.State)
+	assert.Contains(t, err.Error(), "refresh failed")
+	assert.Contains(t, err.Error(), "Re-authenticate with GET /auth")
+	assert.Nil(t, useCase.DeviceFlowStatus())
+	assert.Equal(t, entities.AuthStateRefreshFailed, useCase.AuthState())
+
+	// A successful device flow recovers the account
+	expectDeviceFlow(oauthService, "test-client-id", "test-scope", deviceFlowCreds, nil)
+	_, err = useCase.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return useCase.DeviceFlowStatus().State == entities.DeviceFlowSucceeded
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, entities.AuthStateValid, useCase.AuthState())
+
+	result, err = useCase.EnsureAuthenticated(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, deviceFlowCreds.AccessToken, result.AccessToken)
+}
+
+func TestAuthUseCase_EnsureAuthenticated_RefreshFailure_RefreshedElsewhere(t *testing.T) {
+	expiredCreds := &entities.Credentials{
+		AccessToken:  "expired-token",
+		RefreshToken: "used-refresh",
+		ExpiryDate:   time.Now().Add(-time.Hour).UnixMilli(),
+		Version:      3,
+	}
+
+	tests := []struct {
+		name   string
+		stored *entities.Credentials
+		want   *entities.Credentials
+	}{
+		{
+			name:   "version moved on",
+			stored: &entities.Credentials{AccessToken: "other-token", RefreshToken: "other-refresh", ExpiryDate: time.Now().Add(time.Minute).UnixMilli(), Version: 4},
+			want:   &entities.Credentials{AccessToken: "other-token", RefreshToken: "other-refresh", Version: 4},
+		},
+		{
+			name:   "unversioned credentials replaced",
+			stored: &entities.Credentials{AccessToken: "other-token", RefreshToken: "other-refresh", ExpiryDate: time.Now().Add(time.Hour).UnixMilli(), Version: 3},
+			want:   &entities.Credentials{AccessToken: "other-token", RefreshToken: "other-refresh", Version: 3},
+		},
+		{
+			name:   "replaced credentials within the buffer",
+			stored: &entities.Credentials{AccessToken: "other-token", RefreshToken: "other-refresh", ExpiryDate: time.Now().Add(time.Minute).UnixMilli(), Version: 3},
+		},
+		{
+			name:   "unchanged",
+			stored: expiredCreds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			config := &entities.Config{QWENOAuthClientID: "test-client-id", TokenRefreshBuffer: 5 * time.Minute}
+
+			// Another replica used the refresh token first, so refreshing it fails upstream
+			oauthService := mocks.NewMockOAuthService(ctrl)
+			oauthService.EXPECT().RefreshToken("used-refresh", "test-client-id").Return(nil, errors.New("invalid_grant"))
+
+			repo := mocks.NewMockCredentialRepository(ctrl)
+			gomock.InOrder(
+				repo.EXPECT().Load().Return(expiredCreds, nil).Times(2),
+				repo.EXPECT().Load().Return(tt.stored, nil),
+			)
+
+			logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+			useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
+
+			result, err := useCase.EnsureAuthenticated(context.Background())
+			if tt.want == nil {
+				assert.Nil(t, result)
+				var authRequired *AuthenticationRequiredError
+				require.ErrorAs(t, err, &authRequired)
+				assert.Equal(t, entities.AuthStateRefreshFailed, authRequired.State)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.AccessToken, result.AccessToken)
+			assert.Equal(t, tt.want.RefreshToken, result.RefreshToken)
+			assert.Equal(t, tt.want.Version, result.Version)
+			assert.Empty(t, useCase.RefreshStatus().LastError)
+		})
 	}
 }
 
@@ -306,9 +376,9 @@ func TestAuthUseCase_refreshAccessToken_Success(t *testing.T) {
 
 	repo := &mockCredentialRepository{}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	refreshed, err := useCase.refreshAccessToken(credentials)
+	refreshed, err := useCase.refreshAccessToken(context.Background(), credentials)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -350,9 +420,9 @@ func TestAuthUseCase_refreshAccessToken_NoRefreshToken(t *testing.T) {
 
 	repo := &mockCredentialRepository{}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	_, err := useCase.refreshAccessToken(credentials)
+	_, err := useCase.refreshAccessToken(context.Background(), credentials)
 
 	if err == nil {
 		t.Error("Expected error for missing refresh token")
@@ -387,9 +457,9 @@ func TestAuthUseCase_refreshAccessToken_RefreshFailure(t *testing.T) {
 
 	repo := &mockCredentialRepository{}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	_, err := useCase.refreshAccessToken(credentials)
+	_, err := useCase.refreshAccessToken(context.Background(), credentials)
 
 	if err == nil {
 		t.Error("Expected error for refresh failure")
@@ -399,116 +469,300 @@ func TestAuthUseCase_refreshAccessToken_RefreshFailure(t *testing.T) {
 	}
 }
 
-func TestAuthUseCase_authenticateWithDeviceFlow_Success(t *testing.T) {
+func TestAuthUseCase_StartDeviceFlow_AuthorizationError(t *testing.T) {
 	config := &entities.Config{
 		QWENOAuthClientID: "test-client-id",
 		QWENOAuthScope:    "test-scope",
 	}
 
-	testCreds := &entities.Credentials{
-		AccessToken:  "device-token",
-		TokenType:    "Bearer",
-		RefreshToken: "device-refresh",
-		ExpiryDate:   time.Now().Add(time.Hour).UnixMilli(),
-		ResourceURL:  "https://api.example.com",
-	}
-
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	oauthService := mocks.NewMockOAuthService(ctrl)
 	oauthService.EXPECT().
-		AuthenticateWithDeviceFlow("test-client-id", "test-scope").
-		Return(testCreds, nil).
+		StartDeviceFlow(gomock.Any(), "test-client-id", "test-scope").
+		Return(nil, errors.New("device flow failed")).
 		Times(1)
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
 	repo := &mockCredentialRepository{}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	result, err := useCase.authenticateWithDeviceFlow()
+	status, err := useCase.StartDeviceFlow(context.Background())
 
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
-	}
-	if result == nil {
-		t.Fatal("Expected credentials")
-	}
-	if result.AccessToken != testCreds.AccessToken {
-		t.Errorf("Expected access token %s, got %s", testCreds.AccessToken, result.AccessToken)
-	}
-	if repo.saveCallCount != 1 {
-		t.Error("Expected save to be called")
-	}
+	assert.Nil(t, status)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "device authentication failed")
+	assert.Nil(t, useCase.DeviceFlowStatus())
+	assert.Equal(t, 0, repo.saveCallCount)
 }
 
-func TestAuthUseCase_authenticateWithDeviceFlow_Failure(t *testing.T) {
+func TestAuthUseCase_StartDeviceFlow(t *testing.T) {
 	config := &entities.Config{
 		QWENOAuthClientID: "test-client-id",
 		QWENOAuthScope:    "test-scope",
 	}
 
+	testCreds := &entities.Credentials{
+		AccessToken: "device-token",
+		TokenType:   "Bearer",
+		ExpiryDate:  time.Now().Add(time.Hour).UnixMilli(),
+	}
+
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	oauthService := mocks.NewMockOAuthService(ctrl)
+	authorization := &entities.DeviceAuthorization{
+		DeviceCode:              "device-code",
+		UserCode:                "ABCD-EFGH",
+		VerificationURI:         "https://chat.qwen.ai/authorize",
+		VerificationURIComplete: "https://chat.qwen.ai/authorize?user_code=ABCD-EFGH&client=qwen-code",
+		ExpiresAt:               time.Now().Add(time.Minute),
+		Interval:                time.Millisecond,
+	}
 	oauthService.EXPECT().
-		AuthenticateWithDeviceFlow("test-client-id", "test-scope").
-		Return(nil, errors.New("device flow failed")).
+		StartDeviceFlow(gomock.Any(), "test-client-id", "test-scope").
+		Return(authorization, nil).
 		Times(1)
+	// The user approves the flow only when the test says so
+	approve := make(chan struct{})
+	oauthService.EXPECT().
+		PollDeviceToken(gomock.Any(), "test-client-id", authorization).
+		DoAndReturn(func(ctx context.Context, clientID string, authorization *entities.DeviceAuthorization) (*entities.Credentials, error) {
+			select {
+			case <-approve:
+				return testCreds, nil
+			default:
+				return nil, entities.ErrAuthorizationPending
+			}
+		}).
+		MinTimes(1)
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
 	repo := &mockCredentialRepository{}
+	notifier := &recordingNotifier{}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
-
-	result, err := useCase.authenticateWithDeviceFlow()
+	useCase := NewAuthUseCase(config, oauthService, repo, notifier, logger)
+	assert.Nil(t, useCase.DeviceFlowStatus())
 
-	if result != nil {
-		t.Error("Expected nil result on failure")
+	// Concurrent callers join the same flow
+	var wg sync.WaitGroup
+	statuses := make([]*entities.DeviceFlowStatus, 5)
+	for i := range statuses {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			status, err := useCase.StartDeviceFlow(context.Background())
+			assert.NoError(t, err)
+			statuses[index] = status
+		}(i)
 	}
-	if err == nil {
-		t.Error("Expected error")
+	wg.Wait()
+
+	for _, status := range statuses {
+		require.NotNil(t, status)
+		assert.Equal(t, entities.DeviceFlowPending, status.State)
+		assert.Equal(t, "ABCD-EFGH", status.UserCode)
+		assert.Equal(t, authorization.VerificationURIComplete, status.VerificationURIComplete)
 	}
-	if !strings.Contains(err.Error(), "device authentication failed") {
-		t.Errorf("Expected device auth error, got %v", err)
+	assert.Eventually(t, func() bool {
+		return useCase.DeviceFlowStatus().Polls > 0
+	}, time.Second, time.Millisecond)
+
+	close(approve)
+	assert.Eventually(t, func() bool {
+		return useCase.DeviceFlowStatus().State == entities.DeviceFlowSucceeded
+	}, time.Second, time.Millisecond)
+
+	status := useCase.DeviceFlowStatus()
+	assert.NotNil(t, status.CompletedAt)
+	assert.NotNil(t, status.LastPollAt)
+	assert.Empty(t, status.Error)
+
+	notified := notifier.Statuses()
+	require.Len(t, notified, 1)
+	assert.Equal(t, entities.DeviceFlowSucceeded, notified[0].State)
+	assert.Equal(t, "ABCD-EFGH", notified[0].UserCode)
+
+	result, err := useCase.CheckAuthentication()
+	require.NoError(t, err)
+	assert.Equal(t, "device-token", result.AccessToken)
+}
+
+func TestAuthUseCase_StartDeviceFlow_NotLockedWhileRequesting(t *testing.T) {
+	config := &entities.Config{
+		QWENOAuthClientID: "test-client-id",
+		QWENOAuthScope:    "test-scope",
 	}
-	if repo.saveCallCount != 0 {
-		t.Error("Expected save not to be called on failure")
+
+	ctrl := gomock.NewController(t)
+	oauthService := mocks.NewMockOAuthService(ctrl)
+	authorization := &entities.DeviceAuthorization{
+		DeviceCode: "device-code",
+		UserCode:   "ABCD-EFGH",
+		ExpiresAt:  time.Now().Add(time.Minute),
+		Interval:   time.Millisecond,
+	}
+	// The authorization server answers only when the test says so
+	requested := make(chan struct{})
+	respond := make(chan struct{})
+	oauthService.EXPECT().
+		StartDeviceFlow(gomock.Any(), "test-client-id", "test-scope").
+		DoAndReturn(func(ctx context.Context, clientID, scope string) (*entities.DeviceAuthorization, error) {
+			close(requested)
+			<-respond
+			return authorization, nil
+		}).
+		Times(1)
+	oauthService.EXPECT().
+		PollDeviceToken(gomock.Any(), "test-client-id", authorization).
+		Return(nil, entities.ErrAuthorizationPending).
+		AnyTimes()
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	repo := &mockCredentialRepository{loadError: errors.New("no credentials")}
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
+
+	var wg sync.WaitGroup
+	statuses := make([]*entities.DeviceFlowStatus, 5)
+	start := func(index int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := useCase.StartDeviceFlow(context.Background())
+			assert.NoError(t, err)
+			statuses[index] = status
+		}()
+	}
+	start(0)
+	<-requested
+
+	// While the authorization is requested the account is authenticating, and its state can be read
+	assert.Nil(t, useCase.DeviceFlowStatus())
+	assert.Equal(t, entities.AuthStateAuthenticating, useCase.AuthState())
+	_, err := useCase.EnsureAuthenticated(context.Background())
+	var authRequired *AuthenticationRequiredError
+	require.ErrorAs(t, err, &authRequired)
+	assert.Equal(t, entities.AuthStateAuthenticating, authRequired.State)
+
+	// Callers arriving meanwhile join the flow being started
+	for i := 1; i < len(statuses); i++ {
+		start(i)
+	}
+	close(respond)
+	wg.Wait()
+
+	for _, status := range statuses {
+		require.NotNil(t, status)
+		assert.Equal(t, entities.DeviceFlowPending, status.State)
+		assert.Equal(t, "ABCD-EFGH", status.UserCode)
 	}
 }
 
-func TestAuthUseCase_AuthenticateManually_Success(t *testing.T) {
+func TestAuthUseCase_StartDeviceFlow_Failure(t *testing.T) {
 	config := &entities.Config{
 		QWENOAuthClientID: "test-client-id",
 		QWENOAuthScope:    "test-scope",
 	}
 
-	testCreds := &entities.Credentials{
-		AccessToken: "manual-token",
-		TokenType:   "Bearer",
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oauthService := mocks.NewMockOAuthService(ctrl)
+	expectDeviceFlow(oauthService, "test-client-id", "test-scope", nil, errors.New("access_denied"))
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	repo := &mockCredentialRepository{}
+	notifier := &recordingNotifier{}
+
+	useCase := NewAuthUseCase(config, oauthService, repo, notifier, logger)
+
+	_, err := useCase.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return useCase.DeviceFlowStatus().State == entities.DeviceFlowFailed
+	}, time.Second, time.Millisecond)
+
+	status := useCase.DeviceFlowStatus()
+	assert.Equal(t, 2, status.Polls)
+	assert.Contains(t, status.Error, "access_denied")
+	assert.Equal(t, 0, repo.saveCallCount)
+
+	notified := notifier.Statuses()
+	require.Len(t, notified, 1)
+	assert.Equal(t, entities.DeviceFlowFailed, notified[0].State)
+}
+
+func TestAuthUseCase_StartDeviceFlow_SlowDown(t *testing.T) {
+	config := &entities.Config{
+		QWENOAuthClientID: "test-client-id",
+		QWENOAuthScope:    "test-scope",
 	}
 
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	oauthService := mocks.NewMockOAuthService(ctrl)
-	oauthService.EXPECT().
-		AuthenticateWithDeviceFlow("test-client-id", "test-scope").
-		Return(testCreds, nil).
-		Times(1)
+	authorization := &entities.DeviceAuthorization{
+		DeviceCode: "device-code",
+		UserCode:   "ABCD-EFGH",
+		ExpiresAt:  time.Now().Add(100 * time.Millisecond),
+		Interval:   time.Millisecond,
+	}
+	oauthService.EXPECT().StartDeviceFlow(gomock.Any(), "test-client-id", "test-scope").Return(authorization, nil)
+	oauthService.EXPECT().PollDeviceToken(gomock.Any(), "test-client-id", authorization).Return(nil, entities.ErrSlowDown).Times(1)
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
-	repo := &mockCredentialRepository{}
+	useCase := NewAuthUseCase(config, oauthService, &mockCredentialRepository{}, &recordingNotifier{}, logger)
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	_, err := useCase.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
 
-	err := useCase.AuthenticateManually()
+	// After slow_down the next poll would only come after the authorization expired
+	assert.Eventually(t, func() bool {
+		return useCase.DeviceFlowStatus().State == entities.DeviceFlowFailed
+	}, time.Second, time.Millisecond)
+	status := useCase.DeviceFlowStatus()
+	assert.Equal(t, 1, status.Polls)
+	assert.Contains(t, status.Error, "expired")
+}
 
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
+func TestAuthUseCase_StartDeviceFlow_Expired(t *testing.T) {
+	config := &entities.Config{
+		QWENOAuthClientID: "test-client-id",
+		QWENOAuthScope:    "test-scope",
 	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oauthService := mocks.NewMockOAuthService(ctrl)
+	authorization := &entities.DeviceAuthorization{
+		DeviceCode: "device-code",
+		UserCode:   "ABCD-EFGH",
+		ExpiresAt:  time.Now().Add(50 * time.Millisecond),
+		Interval:   time.Millisecond,
+	}
+	oauthService.EXPECT().StartDeviceFlow(gomock.Any(), "test-client-id", "test-scope").Return(authorization, nil).Times(2)
+	oauthService.EXPECT().PollDeviceToken(gomock.Any(), "test-client-id", authorization).Return(nil, entities.ErrAuthorizationPending).AnyTimes()
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	useCase := NewAuthUseCase(config, oauthService, &mockCredentialRepository{}, &recordingNotifier{}, logger)
+
+	_, err := useCase.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return useCase.DeviceFlowStatus().State == entities.DeviceFlowFailed
+	}, time.Second, time.Millisecond)
+	assert.Contains(t, useCase.DeviceFlowStatus().Error, "expired")
+
+	// A finished flow does not prevent starting a new one
+	authorization.ExpiresAt = time.Now().Add(time.Minute)
+	status, err := useCase.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, entities.DeviceFlowPending, status.State)
 }
 
 func TestAuthUseCase_CheckAuthentication(t *testing.T) {
@@ -529,7 +783,7 @@ func TestAuthUseCase_CheckAuthentication(t *testing.T) {
 		loadCredentials: testCreds,
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
 	result, err := useCase.CheckAuthentication()
 
@@ -542,6 +796,7 @@ func TestAuthUseCase_CheckAuthentication(t *testing.T) {
 	if result.AccessToken != testCreds.AccessToken {
 		t.Errorf("Expected access token %s, got %s", testCreds.AccessToken, result.AccessToken)
 	}
+	assert.Equal(t, entities.AuthStateValid, useCase.AuthState())
 }
 
 // Mock credential repository for testing
@@ -605,7 +860,7 @@ func TestAuthUseCase_EnsureAuthenticated_ConcurrentAccess(t *testing.T) {
 		loadCredentials: expiredCreds,
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
 	// Run multiple concurrent calls on the same useCase instance
 	var wg sync.WaitGroup
@@ -616,7 +871,7 @@ func TestAuthUseCase_EnsureAuthenticated_ConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func(index int) {
 			defer wg.Done()
-			results[index], errors[index] = useCase.EnsureAuthenticated()
+			results[index], errors[index] = useCase.EnsureAuthenticated(context.Background())
 		}(i)
 	}
 
@@ -649,7 +904,7 @@ func TestNewAuthUseCase_NilConfig(t *testing.T) {
 
 	// Test with nil config - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewAuthUseCase(nil, oauthService, repo, logger)
+		NewAuthUseCase(nil, oauthService, repo, &recordingNotifier{}, logger)
 	})
 }
 
@@ -667,7 +922,7 @@ func TestNewAuthUseCase_NilOAuthService(t *testing.T) {
 
 	// Test with nil oauth service - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewAuthUseCase(config, nil, repo, logger)
+		NewAuthUseCase(config, nil, repo, &recordingNotifier{}, logger)
 	})
 }
 
@@ -685,7 +940,20 @@ func TestNewAuthUseCase_NilRepository(t *testing.T) {
 
 	// Test with nil repository - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewAuthUseCase(config, oauthService, nil, logger)
+		NewAuthUseCase(config, oauthService, nil, &recordingNotifier{}, logger)
+	})
+}
+
+func TestNewAuthUseCase_NilNotifier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := &entities.Config{}
+	oauthService := mocks.NewMockOAuthService(ctrl)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	assert.Panics(t, func() {
+		NewAuthUseCase(config, oauthService, &mockCredentialRepository{}, nil, logger)
 	})
 }
 
@@ -703,11 +971,11 @@ func TestNewAuthUseCase_NilLogger(t *testing.T) {
 
 	// Test with nil logger - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewAuthUseCase(config, oauthService, repo, nil)
+		NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, nil)
 	})
 }
 
-func TestAuthUseCase_EnsureAuthenticated_ConfigWithEmptyClientID(t *testing.T) {
+func TestAuthUseCase_StartDeviceFlow_ConfigWithEmptyClientID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -724,17 +992,17 @@ func TestAuthUseCase_EnsureAuthenticated_ConfigWithEmptyClientID(t *testing.T) {
 		loadError: fmt.Errorf("file not found"),
 	}
 
-	oauthService.EXPECT().AuthenticateWithDeviceFlow("", "test-scope").Return(nil, fmt.Errorf("empty client ID"))
+	oauthService.EXPECT().StartDeviceFlow(gomock.Any(), "", "test-scope").Return(nil, fmt.Errorf("empty client ID"))
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	_, err := useCase.EnsureAuthenticated()
+	_, err := useCase.StartDeviceFlow(context.Background())
 
 	// Should handle empty client ID gracefully
 	assert.Error(t, err)
 }
 
-func TestAuthUseCase_EnsureAuthenticated_ConfigWithEmptyScope(t *testing.T) {
+func TestAuthUseCase_StartDeviceFlow_ConfigWithEmptyScope(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -751,11 +1019,11 @@ func TestAuthUseCase_EnsureAuthenticated_ConfigWithEmptyScope(t *testing.T) {
 		loadError: fmt.Errorf("file not found"),
 	}
 
-	oauthService.EXPECT().AuthenticateWithDeviceFlow("test-client-id", "").Return(nil, fmt.Errorf("empty scope"))
+	oauthService.EXPECT().StartDeviceFlow(gomock.Any(), "test-client-id", "").Return(nil, fmt.Errorf("empty scope"))
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	_, err := useCase.EnsureAuthenticated()
+	_, err := useCase.StartDeviceFlow(context.Background())
 
 	// Should handle empty scope gracefully
 	assert.Error(t, err)
@@ -777,15 +1045,15 @@ func TestAuthUseCase_EnsureAuthenticated_RepositoryLoadPanic(t *testing.T) {
 	// Repository that panics on Load
 	repo := &panickingRepository{}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
 	// Should handle repository panic gracefully
 	assert.Panics(t, func() {
-		useCase.EnsureAuthenticated()
+		useCase.EnsureAuthenticated(context.Background())
 	})
 }
 
-func TestAuthUseCase_EnsureAuthenticated_RepositorySavePanic(t *testing.T) {
+func TestAuthUseCase_StartDeviceFlow_RepositorySaveError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -804,22 +1072,26 @@ func TestAuthUseCase_EnsureAuthenticated_RepositorySavePanic(t *testing.T) {
 	}
 
 	oauthService := mocks.NewMockOAuthService(ctrl)
-	oauthService.EXPECT().
-		AuthenticateWithDeviceFlow("test-client-id", "test-scope").
-		Return(testCreds, nil).
-		Times(1)
+	expectDeviceFlow(oauthService, "test-client-id", "test-scope", testCreds, nil)
 
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
-	// Repository that panics on Save
-	repo := &panickingSaveRepository{}
+	// Repository without credentials that fails on Save
+	repo := &mockCredentialRepository{
+		loadError: fmt.Errorf("no credentials"),
+		saveError: fmt.Errorf("disk full"),
+	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	// Should handle repository save panic gracefully
-	assert.Panics(t, func() {
-		useCase.EnsureAuthenticated()
-	})
+	_, err := useCase.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return useCase.DeviceFlowStatus().State == entities.DeviceFlowFailed
+	}, time.Second, time.Millisecond)
+
+	assert.Contains(t, useCase.DeviceFlowStatus().Error, "failed to save credentials")
+	assert.Equal(t, entities.AuthStateUnauthenticated, useCase.AuthState())
 }
 
 func TestAuthUseCase_refreshAccessToken_RepositorySaveError(t *testing.T) {
@@ -859,9 +1131,9 @@ func TestAuthUseCase_refreshAccessToken_RepositorySaveError(t *testing.T) {
 		saveError: fmt.Errorf("save failed"),
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
-	refreshed, err := useCase.refreshAccessToken(credentials)
+	refreshed, err := useCase.refreshAccessToken(context.Background(), credentials)
 
 	// Should handle save error gracefully
 	assert.Error(t, err)
@@ -869,16 +1141,62 @@ func TestAuthUseCase_refreshAccessToken_RepositorySaveError(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to save refreshed credentials")
 }
 
+func TestAuthUseCase_refreshAccessToken_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := &entities.Config{
+		QWENOAuthClientID: "test-client-id",
+	}
+
+	credentials := &entities.Credentials{
+		AccessToken:  "old-token",
+		RefreshToken: "refresh-token",
+		ExpiryDate:   time.Now().UnixMilli(),
+		ResourceURL:  "https://api.example.com",
+		Version:      3,
+	}
+	// Credentials another replica stored after refreshing the same token
+	storedCreds := &entities.Credentials{
+		AccessToken:  "other-token",
+		RefreshToken: "other-refresh-token",
+		ExpiryDate:   time.Now().Add(time.Hour).UnixMilli(),
+		ResourceURL:  "https://api.example.com",
+		Version:      4,
+	}
+
+	oauthService := mocks.NewMockOAuthService(ctrl)
+	oauthService.EXPECT().
+		RefreshToken("refresh-token", "test-client-id").
+		Return(&entities.Credentials{AccessToken: "new-token", ExpiryDate: time.Now().Add(time.Hour).UnixMilli()}, nil)
+
+	repo := mocks.NewMockCredentialRepository(ctrl)
+	gomock.InOrder(
+		repo.EXPECT().Save(gomock.Any()).DoAndReturn(func(saved *entities.Credentials) error {
+			// The refreshed credentials replace only the version that was refreshed
+			assert.Equal(t, int64(3), saved.Version)
+			assert.Equal(t, "https://api.example.com", saved.ResourceURL)
+			return fmt.Errorf("failed to save: %w", entities.ErrCredentialConflict)
+		}),
+		repo.EXPECT().Load().Return(storedCreds, nil),
+	)
+
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
+
+	refreshed, err := useCase.refreshAccessToken(context.Background(), credentials)
+	require.NoError(t, err)
+	assert.Equal(t, storedCreds, refreshed)
+}
+
 func TestAuthUseCase_CheckAuthentication_RepositoryLoadError(t *testing.T) {
 	config := &entities.Config{}
 
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	// CheckAuthentication never starts device flow, so the OAuth service is not called
 	oauthService := mocks.NewMockOAuthService(ctrl)
-	// Since CheckAuthentication calls EnsureAuthenticated which tries to authenticate on load failure,
-	// we need to set up the mock to return an error
-	oauthService.EXPECT().AuthenticateWithDeviceFlow(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("auth failed")).AnyTimes()
 	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
 
 	// Repository that fails on Load
@@ -886,14 +1204,16 @@ func TestAuthUseCase_CheckAuthentication_RepositoryLoadError(t *testing.T) {
 		loadError: fmt.Errorf("load failed"),
 	}
 
-	useCase := NewAuthUseCase(config, oauthService, repo, logger)
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
 
 	result, err := useCase.CheckAuthentication()
 
-	// Should handle load error gracefully (by attempting authentication, which fails)
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "device authentication failed")
+	var authRequired *AuthenticationRequiredError
+	assert.ErrorAs(t, err, &authRequired)
+	assert.Contains(t, err.Error(), "No Qwen OAuth credentials are available")
+	assert.Nil(t, useCase.DeviceFlowStatus())
 }
 
 // Mock repository that panics for testing
@@ -907,13 +1227,36 @@ func (p *panickingRepository) Save(credentials *entities.Credentials) error {
 	panic("save panic")
 }
 
-// Mock repository that panics only on Save
-type panickingSaveRepository struct{}
+// recordingNotifier records the device flow notifications it receives
+type recordingNotifier struct {
+	mu       sync.Mutex
+	statuses []*entities.DeviceFlowStatus
+}
 
-func (p *panickingSaveRepository) Load() (*entities.Credentials, error) {
-	return nil, fmt.Errorf("no credentials") // Return error to force authentication
+func (n *recordingNotifier) NotifyDeviceFlow(ctx context.Context, status *entities.DeviceFlowStatus) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.statuses = append(n.statuses, status)
+	return nil
 }
 
-func (p *panickingSaveRepository) Save(credentials *entities.Credentials) error {
-	panic("save panic")
+func (n *recordingNotifier) Statuses() []*entities.DeviceFlowStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*entities.DeviceFlowStatus(nil), n.statuses...)
+}
+
+// expectDeviceFlow expects a device flow whose first poll is pending and whose second poll returns the result
+func expectDeviceFlow(oauthService *mocks.MockOAuthService, clientID, scope string, credentials *entities.Credentials, err error) {
+	authorization := &entities.DeviceAuthorization{
+		DeviceCode: "device-code",
+		UserCode:   "ABCD-EFGH",
+		ExpiresAt:  time.Now().Add(time.Minute),
+		Interval:   time.Millisecond,
+	}
+	oauthService.EXPECT().StartDeviceFlow(gomock.Any(), clientID, scope).Return(authorization, nil).Times(1)
+	gomock.InOrder(
+		oauthService.EXPECT().PollDeviceToken(gomock.Any(), clientID, authorization).Return(nil, entities.ErrAuthorizationPending),
+		oauthService.EXPECT().PollDeviceToken(gomock.Any(), clientID, authorization).Return(credentials, err),
+	)
 }
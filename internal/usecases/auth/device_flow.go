@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+)
+
+const (
+	// defaultPollInterval is used when the authorization server does not say how often to poll
+	defaultPollInterval = 5 * time.Second
+	// slowDownIncrease is added to the poll interval on every slow_down response (RFC 8628)
+	slowDownIncrease = 5 * time.Second
+	// defaultDeviceFlowTimeout bounds flows whose authorization has no expiry
+	defaultDeviceFlowTimeout = 10 * time.Minute
+	// notifyTimeout bounds the delivery of a device flow notification
+	notifyTimeout = 30 * time.Second
+)
+
+// deviceFlow is a device authorization that is polled in the background.
+// Its status is guarded by the flow mutex of the use case.
+type deviceFlow struct {
+	status entities.DeviceFlowStatus
+}
+
+// deviceFlowStart is a device authorization being requested. Callers that find one wait for
+// done and share its outcome, so that concurrent callers join a single flow.
+type deviceFlowStart struct {
+	done chan struct{}
+	flow *deviceFlow
+	err  error
+}
+
+// StartDeviceFlow starts a device authorization flow and returns its status, with the user code and
+// verification URL the user has to open. The token is polled for in the background and the
+// credentials are saved once the user approves. While a flow is pending, its status is returned
+// instead of starting another one.
+func (uc *AuthUseCase) StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error) {
+	flow, err := uc.startDeviceFlow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uc.flowMutex.Lock()
+	defer uc.flowMutex.Unlock()
+	status := flow.status
+	return &status, nil
+}
+
+// DeviceFlowStatus returns the status of the most recent device flow, or nil when none was started
+func (uc *AuthUseCase) DeviceFlowStatus() *entities.DeviceFlowStatus {
+	uc.flowMutex.Lock()
+	defer uc.flowMutex.Unlock()
+	if uc.flow == nil {
+		return nil
+	}
+	status := uc.flow.status
+	return &status
+}
+
+// startDeviceFlow returns the pending device flow, or starts a new one
+func (uc *AuthUseCase) startDeviceFlow(ctx context.Context) (*deviceFlow, error) {
+	uc.flowMutex.Lock()
+	if uc.flow != nil && uc.flow.status.State == entities.DeviceFlowPending {
+		defer uc.flowMutex.Unlock()
+		return uc.flow, nil
+	}
+	if start := uc.flowStart; start != nil {
+		uc.flowMutex.Unlock()
+		select {
+		case <-start.done:
+			return start.flow, start.err
+		case <-ctx.Done():
+			return nil, fmt.Errorf("device authentication failed: %w", ctx.Err())
+		}
+	}
+	// The account is authenticating from here on, but the lock is not held while the authorization
+	// is requested, so that status requests do not wait for the authorization server
+	start := &deviceFlowStart{done: make(chan struct{})}
+	uc.flowStart = start
+	uc.flowMutex.Unlock()
+
+	authorization, err := uc.oauthService.StartDeviceFlow(ctx, uc.config.QWENOAuthClientID, uc.config.QWENOAuthScope)
+	if err != nil {
+		uc.finishDeviceFlowStart(start, nil, fmt.Errorf("device authentication failed: %w", err))
+		return nil, start.err
+	}
+	if authorization.ExpiresAt.IsZero() {
+		authorization.ExpiresAt = time.Now().Add(defaultDeviceFlowTimeout)
+	}
+
+	flow := &deviceFlow{
+		status: entities.DeviceFlowStatus{
+			State:                   entities.DeviceFlowPending,
+			UserCode:                authorization.UserCode,
+			VerificationURI:         authorization.VerificationURI,
+			VerificationURIComplete: authorization.VerificationURIComplete,
+			ExpiresAt:               authorization.ExpiresAt,
+			StartedAt:               time.Now(),
+		},
+	}
+	uc.finishDeviceFlowStart(start, flow, nil)
+	uc.logger.Info("Device authentication started, waiting for the user to approve it",
+		"user_code", authorization.UserCode,
+		"verification_uri", authorization.VerificationURIComplete,
+		"expires_at", authorization.ExpiresAt)
+
+	go uc.pollDeviceFlow(flow, authorization)
+	return flow, nil
+}
+
+// finishDeviceFlowStart records the outcome of a device authorization request, making its flow
+// the current one when it succeeded, and releases the callers waiting for it
+func (uc *AuthUseCase) finishDeviceFlowStart(start *deviceFlowStart, flow *deviceFlow, err error) {
+	uc.flowMutex.Lock()
+	uc.flowStart = nil
+	if flow != nil {
+		uc.flow = flow
+	}
+	start.flow, start.err = flow, err
+	uc.flowMutex.Unlock()
+	close(start.done)
+}
+
+// authenticating reports whether a device authorization is being requested or a device flow is
+// pending. The flow mutex must be held.
+func (uc *AuthUseCase) authenticating() bool {
+	return uc.flowStart != nil || (uc.flow != nil && uc.flow.status.State == entities.DeviceFlowPending)
+}
+
+// pollDeviceFlow polls for the token of a device authorization until the user approves it,
+// the authorization expires or polling fails, and saves the credentials it obtains
+func (uc *AuthUseCase) pollDeviceFlow(flow *deviceFlow, authorization *entities.DeviceAuthorization) {
+	ctx, cancel := context.WithDeadline(context.Background(), authorization.ExpiresAt)
+	defer cancel()
+
+	interval := authorization.Interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			uc.finishDeviceFlow(flow, fmt.Errorf("device authorization expired before it was approved"))
+			return
+		case <-timer.C:
+		}
+
+		credentials, err := uc.oauthService.PollDeviceToken(ctx, uc.config.QWENOAuthClientID, authorization)
+		now := time.Now()
+		uc.flowMutex.Lock()
+		flow.status.Polls++
+		flow.status.LastPollAt = &now
+		uc.flowMutex.Unlock()
+
+		switch {
+		case errors.Is(err, entities.ErrAuthorizationPending):
+		case errors.Is(err, entities.ErrSlowDown):
+			interval += slowDownIncrease
+		case err != nil:
+			if ctx.Err() != nil {
+				continue
+			}
+			uc.finishDeviceFlow(flow, err)
+			return
+		default:
+			uc.finishDeviceFlow(flow, uc.saveCredentials(credentials))
+			return
+		}
+		timer.Reset(interval)
+	}
+}
+
+// saveCredentials stores the credentials obtained by a device flow
+func (uc *AuthUseCase) saveCredentials(credentials *entities.Credentials) error {
+	uc.tokenMutex.Lock()
+	defer uc.tokenMutex.Unlock()
+
+	if err := uc.credentialRepo.Save(credentials); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	return nil
+}
+
+// finishDeviceFlow records the outcome of a device flow and sends the notification
+func (uc *AuthUseCase) finishDeviceFlow(flow *deviceFlow, err error) {
+	now := time.Now()
+	uc.flowMutex.Lock()
+	flow.status.CompletedAt = &now
+	if err != nil {
+		err = fmt.Errorf("device authentication failed: %w", err)
+		flow.status.State = entities.DeviceFlowFailed
+		flow.status.Error = err.Error()
+	} else {
+		flow.status.State = entities.DeviceFlowSucceeded
+		uc.refreshErr = nil
+	}
+	status := flow.status
+	uc.flowMutex.Unlock()
+
+	if err != nil {
+		uc.logger.Warn("Device authentication failed", "user_code", status.UserCode, "error", err)
+	} else {
+		uc.logger.Info("Device authentication successful, credentials saved", "user_code", status.UserCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := uc.notifier.NotifyDeviceFlow(ctx, &status); err != nil {
+		uc.logger.Warn("Failed to send device flow notification", "error", err)
+	}
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+)
+
+// Credential pool selection strategies
+const (
+	StrategyRoundRobin     = "round_robin"
+	StrategyLeastThrottled = "least_recently_throttled"
+)
+
+// ErrNoAccountsAvailable is returned when every account in the pool has been excluded or failed
+var ErrNoAccountsAvailable = errors.New("no accounts available in credential pool")
+
+// PoolAccount pairs an account name with the auth use case that manages its credentials
+type PoolAccount struct {
+	Name        string
+	AuthUseCase AuthUseCaseInterface
+}
+
+// poolMember holds the runtime state of a pooled account
+type poolMember struct {
+	name           string
+	authUseCase    AuthUseCaseInterface
+	throttledUntil time.Time
+	lastThrottled  time.Time
+	throttleCount  int
+}
+
+// CredentialPool rotates requests across several Qwen accounts.
+// Each account keeps its own AuthUseCase, so tokens are refreshed independently.
+type CredentialPool struct {
+	members  []*poolMember
+	strategy string
+	cooldown time.Duration
+	logger   logging.LoggerInterface
+	next     int
+	mu       sync.Mutex
+}
+
+// NewCredentialPool creates a new credential pool over the given accounts
+func NewCredentialPool(accounts []PoolAccount, strategy string, cooldown time.Duration, logger logging.LoggerInterface) *CredentialPool {
+	if len(accounts) == 0 {
+		panic("accounts cannot be empty")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	if strategy == "" {
+		strategy = StrategyRoundRobin
+	}
+	members := make([]*poolMember, len(accounts))
+	for i, account := range accounts {
+		if account.AuthUseCase == nil {
+			panic(fmt.Sprintf("authUseCase for account %s cannot be nil", account.Name))
+		}
+		members[i] = &poolMember{name: account.Name, authUseCase: account.AuthUseCase}
+	}
+	return &CredentialPool{
+		members:  members,
+		strategy: strategy,
+		cooldown: cooldown,
+		logger:   logger,
+	}
+}
+
+// AcquireAccount selects an account according to the pool strategy and returns valid credentials for it.
+// Accounts named in exclude are skipped. Throttled accounts are only used when no healthy account is left.
+func (p *CredentialPool) AcquireAccount(ctx context.Context, exclude map[string]bool) (*entities.AccountCredentials, error) {
+	skipped := make(map[string]bool, len(exclude))
+	for name := range exclude {
+		skipped[name] = true
+	}
+
+	var lastErr error
+	for {
+		member := p.selectMember(skipped)
+		if member == nil {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w: %w", ErrNoAccountsAvailable, lastErr)
+			}
+			return nil, ErrNoAccountsAvailable
+		}
+
+		credentials, err := member.authUseCase.EnsureAuthenticated(ctx)
+		if err != nil {
+			p.logger.Warn("Pooled account authentication failed", "account", member.name, "error", err)
+			skipped[member.name] = true
+			lastErr = p.withAccount(err, member)
+			continue
+		}
+
+		p.logger.Debug("Selected pooled account", "account", member.name, "strategy", p.strategy)
+		return &entities.AccountCredentials{Name: member.name, Credentials: credentials}, nil
+	}
+}
+
+// withAccount names the pooled account in an authentication required error, so that its
+// message tells how to authenticate that account rather than the one GET /auth authenticates
+func (p *CredentialPool) withAccount(err error, member *poolMember) error {
+	var authRequired *AuthenticationRequiredError
+	if !errors.As(err, &authRequired) {
+		return err
+	}
+	named := *authRequired
+	named.Account = member.name
+	named.DeviceFlowAccount = member == p.members[0]
+	return &named
+}
+
+// selectMember picks the next member that is not skipped, preferring accounts that are not throttled
+func (p *CredentialPool) selectMember(skipped map[string]bool) *poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var selected, fallback *poolMember
+	selectedIndex := -1
+	for offset := 0; offset < len(p.members); offset++ {
+		index := (p.next + offset) % len(p.members)
+		member := p.members[index]
+		if skipped[member.name] {
+			continue
+		}
+		if member.throttledUntil.After(now) {
+			// Remember the throttled account that recovers first in case nothing healthy is left
+			if fallback == nil || member.throttledUntil.Before(fallback.throttledUntil) {
+				fallback = member
+			}
+			continue
+		}
+		if selected == nil || (p.strategy == StrategyLeastThrottled && member.lastThrottled.Before(selected.lastThrottled)) {
+			selected = member
+			selectedIndex = index
+		}
+		if p.strategy != StrategyLeastThrottled {
+			break
+		}
+	}
+
+	if selected == nil {
+		return fallback
+	}
+	p.next = (selectedIndex + 1) % len(p.members)
+	return selected
+}
+
+// ReportThrottled marks an account as rate limited so that it is skipped until the cooldown expires
+func (p *CredentialPool) ReportThrottled(name string, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = p.cooldown
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, member := range p.members {
+		if member.name == name {
+			now := time.Now()
+			member.throttledUntil = now.Add(retryAfter)
+			member.lastThrottled = now
+			member.throttleCount++
+			p.logger.Warn("Pooled account throttled", "account", name, "retry_after", retryAfter, "throttle_count", member.throttleCount)
+			return
+		}
+	}
+}
+
+// AccountStatuses returns the current status of every account in the pool
+func (p *CredentialPool) AccountStatuses() []entities.AccountStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]entities.AccountStatus, len(p.members))
+	for i, member := range p.members {
+		statuses[i] = entities.AccountStatus{
+			Name:          member.name,
+			Throttled:     member.throttledUntil.After(now),
+			ThrottleCount: member.throttleCount,
+		}
+		if statuses[i].Throttled {
+			throttledUntil := member.throttledUntil
+			statuses[i].ThrottledUntil = &throttledUntil
+		}
+	}
+	return statuses
+}
+
+// EnsureAuthenticated returns credentials from the next available account
+func (p *CredentialPool) EnsureAuthenticated(ctx context.Context) (*entities.Credentials, error) {
+	account, err := p.AcquireAccount(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return account.Credentials, nil
+}
+
+// StartDeviceFlow starts device flow authentication for the first account in the pool.
+// Other accounts are authenticated with the auth command, which the errors of their requests name.
+func (p *CredentialPool) StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error) {
+	return p.members[0].authUseCase.StartDeviceFlow(ctx)
+}
+
+// DeviceFlowStatus returns the status of the device flow of the first account in the pool
+func (p *CredentialPool) DeviceFlowStatus() *entities.DeviceFlowStatus {
+	return p.members[0].authUseCase.DeviceFlowStatus()
+}
+
+// CheckAuthentication checks if any account in the pool is authenticated, without performing device flow
+func (p *CredentialPool) CheckAuthentication() (*entities.Credentials, error) {
+	var lastErr error
+	for _, member := range p.members {
+		credentials, err := member.authUseCase.CheckAuthentication()
+		if err == nil {
+			return credentials, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %w", ErrNoAccountsAvailable, lastErr)
+}
+
+// authStatePriority orders the authentication states from the least to the most usable
+var authStatePriority = map[string]int{
+	entities.AuthStateUnauthenticated: 0,
+	entities.AuthStateRefreshFailed:   1,
+	entities.AuthStateAuthenticating:  2,
+	entities.AuthStateValid:           3,
+}
+
+// AuthState returns the most usable authentication state of the accounts in the pool
+func (p *CredentialPool) AuthState() string {
+	state := entities.AuthStateUnauthenticated
+	for _, member := range p.members {
+		if memberState := member.authUseCase.AuthState(); authStatePriority[memberState] > authStatePriority[state] {
+			state = memberState
+		}
+	}
+	return state
+}
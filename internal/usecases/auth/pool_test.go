@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestPool(t *testing.T, strategy string, names ...string) (*CredentialPool, map[string]*mocks.MockAuthUseCaseInterface) {
+	ctrl := gomock.NewController(t)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	members := make(map[string]*mocks.MockAuthUseCaseInterface, len(names))
+	accounts := make([]PoolAccount, len(names))
+	for i, name := range names {
+		members[name] = mocks.NewMockAuthUseCaseInterface(ctrl)
+		members[name].EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{AccessToken: name}, nil).AnyTimes()
+		accounts[i] = PoolAccount{Name: name, AuthUseCase: members[name]}
+	}
+	return NewCredentialPool(accounts, strategy, time.Minute, logger), members
+}
+
+func TestNewCredentialPool_Panics(t *testing.T) {
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	assert.PanicsWithValue(t, "accounts cannot be empty", func() {
+		NewCredentialPool(nil, StrategyRoundRobin, time.Minute, logger)
+	})
+	assert.PanicsWithValue(t, "logger cannot be nil", func() {
+		NewCredentialPool([]PoolAccount{{Name: "a", AuthUseCase: &AuthUseCase{}}}, StrategyRoundRobin, time.Minute, nil)
+	})
+}
+
+func TestCredentialPool_RoundRobin(t *testing.T) {
+	pool, _ := newTestPool(t, StrategyRoundRobin, "a", "b", "c")
+
+	var selected []string
+	for i := 0; i < 4; i++ {
+		account, err := pool.AcquireAccount(context.Background(), nil)
+		require.NoError(t, err)
+		selected = append(selected, account.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, selected)
+}
+
+func TestCredentialPool_SkipsThrottledAccounts(t *testing.T) {
+	pool, _ := newTestPool(t, StrategyRoundRobin, "a", "b")
+
+	pool.ReportThrottled("a", 0)
+
+	for i := 0; i < 3; i++ {
+		account, err := pool.AcquireAccount(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "b", account.Name)
+	}
+
+	statuses := pool.AccountStatuses()
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Throttled)
+	require.NotNil(t, statuses[0].ThrottledUntil)
+	assert.True(t, statuses[0].ThrottledUntil.After(time.Now()))
+	assert.Equal(t, 1, statuses[0].ThrottleCount)
+	assert.False(t, statuses[1].Throttled)
+	assert.Nil(t, statuses[1].ThrottledUntil)
+
+	encoded, err := json.Marshal(statuses[1])
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "throttled_until")
+}
+
+func TestCredentialPool_AllThrottledUsesSoonestRecovery(t *testing.T) {
+	pool, _ := newTestPool(t, StrategyRoundRobin, "a", "b")
+
+	pool.ReportThrottled("a", 2*time.Minute)
+	pool.ReportThrottled("b", 10*time.Second)
+
+	account, err := pool.AcquireAccount(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "b", account.Name)
+}
+
+func TestCredentialPool_ThrottleExpires(t *testing.T) {
+	pool, _ := newTestPool(t, StrategyRoundRobin, "a", "b")
+
+	pool.ReportThrottled("a", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	assert.False(t, pool.AccountStatuses()[0].Throttled)
+}
+
+func TestCredentialPool_Exclude(t *testing.T) {
+	pool, _ := newTestPool(t, StrategyRoundRobin, "a", "b")
+
+	account, err := pool.AcquireAccount(context.Background(), map[string]bool{"a": true})
+	require.NoError(t, err)
+	assert.Equal(t, "b", account.Name)
+
+	_, err = pool.AcquireAccount(context.Background(), map[string]bool{"a": true, "b": true})
+	assert.ErrorIs(t, err, ErrNoAccountsAvailable)
+}
+
+func TestCredentialPool_LeastRecentlyThrottled(t *testing.T) {
+	pool, _ := newTestPool(t, StrategyLeastThrottled, "a", "b", "c")
+
+	pool.ReportThrottled("a", time.Millisecond)
+	pool.ReportThrottled("b", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// c has never been throttled, so it is preferred over a and b
+	account, err := pool.AcquireAccount(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c", account.Name)
+
+	// a was throttled before b
+	account, err = pool.AcquireAccount(context.Background(), map[string]bool{"c": true})
+	require.NoError(t, err)
+	assert.Equal(t, "a", account.Name)
+}
+
+func TestCredentialPool_SkipsAccountsFailingAuthentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	failing := mocks.NewMockAuthUseCaseInterface(ctrl)
+	failing.EXPECT().EnsureAuthenticated(gomock.Any()).Return(nil, errors.New("refresh failed"))
+	healthy := mocks.NewMockAuthUseCaseInterface(ctrl)
+	healthy.EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{AccessToken: "b"}, nil)
+
+	pool := NewCredentialPool([]PoolAccount{
+		{Name: "a", AuthUseCase: failing},
+		{Name: "b", AuthUseCase: healthy},
+	}, StrategyRoundRobin, time.Minute, logger)
+
+	credentials, err := pool.EnsureAuthenticated(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "b", credentials.AccessToken)
+}
+
+func TestCredentialPool_AllAccountsFailAuthentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	failing := mocks.NewMockAuthUseCaseInterface(ctrl)
+	failing.EXPECT().EnsureAuthenticated(gomock.Any()).Return(nil, &AuthenticationRequiredError{State: entities.AuthStateRefreshFailed, Err: errors.New("refresh failed")})
+
+	pool := NewCredentialPool([]PoolAccount{{Name: "a", AuthUseCase: failing}}, StrategyRoundRobin, time.Minute, logger)
+
+	_, err := pool.EnsureAuthenticated(context.Background())
+	assert.ErrorIs(t, err, ErrNoAccountsAvailable)
+	assert.Contains(t, err.Error(), "refresh failed")
+	var authRequired *AuthenticationRequiredError
+	assert.ErrorAs(t, err, &authRequired)
+	assert.Equal(t, "a", authRequired.Account)
+	assert.Contains(t, err.Error(), "GET /auth or `qwen-go-proxy auth --account a`")
+}
+
+func TestCredentialPool_AuthenticationErrorNamesAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	first := mocks.NewMockAuthUseCaseInterface(ctrl)
+	failing := mocks.NewMockAuthUseCaseInterface(ctrl)
+	failing.EXPECT().EnsureAuthenticated(gomock.Any()).Return(nil, &AuthenticationRequiredError{State: entities.AuthStateRefreshFailed, Err: errors.New("invalid_grant")})
+
+	pool := NewCredentialPool([]PoolAccount{
+		{Name: "default", AuthUseCase: first},
+		{Name: "work", AuthUseCase: failing},
+	}, StrategyRoundRobin, time.Minute, logger)
+
+	_, err := pool.AcquireAccount(context.Background(), map[string]bool{"default": true})
+
+	var authRequired *AuthenticationRequiredError
+	require.ErrorAs(t, err, &authRequired)
+	assert.Equal(t, "work", authRequired.Account)
+	assert.False(t, authRequired.DeviceFlowAccount)
+	assert.Contains(t, err.Error(), `Refreshing the Qwen OAuth token of account "work" failed (invalid_grant)`)
+	assert.Contains(t, err.Error(), "Re-authenticate with `qwen-go-proxy auth --account work`.")
+	assert.NotContains(t, err.Error(), "GET /auth")
+}
+
+func TestCredentialPool_CheckAuthentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	unauthenticated := mocks.NewMockAuthUseCaseInterface(ctrl)
+	unauthenticated.EXPECT().CheckAuthentication().Return(nil, errors.New("no credentials available")).Times(2)
+	authenticated := mocks.NewMockAuthUseCaseInterface(ctrl)
+	authenticated.EXPECT().CheckAuthentication().Return(&entities.Credentials{AccessToken: "b"}, nil)
+
+	pool := NewCredentialPool([]PoolAccount{
+		{Name: "a", AuthUseCase: unauthenticated},
+		{Name: "b", AuthUseCase: authenticated},
+	}, StrategyRoundRobin, time.Minute, logger)
+
+	credentials, err := pool.CheckAuthentication()
+	require.NoError(t, err)
+	assert.Equal(t, "b", credentials.AccessToken)
+
+	pool = NewCredentialPool([]PoolAccount{{Name: "a", AuthUseCase: unauthenticated}}, StrategyRoundRobin, time.Minute, logger)
+	_, err = pool.CheckAuthentication()
+	assert.ErrorIs(t, err, ErrNoAccountsAvailable)
+}
+
+func TestCredentialPool_StartDeviceFlowUsesFirstAccount(t *testing.T) {
+	pool, members := newTestPool(t, StrategyRoundRobin, "a", "b")
+	status := &entities.DeviceFlowStatus{State: entities.DeviceFlowPending, UserCode: "ABCD-EFGH"}
+	members["a"].EXPECT().StartDeviceFlow(gomock.Any()).Return(status, nil)
+	members["a"].EXPECT().DeviceFlowStatus().Return(status)
+
+	result, err := pool.StartDeviceFlow(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, status, result)
+	assert.Equal(t, status, pool.DeviceFlowStatus())
+}
+
+func TestCredentialPool_AuthState(t *testing.T) {
+	pool, members := newTestPool(t, StrategyRoundRobin, "a", "b", "c")
+	members["a"].EXPECT().AuthState().Return(entities.AuthStateRefreshFailed).Times(2)
+	members["b"].EXPECT().AuthState().Return(entities.AuthStateAuthenticating)
+	members["c"].EXPECT().AuthState().Return(entities.AuthStateUnauthenticated)
+
+	assert.Equal(t, entities.AuthStateAuthenticating, pool.AuthState())
+
+	members["b"].EXPECT().AuthState().Return(entities.AuthStateUnauthenticated)
+	members["c"].EXPECT().AuthState().Return(entities.AuthStateValid)
+
+	assert.Equal(t, entities.AuthStateValid, pool.AuthState())
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+)
+
+const (
+	// refreshInitialBackoff is the delay before the first retry of a failed background refresh
+	refreshInitialBackoff = 5 * time.Second
+	// defaultRefreshMaxBackoff is used when no maximum backoff is configured
+	defaultRefreshMaxBackoff = 5 * time.Minute
+	// minRefreshInterval keeps tokens that live shorter than the refresh buffer from being refreshed in a loop
+	minRefreshInterval = 30 * time.Second
+)
+
+// RefreshAccount is an account whose tokens are refreshed by the TokenRefresher
+type RefreshAccount struct {
+	Name        string
+	AuthUseCase *AuthUseCase
+}
+
+// refreshState is the schedule of the background refreshes of an account
+type refreshState struct {
+	next     time.Time
+	failures int
+}
+
+// TokenRefresher refreshes the tokens of its accounts in the background, before they come within
+// the token refresh buffer, so that requests never wait for a refresh. Every refresh happens at a
+// random point up to the jitter ahead of the buffer, so that accounts do not refresh together, and
+// failed refreshes are retried with exponential backoff. Accounts without credentials are checked
+// again every max backoff.
+type TokenRefresher struct {
+	accounts       []RefreshAccount
+	buffer         time.Duration
+	jitter         time.Duration
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	logger         logging.LoggerInterface
+
+	mu     sync.Mutex
+	states map[string]*refreshState
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTokenRefresher creates a background token refresher for the accounts
+func NewTokenRefresher(accounts []RefreshAccount, config *entities.Config, logger logging.LoggerInterface) *TokenRefresher {
+	if len(accounts) == 0 {
+		panic("accounts cannot be empty")
+	}
+	if config == nil {
+		panic("config cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	states := make(map[string]*refreshState, len(accounts))
+	for _, account := range accounts {
+		if account.AuthUseCase == nil {
+			panic(fmt.Sprintf("authUseCase for account %s cannot be nil", account.Name))
+		}
+		states[account.Name] = &refreshState{}
+	}
+	maxBackoff := config.TokenRefreshMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRefreshMaxBackoff
+	}
+	return &TokenRefresher{
+		accounts:       accounts,
+		buffer:         config.TokenRefreshBuffer,
+		jitter:         config.TokenRefreshJitter,
+		initialBackoff: min(refreshInitialBackoff, maxBackoff),
+		maxBackoff:     maxBackoff,
+		logger:         logger,
+		states:         states,
+	}
+}
+
+// Start starts refreshing the tokens of every account in the background
+func (r *TokenRefresher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	for _, account := range r.accounts {
+		r.wg.Add(1)
+		go func(account RefreshAccount) {
+			defer r.wg.Done()
+			r.run(ctx, account)
+		}(account)
+	}
+	r.logger.Info("Background token refresh started", "accounts", len(r.accounts), "buffer", r.buffer, "jitter", r.jitter)
+}
+
+// Stop stops the background refreshes and waits until a refresh in progress has saved its
+// credentials, or until ctx is done
+func (r *TokenRefresher) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		r.logger.Info("Background token refresh stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("token refresh did not stop: %w", ctx.Err())
+	}
+}
+
+// Statuses returns the last refresh, its error and the next scheduled refresh of every account
+func (r *TokenRefresher) Statuses() []entities.TokenRefreshStatus {
+	statuses := make([]entities.TokenRefreshStatus, len(r.accounts))
+	for i, account := range r.accounts {
+		status := account.AuthUseCase.RefreshStatus()
+		status.Account = account.Name
+
+		r.mu.Lock()
+		state := r.states[account.Name]
+		if !state.next.IsZero() {
+			next := state.next
+			status.NextRefreshAt = &next
+		}
+		status.ConsecutiveFailures = state.failures
+		r.mu.Unlock()
+
+		statuses[i] = status
+	}
+	return statuses
+}
+
+// run refreshes the tokens of an account until ctx is done
+func (r *TokenRefresher) run(ctx context.Context, account RefreshAccount) {
+	buffer := r.buffer
+	failures := 0
+	for {
+		var wait time.Duration
+		credentials, err := account.AuthUseCase.refreshWithin(ctx, buffer)
+		var authRequired *AuthenticationRequiredError
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.As(err, &authRequired) && authRequired.State != entities.AuthStateRefreshFailed:
+			// There is nothing to refresh until the account is authenticated
+			failures = 0
+			wait = r.maxBackoff
+		case err != nil:
+			failures++
+			wait = r.backoff(failures)
+			r.logger.Warn("Background token refresh failed",
+				"account", account.Name,
+				"failures", failures,
+				"retry_in", wait,
+				"error", err)
+		default:
+			if failures > 0 {
+				r.logger.Info("Background token refresh recovered", "account", account.Name, "failures", failures)
+			}
+			failures = 0
+			buffer = r.buffer + r.randomJitter()
+			wait = max(time.Until(time.UnixMilli(credentials.ExpiryDate))-buffer, minRefreshInterval)
+		}
+
+		r.mu.Lock()
+		r.states[account.Name].next = time.Now().Add(wait)
+		r.states[account.Name].failures = failures
+		r.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// randomJitter returns a random duration up to the jitter
+func (r *TokenRefresher) randomJitter() time.Duration {
+	if r.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(r.jitter) + 1))
+}
+
+// backoff returns the delay before retrying after the given number of failed refreshes: an
+// exponentially growing delay, capped at the max backoff, of which the upper half is random
+func (r *TokenRefresher) backoff(failures int) time.Duration {
+	delay := r.initialBackoff << (failures - 1)
+	if delay <= 0 || delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestRefresher(t *testing.T, repo *mockCredentialRepository) (*TokenRefresher, *AuthUseCase, *mocks.MockOAuthService) {
+	ctrl := gomock.NewController(t)
+	config := &entities.Config{
+		QWENOAuthClientID:      "test-client-id",
+		TokenRefreshBuffer:     5 * time.Minute,
+		TokenRefreshJitter:     time.Minute,
+		TokenRefreshMaxBackoff: 10 * time.Millisecond,
+	}
+	oauthService := mocks.NewMockOAuthService(ctrl)
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+
+	useCase := NewAuthUseCase(config, oauthService, repo, &recordingNotifier{}, logger)
+	refresher := NewTokenRefresher([]RefreshAccount{{Name: "default", AuthUseCase: useCase}}, config, logger)
+	refresher.initialBackoff = time.Millisecond
+	t.Cleanup(func() {
+		require.NoError(t, refresher.Stop(context.Background()))
+	})
+	return refresher, useCase, oauthService
+}
+
+func TestNewTokenRefresher_Panics(t *testing.T) {
+	config := &entities.Config{}
+	logger := logging.NewLoggerFromConfig(&entities.Config{LogLevel: "error"})
+	useCase := NewAuthUseCase(config, mocks.NewMockOAuthService(gomock.NewController(t)), &mockCredentialRepository{}, &recordingNotifier{}, logger)
+	accounts := []RefreshAccount{{Name: "default", AuthUseCase: useCase}}
+
+	assert.Panics(t, func() { NewTokenRefresher(nil, config, logger) })
+	assert.Panics(t, func() { NewTokenRefresher(accounts, nil, logger) })
+	assert.Panics(t, func() { NewTokenRefresher(accounts, config, nil) })
+	assert.Panics(t, func() { NewTokenRefresher([]RefreshAccount{{Name: "default"}}, config, logger) })
+}
+
+func TestTokenRefresher_RefreshesAheadOfBuffer(t *testing.T) {
+	repo := &mockCredentialRepository{
+		loadCredentials: &entities.Credentials{
+			AccessToken:  "old-token",
+			RefreshToken: "refresh-token",
+			ExpiryDate:   time.Now().Add(time.Minute).UnixMilli(),
+			ResourceURL:  "https://api.example.com",
+		},
+	}
+	refresher, useCase, oauthService := newTestRefresher(t, repo)
+	expiry := time.Now().Add(time.Hour)
+	oauthService.EXPECT().
+		RefreshToken("refresh-token", "test-client-id").
+		Return(&entities.Credentials{AccessToken: "new-token", RefreshToken: "new-refresh-token", ExpiryDate: expiry.UnixMilli()}, nil).
+		Times(1)
+
+	refresher.Start()
+	assert.Eventually(t, func() bool {
+		return refresher.Statuses()[0].NextRefreshAt != nil
+	}, time.Second, time.Millisecond)
+
+	status := refresher.Statuses()[0]
+	assert.Equal(t, "default", status.Account)
+	require.NotNil(t, status.LastRefreshAt)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	// The next refresh is scheduled up to the jitter ahead of the refresh buffer
+	assert.WithinRange(t, *status.NextRefreshAt, expiry.Add(-6*time.Minute-time.Second), expiry.Add(-5*time.Minute))
+
+	credentials, err := useCase.EnsureAuthenticated(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "new-token", credentials.AccessToken)
+	assert.Equal(t, "https://api.example.com", credentials.ResourceURL)
+}
+
+func TestTokenRefresher_RetriesWithBackoff(t *testing.T) {
+	repo := &mockCredentialRepository{
+		loadCredentials: &entities.Credentials{
+			AccessToken:  "old-token",
+			RefreshToken: "refresh-token",
+			ExpiryDate:   time.Now().Add(-time.Minute).UnixMilli(),
+		},
+	}
+	refresher, useCase, oauthService := newTestRefresher(t, repo)
+	gomock.InOrder(
+		oauthService.EXPECT().RefreshToken("refresh-token", "test-client-id").Return(nil, errors.New("connection reset")).Times(2),
+		oauthService.EXPECT().RefreshToken("refresh-token", "test-client-id").
+			Return(&entities.Credentials{AccessToken: "new-token", ExpiryDate: time.Now().Add(time.Hour).UnixMilli()}, nil),
+	)
+
+	refresher.Start()
+	assert.Eventually(t, func() bool {
+		return refresher.Statuses()[0].LastRefreshAt != nil
+	}, time.Second, time.Millisecond)
+
+	status := refresher.Statuses()[0]
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Equal(t, entities.AuthStateValid, useCase.AuthState())
+}
+
+func TestTokenRefresher_ReportsFailures(t *testing.T) {
+	repo := &mockCredentialRepository{
+		loadCredentials: &entities.Credentials{
+			AccessToken:  "old-token",
+			RefreshToken: "refresh-token",
+			ExpiryDate:   time.Now().Add(-time.Minute).UnixMilli(),
+		},
+	}
+	refresher, useCase, oauthService := newTestRefresher(t, repo)
+	oauthService.EXPECT().RefreshToken("refresh-token", "test-client-id").Return(nil, errors.New("invalid_grant")).MinTimes(2)
+
+	refresher.Start()
+	assert.Eventually(t, func() bool {
+		return refresher.Statuses()[0].ConsecutiveFailures >= 2
+	}, time.Second, time.Millisecond)
+
+	status := refresher.Statuses()[0]
+	assert.Nil(t, status.LastRefreshAt)
+	assert.Contains(t, status.LastError, "invalid_grant")
+	require.NotNil(t, status.NextRefreshAt)
+	assert.Equal(t, entities.AuthStateRefreshFailed, useCase.AuthState())
+}
+
+func TestTokenRefresher_WaitsForAuthentication(t *testing.T) {
+	// Without credentials there is nothing to refresh, so the OAuth service is not called
+	repo := &mockCredentialRepository{loadError: fmt.Errorf("file not found")}
+	refresher, _, _ := newTestRefresher(t, repo)
+
+	refresher.Start()
+	assert.Eventually(t, func() bool {
+		return refresher.Statuses()[0].NextRefreshAt != nil
+	}, time.Second, time.Millisecond)
+
+	status := refresher.Statuses()[0]
+	assert.Nil(t, status.LastRefreshAt)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+}
+
+func TestTokenRefresher_Stop(t *testing.T) {
+	repo := &mockCredentialRepository{loadError: fmt.Errorf("file not found")}
+	refresher, _, _ := newTestRefresher(t, repo)
+
+	// Stopping a refresher that was never started does nothing
+	require.NoError(t, refresher.Stop(context.Background()))
+
+	refresher.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, refresher.Stop(ctx))
+}
+
+func TestTokenRefresher_Backoff(t *testing.T) {
+	refresher := &TokenRefresher{initialBackoff: 5 * time.Second, maxBackoff: time.Minute}
+
+	for failures, ceiling := range map[int]time.Duration{1: 5 * time.Second, 3: 20 * time.Second, 5: time.Minute, 100: time.Minute} {
+		backoff := refresher.backoff(failures)
+		assert.GreaterOrEqual(t, backoff, ceiling/2, "failures %d", failures)
+		assert.LessOrEqual(t, backoff, ceiling, "failures %d", failures)
+	}
+}
@@ -0,0 +1,347 @@
+// Package models implements the model catalog: the models served by the proxy, their aliases
+// and capabilities, merged from the configured catalog and the upstream model list.
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/interfaces/gateways"
+	"qwen-go-proxy/internal/usecases/auth"
+)
+
+// discoveryRetryInterval is how long a failed upstream discovery is not retried, unless the discovery TTL is shorter
+const discoveryRetryInterval = 30 * time.Second
+
+// builtinModels are served when no catalog file is configured
+var builtinModels = []entities.ModelCatalogEntry{
+	{ID: "qwen3-coder-plus", OwnedBy: "qwen", Created: 1754686206},
+	{ID: "qwen3-coder-flash", OwnedBy: "qwen", Created: 1754686206},
+	{ID: "vision-model", OwnedBy: "qwen", Created: 1754686206},
+}
+
+// ModelNotFoundError is returned for models that are neither in the catalog nor offered upstream
+type ModelNotFoundError struct {
+	Model string
+}
+
+// Error implements the error interface
+func (e *ModelNotFoundError) Error() string {
+	return fmt.Sprintf("model %q not found", e.Model)
+}
+
+// ModelCatalogInterface defines the interface for model catalog operations
+type ModelCatalogInterface interface {
+	ListModels(ctx context.Context) ([]*entities.ModelInfo, error)
+	GetModel(ctx context.Context, id string) (*entities.ModelInfo, error)
+	ResolveModel(ctx context.Context, name string) (string, error)
+}
+
+// ModelCatalog merges the configured model catalog with the models discovered upstream.
+// Catalog entries take precedence and supply aliases and capabilities; upstream models that
+// are not in the catalog are served with their upstream metadata. The upstream list is
+// cached for the discovery TTL, and failed lookups are retried after a short interval, so that
+// an upstream without a model list is not queried on every request. Upstream is queried
+// without holding the lock, so a slow discovery does not hold up catalog lookups.
+type ModelCatalog struct {
+	authUseCase  auth.AuthUseCaseInterface
+	qwenGateway  gateways.QwenAPIGateway
+	entries      []entities.ModelCatalogEntry
+	discoveryTTL time.Duration
+	logger       logging.LoggerInterface
+	loadedAt     int64
+	now          func() time.Time
+
+	mu            sync.Mutex
+	discovered    []*entities.ModelInfo
+	nextDiscovery time.Time
+	// discovering is closed when the discovery in flight finishes, and nil when none is
+	discovering chan struct{}
+}
+
+// NewModelCatalog creates a model catalog from the configured entries, falling back to the
+// built-in models when there are none. The default model is always served.
+// A discovery TTL of 0 disables upstream discovery.
+func NewModelCatalog(authUseCase auth.AuthUseCaseInterface, qwenGateway gateways.QwenAPIGateway, entries []entities.ModelCatalogEntry, defaultModel string, discoveryTTL time.Duration, logger logging.LoggerInterface) *ModelCatalog {
+	if authUseCase == nil {
+		panic("authUseCase cannot be nil")
+	}
+	if qwenGateway == nil {
+		panic("qwenGateway cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	if len(entries) == 0 {
+		entries = builtinModels
+	}
+	catalog := &ModelCatalog{
+		authUseCase:  authUseCase,
+		qwenGateway:  qwenGateway,
+		entries:      entries,
+		discoveryTTL: discoveryTTL,
+		logger:       logger,
+		loadedAt:     time.Now().Unix(),
+		now:          time.Now,
+	}
+	if defaultModel != "" && catalog.findEntry(defaultModel) == nil {
+		catalog.entries = append(append([]entities.ModelCatalogEntry{}, entries...), entities.ModelCatalogEntry{ID: defaultModel, OwnedBy: "qwen"})
+	}
+	return catalog
+}
+
+// WithProviderModels returns the catalog entries extended with the models declared by upstream
+// providers that the entries do not declare yet. The built-in models are kept when there are no entries.
+func WithProviderModels(entries []entities.ModelCatalogEntry, providers []entities.ProviderConfig) []entities.ModelCatalogEntry {
+	if len(entries) == 0 {
+		entries = builtinModels
+	}
+	catalog := &ModelCatalog{entries: append([]entities.ModelCatalogEntry{}, entries...)}
+	for _, provider := range providers {
+		for _, model := range provider.Models {
+			if catalog.findEntry(model) == nil {
+				catalog.entries = append(catalog.entries, entities.ModelCatalogEntry{ID: model, OwnedBy: provider.Name})
+			}
+		}
+	}
+	return catalog.entries
+}
+
+// ListModels returns the catalog models followed by the upstream models missing from the catalog
+func (c *ModelCatalog) ListModels(ctx context.Context) ([]*entities.ModelInfo, error) {
+	discovered := c.discoverModels(ctx)
+
+	models := make([]*entities.ModelInfo, 0, len(c.entries)+len(discovered))
+	listed := make(map[string]bool)
+	for i := range c.entries {
+		models = append(models, c.modelFromEntry(&c.entries[i]))
+		listed[c.entries[i].ID] = true
+	}
+	for _, model := range discovered {
+		if !listed[model.ID] {
+			models = append(models, model)
+			listed[model.ID] = true
+		}
+	}
+	return models, nil
+}
+
+// GetModel returns a model by ID or alias
+func (c *ModelCatalog) GetModel(ctx context.Context, id string) (*entities.ModelInfo, error) {
+	if entry := c.findEntry(id); entry != nil {
+		return c.modelFromEntry(entry), nil
+	}
+	for _, model := range c.discoverModels(ctx) {
+		if model.ID == id {
+			return model, nil
+		}
+	}
+	return nil, &ModelNotFoundError{Model: id}
+}
+
+// ResolveModel returns the model ID to send upstream for a requested model name or alias
+func (c *ModelCatalog) ResolveModel(ctx context.Context, name string) (string, error) {
+	model, err := c.GetModel(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if model.Deprecated {
+		c.logger.Warn("Deprecated model requested", "model", model.ID, "replaced_by", model.ReplacedBy)
+	}
+	return model.ID, nil
+}
+
+// IsCatalogModel reports whether a model ID or alias is in the catalog. Models only offered
+// upstream are not, so that the answer does not depend on upstream discovery.
+func (c *ModelCatalog) IsCatalogModel(name string) bool {
+	return c.findEntry(name) != nil
+}
+
+// findEntry returns the catalog entry with the given ID or alias
+func (c *ModelCatalog) findEntry(name string) *entities.ModelCatalogEntry {
+	for i := range c.entries {
+		entry := &c.entries[i]
+		if entry.ID == name {
+			return entry
+		}
+		for _, alias := range entry.Aliases {
+			if alias == name {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// modelFromEntry converts a catalog entry to a model, filling in upstream metadata when the
+// catalog does not declare it
+func (c *ModelCatalog) modelFromEntry(entry *entities.ModelCatalogEntry) *entities.ModelInfo {
+	created, ownedBy := entry.Created, entry.OwnedBy
+	if created == 0 || ownedBy == "" {
+		for _, upstream := range c.cachedModels() {
+			if upstream.ID == entry.ID {
+				if created == 0 {
+					created = upstream.Created
+				}
+				if ownedBy == "" {
+					ownedBy = upstream.OwnedBy
+				}
+				break
+			}
+		}
+	}
+	if created == 0 {
+		created = c.loadedAt
+	}
+	if ownedBy == "" {
+		ownedBy = "qwen"
+	}
+
+	model := newModelInfo(entry.ID, created, ownedBy)
+	model.Aliases = entry.Aliases
+	model.ContextWindow = entry.ContextWindow
+	model.MaxOutputTokens = entry.MaxOutputTokens
+	model.Modalities = entry.Modalities
+	model.SupportsTools = entry.SupportsTools
+	model.Deprecated = entry.Deprecated
+	model.ReplacedBy = entry.ReplacedBy
+	return model
+}
+
+// cachedModels returns the last discovered upstream models without querying upstream
+func (c *ModelCatalog) cachedModels() []*entities.ModelInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.discovered
+}
+
+// discoverModels returns the upstream models, refreshing them once the discovery TTL has passed.
+// Only one refresh runs at a time; meanwhile other callers get the last known list, or wait for
+// the refresh while their context allows when no list was discovered yet. A failed refresh is
+// retried after the retry interval.
+func (c *ModelCatalog) discoverModels(ctx context.Context) []*entities.ModelInfo {
+	if c.discoveryTTL <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	if c.discovering != nil {
+		discovered, discovering := c.discovered, c.discovering
+		c.mu.Unlock()
+		if discovered != nil {
+			return discovered
+		}
+		select {
+		case <-discovering:
+			return c.cachedModels()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if !c.nextDiscovery.IsZero() && c.now().Before(c.nextDiscovery) {
+		defer c.mu.Unlock()
+		return c.discovered
+	}
+	discovering := make(chan struct{})
+	c.discovering = discovering
+	c.mu.Unlock()
+
+	models, err := c.fetchModels(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.discovering = nil
+	close(discovering)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// The caller went away; try again on the next request
+	case err != nil:
+		// Keep serving the last known list until the next attempt
+		c.logger.Warn("Upstream model discovery failed", "error", err, "cached_models", len(c.discovered))
+		c.nextDiscovery = c.now().Add(min(discoveryRetryInterval, c.discoveryTTL))
+	default:
+		c.logger.Debug("Discovered upstream models", "count", len(models))
+		c.discovered = models
+		c.nextDiscovery = c.now().Add(c.discoveryTTL)
+	}
+	return c.discovered
+}
+
+// fetchModels requests the model list from upstream
+func (c *ModelCatalog) fetchModels(ctx context.Context) ([]*entities.ModelInfo, error) {
+	credentials, err := c.authUseCase.EnsureAuthenticated(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	resp, err := c.qwenGateway.ListModels(ctx, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("model list request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var list struct {
+		Data []struct {
+			ID      string `json:"id"`
+			Created int64  `json:"created"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+
+	models := make([]*entities.ModelInfo, 0, len(list.Data))
+	for _, item := range list.Data {
+		if item.ID == "" {
+			continue
+		}
+		created, ownedBy := item.Created, item.OwnedBy
+		if created == 0 {
+			created = c.loadedAt
+		}
+		if ownedBy == "" {
+			ownedBy = "qwen"
+		}
+		models = append(models, newModelInfo(item.ID, created, ownedBy))
+	}
+	return models, nil
+}
+
+// newModelInfo creates a model with the default OpenAI-compatible permissions
+func newModelInfo(id string, created int64, ownedBy string) *entities.ModelInfo {
+	return &entities.ModelInfo{
+		ID:      id,
+		Object:  "model",
+		Created: created,
+		OwnedBy: ownedBy,
+		Permission: []entities.ModelPermission{
+			{
+				ID:                 "modelperm-" + id,
+				Object:             "model_permission",
+				Created:            created,
+				AllowCreateEngine:  false,
+				AllowSampling:      true,
+				AllowLogprobs:      true,
+				AllowSearchIndices: false,
+				AllowView:          true,
+				AllowFineTuning:    false,
+				Organization:       "*",
+				Group:              nil,
+				IsBlocking:         false,
+			},
+		},
+	}
+}
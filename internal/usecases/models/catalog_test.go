@@ -0,0 +1,289 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestCatalog(t *testing.T, entries []entities.ModelCatalogEntry, discoveryTTL time.Duration) (*ModelCatalog, *mocks.MockAuthUseCaseInterface, *mocks.MockQwenAPIGateway) {
+	ctrl := gomock.NewController(t)
+	mockAuth := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+	return NewModelCatalog(mockAuth, mockGateway, entries, "qwen3-coder-plus", discoveryTTL, logger), mockAuth, mockGateway
+}
+
+func modelListResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func modelIDs(models []*entities.ModelInfo) []string {
+	ids := make([]string, len(models))
+	for i, model := range models {
+		ids[i] = model.ID
+	}
+	return ids
+}
+
+func TestNewModelCatalog_NilArguments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAuth := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+
+	assert.PanicsWithValue(t, "authUseCase cannot be nil", func() { NewModelCatalog(nil, mockGateway, nil, "", 0, logger) })
+	assert.PanicsWithValue(t, "qwenGateway cannot be nil", func() { NewModelCatalog(mockAuth, nil, nil, "", 0, logger) })
+	assert.PanicsWithValue(t, "logger cannot be nil", func() { NewModelCatalog(mockAuth, mockGateway, nil, "", 0, nil) })
+}
+
+func TestModelCatalog_BuiltinModels(t *testing.T) {
+	catalog, _, _ := newTestCatalog(t, nil, 0)
+
+	listed, err := catalog.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qwen3-coder-plus", "qwen3-coder-flash", "vision-model"}, modelIDs(listed))
+	assert.Equal(t, "model", listed[0].Object)
+	assert.Equal(t, "qwen", listed[0].OwnedBy)
+	require.Len(t, listed[0].Permission, 1)
+	assert.Equal(t, "modelperm-qwen3-coder-plus", listed[0].Permission[0].ID)
+}
+
+func TestModelCatalog_DefaultModelIsAlwaysServed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+	catalog := NewModelCatalog(mocks.NewMockAuthUseCaseInterface(ctrl), mocks.NewMockQwenAPIGateway(ctrl), nil, "qwen-max", 0, logger)
+
+	model, err := catalog.ResolveModel(context.Background(), "qwen-max")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen-max", model)
+	assert.Len(t, builtinModels, 3, "the built-in models must not be modified")
+}
+
+func TestModelCatalog_AliasesAndCapabilities(t *testing.T) {
+	supportsTools := true
+	catalog, _, _ := newTestCatalog(t, []entities.ModelCatalogEntry{
+		{
+			ID:            "qwen3-coder-plus",
+			Aliases:       []string{"coder", "gpt-4o"},
+			ContextWindow: 1000000,
+			Modalities:    []string{"text"},
+			SupportsTools: &supportsTools,
+		},
+		{ID: "qwen-old", Created: 1700000000, Deprecated: true, ReplacedBy: "qwen3-coder-plus"},
+	}, 0)
+
+	model, err := catalog.ResolveModel(context.Background(), "gpt-4o")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen3-coder-plus", model)
+
+	info, err := catalog.GetModel(context.Background(), "coder")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen3-coder-plus", info.ID)
+	assert.Equal(t, []string{"coder", "gpt-4o"}, info.Aliases)
+	assert.Equal(t, 1000000, info.ContextWindow)
+	assert.Equal(t, []string{"text"}, info.Modalities)
+	assert.True(t, *info.SupportsTools)
+	assert.NotZero(t, info.Created)
+
+	// Deprecated models are still served
+	model, err = catalog.ResolveModel(context.Background(), "qwen-old")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen-old", model)
+	info, _ = catalog.GetModel(context.Background(), "qwen-old")
+	assert.True(t, info.Deprecated)
+	assert.Equal(t, int64(1700000000), info.Created)
+}
+
+func TestModelCatalog_UnknownModel(t *testing.T) {
+	catalog, _, _ := newTestCatalog(t, nil, 0)
+
+	_, err := catalog.ResolveModel(context.Background(), "gpt-4")
+	var notFound *ModelNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "gpt-4", notFound.Model)
+
+	_, err = catalog.GetModel(context.Background(), "gpt-4")
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestModelCatalog_IsCatalogModel(t *testing.T) {
+	catalog, _, _ := newTestCatalog(t, []entities.ModelCatalogEntry{
+		{ID: "qwen3-coder-plus", Aliases: []string{"coder"}},
+	}, 0)
+
+	assert.True(t, catalog.IsCatalogModel("qwen3-coder-plus"))
+	assert.True(t, catalog.IsCatalogModel("coder"))
+	// Upstream discovery is not consulted
+	assert.False(t, catalog.IsCatalogModel("qwen-max"))
+	assert.False(t, catalog.IsCatalogModel(""))
+}
+
+func TestModelCatalog_MergesUpstreamModels(t *testing.T) {
+	catalog, mockAuth, mockGateway := newTestCatalog(t, []entities.ModelCatalogEntry{
+		{ID: "qwen3-coder-plus", Aliases: []string{"coder"}},
+	}, time.Minute)
+
+	credentials := &entities.Credentials{AccessToken: "token"}
+	mockAuth.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockGateway.EXPECT().ListModels(gomock.Any(), credentials).Return(modelListResponse(http.StatusOK,
+		`{"object":"list","data":[{"id":"qwen3-coder-plus","created":1754000000,"owned_by":"alibaba"},{"id":"qwen3-max","created":1755000000}]}`), nil)
+
+	listed, err := catalog.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qwen3-coder-plus", "qwen3-max"}, modelIDs(listed))
+	// Upstream metadata fills in what the catalog does not declare
+	assert.Equal(t, int64(1754000000), listed[0].Created)
+	assert.Equal(t, "alibaba", listed[0].OwnedBy)
+	assert.Equal(t, []string{"coder"}, listed[0].Aliases)
+	assert.Equal(t, int64(1755000000), listed[1].Created)
+	assert.Equal(t, "qwen", listed[1].OwnedBy)
+
+	// Upstream models are accepted, and the list is served from cache within the TTL
+	model, err := catalog.ResolveModel(context.Background(), "qwen3-max")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen3-max", model)
+}
+
+func TestModelCatalog_DiscoveryRefreshAfterTTL(t *testing.T) {
+	catalog, mockAuth, mockGateway := newTestCatalog(t, nil, time.Minute)
+	now := time.Unix(1700000000, 0)
+	catalog.now = func() time.Time { return now }
+
+	mockAuth.EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{}, nil).Times(3)
+	gomock.InOrder(
+		mockGateway.EXPECT().ListModels(gomock.Any(), gomock.Any()).Return(modelListResponse(http.StatusOK, `{"data":[{"id":"qwen3-max"}]}`), nil),
+		mockGateway.EXPECT().ListModels(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused")),
+		mockGateway.EXPECT().ListModels(gomock.Any(), gomock.Any()).Return(modelListResponse(http.StatusOK, `{"data":[{"id":"qwen3-next"}]}`), nil),
+	)
+
+	_, err := catalog.ResolveModel(context.Background(), "qwen3-max")
+	require.NoError(t, err)
+
+	// A failed refresh keeps serving the last known list
+	now = now.Add(time.Minute)
+	_, err = catalog.ResolveModel(context.Background(), "qwen3-max")
+	require.NoError(t, err)
+
+	// and is not retried before the retry interval passes
+	_, err = catalog.ResolveModel(context.Background(), "qwen3-next")
+	assert.Error(t, err)
+
+	now = now.Add(discoveryRetryInterval)
+	_, err = catalog.ResolveModel(context.Background(), "qwen3-next")
+	require.NoError(t, err)
+	_, err = catalog.ResolveModel(context.Background(), "qwen3-max")
+	assert.Error(t, err, "models removed upstream are no longer served")
+}
+
+func TestModelCatalog_DiscoveryRetriedAfterAuthentication(t *testing.T) {
+	catalog, mockAuth, mockGateway := newTestCatalog(t, nil, time.Hour)
+	now := time.Unix(1700000000, 0)
+	catalog.now = func() time.Time { return now }
+
+	gomock.InOrder(
+		mockAuth.EXPECT().EnsureAuthenticated(gomock.Any()).Return(nil, errors.New("authentication required")),
+		mockAuth.EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{}, nil),
+	)
+	mockGateway.EXPECT().ListModels(gomock.Any(), gomock.Any()).Return(modelListResponse(http.StatusOK, `{"data":[{"id":"qwen3-max"}]}`), nil)
+
+	// A discovery before any account is authenticated does not block discovery for the whole TTL
+	_, err := catalog.ResolveModel(context.Background(), "qwen3-max")
+	assert.Error(t, err)
+	_, err = catalog.ResolveModel(context.Background(), "qwen3-max")
+	assert.Error(t, err)
+
+	now = now.Add(discoveryRetryInterval)
+	_, err = catalog.ResolveModel(context.Background(), "qwen3-max")
+	require.NoError(t, err)
+}
+
+func TestModelCatalog_SlowDiscoveryDoesNotBlockLookups(t *testing.T) {
+	catalog, mockAuth, mockGateway := newTestCatalog(t, nil, time.Minute)
+
+	requested := make(chan struct{})
+	respond := make(chan struct{})
+	mockAuth.EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{}, nil)
+	mockGateway.EXPECT().ListModels(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, credentials *entities.Credentials) (*http.Response, error) {
+			close(requested)
+			<-respond
+			return modelListResponse(http.StatusOK, `{"data":[{"id":"qwen3-max"}]}`), nil
+		})
+
+	discovered := make(chan error)
+	go func() {
+		_, err := catalog.ResolveModel(context.Background(), "qwen3-max")
+		discovered <- err
+	}()
+	<-requested
+
+	// Catalog models are served while upstream is queried
+	model, err := catalog.ResolveModel(context.Background(), "qwen3-coder-plus")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen3-coder-plus", model)
+
+	// Callers waiting for the first discovery give up with their context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = catalog.ResolveModel(ctx, "qwen3-max")
+	assert.Error(t, err)
+
+	close(respond)
+	require.NoError(t, <-discovered)
+}
+
+func TestModelCatalog_UpstreamWithoutModelList(t *testing.T) {
+	catalog, mockAuth, mockGateway := newTestCatalog(t, nil, time.Minute)
+
+	mockAuth.EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{}, nil)
+	mockGateway.EXPECT().ListModels(gomock.Any(), gomock.Any()).Return(modelListResponse(http.StatusNotFound, `not found`), nil)
+
+	for i := 0; i < 3; i++ {
+		listed, err := catalog.ListModels(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"qwen3-coder-plus", "qwen3-coder-flash", "vision-model"}, modelIDs(listed))
+	}
+}
+
+func TestModelCatalog_DiscoveryDisabled(t *testing.T) {
+	// The mocks fail the test if upstream is queried
+	catalog, _, _ := newTestCatalog(t, nil, 0)
+
+	listed, err := catalog.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, listed, 3)
+}
+
+func TestWithProviderModels(t *testing.T) {
+	providers := []entities.ProviderConfig{
+		{Name: "qwen", AuthType: entities.ProviderAuthQwenOAuth, Models: []string{"qwen3-coder-plus"}},
+		{Name: "local", AuthType: entities.ProviderAuthNone, Models: []string{"llama-3.1-8b"}},
+	}
+
+	entries := WithProviderModels(nil, providers)
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	assert.Equal(t, []string{"qwen3-coder-plus", "qwen3-coder-flash", "vision-model", "llama-3.1-8b"}, ids)
+	assert.Equal(t, "local", entries[3].OwnedBy)
+	assert.Len(t, builtinModels, 3, "the built-in models must not be modified")
+
+	// Models declared by an alias are not added twice
+	entries = WithProviderModels([]entities.ModelCatalogEntry{{ID: "llama", Aliases: []string{"llama-3.1-8b"}}}, providers)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "qwen3-coder-plus", entries[1].ID)
+}
@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/metrics"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CachingProxyUseCase wraps a ProxyUseCaseInterface with a response cache for deterministic
+// chat completions, i.e. requests with a seed and a temperature of 0.
+// Streaming requests are answered from cache by replaying the stored response as SSE chunks,
+// and streamed upstream responses are stored once every choice has finished.
+type CachingProxyUseCase struct {
+	ProxyUseCaseInterface
+	cache  interfaces.CompletionCache
+	logger logging.LoggerInterface
+}
+
+// NewCachingProxyUseCase creates a proxy use case that serves deterministic requests from cache
+func NewCachingProxyUseCase(next ProxyUseCaseInterface, cache interfaces.CompletionCache, logger logging.LoggerInterface) *CachingProxyUseCase {
+	if next == nil {
+		panic("next cannot be nil")
+	}
+	if cache == nil {
+		panic("cache cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	return &CachingProxyUseCase{
+		ProxyUseCaseInterface: next,
+		cache:                 cache,
+		logger:                logger,
+	}
+}
+
+// ChatCompletions returns a cached response for deterministic requests and caches fresh responses.
+// Cache-Control: no-cache skips the lookup but stores the new response, no-store bypasses the cache.
+func (uc *CachingProxyUseCase) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+	if !IsCacheable(req) {
+		return uc.ProxyUseCaseInterface.ChatCompletions(ctx, req)
+	}
+
+	control := middleware.GetCacheControl(ctx)
+	if control.NoStore {
+		uc.recordStatus(ctx, control, middleware.CacheStatusBypass)
+		return uc.ProxyUseCaseInterface.ChatCompletions(ctx, req)
+	}
+
+	key, err := CacheKey(req)
+	if err != nil {
+		return nil, err
+	}
+	if !control.NoCache {
+		if cached := uc.lookup(ctx, key); cached != nil {
+			uc.recordStatus(ctx, control, middleware.CacheStatusHit)
+			return cached, nil
+		}
+	}
+
+	response, err := uc.ProxyUseCaseInterface.ChatCompletions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.cache.Set(key, response); err != nil {
+		uc.logger.Warn("Failed to store response in cache", "request_id", middleware.GetRequestID(ctx), "error", err)
+	}
+
+	status := middleware.CacheStatusMiss
+	if control.NoCache {
+		status = middleware.CacheStatusRefresh
+	}
+	uc.recordStatus(ctx, control, status)
+	return response, nil
+}
+
+// StreamChatCompletions replays a cached response as synthetic SSE chunks for deterministic requests
+// and otherwise streams from upstream, storing the reassembled stream once it has finished.
+// Cache-Control applies as for ChatCompletions.
+func (uc *CachingProxyUseCase) StreamChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+	if !IsCacheable(req) {
+		return uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, req, writer)
+	}
+
+	control := middleware.GetCacheControl(ctx)
+	if control.NoStore {
+		uc.recordStatus(ctx, control, middleware.CacheStatusBypass)
+		return uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, req, writer)
+	}
+
+	key, err := CacheKey(req)
+	if err != nil {
+		return err
+	}
+	if !control.NoCache {
+		if cached := uc.lookup(ctx, key); cached != nil {
+			uc.recordStatus(ctx, control, middleware.CacheStatusHit)
+			if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+				return replayStream(writer, cached, false)
+			}
+			// The response may have been stored from a stream that did not ask for usage
+			usageWriter := newUsageStreamWriter(writer, req, tokenizer.NewEstimator())
+			if err := replayStream(usageWriter, cached, true); err != nil {
+				return err
+			}
+			return usageWriter.Finish()
+		}
+	}
+
+	status := middleware.CacheStatusMiss
+	if control.NoCache {
+		status = middleware.CacheStatusRefresh
+	}
+	uc.recordStatus(ctx, control, status)
+
+	recorder := newStreamRecorder(writer)
+	if err := uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, req, recorder); err != nil {
+		return err
+	}
+	// A stream cut short, e.g. by a client disconnect, is not a response worth replaying
+	if !recorder.Complete() {
+		return nil
+	}
+	if err := uc.cache.Set(key, recorder.Response()); err != nil {
+		uc.logger.Warn("Failed to store response in cache", "request_id", middleware.GetRequestID(ctx), "error", err)
+	}
+	return nil
+}
+
+// lookup returns the cached response for key, treating cache errors as misses
+func (uc *CachingProxyUseCase) lookup(ctx context.Context, key string) *entities.ChatCompletionResponse {
+	cached, found, err := uc.cache.Get(key)
+	if err != nil {
+		uc.logger.Warn("Failed to read response cache", "request_id", middleware.GetRequestID(ctx), "error", err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	return cached
+}
+
+// recordStatus reports the cache status to the client, metrics and the current span
+func (uc *CachingProxyUseCase) recordStatus(ctx context.Context, control *middleware.CacheControl, status string) {
+	control.SetStatus(status)
+	metrics.ObserveCache(status)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("cache.status", status))
+	uc.logger.Debug("Response cache consulted", "request_id", middleware.GetRequestID(ctx), "status", status)
+}
+
+// IsCacheable reports whether a request is deterministic enough to be served from cache: it needs
+// a seed and an explicit temperature of 0, since an omitted temperature lets upstream sample
+func IsCacheable(req *entities.ChatCompletionRequest) bool {
+	return req != nil && req.Seed != nil && req.Temperature != nil && *req.Temperature == 0
+}
+
+// CacheKey returns a canonical hash of a request. Fields that only affect how a response is
+// delivered, such as streaming, are excluded so that streaming requests share entries.
+func CacheKey(req *entities.ChatCompletionRequest) (string, error) {
+	canonical := *req
+	canonical.Stream = false
+	canonical.StreamOptions = nil
+
+	// encoding/json writes struct fields in declaration order and sorts map keys, so equal requests encode equally
+	data, err := json.Marshal(&canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replayChunk is a chat completion chunk synthesized from a cached response
+type replayChunk struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []replayChunkChoice `json:"choices"`
+	Usage   *entities.Usage     `json:"usage,omitempty"`
+}
+
+// replayChunkChoice is a choice of a synthesized chunk
+type replayChunkChoice struct {
+	Index        int         `json:"index"`
+	Delta        replayDelta `json:"delta"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+// replayDelta is the delta of a synthesized chunk; unlike ChatMessage all fields are optional
+type replayDelta struct {
+	Role             string              `json:"role,omitempty"`
+	Content          any                 `json:"content,omitempty"`
+	ReasoningContent string              `json:"reasoning_content,omitempty"`
+	ToolCalls        []entities.ToolCall `json:"tool_calls,omitempty"`
+}
+
+// replayStream writes a cached response as an OpenAI SSE stream: one chunk with the full message
+// and one with the finish reason per choice, the usage chunk when requested, and [DONE]
+func replayStream(writer http.ResponseWriter, response *entities.ChatCompletionResponse, includeUsage bool) error {
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+
+	newChunk := func(choices []replayChunkChoice) *replayChunk {
+		return &replayChunk{
+			ID:      response.ID,
+			Object:  "chat.completion.chunk",
+			Created: response.Created,
+			Model:   response.Model,
+			Choices: choices,
+		}
+	}
+
+	var chunks []*replayChunk
+	for _, choice := range response.Choices {
+		delta := replayDelta{
+			Role:             "assistant",
+			Content:          choice.Message.Content,
+			ReasoningContent: choice.Message.ReasoningContent,
+			ToolCalls:        slices.Clone(choice.Message.ToolCalls),
+		}
+		for i := range delta.ToolCalls {
+			index := i
+			delta.ToolCalls[i].Index = &index
+		}
+		finishReason := choice.FinishReason
+		chunks = append(chunks,
+			newChunk([]replayChunkChoice{{Index: choice.Index, Delta: delta}}),
+			newChunk([]replayChunkChoice{{Index: choice.Index, FinishReason: &finishReason}}),
+		)
+	}
+	if includeUsage && response.Usage != nil {
+		usageChunk := newChunk([]replayChunkChoice{})
+		usageChunk.Usage = response.Usage
+		chunks = append(chunks, usageChunk)
+	}
+
+	for _, chunk := range chunks {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return fmt.Errorf("failed to encode cached chunk: %w", err)
+		}
+		if _, err := fmt.Fprintf(writer, "data: %s\n\n", data); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(writer, "data: [DONE]\n\n"); err != nil {
+		return err
+	}
+	if flusher, ok := writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/repositories"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newCachingTestUseCase(t *testing.T) (*CachingProxyUseCase, *mocks.MockProxyUseCaseInterface) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+	return NewCachingProxyUseCase(next, repositories.NewMemoryCompletionCache(10, 0), logger), next
+}
+
+func deterministicRequest() *entities.ChatCompletionRequest {
+	seed := 42
+	temperature := 0.0
+	return &entities.ChatCompletionRequest{
+		Model:       "qwen3-coder-plus",
+		Messages:    []entities.ChatMessage{{Role: "user", Content: "Hello"}},
+		Seed:        &seed,
+		Temperature: &temperature,
+	}
+}
+
+func cacheControlContext(control *middleware.CacheControl) context.Context {
+	return context.WithValue(context.Background(), middleware.CacheControlKey, control)
+}
+
+func upstreamResponse(content string) *entities.ChatCompletionResponse {
+	return &entities.ChatCompletionResponse{
+		ID:      "chatcmpl-123",
+		Object:  "chat.completion",
+		Created: 1700000000,
+		Model:   "qwen3-coder-plus",
+		Choices: []entities.ChatCompletionChoice{
+			{Index: 0, Message: entities.ChatMessage{Role: "assistant", Content: content}, FinishReason: "stop"},
+		},
+		Usage: &entities.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7},
+	}
+}
+
+func TestNewCachingProxyUseCase_NilArguments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	cache := repositories.NewMemoryCompletionCache(0, 0)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+
+	assert.PanicsWithValue(t, "next cannot be nil", func() { NewCachingProxyUseCase(nil, cache, logger) })
+	assert.PanicsWithValue(t, "cache cannot be nil", func() { NewCachingProxyUseCase(next, nil, logger) })
+	assert.PanicsWithValue(t, "logger cannot be nil", func() { NewCachingProxyUseCase(next, cache, nil) })
+}
+
+func TestCacheKey(t *testing.T) {
+	req := deterministicRequest()
+	key, err := CacheKey(req)
+	require.NoError(t, err)
+	assert.Len(t, key, 64)
+
+	// Streaming does not change the key
+	streaming := deterministicRequest()
+	streaming.Stream = true
+	streaming.StreamOptions = &entities.StreamOptions{IncludeUsage: true}
+	streamingKey, err := CacheKey(streaming)
+	require.NoError(t, err)
+	assert.Equal(t, key, streamingKey)
+	assert.True(t, streaming.Stream, "the request must not be modified")
+
+	// Anything that affects the completion does
+	other := deterministicRequest()
+	other.Messages[0].Content = "Goodbye"
+	otherKey, err := CacheKey(other)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+}
+
+func TestIsCacheable(t *testing.T) {
+	assert.True(t, IsCacheable(deterministicRequest()))
+
+	withoutSeed := deterministicRequest()
+	withoutSeed.Seed = nil
+	assert.False(t, IsCacheable(withoutSeed))
+
+	sampled := deterministicRequest()
+	*sampled.Temperature = 0.7
+	assert.False(t, IsCacheable(sampled))
+
+	// Upstream samples when the temperature is omitted
+	seedOnly := deterministicRequest()
+	seedOnly.Temperature = nil
+	assert.False(t, IsCacheable(seedOnly))
+
+	assert.False(t, IsCacheable(nil))
+}
+
+func TestCachingProxyUseCase_ChatCompletions_MissThenHit(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil).Times(1)
+
+	miss := &middleware.CacheControl{}
+	response, err := useCase.ChatCompletions(cacheControlContext(miss), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", response.Choices[0].Message.Content)
+	assert.Equal(t, middleware.CacheStatusMiss, miss.Status())
+
+	hit := &middleware.CacheControl{}
+	response, err = useCase.ChatCompletions(cacheControlContext(hit), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", response.Choices[0].Message.Content)
+	assert.Equal(t, middleware.CacheStatusHit, hit.Status())
+}
+
+func TestCachingProxyUseCase_ChatCompletions_NoCacheRefreshes(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	gomock.InOrder(
+		next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("first"), nil),
+		next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("second"), nil),
+	)
+
+	_, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+
+	refresh := &middleware.CacheControl{NoCache: true}
+	response, err := useCase.ChatCompletions(cacheControlContext(refresh), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "second", response.Choices[0].Message.Content)
+	assert.Equal(t, middleware.CacheStatusRefresh, refresh.Status())
+
+	// The refreshed response replaces the cached one
+	response, err = useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "second", response.Choices[0].Message.Content)
+}
+
+func TestCachingProxyUseCase_ChatCompletions_NoStoreBypasses(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil).Times(2)
+
+	bypass := &middleware.CacheControl{NoStore: true}
+	_, err := useCase.ChatCompletions(cacheControlContext(bypass), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, middleware.CacheStatusBypass, bypass.Status())
+
+	// Nothing was stored, so the next request goes upstream again
+	miss := &middleware.CacheControl{}
+	_, err = useCase.ChatCompletions(cacheControlContext(miss), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, middleware.CacheStatusMiss, miss.Status())
+}
+
+func TestCachingProxyUseCase_ChatCompletions_NotCacheable(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil).Times(2)
+
+	req := deterministicRequest()
+	*req.Temperature = 0.7
+	control := &middleware.CacheControl{}
+	for i := 0; i < 2; i++ {
+		_, err := useCase.ChatCompletions(cacheControlContext(control), req)
+		require.NoError(t, err)
+	}
+	assert.Empty(t, control.Status())
+}
+
+func TestCachingProxyUseCase_ChatCompletions_SeedWithoutTemperatureNotCached(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil).Times(2)
+
+	// A seed alone does not make a request deterministic, the temperature must be 0 explicitly
+	seed := 42
+	control := &middleware.CacheControl{}
+	for i := 0; i < 2; i++ {
+		req := &entities.ChatCompletionRequest{
+			Model:    "qwen3-coder-plus",
+			Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}},
+			Seed:     &seed,
+		}
+		_, err := useCase.ChatCompletions(cacheControlContext(control), req)
+		require.NoError(t, err)
+	}
+	assert.Empty(t, control.Status())
+}
+
+func TestCachingProxyUseCase_ChatCompletions_ErrorsAreNotCached(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	gomock.InOrder(
+		next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, errors.New("upstream failure")),
+		next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil),
+	)
+
+	_, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	assert.Error(t, err)
+
+	response, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", response.Choices[0].Message.Content)
+}
+
+func TestCachingProxyUseCase_ChatCompletions_CacheErrorFallsBackToUpstream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	cache := mocks.NewMockCompletionCache(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+	useCase := NewCachingProxyUseCase(next, cache, logger)
+
+	cache.EXPECT().Get(gomock.Any()).Return(nil, false, errors.New("disk failure"))
+	cache.EXPECT().Set(gomock.Any(), gomock.Any()).Return(errors.New("disk failure"))
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil)
+
+	response, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", response.Choices[0].Message.Content)
+}
+
+func TestCachingProxyUseCase_StreamChatCompletions_ReplaysHit(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	cached := upstreamResponse("Hi")
+	cached.Choices = append(cached.Choices, entities.ChatCompletionChoice{
+		Index: 1,
+		Message: entities.ChatMessage{
+			Role:      "assistant",
+			ToolCalls: []entities.ToolCall{{ID: "call_1", Type: "function", Function: entities.Function{Name: "lookup", Arguments: `{"q":"x"}`}}},
+		},
+		FinishReason: "tool_calls",
+	})
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(cached, nil)
+	_, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+
+	req := deterministicRequest()
+	req.Stream = true
+	req.StreamOptions = &entities.StreamOptions{IncludeUsage: true}
+	control := &middleware.CacheControl{}
+	recorder := httptest.NewRecorder()
+	err = useCase.StreamChatCompletions(cacheControlContext(control), req, recorder)
+	require.NoError(t, err)
+
+	assert.Equal(t, middleware.CacheStatusHit, control.Status())
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+
+	var events []string
+	scanner := bufio.NewScanner(strings.NewReader(recorder.Body.String()))
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	require.Len(t, events, 6)
+	assert.Equal(t, "[DONE]", events[5])
+
+	var chunks []entities.ChatCompletionResponse
+	for _, event := range events[:5] {
+		var chunk entities.ChatCompletionResponse
+		require.NoError(t, json.Unmarshal([]byte(event), &chunk))
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		assert.Equal(t, "chatcmpl-123", chunk.ID)
+		chunks = append(chunks, chunk)
+	}
+
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "Hi", chunks[0].Choices[0].Delta.Content)
+	assert.Equal(t, "stop", chunks[1].Choices[0].FinishReason)
+	assert.Equal(t, 1, chunks[2].Choices[0].Index)
+	require.Len(t, chunks[2].Choices[0].Delta.ToolCalls, 1)
+	require.NotNil(t, chunks[2].Choices[0].Delta.ToolCalls[0].Index)
+	assert.Equal(t, 0, *chunks[2].Choices[0].Delta.ToolCalls[0].Index)
+	assert.Equal(t, `{"q":"x"}`, chunks[2].Choices[0].Delta.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "tool_calls", chunks[3].Choices[0].FinishReason)
+	assert.Empty(t, chunks[4].Choices)
+	require.NotNil(t, chunks[4].Usage)
+	assert.Equal(t, 7, chunks[4].Usage.TotalTokens)
+}
+
+func TestCachingProxyUseCase_StreamChatCompletions_ReplayEstimatesMissingUsage(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	cached := upstreamResponse("Hi")
+	cached.Usage = nil
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(cached, nil)
+	_, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+
+	req := deterministicRequest()
+	req.Stream = true
+	req.StreamOptions = &entities.StreamOptions{IncludeUsage: true}
+	recorder := httptest.NewRecorder()
+	err = useCase.StreamChatCompletions(cacheControlContext(&middleware.CacheControl{}), req, recorder)
+	require.NoError(t, err)
+
+	events := streamEvents(recorder.Body.String())
+	require.Len(t, events, 4)
+	assert.True(t, decodeUsageChunk(t, events[2]).Estimated)
+	assert.Equal(t, "[DONE]", events[3])
+}
+
+// streamedResponse is an upstream stream of a response with reasoning and a tool call
+const streamedResponse = `data: {"id":"chatcmpl-9","created":1700000000,"model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Think","content":"Hi"}}]}` + "\n\n" +
+	`data: {"id":"chatcmpl-9","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"content":" there"},"finish_reason":"stop"}]}` + "\n\n" +
+	"data: [DONE]\n\n"
+
+func TestCachingProxyUseCase_StreamChatCompletions_MissStoresStream(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	req := deterministicRequest()
+	req.Stream = true
+	recorder := httptest.NewRecorder()
+	next.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+			fmt.Fprint(writer, streamedResponse)
+			return nil
+		})
+
+	control := &middleware.CacheControl{}
+	err := useCase.StreamChatCompletions(cacheControlContext(control), req, recorder)
+	require.NoError(t, err)
+	assert.Equal(t, middleware.CacheStatusMiss, control.Status())
+	assert.Equal(t, streamedResponse, recorder.Body.String())
+
+	// The reassembled stream answers a later non-streaming request
+	hitControl := &middleware.CacheControl{}
+	response, err := useCase.ChatCompletions(cacheControlContext(hitControl), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, middleware.CacheStatusHit, hitControl.Status())
+	assert.Equal(t, "chatcmpl-9", response.ID)
+	assert.Equal(t, "Hi there", response.Choices[0].Message.Content)
+	assert.Equal(t, "Think", response.Choices[0].Message.ReasoningContent)
+	assert.Equal(t, "stop", response.Choices[0].FinishReason)
+}
+
+func TestCachingProxyUseCase_StreamChatCompletions_IncompleteStreamNotStored(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	req := deterministicRequest()
+	req.Stream = true
+	next.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+			// The client disconnected before the finish reason arrived
+			fmt.Fprint(writer, strings.SplitAfterN(streamedResponse, "\n\n", 2)[0])
+			return nil
+		})
+
+	require.NoError(t, useCase.StreamChatCompletions(context.Background(), req, httptest.NewRecorder()))
+
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil)
+	control := &middleware.CacheControl{}
+	_, err := useCase.ChatCompletions(cacheControlContext(control), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, middleware.CacheStatusMiss, control.Status())
+}
+
+func TestCachingProxyUseCase_StreamChatCompletions_NoCacheRefreshes(t *testing.T) {
+	useCase, next := newCachingTestUseCase(t)
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("Hi"), nil)
+	_, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+
+	req := deterministicRequest()
+	req.Stream = true
+	next.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+			fmt.Fprint(writer, streamedResponse)
+			return nil
+		})
+
+	control := &middleware.CacheControl{NoCache: true}
+	err = useCase.StreamChatCompletions(cacheControlContext(control), req, httptest.NewRecorder())
+	require.NoError(t, err)
+	assert.Equal(t, middleware.CacheStatusRefresh, control.Status())
+
+	response, err := useCase.ChatCompletions(context.Background(), deterministicRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", response.Choices[0].Message.Content)
+}
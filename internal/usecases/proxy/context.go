@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/infrastructure/tracing"
+)
+
+// ContextLengthExceededError is returned when a request does not fit the context window of its model
+type ContextLengthExceededError struct {
+	Model            string
+	ContextWindow    int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Error implements the error interface with the message OpenAI uses for over-long requests
+func (e *ContextLengthExceededError) Error() string {
+	if e.CompletionTokens > 0 {
+		return fmt.Sprintf("This model's maximum context length is %d tokens. However, you requested %d tokens (%d in the messages, %d in the completion). Please reduce the length of the messages or completion.",
+			e.ContextWindow, e.PromptTokens+e.CompletionTokens, e.PromptTokens, e.CompletionTokens)
+	}
+	return fmt.Sprintf("This model's maximum context length is %d tokens. However, your messages resulted in %d tokens. Please reduce the length of the messages.",
+		e.ContextWindow, e.PromptTokens)
+}
+
+// ContextPolicy configures how requests beyond the context window of their model are handled
+type ContextPolicy struct {
+	// Overflow is one of the entities.ContextOverflow policies; empty disables the preflight
+	Overflow string
+	// Strategy is one of the entities.ContextStrategy values used to trim requests; empty keeps tool pairs
+	Strategy string
+}
+
+const (
+	// summaryMaxTokens bounds the length of the summary that replaces elided messages
+	summaryMaxTokens = 1024
+	// summaryPrefix introduces the summary in the message that replaces the elided messages
+	summaryPrefix = "Summary of the earlier conversation, which was shortened to fit the context window:\n\n"
+	// summaryInstructions ask the model for a summary of the elided messages
+	summaryInstructions = "Summarize the following conversation so that it can continue without it. " +
+		"Keep the goals, decisions, facts, file names, code identifiers and tool results that later messages may rely on. " +
+		"Answer with the summary only."
+)
+
+// checkContextLength counts the prompt tokens of a request and compares them, plus the requested
+// max_tokens, with the context window of its model. Depending on the context policy an over-long
+// request is rejected, or trimmed by the context strategy. The number of messages elided by
+// trimming is reported in the X-Context-Elided response header.
+// Models without a known context window are not checked.
+func (uc *ProxyUseCase) checkContextLength(ctx context.Context, req *entities.ChatCompletionRequest) error {
+	if uc.contextPolicy.Overflow == "" || uc.contextPolicy.Overflow == entities.ContextOverflowOff {
+		return nil
+	}
+	// Fallback attempts start again from the original request
+	report := middleware.GetModelReport(ctx)
+	report.SetElidedMessages(0)
+	model, err := uc.modelCatalog.GetModel(ctx, req.Model)
+	if err != nil || model.ContextWindow <= 0 {
+		return nil
+	}
+	window := model.ContextWindow
+	prompt := tokenizer.CountPromptTokens(uc.tokenizer, req)
+	if prompt+req.MaxTokens <= window {
+		return nil
+	}
+
+	if uc.contextPolicy.Overflow == entities.ContextOverflowTrim {
+		budget := window - req.MaxTokens
+		elided := 0
+		if uc.contextPolicy.Strategy == entities.ContextStrategySummarize {
+			var summarized int
+			prompt, summarized, err = uc.summarizeMessages(ctx, req, window, budget, prompt)
+			if err != nil {
+				uc.logger.Warn("Failed to summarize messages, dropping them instead",
+					"request_id", middleware.GetRequestID(ctx),
+					"model", req.Model,
+					"error", err)
+			}
+			elided += summarized
+		}
+		if prompt > budget {
+			var dropped int
+			prompt, dropped = uc.dropMessages(req, budget, prompt, uc.contextPolicy.Strategy == entities.ContextStrategyDropOldest)
+			elided += dropped
+		}
+		if prompt+req.MaxTokens > window && prompt < window {
+			// Leave the reply whatever room the prompt does not take
+			req.MaxTokens = window - prompt
+		}
+		if prompt+req.MaxTokens <= window {
+			report.SetElidedMessages(elided)
+			uc.logger.Info("Trimmed request to fit the context window",
+				"request_id", middleware.GetRequestID(ctx),
+				"model", req.Model,
+				"context_window", window,
+				"prompt_tokens", prompt,
+				"elided_messages", elided)
+			return nil
+		}
+	}
+
+	uc.logger.Warn("Request exceeds the context window",
+		"request_id", middleware.GetRequestID(ctx),
+		"model", req.Model,
+		"context_window", window,
+		"prompt_tokens", prompt,
+		"max_tokens", req.MaxTokens)
+	return &ContextLengthExceededError{
+		Model:            req.Model,
+		ContextWindow:    window,
+		PromptTokens:     prompt,
+		CompletionTokens: req.MaxTokens,
+	}
+}
+
+// dropMessages drops the oldest messages until the prompt fits the budget, keeping the system
+// messages and the last message. Messages are dropped by unit, see messageUnits.
+// It returns the prompt tokens left and the number of messages dropped.
+func (uc *ProxyUseCase) dropMessages(req *entities.ChatCompletionRequest, budget, prompt int, turns bool) (int, int) {
+	units := messageUnits(req.Messages, turns)
+	kept := make([]entities.ChatMessage, 0, len(req.Messages))
+	dropped := 0
+	for i, unit := range units {
+		messages := req.Messages[unit[0]:unit[1]]
+		if prompt > budget && i < len(units)-1 && messages[0].Role != "system" {
+			prompt -= uc.countMessages(messages)
+			dropped += len(messages)
+			continue
+		}
+		kept = append(kept, messages...)
+	}
+	req.Messages = kept
+	return prompt, dropped
+}
+
+// summarizeMessages replaces the messages before the most recent ones, which take up to half the
+// budget, with a summary written by the model. System messages and the last message are kept.
+// It returns the prompt tokens and the number of messages replaced by the summary.
+func (uc *ProxyUseCase) summarizeMessages(ctx context.Context, req *entities.ChatCompletionRequest, window, budget, prompt int) (int, int, error) {
+	units := messageUnits(req.Messages, false)
+	tail := len(units) - 1
+	tailTokens := uc.countMessages(req.Messages[units[tail][0]:])
+	for tail > 0 {
+		tokens := uc.countMessages(req.Messages[units[tail-1][0]:units[tail-1][1]])
+		if tailTokens+tokens > budget/2 {
+			break
+		}
+		tailTokens += tokens
+		tail--
+	}
+
+	start := units[tail][0]
+	var kept, elided []entities.ChatMessage
+	for _, message := range req.Messages[:start] {
+		if message.Role == "system" {
+			kept = append(kept, message)
+		} else {
+			elided = append(elided, message)
+		}
+	}
+	if len(elided) == 0 {
+		return prompt, 0, nil
+	}
+
+	summary, err := uc.summarize(ctx, req.Model, elided, window)
+	if err != nil {
+		return prompt, 0, err
+	}
+	kept = append(kept, entities.ChatMessage{Role: "user", Content: summaryPrefix + summary})
+	req.Messages = append(kept, req.Messages[start:]...)
+	return tokenizer.CountPromptTokens(uc.tokenizer, req), len(elided), nil
+}
+
+// summarize asks the model for a summary of the messages. The oldest messages are left out of the
+// transcript when it does not fit in the context window together with the summary.
+func (uc *ProxyUseCase) summarize(ctx context.Context, model string, messages []entities.ChatMessage, window int) (summary string, err error) {
+	ctx, span := tracing.Start(ctx, "ProxyUseCase.summarize")
+	defer func() { tracing.End(span, err) }()
+
+	entries := make([]string, len(messages))
+	tokens := make([]int, len(messages))
+	total := 0
+	for i, message := range messages {
+		entries[i] = transcriptEntry(message)
+		tokens[i] = uc.tokenizer.CountTokens(entries[i]) + 2
+		total += tokens[i]
+	}
+	instructions := []entities.ChatMessage{{Role: "system", Content: summaryInstructions}, {Role: "user", Content: ""}}
+	limit := window - summaryMaxTokens - tokenizer.CountPromptTokens(uc.tokenizer, &entities.ChatCompletionRequest{Messages: instructions})
+	first := 0
+	for first < len(entries) && total > limit {
+		total -= tokens[first]
+		first++
+	}
+	if first == len(entries) {
+		return "", fmt.Errorf("the context window of %d tokens is too small to summarize the conversation", window)
+	}
+
+	instructions[1].Content = strings.Join(entries[first:], "\n\n")
+	response, err := uc.complete(ctx, &entities.ChatCompletionRequest{Model: model, Messages: instructions, MaxTokens: summaryMaxTokens})
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) > 0 {
+		summary = strings.TrimSpace(tokenizer.MessageText(response.Choices[0].Message.Content))
+	}
+	if summary == "" {
+		return "", fmt.Errorf("the model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// transcriptEntry renders a message for the transcript that is summarized
+func transcriptEntry(message entities.ChatMessage) string {
+	var entry strings.Builder
+	entry.WriteString(message.Role)
+	entry.WriteString(": ")
+	entry.WriteString(tokenizer.MessageText(message.Content))
+	for _, toolCall := range message.ToolCalls {
+		fmt.Fprintf(&entry, "\n[called %s with %s]", toolCall.Function.Name, toolCall.Function.Arguments)
+	}
+	return entry.String()
+}
+
+// countMessages returns the number of tokens of the messages without the reply header
+func (uc *ProxyUseCase) countMessages(messages []entities.ChatMessage) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += tokenizer.CountMessageTokens(uc.tokenizer, message)
+	}
+	return tokens
+}
+
+// messageUnits splits messages into the runs that trimming drops together, as [start, end) ranges.
+// A tool call is dropped with the tool results that follow it, and with turns a user message is
+// dropped with every message up to the next user message. System messages are units of their own.
+func messageUnits(messages []entities.ChatMessage, turns bool) [][2]int {
+	var units [][2]int
+	for start := 0; start < len(messages); {
+		end := start + 1
+		switch {
+		case messages[start].Role == "system":
+		case turns:
+			for end < len(messages) && messages[end].Role != "user" && messages[end].Role != "system" {
+				end++
+			}
+		case len(messages[start].ToolCalls) > 0:
+			for end < len(messages) && messages[end].Role == "tool" {
+				end++
+			}
+		}
+		units = append(units, [2]int{start, end})
+		start = end
+	}
+	return units
+}
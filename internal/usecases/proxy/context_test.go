@@ -0,0 +1,255 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// newContextTestUseCase returns a proxy use case whose models have the given context window and
+// context policy, and the gateway mock that receives the requests that pass the preflight
+func newContextTestUseCase(t *testing.T, contextWindow int, policy ContextPolicy) (*ProxyUseCase, *mocks.MockQwenAPIGateway) {
+	ctrl := gomock.NewController(t)
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+	catalog := passThroughCatalog(ctrl)
+	catalog.EXPECT().GetModel(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id string) (*entities.ModelInfo, error) {
+		return &entities.ModelInfo{ID: id, ContextWindow: contextWindow}, nil
+	}).AnyTimes()
+
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{ResourceURL: "https://api.example.com"}, nil).AnyTimes()
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mocks.NewMockStreamingUseCaseInterface(ctrl), catalog, mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), policy)
+	return useCase, mockQwenGateway
+}
+
+// userMessage is a message of 4 words that the estimator counts as 9 tokens with its role and template
+func userMessage() entities.ChatMessage {
+	return entities.ChatMessage{Role: "user", Content: "one two six ten"}
+}
+
+func TestProxyUseCase_ContextPreflight_Rejects(t *testing.T) {
+	useCase, _ := newContextTestUseCase(t, 10, ContextPolicy{Overflow: entities.ContextOverflowReject})
+
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: []entities.ChatMessage{userMessage()}, MaxTokens: 5}
+	response, err := useCase.ChatCompletions(context.Background(), req)
+
+	assert.Nil(t, response)
+	var tooLong *ContextLengthExceededError
+	require.ErrorAs(t, err, &tooLong)
+	assert.Equal(t, "qwen3-coder-plus", tooLong.Model)
+	assert.Equal(t, 10, tooLong.ContextWindow)
+	assert.Equal(t, 12, tooLong.PromptTokens)
+	assert.Equal(t, 5, tooLong.CompletionTokens)
+	assert.Contains(t, err.Error(), "you requested 17 tokens (12 in the messages, 5 in the completion)")
+
+	// Streams are rejected before anything is sent upstream
+	req.Stream = true
+	err = useCase.StreamChatCompletions(context.Background(), req, nil)
+	assert.Error(t, err)
+}
+
+func TestProxyUseCase_ContextPreflight_TrimsOldestMessages(t *testing.T) {
+	useCase, mockQwenGateway := newContextTestUseCase(t, 30, ContextPolicy{Overflow: entities.ContextOverflowTrim})
+
+	system := entities.ChatMessage{Role: "system", Content: "sys"}
+	toolCall := entities.ChatMessage{Role: "assistant", Content: "", ToolCalls: []entities.ToolCall{{ID: "call_1", Type: "function", Function: entities.Function{Name: "lookup", Arguments: "{}"}}}}
+	toolResult := entities.ChatMessage{Role: "tool", Content: "one two six ten", ToolCallID: "call_1"}
+	last := entities.ChatMessage{Role: "user", Content: "red fox and dog"}
+	req := &entities.ChatCompletionRequest{
+		Model:    "qwen3-coder-plus",
+		Messages: []entities.ChatMessage{system, userMessage(), toolCall, toolResult, last},
+	}
+
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, gomock.Any()).
+		Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "chatcmpl-1"}), nil)
+
+	response, err := useCase.ChatCompletions(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "chatcmpl-1", response.ID)
+	// Dropping the tool call also drops its result, and the system and last messages are kept
+	assert.Equal(t, []entities.ChatMessage{system, last}, req.Messages)
+}
+
+func TestProxyUseCase_ContextPreflight_DropOldestTurns(t *testing.T) {
+	useCase, mockQwenGateway := newContextTestUseCase(t, 30, ContextPolicy{Overflow: entities.ContextOverflowTrim, Strategy: entities.ContextStrategyDropOldest})
+
+	first := entities.ChatMessage{Role: "user", Content: "one"}
+	reply := entities.ChatMessage{Role: "assistant", Content: "one two six ten"}
+	second := userMessage()
+	last := entities.ChatMessage{Role: "user", Content: "red fox and dog"}
+	req := &entities.ChatCompletionRequest{
+		Model:    "qwen3-coder-plus",
+		Messages: []entities.ChatMessage{first, reply, second, last},
+	}
+
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, gomock.Any()).
+		Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "chatcmpl-1"}), nil)
+
+	ctx, report := reportContext()
+	_, err := useCase.ChatCompletions(ctx, req)
+
+	require.NoError(t, err)
+	// The first turn is dropped with its reply, although dropping the user message alone would fit
+	assert.Equal(t, []entities.ChatMessage{second, last}, req.Messages)
+	assert.Equal(t, 2, report.ElidedMessages())
+}
+
+func TestProxyUseCase_ContextPreflight_Summarizes(t *testing.T) {
+	useCase, mockQwenGateway := newContextTestUseCase(t, 2000, ContextPolicy{Overflow: entities.ContextOverflowTrim, Strategy: entities.ContextStrategySummarize})
+
+	system := entities.ChatMessage{Role: "system", Content: "sys"}
+	last := entities.ChatMessage{Role: "user", Content: "red fox and dog"}
+	messages := []entities.ChatMessage{system}
+	for range 300 {
+		messages = append(messages, userMessage())
+	}
+	messages = append(messages, last)
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: messages}
+
+	summaryRequest := mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, summary *entities.ChatCompletionRequest, _ *entities.Credentials) (*http.Response, error) {
+			assert.Equal(t, "qwen3-coder-plus", summary.Model)
+			assert.Equal(t, summaryMaxTokens, summary.MaxTokens)
+			require.Len(t, summary.Messages, 2)
+			assert.Equal(t, summaryInstructions, summary.Messages[0].Content)
+			assert.Contains(t, summary.Messages[1].Content, "user: one two six ten")
+			return createMockHttpResponse(&entities.ChatCompletionResponse{Choices: []entities.ChatCompletionChoice{
+				{Message: entities.ChatMessage{Role: "assistant", Content: " Counted to ten. "}},
+			}}), nil
+		})
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, gomock.Any()).
+		Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "chatcmpl-1"}), nil).
+		After(summaryRequest)
+
+	ctx, report := reportContext()
+	_, err := useCase.ChatCompletions(ctx, req)
+
+	require.NoError(t, err)
+	// The system message is kept, followed by the summary and the most recent messages
+	assert.Equal(t, system, req.Messages[0])
+	assert.Equal(t, "user", req.Messages[1].Role)
+	assert.Equal(t, summaryPrefix+"Counted to ten.", req.Messages[1].Content)
+	assert.Equal(t, last, req.Messages[len(req.Messages)-1])
+	kept := len(req.Messages) - 2
+	assert.Equal(t, len(messages)-1-kept, report.ElidedMessages())
+	assert.LessOrEqual(t, tokenizer.CountPromptTokens(tokenizer.NewEstimator(), req), 2000)
+}
+
+func TestProxyUseCase_ContextPreflight_SummarizeFailureDropsMessages(t *testing.T) {
+	useCase, mockQwenGateway := newContextTestUseCase(t, 2000, ContextPolicy{Overflow: entities.ContextOverflowTrim, Strategy: entities.ContextStrategySummarize})
+
+	var messages []entities.ChatMessage
+	for range 300 {
+		messages = append(messages, userMessage())
+	}
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: messages}
+
+	summaryRequest := mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("upstream unavailable"))
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, gomock.Any()).
+		Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "chatcmpl-1"}), nil).
+		After(summaryRequest)
+
+	ctx, report := reportContext()
+	_, err := useCase.ChatCompletions(ctx, req)
+
+	require.NoError(t, err)
+	// 221 messages of 9 tokens and the reply header fit in 2000 tokens
+	assert.Len(t, req.Messages, 221)
+	assert.Equal(t, 79, report.ElidedMessages())
+}
+
+func TestProxyUseCase_ContextPreflight_TrimsMaxTokens(t *testing.T) {
+	useCase, mockQwenGateway := newContextTestUseCase(t, 30, ContextPolicy{Overflow: entities.ContextOverflowTrim})
+
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: []entities.ChatMessage{userMessage()}, MaxTokens: 25}
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, gomock.Any()).
+		Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "chatcmpl-1"}), nil)
+
+	_, err := useCase.ChatCompletions(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Len(t, req.Messages, 1)
+	assert.Equal(t, 30-12, req.MaxTokens)
+}
+
+func TestProxyUseCase_ContextPreflight_TrimStillTooLong(t *testing.T) {
+	useCase, _ := newContextTestUseCase(t, 10, ContextPolicy{Overflow: entities.ContextOverflowTrim})
+
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: []entities.ChatMessage{userMessage(), userMessage()}}
+	_, err := useCase.ChatCompletions(context.Background(), req)
+
+	var tooLong *ContextLengthExceededError
+	require.ErrorAs(t, err, &tooLong)
+	assert.Equal(t, 12, tooLong.PromptTokens)
+	assert.Contains(t, err.Error(), "your messages resulted in 12 tokens")
+}
+
+func TestProxyUseCase_ContextPreflight_Skipped(t *testing.T) {
+	for name, useCase := range map[string]func(t *testing.T) (*ProxyUseCase, *mocks.MockQwenAPIGateway){
+		"unknown context window": func(t *testing.T) (*ProxyUseCase, *mocks.MockQwenAPIGateway) {
+			return newContextTestUseCase(t, 0, ContextPolicy{Overflow: entities.ContextOverflowReject})
+		},
+		"policy off": func(t *testing.T) (*ProxyUseCase, *mocks.MockQwenAPIGateway) {
+			return newContextTestUseCase(t, 1, ContextPolicy{Overflow: entities.ContextOverflowOff})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			useCase, mockQwenGateway := useCase(t)
+			req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: []entities.ChatMessage{userMessage()}}
+			mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, gomock.Any()).
+				Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "chatcmpl-1"}), nil)
+
+			_, err := useCase.ChatCompletions(context.Background(), req)
+
+			require.NoError(t, err)
+			assert.Len(t, req.Messages, 1)
+		})
+	}
+}
+
+func TestMessageUnits(t *testing.T) {
+	messages := []entities.ChatMessage{
+		{Role: "system"},
+		{Role: "user"},
+		{Role: "assistant", ToolCalls: []entities.ToolCall{{ID: "call_1"}, {ID: "call_2"}}},
+		{Role: "tool", ToolCallID: "call_1"},
+		{Role: "tool", ToolCallID: "call_2"},
+		{Role: "assistant"},
+		{Role: "user"},
+	}
+
+	assert.Equal(t, [][2]int{{0, 1}, {1, 2}, {2, 5}, {5, 6}, {6, 7}}, messageUnits(messages, false))
+	assert.Equal(t, [][2]int{{0, 1}, {1, 6}, {6, 7}}, messageUnits(messages, true))
+}
+
+// reportContext returns a context carrying a model report, as requests served by the router have
+func reportContext() (context.Context, *middleware.ModelReport) {
+	report := &middleware.ModelReport{}
+	return context.WithValue(context.Background(), middleware.ModelReportKey, report), report
+}
+
+func TestNewProxyUseCase_NilTokenizer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	assert.Panics(t, func() {
+		NewProxyUseCase(mocks.NewMockAuthUseCaseInterface(ctrl), mocks.NewMockQwenAPIGateway(ctrl), mocks.NewMockStreamingUseCaseInterface(ctrl), passThroughCatalog(ctrl), mocks.NewMockLoggerInterface(ctrl), "qwen3-coder-plus", nil, ContextPolicy{})
+	})
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// upstreamEmbeddings is an embeddings response of the upstream, which is always asked for floats
+type upstreamEmbeddings struct {
+	Object string `json:"object"`
+	Data   []struct {
+		Object    string    `json:"object"`
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Model string          `json:"model"`
+	Usage *entities.Usage `json:"usage"`
+}
+
+// Embeddings creates embeddings for the input of a request.
+// The upstream request is bound to ctx and is cancelled when the client goes away.
+func (uc *ProxyUseCase) Embeddings(ctx context.Context, req *entities.EmbeddingRequest) (*entities.EmbeddingResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProxyUseCase.Embeddings")
+	response, err := uc.embeddings(ctx, req)
+	if response != nil {
+		span.SetAttributes(tracing.UsageAttributes(response.Usage)...)
+	}
+	tracing.End(span, err)
+	return response, err
+}
+
+// embeddings sends an embeddings request upstream and encodes the embeddings in the requested format.
+// Models in the catalog are resolved by name or alias; other models are passed on as they are,
+// since the catalog lists chat models.
+func (uc *ProxyUseCase) embeddings(ctx context.Context, req *entities.EmbeddingRequest) (*entities.EmbeddingResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+	if model, err := uc.modelCatalog.GetModel(ctx, req.Model); err == nil {
+		req.Model = model.ID
+	}
+	middleware.GetModelReport(ctx).SetModel(req.Model)
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrModel.String(req.Model))
+
+	// Ask for floats and encode them here, so that base64 also works with upstreams that only return floats
+	upstream := *req
+	upstream.EncodingFormat = entities.EncodingFormatFloat
+	resp, err := uc.send(ctx, req.Model, func(credentials *entities.Credentials) (*http.Response, error) {
+		return uc.qwenGateway.Embeddings(ctx, &upstream, credentials)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newUpstreamError(resp)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	var decoded upstreamEmbeddings
+	if err := json.Unmarshal(bodyBytes, &decoded); err != nil {
+		uc.logger.Error("Failed to decode embeddings response", "error", err, "raw_response", string(bodyBytes))
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	response := &entities.EmbeddingResponse{
+		Object: "list",
+		Data:   make([]entities.Embedding, len(decoded.Data)),
+		Model:  decoded.Model,
+		Usage:  decoded.Usage,
+	}
+	if response.Model == "" {
+		response.Model = req.Model
+	}
+	for i, data := range decoded.Data {
+		response.Data[i] = entities.Embedding{Object: "embedding", Index: data.Index, Embedding: data.Embedding}
+		if req.EncodingFormat == entities.EncodingFormatBase64 {
+			response.Data[i].Embedding = encodeEmbedding(data.Embedding)
+		}
+	}
+	return response, nil
+}
+
+// encodeEmbedding encodes an embedding the way OpenAI does for encoding_format base64,
+// as base64 of its little-endian float32 values
+func encodeEmbedding(embedding []float64) string {
+	data := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(float32(value)))
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
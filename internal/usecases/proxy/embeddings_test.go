@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const embeddingsBody = `{"object":"list","model":"text-embedding-v3","data":[{"object":"embedding","index":0,"embedding":[0.5,-1.25]}],"usage":{"prompt_tokens":2,"total_tokens":2}}`
+
+// newEmbeddingsTestUseCase returns a proxy use case whose catalog only knows the alias "embed"
+// for text-embedding-v3, and the gateway mock
+func newEmbeddingsTestUseCase(t *testing.T) (*ProxyUseCase, *mocks.MockQwenAPIGateway) {
+	ctrl := gomock.NewController(t)
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	catalog := mocks.NewMockModelCatalogInterface(ctrl)
+	catalog.EXPECT().GetModel(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id string) (*entities.ModelInfo, error) {
+		if id == "embed" {
+			return &entities.ModelInfo{ID: "text-embedding-v3"}, nil
+		}
+		return nil, &models.ModelNotFoundError{Model: id}
+	}).AnyTimes()
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(&entities.Credentials{AccessToken: "token"}, nil).AnyTimes()
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mocks.NewMockStreamingUseCaseInterface(ctrl), catalog, mocks.NewMockLoggerInterface(ctrl), "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+	return useCase, mockQwenGateway
+}
+
+func TestProxyUseCase_Embeddings_Float(t *testing.T) {
+	useCase, mockQwenGateway := newEmbeddingsTestUseCase(t)
+
+	mockQwenGateway.EXPECT().Embeddings(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, sent *entities.EmbeddingRequest, credentials *entities.Credentials) (*http.Response, error) {
+			assert.Equal(t, "text-embedding-v3", sent.Model)
+			assert.Equal(t, []any{"a", "b"}, sent.Input)
+			assert.Equal(t, 256, sent.Dimensions)
+			assert.Equal(t, "token", credentials.AccessToken)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(embeddingsBody))}, nil
+		})
+
+	req := &entities.EmbeddingRequest{Model: "embed", Input: []any{"a", "b"}, Dimensions: 256}
+	response, err := useCase.Embeddings(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "list", response.Object)
+	assert.Equal(t, "text-embedding-v3", response.Model)
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "embedding", response.Data[0].Object)
+	assert.Equal(t, []float64{0.5, -1.25}, response.Data[0].Embedding)
+	assert.Equal(t, 2, response.Usage.PromptTokens)
+}
+
+func TestProxyUseCase_Embeddings_Base64(t *testing.T) {
+	useCase, mockQwenGateway := newEmbeddingsTestUseCase(t)
+
+	mockQwenGateway.EXPECT().Embeddings(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, sent *entities.EmbeddingRequest, _ *entities.Credentials) (*http.Response, error) {
+			// Models outside the catalog are sent as they are, and always asked for floats
+			assert.Equal(t, "text-embedding-v4", sent.Model)
+			assert.Equal(t, entities.EncodingFormatFloat, sent.EncodingFormat)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(embeddingsBody))}, nil
+		})
+
+	req := &entities.EmbeddingRequest{Model: "text-embedding-v4", Input: "Hello", EncodingFormat: entities.EncodingFormatBase64}
+	response, err := useCase.Embeddings(context.Background(), req)
+
+	require.NoError(t, err)
+	require.Len(t, response.Data, 1)
+	encoded, ok := response.Data[0].Embedding.(string)
+	require.True(t, ok)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	require.Len(t, data, 8)
+	assert.Equal(t, float32(0.5), math.Float32frombits(binary.LittleEndian.Uint32(data[0:])))
+	assert.Equal(t, float32(-1.25), math.Float32frombits(binary.LittleEndian.Uint32(data[4:])))
+}
+
+func TestProxyUseCase_Embeddings_UpstreamError(t *testing.T) {
+	useCase, mockQwenGateway := newEmbeddingsTestUseCase(t)
+
+	mockQwenGateway.EXPECT().Embeddings(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(`{"error":"unknown model"}`))}, nil)
+
+	_, err := useCase.Embeddings(context.Background(), &entities.EmbeddingRequest{Model: "unknown", Input: "Hello"})
+
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, err, &upstreamErr)
+	assert.Equal(t, http.StatusBadRequest, upstreamErr.StatusCode)
+
+	_, err = useCase.Embeddings(context.Background(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+)
+
+// redactedContent replaces message content in the journal in redact mode
+const redactedContent = "[redacted]"
+
+// JournalingProxyUseCase wraps a ProxyUseCaseInterface with an audit journal.
+// Every chat completion is recorded with the request as sent by the client, the response or the
+// reassembled stream output, usage, latency, request ID and API key.
+// A failure to write the journal is logged and never fails the request.
+type JournalingProxyUseCase struct {
+	ProxyUseCaseInterface
+	journal   interfaces.Journal
+	redaction string
+	logger    logging.LoggerInterface
+	now       func() time.Time
+}
+
+// NewJournalingProxyUseCase creates a proxy use case that records chat completions in the journal.
+// redaction is one of the JournalRedaction modes and applies to message content and tool call arguments.
+func NewJournalingProxyUseCase(next ProxyUseCaseInterface, journal interfaces.Journal, redaction string, logger logging.LoggerInterface) *JournalingProxyUseCase {
+	if next == nil {
+		panic("next cannot be nil")
+	}
+	if journal == nil {
+		panic("journal cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	return &JournalingProxyUseCase{
+		ProxyUseCaseInterface: next,
+		journal:               journal,
+		redaction:             redaction,
+		logger:                logger,
+		now:                   time.Now,
+	}
+}
+
+// ChatCompletions forwards the request and records it with its response
+func (uc *JournalingProxyUseCase) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+	if req == nil {
+		return uc.ProxyUseCaseInterface.ChatCompletions(ctx, req)
+	}
+	start := uc.now()
+	recorded := copyRequest(req)
+
+	response, err := uc.ProxyUseCaseInterface.ChatCompletions(ctx, req)
+	uc.record(ctx, recorded, response, err, start)
+	return response, err
+}
+
+// StreamChatCompletions forwards the stream to the client and records it reassembled into a single response
+func (uc *JournalingProxyUseCase) StreamChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+	if req == nil || writer == nil {
+		return uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, req, writer)
+	}
+	start := uc.now()
+	recorded := copyRequest(req)
+
+	recorder := newStreamRecorder(writer)
+	err := uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, req, recorder)
+	uc.record(ctx, recorded, recorder.Response(), err, start)
+	return err
+}
+
+// record appends the journal entry of a completed request
+func (uc *JournalingProxyUseCase) record(ctx context.Context, req *entities.ChatCompletionRequest, response *entities.ChatCompletionResponse, err error, start time.Time) {
+	requestID := middleware.GetRequestID(ctx)
+	entry := &entities.JournalEntry{
+		Timestamp: start.UTC(),
+		RequestID: requestID,
+		Model:     req.Model,
+		ModelUsed: middleware.GetModelReport(ctx).Model(),
+		Stream:    req.Stream,
+		Request:   redactRequest(req, uc.redaction),
+		Response:  redactResponse(response, uc.redaction),
+		LatencyMs: uc.now().Sub(start).Milliseconds(),
+	}
+	if key := middleware.GetAPIKey(ctx); key != nil {
+		entry.APIKeyID = key.ID
+		entry.APIKeyLabel = key.Label
+	}
+	if response != nil {
+		entry.Usage = response.Usage
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if appendErr := uc.journal.Append(entry); appendErr != nil {
+		uc.logger.Warn("Failed to write journal entry", "request_id", requestID, "error", appendErr)
+	}
+}
+
+// copyRequest copies a request before the inner use cases resolve its model and apply routes to it
+func copyRequest(req *entities.ChatCompletionRequest) *entities.ChatCompletionRequest {
+	recorded := *req
+	recorded.Messages = slices.Clone(req.Messages)
+	return &recorded
+}
+
+// redactRequest returns a copy of the request with its message content redacted
+func redactRequest(req *entities.ChatCompletionRequest, mode string) *entities.ChatCompletionRequest {
+	if !redacts(mode) {
+		return req
+	}
+	redacted := *req
+	redacted.Messages = make([]entities.ChatMessage, len(req.Messages))
+	for i, message := range req.Messages {
+		redacted.Messages[i] = redactMessage(message, mode)
+	}
+	return &redacted
+}
+
+// redactResponse returns a copy of the response with its message content redacted
+func redactResponse(response *entities.ChatCompletionResponse, mode string) *entities.ChatCompletionResponse {
+	if response == nil || !redacts(mode) {
+		return response
+	}
+	redacted := *response
+	redacted.Choices = make([]entities.ChatCompletionChoice, len(response.Choices))
+	for i, choice := range response.Choices {
+		choice.Message = redactMessage(choice.Message, mode)
+		choice.Delta = redactMessage(choice.Delta, mode)
+		choice.Logprobs = nil
+		redacted.Choices[i] = choice
+	}
+	return &redacted
+}
+
+// redacts reports whether a redaction mode changes message content
+func redacts(mode string) bool {
+	return mode == entities.JournalRedactionRedact || mode == entities.JournalRedactionHash
+}
+
+// redactMessage redacts the content, reasoning and tool call arguments of a message, keeping its structure
+func redactMessage(message entities.ChatMessage, mode string) entities.ChatMessage {
+	if message.Content != nil {
+		message.Content = redactValue(message.Content, mode)
+	}
+	if message.ReasoningContent != "" {
+		message.ReasoningContent = redactValue(message.ReasoningContent, mode)
+	}
+	if len(message.ToolCalls) > 0 {
+		toolCalls := make([]entities.ToolCall, len(message.ToolCalls))
+		for i, toolCall := range message.ToolCalls {
+			if toolCall.Function.Arguments != "" {
+				toolCall.Function.Arguments = redactValue(toolCall.Function.Arguments, mode)
+			}
+			toolCalls[i] = toolCall
+		}
+		message.ToolCalls = toolCalls
+	}
+	return message
+}
+
+// redactValue replaces a value with a placeholder, or with its SHA-256 digest in hash mode
+// so that identical content can still be correlated across entries
+func redactValue(value any, mode string) string {
+	if mode != entities.JournalRedactionHash {
+		return redactedContent
+	}
+	data, ok := value.(string)
+	if !ok {
+		encoded, _ := json.Marshal(value)
+		data = string(encoded)
+	}
+	digest := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(digest[:])
+}
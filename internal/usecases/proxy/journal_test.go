@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// recordingJournal is a Journal that keeps appended entries in memory
+type recordingJournal struct {
+	entries []*entities.JournalEntry
+	err     error
+}
+
+func (j *recordingJournal) Append(entry *entities.JournalEntry) error {
+	if j.err != nil {
+		return j.err
+	}
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+func (j *recordingJournal) Search(query entities.JournalQuery) ([]*entities.JournalEntry, error) {
+	return j.entries, nil
+}
+
+func (j *recordingJournal) Close() error {
+	return nil
+}
+
+func newJournalingTestUseCase(t *testing.T, redaction string) (*JournalingProxyUseCase, *mocks.MockProxyUseCaseInterface, *recordingJournal) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	journal := &recordingJournal{}
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+	uc := NewJournalingProxyUseCase(next, journal, redaction, logger)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	calls := 0
+	uc.now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls-1) * 250 * time.Millisecond)
+	}
+	return uc, next, journal
+}
+
+func journalContext() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-123")
+	ctx = context.WithValue(ctx, middleware.APIKeyContextKey, &entities.APIKey{ID: "key-1", Label: "ci"})
+	return context.WithValue(ctx, middleware.ModelReportKey, &middleware.ModelReport{})
+}
+
+func TestNewJournalingProxyUseCase_NilArguments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+
+	assert.Panics(t, func() { NewJournalingProxyUseCase(nil, &recordingJournal{}, "", logger) })
+	assert.Panics(t, func() { NewJournalingProxyUseCase(next, nil, "", logger) })
+	assert.Panics(t, func() { NewJournalingProxyUseCase(next, &recordingJournal{}, "", nil) })
+}
+
+func TestJournalingProxyUseCase_ChatCompletions(t *testing.T) {
+	uc, next, journal := newJournalingTestUseCase(t, entities.JournalRedactionNone)
+	ctx := journalContext()
+	req := &entities.ChatCompletionRequest{Model: "gpt-4o", Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+
+	next.EXPECT().ChatCompletions(ctx, req).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+			// Inner use cases rewrite the request, the journal keeps what the client sent
+			req.Model = "qwen3-coder-plus"
+			middleware.GetModelReport(ctx).SetModel("qwen3-coder-plus")
+			return upstreamResponse("Hi there"), nil
+		})
+
+	response, err := uc.ChatCompletions(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", response.Choices[0].Message.Content)
+
+	require.Len(t, journal.entries, 1)
+	entry := journal.entries[0]
+	assert.Equal(t, "req-123", entry.RequestID)
+	assert.Equal(t, "key-1", entry.APIKeyID)
+	assert.Equal(t, "ci", entry.APIKeyLabel)
+	assert.Equal(t, "gpt-4o", entry.Model)
+	assert.Equal(t, "qwen3-coder-plus", entry.ModelUsed)
+	assert.Equal(t, "gpt-4o", entry.Request.Model)
+	assert.Equal(t, "Hello", entry.Request.Messages[0].Content)
+	assert.Equal(t, "Hi there", entry.Response.Choices[0].Message.Content)
+	assert.Equal(t, 7, entry.Usage.TotalTokens)
+	assert.Equal(t, int64(250), entry.LatencyMs)
+	assert.Empty(t, entry.Error)
+}
+
+func TestJournalingProxyUseCase_ChatCompletions_Error(t *testing.T) {
+	uc, next, journal := newJournalingTestUseCase(t, entities.JournalRedactionNone)
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+	upstreamErr := &UpstreamError{StatusCode: 500, Message: "boom"}
+
+	next.EXPECT().ChatCompletions(gomock.Any(), req).Return(nil, upstreamErr)
+
+	_, err := uc.ChatCompletions(context.Background(), req)
+	assert.Equal(t, upstreamErr, err)
+	require.Len(t, journal.entries, 1)
+	assert.Nil(t, journal.entries[0].Response)
+	assert.Contains(t, journal.entries[0].Error, "status 500")
+}
+
+func TestJournalingProxyUseCase_JournalErrorDoesNotFailRequest(t *testing.T) {
+	uc, next, journal := newJournalingTestUseCase(t, entities.JournalRedactionNone)
+	journal.err = errors.New("disk full")
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+
+	next.EXPECT().ChatCompletions(gomock.Any(), req).Return(upstreamResponse("Hi"), nil)
+
+	response, err := uc.ChatCompletions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", response.Choices[0].Message.Content)
+}
+
+func TestJournalingProxyUseCase_Redaction(t *testing.T) {
+	toolCall := entities.ToolCall{ID: "call_1", Type: "function", Function: entities.Function{Name: "lookup", Arguments: `{"q":"secret"}`}}
+	req := &entities.ChatCompletionRequest{
+		Model: "qwen3-coder-plus",
+		Messages: []entities.ChatMessage{
+			{Role: "user", Content: "my secret"},
+			{Role: "assistant", Content: "", ToolCalls: []entities.ToolCall{toolCall}},
+		},
+	}
+
+	tests := []struct {
+		mode        string
+		wantContent string
+	}{
+		{entities.JournalRedactionRedact, "[redacted]"},
+		{entities.JournalRedactionHash, "sha256:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			uc, next, journal := newJournalingTestUseCase(t, tt.mode)
+			next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(upstreamResponse("secret answer"), nil)
+
+			response, err := uc.ChatCompletions(context.Background(), req)
+			require.NoError(t, err)
+			// Only the journal copy is redacted
+			assert.Equal(t, "secret answer", response.Choices[0].Message.Content)
+			assert.Equal(t, "my secret", req.Messages[0].Content)
+			assert.Equal(t, `{"q":"secret"}`, req.Messages[1].ToolCalls[0].Function.Arguments)
+
+			entry := journal.entries[0]
+			assert.True(t, strings.HasPrefix(entry.Request.Messages[0].Content.(string), tt.wantContent))
+			assert.True(t, strings.HasPrefix(entry.Request.Messages[1].ToolCalls[0].Function.Arguments, tt.wantContent))
+			assert.Equal(t, "lookup", entry.Request.Messages[1].ToolCalls[0].Function.Name)
+			assert.True(t, strings.HasPrefix(entry.Response.Choices[0].Message.Content.(string), tt.wantContent))
+			assert.Equal(t, "user", entry.Request.Messages[0].Role)
+		})
+	}
+}
+
+func TestJournalingProxyUseCase_Redaction_ReasoningContent(t *testing.T) {
+	req := &entities.ChatCompletionRequest{Model: "qwq-plus", Stream: true, Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+	stream := `data: {"id":"chatcmpl-1","model":"qwq-plus","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"secret "}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-1","model":"qwq-plus","choices":[{"index":0,"delta":{"reasoning_content":"thoughts"}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-1","model":"qwq-plus","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+
+	tests := []struct {
+		mode          string
+		wantReasoning string
+	}{
+		{entities.JournalRedactionNone, "secret thoughts"},
+		{entities.JournalRedactionRedact, "[redacted]"},
+		{entities.JournalRedactionHash, redactValue("secret thoughts", entities.JournalRedactionHash)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			uc, next, journal := newJournalingTestUseCase(t, tt.mode)
+			next.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+					fmt.Fprint(writer, stream)
+					return nil
+				})
+
+			recorder := httptest.NewRecorder()
+			require.NoError(t, uc.StreamChatCompletions(context.Background(), req, recorder))
+			// The client still receives the reasoning
+			assert.Contains(t, recorder.Body.String(), "secret ")
+
+			require.Len(t, journal.entries, 1)
+			message := journal.entries[0].Response.Choices[0].Message
+			assert.Equal(t, tt.wantReasoning, message.ReasoningContent)
+		})
+	}
+
+	uc, next, journal := newJournalingTestUseCase(t, entities.JournalRedactionRedact)
+	response := upstreamResponse("answer")
+	response.Choices[0].Message.ReasoningContent = "secret thoughts"
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(response, nil)
+	_, err := uc.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "qwq-plus"})
+	require.NoError(t, err)
+	assert.Equal(t, "[redacted]", journal.entries[0].Response.Choices[0].Message.ReasoningContent)
+	assert.Equal(t, "secret thoughts", response.Choices[0].Message.ReasoningContent)
+}
+
+func TestRedactValue_HashIsStable(t *testing.T) {
+	first := redactValue("Hello", entities.JournalRedactionHash)
+	assert.Equal(t, first, redactValue("Hello", entities.JournalRedactionHash))
+	assert.NotEqual(t, first, redactValue("Hello!", entities.JournalRedactionHash))
+	assert.Equal(t, "[redacted]", redactValue("Hello", entities.JournalRedactionRedact))
+}
+
+func TestJournalingProxyUseCase_StreamChatCompletions(t *testing.T) {
+	uc, next, journal := newJournalingTestUseCase(t, entities.JournalRedactionNone)
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Stream: true, Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+	recorder := httptest.NewRecorder()
+	stream := `data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"content":" there","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":"}}]}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"x\"}"}}]},"finish_reason":"tool_calls"}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-1","model":"qwen3-coder-plus","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}` + "\n\n" +
+		"data: [DONE]\n\n"
+
+	next.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+			// Split writes must not lose chunks
+			fmt.Fprint(writer, stream[:50])
+			fmt.Fprint(writer, stream[50:])
+			return nil
+		})
+
+	require.NoError(t, uc.StreamChatCompletions(context.Background(), req, recorder))
+	assert.Equal(t, stream, recorder.Body.String())
+
+	require.Len(t, journal.entries, 1)
+	entry := journal.entries[0]
+	assert.True(t, entry.Stream)
+	require.NotNil(t, entry.Response)
+	assert.Equal(t, "chatcmpl-1", entry.Response.ID)
+	assert.Equal(t, "chat.completion", entry.Response.Object)
+	require.Len(t, entry.Response.Choices, 1)
+	choice := entry.Response.Choices[0]
+	assert.Equal(t, "assistant", choice.Message.Role)
+	assert.Equal(t, "Hi there", choice.Message.Content)
+	assert.Equal(t, "tool_calls", choice.FinishReason)
+	require.Len(t, choice.Message.ToolCalls, 1)
+	assert.Equal(t, "call_1", choice.Message.ToolCalls[0].ID)
+	assert.Equal(t, `{"q":"x"}`, choice.Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, 7, entry.Usage.TotalTokens)
+}
+
+func TestJournalingProxyUseCase_StreamChatCompletions_ErrorBeforeOutput(t *testing.T) {
+	uc, next, journal := newJournalingTestUseCase(t, entities.JournalRedactionNone)
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Stream: true, Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+
+	next.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).Return(errors.New("connection refused"))
+
+	err := uc.StreamChatCompletions(context.Background(), req, httptest.NewRecorder())
+	require.Error(t, err)
+	require.Len(t, journal.entries, 1)
+	assert.Nil(t, journal.entries[0].Response)
+	assert.Equal(t, "connection refused", journal.entries[0].Error)
+}
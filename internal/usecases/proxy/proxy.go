@@ -1,26 +1,98 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
 	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+	"qwen-go-proxy/internal/infrastructure/tracing"
 	"qwen-go-proxy/internal/interfaces/gateways"
 	"qwen-go-proxy/internal/usecases/auth"
+	"qwen-go-proxy/internal/usecases/models"
 	"qwen-go-proxy/internal/usecases/streaming"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ProxyUseCaseInterface defines the interface for proxy use case operations
 type ProxyUseCaseInterface interface {
-	ChatCompletions(req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error)
-	StreamChatCompletions(req *entities.ChatCompletionRequest, writer http.ResponseWriter) error
-	GetModels() ([]*entities.ModelInfo, error)
-	AuthenticateManually() error
+	ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error)
+	StreamChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error
+	Embeddings(ctx context.Context, req *entities.EmbeddingRequest) (*entities.EmbeddingResponse, error)
+	GetModels(ctx context.Context) ([]*entities.ModelInfo, error)
+	GetModel(ctx context.Context, id string) (*entities.ModelInfo, error)
+	StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error)
+	DeviceFlowStatus() *entities.DeviceFlowStatus
 	CheckAuthentication() (*entities.Credentials, error)
+	AuthState() string
+}
+
+// UpstreamError is returned when the upstream API answers with a non-200 status
+type UpstreamError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the wait the upstream asked for in its Retry-After header, zero when it sent none
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// newUpstreamError reads the error response body of an upstream response
+func newUpstreamError(resp *http.Response) *UpstreamError {
+	body, err := io.ReadAll(resp.Body)
+	errorMsg := string(body)
+	if err != nil {
+		errorMsg = fmt.Sprintf("Failed to read error response: %v", err)
+	}
+	return &UpstreamError{
+		StatusCode: resp.StatusCode,
+		Message:    errorMsg,
+		RetryAfter: gateways.ParseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// Detail returns the message of the upstream error body, taken from its error object or error
+// string when the body is JSON and falling back to the raw body or the status text
+func (e *UpstreamError) Detail() string {
+	var body struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal([]byte(e.Message), &body) == nil {
+		var object struct {
+			Message string `json:"message"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(body.Error, &object) == nil && object.Message != "":
+			return object.Message
+		case json.Unmarshal(body.Error, &text) == nil && text != "":
+			return text
+		case body.Message != "":
+			return body.Message
+		}
+	}
+	if message := strings.TrimSpace(e.Message); message != "" {
+		return message
+	}
+	return http.StatusText(e.StatusCode)
+}
+
+// QuotaExceeded reports whether the upstream rejected the request for a rate limit or an exhausted quota
+func (e *UpstreamError) QuotaExceeded() bool {
+	return e.StatusCode == http.StatusTooManyRequests || strings.Contains(strings.ToLower(e.Message), "quota")
 }
 
 // ProxyUseCase defines the API proxy use case
@@ -28,12 +100,18 @@ type ProxyUseCase struct {
 	authUseCase      auth.AuthUseCaseInterface
 	qwenGateway      gateways.QwenAPIGateway
 	streamingUseCase streaming.StreamingUseCaseInterface
+	modelCatalog     models.ModelCatalogInterface
+	tokenizer        interfaces.Tokenizer
 	logger           logging.LoggerInterface
 	defaultModel     string
+	contextPolicy    ContextPolicy
 }
 
-// NewProxyUseCase creates a new proxy use case
-func NewProxyUseCase(authUseCase auth.AuthUseCaseInterface, qwenGateway gateways.QwenAPIGateway, streamingUseCase streaming.StreamingUseCaseInterface, logger logging.LoggerInterface, defaultModel string) *ProxyUseCase {
+// NewProxyUseCase creates a new proxy use case.
+// Requests are checked against the context window of their model with the tokenizer, and the
+// context policy decides whether over-long requests are rejected or trimmed; an empty policy
+// disables the check.
+func NewProxyUseCase(authUseCase auth.AuthUseCaseInterface, qwenGateway gateways.QwenAPIGateway, streamingUseCase streaming.StreamingUseCaseInterface, modelCatalog models.ModelCatalogInterface, logger logging.LoggerInterface, defaultModel string, tokenizer interfaces.Tokenizer, contextPolicy ContextPolicy) *ProxyUseCase {
 	if authUseCase == nil {
 		panic("authUseCase cannot be nil")
 	}
@@ -43,9 +121,15 @@ func NewProxyUseCase(authUseCase auth.AuthUseCaseInterface, qwenGateway gateways
 	if streamingUseCase == nil {
 		panic("streamingUseCase cannot be nil")
 	}
+	if modelCatalog == nil {
+		panic("modelCatalog cannot be nil")
+	}
 	if logger == nil {
 		panic("logger cannot be nil")
 	}
+	if tokenizer == nil {
+		panic("tokenizer cannot be nil")
+	}
 	if defaultModel == "" {
 		defaultModel = "qwen3-coder-plus"
 	}
@@ -53,44 +137,57 @@ func NewProxyUseCase(authUseCase auth.AuthUseCaseInterface, qwenGateway gateways
 		authUseCase:      authUseCase,
 		qwenGateway:      qwenGateway,
 		streamingUseCase: streamingUseCase,
+		modelCatalog:     modelCatalog,
+		tokenizer:        tokenizer,
 		logger:           logger,
 		defaultModel:     defaultModel,
+		contextPolicy:    contextPolicy,
 	}
 }
 
-// ChatCompletions handles chat completion requests
-func (uc *ProxyUseCase) ChatCompletions(req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+// ChatCompletions handles chat completion requests.
+// The upstream request is bound to ctx and is cancelled when the client goes away.
+func (uc *ProxyUseCase) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProxyUseCase.ChatCompletions")
+	response, err := uc.chatCompletions(ctx, req)
+	if response != nil {
+		span.SetAttributes(tracing.UsageAttributes(response.Usage)...)
+	}
+	tracing.End(span, err)
+	return response, err
+}
+
+// chatCompletions sends a non-streaming request upstream and decodes the response
+func (uc *ProxyUseCase) chatCompletions(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
 	if req == nil {
 		return nil, fmt.Errorf("request cannot be nil")
 	}
-	credentials, err := uc.authUseCase.EnsureAuthenticated()
-	if err != nil {
-		return nil, fmt.Errorf("authentication failed: %w", err)
+	if err := uc.resolveModel(ctx, req); err != nil {
+		return nil, err
 	}
-
-	// Set default model if not provided
-	if req.Model == "" {
-		req.Model = uc.defaultModel
+	if err := uc.checkContextLength(ctx, req); err != nil {
+		return nil, err
 	}
+	middleware.GetModelReport(ctx).SetModel(req.Model)
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrModel.String(req.Model), tracing.AttrStream.Bool(req.Stream))
+	return uc.complete(ctx, req)
+}
 
+// complete sends a non-streaming request for a resolved model upstream and decodes the response
+func (uc *ProxyUseCase) complete(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
 	// Strip Qwen-specific fields to maintain OpenAI compatibility
 	// These fields are not part of OpenAI's Chat Completion API specification
 	req.ReasoningEffort = ""
 	req.IncludeReasoning = false
 
-	resp, err := uc.qwenGateway.ChatCompletions(req, credentials)
+	resp, err := uc.sendRequest(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		errorMsg := string(body)
-		if err != nil {
-			errorMsg = fmt.Sprintf("Failed to read error response: %v", err)
-		}
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, errorMsg)
+		return nil, newUpstreamError(resp)
 	}
 
 	// Read the entire response body
@@ -112,46 +209,148 @@ func (uc *ProxyUseCase) ChatCompletions(req *entities.ChatCompletionRequest) (*e
 	return &response, nil
 }
 
-// StreamChatCompletions handles streaming chat completion requests with advanced features
-func (uc *ProxyUseCase) StreamChatCompletions(req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+// StreamChatCompletions handles streaming chat completion requests with advanced features.
+// Both the upstream request and stream processing stop when ctx is done.
+func (uc *ProxyUseCase) StreamChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+	ctx, span := tracing.Start(ctx, "ProxyUseCase.StreamChatCompletions")
+	err := uc.streamChatCompletions(ctx, req, writer)
+	tracing.End(span, err)
+	return err
+}
+
+// streamChatCompletions sends a streaming request upstream and hands the response to the stream processor
+func (uc *ProxyUseCase) streamChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
 	if req == nil {
 		return fmt.Errorf("request cannot be nil")
 	}
 	if writer == nil {
 		return fmt.Errorf("writer cannot be nil")
 	}
-	credentials, err := uc.authUseCase.EnsureAuthenticated()
-	if err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+	if err := uc.resolveModel(ctx, req); err != nil {
+		return err
 	}
-
-	// Set default model if not provided
-	if req.Model == "" {
-		req.Model = uc.defaultModel
+	if err := uc.checkContextLength(ctx, req); err != nil {
+		return err
 	}
+	middleware.GetModelReport(ctx).SetModel(req.Model)
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrModel.String(req.Model), tracing.AttrStream.Bool(req.Stream))
 
 	// Strip Qwen-specific fields to maintain OpenAI compatibility
 	// These fields are not part of OpenAI's Chat Completion API specification
 	req.ReasoningEffort = ""
 	req.IncludeReasoning = false
 
-	resp, err := uc.qwenGateway.ChatCompletions(req, credentials)
+	resp, err := uc.sendRequest(ctx, req)
 	if err != nil {
-		return fmt.Errorf("API request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		errorMsg := string(body)
+		return newUpstreamError(resp)
+	}
+
+	if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+		// Use the advanced streaming usecase for processing
+		return uc.streamingUseCase.ProcessStreamingResponse(ctx, resp, writer)
+	}
+
+	// Clients asking for usage rely on a final usage chunk, so make sure one is sent
+	usageWriter := newUsageStreamWriter(writer, req, uc.tokenizer)
+	if err := uc.streamingUseCase.ProcessStreamingResponse(ctx, resp, usageWriter); err != nil || ctx.Err() != nil {
+		return err
+	}
+	return usageWriter.Finish()
+}
+
+// sendRequest authenticates and sends a chat completion request upstream
+func (uc *ProxyUseCase) sendRequest(ctx context.Context, req *entities.ChatCompletionRequest) (*http.Response, error) {
+	return uc.send(ctx, req.Model, func(credentials *entities.Credentials) (*http.Response, error) {
+		return uc.qwenGateway.ChatCompletions(ctx, req, credentials)
+	})
+}
+
+// send authenticates and sends a request for a model upstream with the credentials passed to send.
+// When the auth use case is a credential pool, requests that hit a rate limit or quota
+// are retried on the next account until one succeeds or every account has been tried.
+// Models served by providers that do not use Qwen OAuth are sent without authenticating.
+func (uc *ProxyUseCase) send(ctx context.Context, model string, send func(credentials *entities.Credentials) (*http.Response, error)) (*http.Response, error) {
+	if router, ok := uc.qwenGateway.(gateways.ProviderRouter); ok && !router.RequiresQwenAuth(model) {
+		resp, err := send(nil)
 		if err != nil {
-			errorMsg = fmt.Sprintf("Failed to read error response: %v", err)
+			return nil, fmt.Errorf("API request failed: %w", err)
 		}
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, errorMsg)
+		return resp, nil
+	}
+
+	pool, ok := uc.authUseCase.(auth.CredentialPoolInterface)
+	if !ok {
+		credentials, err := uc.authUseCase.EnsureAuthenticated(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+		resp, err := send(credentials)
+		if err != nil {
+			return nil, fmt.Errorf("API request failed: %w", err)
+		}
+		return resp, nil
+	}
+
+	tried := make(map[string]bool)
+	var throttledResp *http.Response
+	for {
+		account, err := pool.AcquireAccount(ctx, tried)
+		if err != nil {
+			// Every account is exhausted, surface the last upstream rate limit response
+			if throttledResp != nil {
+				return throttledResp, nil
+			}
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+
+		resp, err := send(account.Credentials)
+		if err != nil {
+			closeResponse(throttledResp)
+			return nil, fmt.Errorf("API request failed: %w", err)
+		}
+
+		if !isQuotaExceeded(resp) {
+			closeResponse(throttledResp)
+			return resp, nil
+		}
+
+		uc.logger.Warn("Account rate limited, failing over to next account", "account", account.Name, "status", resp.StatusCode)
+		pool.ReportThrottled(account.Name, gateways.ParseRetryAfter(resp.Header.Get("Retry-After")))
+		tried[account.Name] = true
+		closeResponse(throttledResp)
+		throttledResp = resp
+	}
+}
+
+// isQuotaExceeded reports whether an upstream response indicates a rate limit or exhausted quota.
+// The response body is restored so that callers can still read it.
+func isQuotaExceeded(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusOK {
+		return false
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
 	}
 
-	// Use the advanced streaming usecase for processing
-	return uc.streamingUseCase.ProcessStreamingResponse(context.Background(), resp, writer)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "quota")
+}
+
+// closeResponse closes a response body if the response is not nil
+func closeResponse(resp *http.Response) {
+	if resp != nil {
+		resp.Body.Close()
+	}
 }
 
 // convertQwenToOpenAIResponse converts Qwen API response format to OpenAI format
@@ -196,84 +395,46 @@ func (uc *ProxyUseCase) convertQwenToOpenAIResponse(response *entities.ChatCompl
 	}
 }
 
-// GetModels returns available models
-func (uc *ProxyUseCase) GetModels() ([]*entities.ModelInfo, error) {
-	return []*entities.ModelInfo{
-		{
-			ID:      "qwen3-coder-plus",
-			Object:  "model",
-			Created: 1754686206,
-			OwnedBy: "qwen",
-			Permission: []entities.ModelPermission{
-				{
-					ID:                 "modelperm-qwen3-coder-plus",
-					Object:             "model_permission",
-					Created:            1754686206,
-					AllowCreateEngine:  false,
-					AllowSampling:      true,
-					AllowLogprobs:      true,
-					AllowSearchIndices: false,
-					AllowView:          true,
-					AllowFineTuning:    false,
-					Organization:       "*",
-					Group:              nil,
-					IsBlocking:         false,
-				},
-			},
-		},
-		{
-			ID:      "qwen3-coder-flash",
-			Object:  "model",
-			Created: 1754686206,
-			OwnedBy: "qwen",
-			Permission: []entities.ModelPermission{
-				{
-					ID:                 "modelperm-qwen3-coder-flash",
-					Object:             "model_permission",
-					Created:            1754686206,
-					AllowCreateEngine:  false,
-					AllowSampling:      true,
-					AllowLogprobs:      true,
-					AllowSearchIndices: false,
-					AllowView:          true,
-					AllowFineTuning:    false,
-					Organization:       "*",
-					Group:              nil,
-					IsBlocking:         false,
-				},
-			},
-		},
-		{
-			ID:      "vision-model",
-			Object:  "model",
-			Created: 1754686206,
-			OwnedBy: "qwen",
-			Permission: []entities.ModelPermission{
-				{
-					ID:                 "modelperm-vision-model",
-					Object:             "model_permission",
-					Created:            1754686206,
-					AllowCreateEngine:  false,
-					AllowSampling:      true,
-					AllowLogprobs:      true,
-					AllowSearchIndices: false,
-					AllowView:          true,
-					AllowFineTuning:    false,
-					Organization:       "*",
-					Group:              nil,
-					IsBlocking:         false,
-				},
-			},
-		},
-	}, nil
+// resolveModel applies the default model and replaces the requested model name or alias with
+// the upstream model ID. Unknown models are rejected with a ModelNotFoundError.
+func (uc *ProxyUseCase) resolveModel(ctx context.Context, req *entities.ChatCompletionRequest) error {
+	if req.Model == "" {
+		req.Model = uc.defaultModel
+	}
+	model, err := uc.modelCatalog.ResolveModel(ctx, req.Model)
+	if err != nil {
+		return err
+	}
+	req.Model = model
+	return nil
+}
+
+// GetModels returns the models available through the proxy
+func (uc *ProxyUseCase) GetModels(ctx context.Context) ([]*entities.ModelInfo, error) {
+	return uc.modelCatalog.ListModels(ctx)
+}
+
+// GetModel returns a model by ID or alias
+func (uc *ProxyUseCase) GetModel(ctx context.Context, id string) (*entities.ModelInfo, error) {
+	return uc.modelCatalog.GetModel(ctx, id)
+}
+
+// StartDeviceFlow starts OAuth2 device flow authentication, or returns the flow already in progress
+func (uc *ProxyUseCase) StartDeviceFlow(ctx context.Context) (*entities.DeviceFlowStatus, error) {
+	return uc.authUseCase.StartDeviceFlow(ctx)
 }
 
-// AuthenticateManually triggers manual OAuth2 device flow authentication
-func (uc *ProxyUseCase) AuthenticateManually() error {
-	return uc.authUseCase.AuthenticateManually()
+// DeviceFlowStatus returns the status of the most recent device flow, or nil when none was started
+func (uc *ProxyUseCase) DeviceFlowStatus() *entities.DeviceFlowStatus {
+	return uc.authUseCase.DeviceFlowStatus()
 }
 
-// CheckAuthentication checks if user is currently authenticated
+// CheckAuthentication checks if user is currently authenticated without performing device flow
 func (uc *ProxyUseCase) CheckAuthentication() (*entities.Credentials, error) {
-	return uc.authUseCase.EnsureAuthenticated()
+	return uc.authUseCase.CheckAuthentication()
+}
+
+// AuthState returns the authentication state of the proxy
+func (uc *ProxyUseCase) AuthState() string {
+	return uc.authUseCase.AuthState()
 }
@@ -4,18 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/interfaces/gateways"
 	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/models"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
 
+// passThroughCatalog returns a model catalog that accepts every model unchanged
+func passThroughCatalog(ctrl *gomock.Controller) *mocks.MockModelCatalogInterface {
+	catalog := mocks.NewMockModelCatalogInterface(ctrl)
+	catalog.EXPECT().ResolveModel(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, name string) (string, error) {
+		return name, nil
+	}).AnyTimes()
+	return catalog
+}
+
 func TestNewProxyUseCase(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -25,7 +40,7 @@ func TestNewProxyUseCase(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	assert.NotNil(t, useCase)
 	assert.Equal(t, mockAuthUseCase, useCase.authUseCase)
@@ -44,7 +59,7 @@ func TestProxyUseCase_ChatCompletions_Success(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Model: "test-model",
@@ -78,11 +93,11 @@ func TestProxyUseCase_ChatCompletions_Success(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(credentials, nil)
-	mockQwenGateway.EXPECT().ChatCompletions(req, credentials).Return(createMockHttpResponse(expectedResponse), nil)
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, credentials).Return(createMockHttpResponse(expectedResponse), nil)
 	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
 
-	response, err := useCase.ChatCompletions(req)
+	response, err := useCase.ChatCompletions(context.Background(), req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
@@ -99,7 +114,7 @@ func TestProxyUseCase_ChatCompletions_AuthenticationFailure(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Messages: []entities.ChatMessage{
@@ -110,10 +125,10 @@ func TestProxyUseCase_ChatCompletions_AuthenticationFailure(t *testing.T) {
 	authError := errors.New("authentication failed")
 
 	// Mock expectations
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(nil, authError)
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(nil, authError)
 	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 
-	response, err := useCase.ChatCompletions(req)
+	response, err := useCase.ChatCompletions(context.Background(), req)
 
 	assert.Error(t, err)
 	assert.Nil(t, response)
@@ -129,7 +144,7 @@ func TestProxyUseCase_ChatCompletions_DefaultModel(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		// Model is empty, should use default
@@ -166,11 +181,11 @@ func TestProxyUseCase_ChatCompletions_DefaultModel(t *testing.T) {
 		Stream: false,
 	}
 
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(credentials, nil)
-	mockQwenGateway.EXPECT().ChatCompletions(expectedReq, credentials).Return(createMockHttpResponse(expectedResponse), nil)
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), expectedReq, credentials).Return(createMockHttpResponse(expectedResponse), nil)
 	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
 
-	response, err := useCase.ChatCompletions(req)
+	response, err := useCase.ChatCompletions(context.Background(), req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
@@ -186,7 +201,7 @@ func TestProxyUseCase_StreamChatCompletions_Success(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Model: "test-model",
@@ -204,16 +219,72 @@ func TestProxyUseCase_StreamChatCompletions_Success(t *testing.T) {
 	writer := httptest.NewRecorder()
 
 	// Mock expectations
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(credentials, nil)
-	mockQwenGateway.EXPECT().ChatCompletions(req, credentials).Return(streamingResponse, nil)
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, credentials).Return(streamingResponse, nil)
 	mockStreamingUseCase.EXPECT().ProcessStreamingResponse(gomock.Any(), streamingResponse, writer).Return(nil)
 	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
 
-	err := useCase.StreamChatCompletions(req, writer)
+	err := useCase.StreamChatCompletions(context.Background(), req, writer)
 
 	assert.NoError(t, err)
 }
 
+func TestProxyUseCase_StreamChatCompletions_PropagatesContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	req := &entities.ChatCompletionRequest{Model: "test-model", Stream: true}
+	credentials := &entities.Credentials{ResourceURL: "https://api.example.com"}
+	streamingResponse := createMockStreamingHttpResponse()
+	writer := httptest.NewRecorder()
+
+	// The client context reaches both the upstream request and the stream processor
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(boundTo(ctx), req, credentials).Return(streamingResponse, nil)
+	mockStreamingUseCase.EXPECT().ProcessStreamingResponse(boundTo(ctx), streamingResponse, writer).Return(context.Canceled)
+
+	err := useCase.StreamChatCompletions(ctx, req, writer)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestProxyUseCase_ChatCompletions_ContextCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	req := &entities.ChatCompletionRequest{Model: "test-model"}
+	credentials := &entities.Credentials{ResourceURL: "https://api.example.com"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(boundTo(ctx), req, credentials).Return(nil, context.Canceled)
+
+	response, err := useCase.ChatCompletions(ctx, req)
+
+	// The cancellation stays detectable so that controllers can tell a disconnect from a failure
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestProxyUseCase_StreamChatCompletions_AuthFailure(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -223,7 +294,7 @@ func TestProxyUseCase_StreamChatCompletions_AuthFailure(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Stream: true,
@@ -233,10 +304,10 @@ func TestProxyUseCase_StreamChatCompletions_AuthFailure(t *testing.T) {
 	writer := httptest.NewRecorder()
 
 	// Mock expectations
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(nil, authError)
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(nil, authError)
 	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 
-	err := useCase.StreamChatCompletions(req, writer)
+	err := useCase.StreamChatCompletions(context.Background(), req, writer)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "authentication failed")
@@ -249,14 +320,76 @@ func TestProxyUseCase_GetModels(t *testing.T) {
 	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
 	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockCatalog := mocks.NewMockModelCatalogInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockCatalog, mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
-	// This method doesn't exist in the current implementation
-	// We would need to add it or test through other means
-	// For now, this is a placeholder test
-	assert.NotNil(t, useCase)
+	ctx := context.Background()
+	catalogModels := []*entities.ModelInfo{{ID: "qwen3-coder-plus", Object: "model"}}
+	mockCatalog.EXPECT().ListModels(ctx).Return(catalogModels, nil)
+	mockCatalog.EXPECT().GetModel(ctx, "coder").Return(catalogModels[0], nil)
+
+	listed, err := useCase.GetModels(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, catalogModels, listed)
+
+	model, err := useCase.GetModel(ctx, "coder")
+	assert.NoError(t, err)
+	assert.Equal(t, "qwen3-coder-plus", model.ID)
+}
+
+func TestProxyUseCase_ChatCompletions_ResolvesAlias(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockCatalog := mocks.NewMockModelCatalogInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockCatalog, mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	credentials := &entities.Credentials{AccessToken: "token"}
+	req := &entities.ChatCompletionRequest{Model: "coder", Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+	mockCatalog.EXPECT().ResolveModel(gomock.Any(), "coder").Return("qwen3-coder-plus", nil)
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), gomock.Any(), credentials).DoAndReturn(
+		func(_ context.Context, sent *entities.ChatCompletionRequest, _ *entities.Credentials) (*http.Response, error) {
+			assert.Equal(t, "qwen3-coder-plus", sent.Model)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))}, nil
+		})
+
+	response, err := useCase.ChatCompletions(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "chatcmpl-1", response.ID)
+}
+
+func TestProxyUseCase_ChatCompletions_UnknownModel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockCatalog := mocks.NewMockModelCatalogInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockCatalog, mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	// Unknown models are rejected before authenticating or calling upstream
+	mockCatalog.EXPECT().ResolveModel(gomock.Any(), "gpt-4").Return("", &models.ModelNotFoundError{Model: "gpt-4"}).Times(2)
+
+	req := &entities.ChatCompletionRequest{Model: "gpt-4", Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+	_, err := useCase.ChatCompletions(context.Background(), req)
+	var notFound *models.ModelNotFoundError
+	assert.ErrorAs(t, err, &notFound)
+
+	req.Stream = true
+	err = useCase.StreamChatCompletions(context.Background(), req, httptest.NewRecorder())
+	assert.ErrorAs(t, err, &notFound)
 }
 
 // Negative Test Cases
@@ -271,7 +404,7 @@ func TestNewProxyUseCase_NilAuthUseCase(t *testing.T) {
 
 	// Test with nil auth use case - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewProxyUseCase(nil, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+		NewProxyUseCase(nil, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 	})
 }
 
@@ -285,7 +418,7 @@ func TestNewProxyUseCase_NilQwenGateway(t *testing.T) {
 
 	// Test with nil qwen gateway - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewProxyUseCase(mockAuthUseCase, nil, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+		NewProxyUseCase(mockAuthUseCase, nil, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 	})
 }
 
@@ -299,7 +432,21 @@ func TestNewProxyUseCase_NilStreamingUseCase(t *testing.T) {
 
 	// Test with nil streaming use case - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewProxyUseCase(mockAuthUseCase, mockQwenGateway, nil, mockLogger, "qwen3-coder-plus")
+		NewProxyUseCase(mockAuthUseCase, mockQwenGateway, nil, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+	})
+}
+
+func TestNewProxyUseCase_NilModelCatalog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	assert.PanicsWithValue(t, "modelCatalog cannot be nil", func() {
+		NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, nil, mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 	})
 }
 
@@ -313,7 +460,7 @@ func TestNewProxyUseCase_NilLogger(t *testing.T) {
 
 	// Test with nil logger - should panic (documenting current behavior)
 	assert.Panics(t, func() {
-		NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, nil, "qwen3-coder-plus")
+		NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), nil, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 	})
 }
 
@@ -326,10 +473,10 @@ func TestProxyUseCase_ChatCompletions_NilRequest(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	// Test with nil request - should return error
-	response, err := useCase.ChatCompletions(nil)
+	response, err := useCase.ChatCompletions(context.Background(), nil)
 
 	assert.Error(t, err)
 	assert.Nil(t, response)
@@ -344,7 +491,7 @@ func TestProxyUseCase_ChatCompletions_AuthUseCasePanic(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Messages: []entities.ChatMessage{
@@ -353,13 +500,13 @@ func TestProxyUseCase_ChatCompletions_AuthUseCasePanic(t *testing.T) {
 	}
 
 	// Mock auth use case to panic
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().DoAndReturn(func() (*entities.Credentials, error) {
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).DoAndReturn(func(_ context.Context) (*entities.Credentials, error) {
 		panic("auth panic")
 	})
 
 	// Should handle auth panic gracefully
 	assert.Panics(t, func() {
-		useCase.ChatCompletions(req)
+		useCase.ChatCompletions(context.Background(), req)
 	})
 }
 
@@ -372,7 +519,7 @@ func TestProxyUseCase_ChatCompletions_QwenGatewayPanic(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Messages: []entities.ChatMessage{
@@ -385,14 +532,14 @@ func TestProxyUseCase_ChatCompletions_QwenGatewayPanic(t *testing.T) {
 	}
 
 	// Mock successful auth but panicking gateway
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(credentials, nil)
-	mockQwenGateway.EXPECT().ChatCompletions(req, credentials).DoAndReturn(func(req *entities.ChatCompletionRequest, creds *entities.Credentials) (*http.Response, error) {
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, credentials).DoAndReturn(func(_ context.Context, req *entities.ChatCompletionRequest, creds *entities.Credentials) (*http.Response, error) {
 		panic("gateway panic")
 	})
 
 	// Should handle gateway panic gracefully
 	assert.Panics(t, func() {
-		useCase.ChatCompletions(req)
+		useCase.ChatCompletions(context.Background(), req)
 	})
 }
 
@@ -405,7 +552,7 @@ func TestProxyUseCase_ChatCompletions_DefaultModelWithInvalidRequest(t *testing.
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	// Request with empty model (should use default)
 	req := &entities.ChatCompletionRequest{
@@ -419,10 +566,10 @@ func TestProxyUseCase_ChatCompletions_DefaultModelWithInvalidRequest(t *testing.
 	}
 
 	// Mock successful auth and gateway call
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(credentials, nil)
-	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), credentials).Return(nil, errors.New("gateway error"))
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), gomock.Any(), credentials).Return(nil, errors.New("gateway error"))
 
-	response, err := useCase.ChatCompletions(req)
+	response, err := useCase.ChatCompletions(context.Background(), req)
 
 	// Should handle gateway error gracefully
 	assert.Error(t, err)
@@ -438,12 +585,12 @@ func TestProxyUseCase_StreamChatCompletions_NilRequest(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	writer := httptest.NewRecorder()
 
 	// Test with nil request - should return error
-	err := useCase.StreamChatCompletions(nil, writer)
+	err := useCase.StreamChatCompletions(context.Background(), nil, writer)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "request cannot be nil")
 }
@@ -457,14 +604,14 @@ func TestProxyUseCase_StreamChatCompletions_NilWriter(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Stream: true,
 	}
 
 	// Test with nil writer - should return error
-	err := useCase.StreamChatCompletions(req, nil)
+	err := useCase.StreamChatCompletions(context.Background(), req, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "writer cannot be nil")
 }
@@ -478,7 +625,7 @@ func TestProxyUseCase_StreamChatCompletions_StreamingUseCasePanic(t *testing.T)
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	req := &entities.ChatCompletionRequest{
 		Stream: true,
@@ -497,15 +644,15 @@ func TestProxyUseCase_StreamChatCompletions_StreamingUseCasePanic(t *testing.T)
 	writer := httptest.NewRecorder()
 
 	// Mock successful auth and gateway but panicking streaming use case
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(credentials, nil)
-	mockQwenGateway.EXPECT().ChatCompletions(req, credentials).Return(streamingResponse, nil)
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, credentials).Return(streamingResponse, nil)
 	mockStreamingUseCase.EXPECT().ProcessStreamingResponse(gomock.Any(), streamingResponse, writer).DoAndReturn(func(ctx context.Context, resp *http.Response, w http.ResponseWriter) error {
 		panic("streaming panic")
 	})
 
 	// Should handle streaming panic gracefully
 	assert.Panics(t, func() {
-		useCase.StreamChatCompletions(req, writer)
+		useCase.StreamChatCompletions(context.Background(), req, writer)
 	})
 }
 
@@ -518,10 +665,10 @@ func TestProxyUseCase_CheckAuthentication_AuthUseCasePanic(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	// Mock auth use case to panic
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().DoAndReturn(func() (*entities.Credentials, error) {
+	mockAuthUseCase.EXPECT().CheckAuthentication().DoAndReturn(func() (*entities.Credentials, error) {
 		panic("auth panic")
 	})
 
@@ -540,14 +687,14 @@ func TestProxyUseCase_CheckAuthentication(t *testing.T) {
 	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
 	mockLogger := mocks.NewMockLoggerInterface(ctrl)
 
-	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, mockLogger, "qwen3-coder-plus")
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
 
 	credentials := &entities.Credentials{
 		ResourceURL: "https://api.example.com",
 	}
 
 	// Mock expectations
-	mockAuthUseCase.EXPECT().EnsureAuthenticated().Return(credentials, nil)
+	mockAuthUseCase.EXPECT().CheckAuthentication().Return(credentials, nil)
 
 	result, err := useCase.CheckAuthentication()
 
@@ -555,6 +702,165 @@ func TestProxyUseCase_CheckAuthentication(t *testing.T) {
 	assert.Equal(t, credentials, result)
 }
 
+func TestProxyUseCase_StartDeviceFlow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	status := &entities.DeviceFlowStatus{State: entities.DeviceFlowPending, UserCode: "ABCD-EFGH"}
+	mockAuthUseCase.EXPECT().StartDeviceFlow(gomock.Any()).Return(status, nil)
+	mockAuthUseCase.EXPECT().DeviceFlowStatus().Return(status)
+
+	result, err := useCase.StartDeviceFlow(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, status, result)
+	assert.Equal(t, status, useCase.DeviceFlowStatus())
+}
+
+func TestProxyUseCase_ChatCompletions_PoolFailover(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPool := mocks.NewMockCredentialPoolInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewProxyUseCase(mockPool, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	req := &entities.ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}},
+	}
+
+	first := &entities.AccountCredentials{Name: "first", Credentials: &entities.Credentials{AccessToken: "first"}}
+	second := &entities.AccountCredentials{Name: "second", Credentials: &entities.Credentials{AccessToken: "second"}}
+	throttled := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Body:       &mockReadCloser{data: []byte(`{"error":"rate limited"}`)},
+		Header:     http.Header{"Retry-After": []string{"30"}},
+	}
+
+	gomock.InOrder(
+		mockPool.EXPECT().AcquireAccount(gomock.Any(), map[string]bool{}).Return(first, nil),
+		mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, first.Credentials).Return(throttled, nil),
+		mockPool.EXPECT().ReportThrottled("first", 30*time.Second),
+		mockPool.EXPECT().AcquireAccount(gomock.Any(), map[string]bool{"first": true}).Return(second, nil),
+		mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, second.Credentials).Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "test-id"}), nil),
+	)
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	response, err := useCase.ChatCompletions(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test-id", response.ID)
+}
+
+func TestProxyUseCase_ChatCompletions_PoolQuotaExhausted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPool := mocks.NewMockCredentialPoolInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewProxyUseCase(mockPool, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	req := &entities.ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}},
+	}
+
+	only := &entities.AccountCredentials{Name: "only", Credentials: &entities.Credentials{AccessToken: "only"}}
+	quotaExceeded := &http.Response{
+		StatusCode: http.StatusForbidden,
+		Body:       &mockReadCloser{data: []byte(`{"error":"Free allocated quota exceeded."}`)},
+		Header:     make(http.Header),
+	}
+
+	mockPool.EXPECT().AcquireAccount(gomock.Any(), gomock.Any()).Return(only, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, only.Credentials).Return(quotaExceeded, nil)
+	mockPool.EXPECT().ReportThrottled("only", time.Duration(0))
+	mockPool.EXPECT().AcquireAccount(gomock.Any(), gomock.Any()).Return(nil, errors.New("no accounts available in credential pool"))
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	response, err := useCase.ChatCompletions(context.Background(), req)
+
+	// The last upstream error is surfaced once every account has been tried
+	assert.Nil(t, response)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "API request failed with status 403")
+	assert.Contains(t, err.Error(), "quota exceeded")
+}
+
+func TestNewUpstreamError(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Body:       &mockReadCloser{data: []byte(`{"error":{"message":"Rate limit exceeded","type":"rate_limit"}}`)},
+		Header:     http.Header{"Retry-After": []string{"12"}},
+	}
+
+	upstreamErr := newUpstreamError(resp)
+
+	assert.Equal(t, http.StatusTooManyRequests, upstreamErr.StatusCode)
+	assert.Equal(t, 12*time.Second, upstreamErr.RetryAfter)
+	assert.Equal(t, "Rate limit exceeded", upstreamErr.Detail())
+	assert.True(t, upstreamErr.QuotaExceeded())
+}
+
+func TestUpstreamError_Detail(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{`{"error":{"message":"bad tools"}}`, "bad tools"},
+		{`{"error":"Free allocated quota exceeded."}`, "Free allocated quota exceeded."},
+		{`{"message":"model overloaded"}`, "model overloaded"},
+		{"plain text\n", "plain text"},
+		{"", "Bad Gateway"},
+	}
+
+	for _, tt := range tests {
+		upstreamErr := &UpstreamError{StatusCode: http.StatusBadGateway, Message: tt.message}
+		assert.Equal(t, tt.want, upstreamErr.Detail(), tt.message)
+	}
+}
+
+func TestProxyUseCase_ChatCompletions_ProviderWithoutQwenAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No authentication is expected: the model is served by a provider without Qwen OAuth
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockLocalGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	registry := gateways.NewProviderRegistry([]gateways.Provider{
+		{Config: entities.ProviderConfig{Name: "qwen", AuthType: entities.ProviderAuthQwenOAuth}, Gateway: mocks.NewMockQwenAPIGateway(ctrl)},
+		{Config: entities.ProviderConfig{Name: "local", AuthType: entities.ProviderAuthNone, Models: []string{"llama-3.1-8b"}}, Gateway: mockLocalGateway},
+	})
+	useCase := NewProxyUseCase(mockAuthUseCase, registry, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	req := &entities.ChatCompletionRequest{
+		Model:    "llama-3.1-8b",
+		Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}},
+	}
+	mockLocalGateway.EXPECT().ChatCompletions(gomock.Any(), req, nil).Return(createMockHttpResponse(&entities.ChatCompletionResponse{ID: "local-id"}), nil)
+
+	response, err := useCase.ChatCompletions(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "local-id", response.ID)
+}
+
 // Helper functions for creating mock responses
 func createMockHttpResponse(response *entities.ChatCompletionResponse) *http.Response {
 	jsonData, _ := json.Marshal(response)
@@ -591,3 +897,22 @@ func (m *mockReadCloser) Read(p []byte) (n int, err error) {
 func (m *mockReadCloser) Close() error {
 	return nil
 }
+
+// contextMatcher matches contexts that are cancelled together with the expected context,
+// which holds for the expected context itself and for contexts derived from it to carry spans
+type contextMatcher struct {
+	ctx context.Context
+}
+
+func boundTo(ctx context.Context) gomock.Matcher {
+	return contextMatcher{ctx: ctx}
+}
+
+func (m contextMatcher) Matches(x any) bool {
+	ctx, ok := x.(context.Context)
+	return ok && ctx.Done() == m.ctx.Done()
+}
+
+func (m contextMatcher) String() string {
+	return "is bound to " + fmt.Sprint(m.ctx)
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/usecases/streaming"
+)
+
+// streamRecorder passes an OpenAI SSE stream through to the client unchanged while
+// merging its chunks into a complete chat completion response.
+// Unlike a stream hook it also sees streams that never reach the stream processor, such as cache replays.
+type streamRecorder struct {
+	http.ResponseWriter
+	pending     []byte
+	accumulator *streaming.StreamAccumulator
+}
+
+// newStreamRecorder creates a stream recorder around the client response writer
+func newStreamRecorder(w http.ResponseWriter) *streamRecorder {
+	return &streamRecorder{ResponseWriter: w, accumulator: streaming.NewStreamAccumulator()}
+}
+
+// Write forwards bytes to the client and merges every complete data line
+func (w *streamRecorder) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.pending = append(w.pending, b[:n]...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+		line := w.pending[:end]
+		w.pending = w.pending[end+1:]
+		if data, found := bytes.CutPrefix(line, []byte("data: ")); found {
+			w.accumulator.AddData(string(data))
+		}
+	}
+	return n, err
+}
+
+// Flush implements the http.Flusher interface
+func (w *streamRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Response returns the recorded stream as a complete response, or nil when no chunk was written
+func (w *streamRecorder) Response() *entities.ChatCompletionResponse {
+	return w.accumulator.Response()
+}
+
+// Complete reports whether every choice of the recorded stream has finished
+func (w *streamRecorder) Complete() bool {
+	return w.accumulator.Complete()
+}
@@ -0,0 +1,340 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"syscall"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/middleware"
+)
+
+// RoutingProxyUseCase wraps a ProxyUseCaseInterface with a model routing table.
+// Requests for a routed model are sent to the route's target model with the route's parameter
+// overrides applied, and the model of the response is rewritten back to the requested name.
+type RoutingProxyUseCase struct {
+	ProxyUseCaseInterface
+	routes []entities.ModelRoute
+	logger logging.LoggerInterface
+}
+
+// NewRoutingProxyUseCase creates a proxy use case that routes requests through the given table.
+// Routes are tried in order and the first matching route wins.
+func NewRoutingProxyUseCase(next ProxyUseCaseInterface, routes []entities.ModelRoute, logger logging.LoggerInterface) *RoutingProxyUseCase {
+	if next == nil {
+		panic("next cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	return &RoutingProxyUseCase{
+		ProxyUseCaseInterface: next,
+		routes:                routes,
+		logger:                logger,
+	}
+}
+
+// ChatCompletions routes the request, falling back along the route's fallback models,
+// and reports the requested model in the response
+func (uc *RoutingProxyUseCase) ChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+	if req == nil {
+		return uc.ProxyUseCaseInterface.ChatCompletions(ctx, req)
+	}
+	requested := req.Model
+	route := uc.applyRoute(ctx, req)
+	if route == nil {
+		return uc.ProxyUseCaseInterface.ChatCompletions(ctx, req)
+	}
+
+	var response *entities.ChatCompletionResponse
+	err := uc.withFallbacks(ctx, req, route, func(attempt *entities.ChatCompletionRequest) error {
+		var err error
+		response, err = uc.ProxyUseCaseInterface.ChatCompletions(ctx, attempt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Copy the response, it may be shared with the response cache
+	routed := *response
+	routed.Model = requested
+	return &routed, nil
+}
+
+// StreamChatCompletions routes the request and reports the requested model in every chunk.
+// Fallback models are only tried while nothing has been written to the client.
+func (uc *RoutingProxyUseCase) StreamChatCompletions(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+	if req == nil || writer == nil {
+		return uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, req, writer)
+	}
+	requested := req.Model
+	route := uc.applyRoute(ctx, req)
+	if route == nil {
+		return uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, req, writer)
+	}
+
+	rewriter := &modelRewriteWriter{ResponseWriter: writer, model: requested}
+	err := uc.withFallbacks(ctx, req, route, func(attempt *entities.ChatCompletionRequest) error {
+		err := uc.ProxyUseCaseInterface.StreamChatCompletions(ctx, attempt, rewriter)
+		if err != nil && rewriter.started {
+			return &permanentError{err}
+		}
+		return err
+	})
+	if flushErr := rewriter.flushPending(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// Embeddings sends the request to the target of the route matching its model and reports the
+// requested model in the response. Parameter overrides and fallbacks only apply to chat completions.
+func (uc *RoutingProxyUseCase) Embeddings(ctx context.Context, req *entities.EmbeddingRequest) (*entities.EmbeddingResponse, error) {
+	if req == nil {
+		return uc.ProxyUseCaseInterface.Embeddings(ctx, req)
+	}
+	route := MatchRoute(uc.routes, req.Model)
+	if route == nil {
+		return uc.ProxyUseCaseInterface.Embeddings(ctx, req)
+	}
+
+	requested := req.Model
+	uc.logger.Debug("Model routed", "request_id", middleware.GetRequestID(ctx), "requested", requested, "target", route.Target)
+	req.Model = route.Target
+	response, err := uc.ProxyUseCaseInterface.Embeddings(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	routed := *response
+	routed.Model = requested
+	return &routed, nil
+}
+
+// applyRoute rewrites the request for the first route matching its model and returns the route,
+// or nil when no route matches
+func (uc *RoutingProxyUseCase) applyRoute(ctx context.Context, req *entities.ChatCompletionRequest) *entities.ModelRoute {
+	route := MatchRoute(uc.routes, req.Model)
+	if route == nil {
+		return nil
+	}
+
+	uc.logger.Debug("Model routed", "request_id", middleware.GetRequestID(ctx), "requested", req.Model, "target", route.Target)
+	req.Model = route.Target
+	if route.MaxTokens > 0 && (req.MaxTokens == 0 || req.MaxTokens > route.MaxTokens) {
+		req.MaxTokens = route.MaxTokens
+	}
+	if route.Temperature != nil {
+		temperature := *route.Temperature
+		req.Temperature = &temperature
+	}
+	if route.TopP != nil {
+		req.TopP = *route.TopP
+	}
+	return route
+}
+
+// withFallbacks calls send with the routed request and, while it fails with one of the route's
+// fallback error classes, with a copy of the request for each fallback model in turn
+func (uc *RoutingProxyUseCase) withFallbacks(ctx context.Context, req *entities.ChatCompletionRequest, route *entities.ModelRoute, send func(attempt *entities.ChatCompletionRequest) error) error {
+	requestID := middleware.GetRequestID(ctx)
+	chain := append([]string{req.Model}, route.Fallbacks...)
+	original := *req
+
+	for i, model := range chain {
+		attempt := req
+		if i > 0 {
+			fallback := original
+			fallback.Model = model
+			attempt = &fallback
+		}
+
+		err := send(attempt)
+		if err == nil {
+			if i > 0 {
+				uc.logger.Info("Request served by fallback model", "request_id", requestID, "target", route.Target, "model", model)
+			}
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if i == len(chain)-1 || ctx.Err() != nil || !ShouldFallBack(err, route.FallbackOn) {
+			return err
+		}
+		uc.logger.Warn("Model failed, falling back", "request_id", requestID, "model", model, "next_model", chain[i+1], "error", err)
+	}
+	return nil
+}
+
+// permanentError marks an error that must not trigger a fallback
+type permanentError struct {
+	err error
+}
+
+// Error implements the error interface
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// ShouldFallBack reports whether an error belongs to one of the given fallback error classes.
+// Every class applies when none are given.
+func ShouldFallBack(err error, classes []string) bool {
+	if len(classes) == 0 {
+		classes = []string{
+			entities.FallbackOnServerError,
+			entities.FallbackOnOverloaded,
+			entities.FallbackOnRateLimited,
+			entities.FallbackOnTimeout,
+			entities.FallbackOnConnection,
+		}
+	}
+	for _, class := range classes {
+		if isFallbackClass(err, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFallbackClass reports whether an error belongs to a fallback error class
+func isFallbackClass(err error, class string) bool {
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		message := strings.ToLower(upstream.Message)
+		switch class {
+		case entities.FallbackOnServerError:
+			return upstream.StatusCode >= http.StatusInternalServerError
+		case entities.FallbackOnOverloaded:
+			return upstream.StatusCode == http.StatusServiceUnavailable || upstream.StatusCode == 529 || strings.Contains(message, "overload")
+		case entities.FallbackOnRateLimited:
+			return upstream.QuotaExceeded()
+		case entities.FallbackOnTimeout:
+			return upstream.StatusCode == http.StatusRequestTimeout || upstream.StatusCode == http.StatusGatewayTimeout
+		}
+		return false
+	}
+
+	var netErr net.Error
+	isNetErr := errors.As(err, &netErr)
+	switch class {
+	case entities.FallbackOnTimeout:
+		return errors.Is(err, context.DeadlineExceeded) || (isNetErr && netErr.Timeout())
+	case entities.FallbackOnConnection:
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			(isNetErr && !netErr.Timeout())
+	}
+	return false
+}
+
+// MatchRoute returns the first route whose pattern matches the model, or nil.
+// Requests without a model are never routed, so that the default model applies.
+func MatchRoute(routes []entities.ModelRoute, model string) *entities.ModelRoute {
+	if model == "" {
+		return nil
+	}
+	for i := range routes {
+		if matched, _ := path.Match(routes[i].Match, model); matched {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// modelRewriteWriter rewrites the model of every SSE data chunk written through it.
+// Writes are buffered until a line is complete, so chunks split across writes are rewritten too.
+// It also records whether anything was written, after which a stream can no longer fall back.
+type modelRewriteWriter struct {
+	http.ResponseWriter
+	model   string
+	pending []byte
+	started bool
+}
+
+// WriteHeader records that the response has started and writes the status code
+func (w *modelRewriteWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write rewrites and forwards every complete line, keeping a trailing partial line for later
+func (w *modelRewriteWriter) Write(b []byte) (int, error) {
+	w.started = true
+	w.pending = append(w.pending, b...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(b), nil
+		}
+		line := rewriteChunkModel(w.pending[:end+1], w.model)
+		w.pending = w.pending[end+1:]
+		if _, err := w.ResponseWriter.Write(line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Flush implements the http.Flusher interface
+func (w *modelRewriteWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// flushPending forwards a trailing line that was never terminated
+func (w *modelRewriteWriter) flushPending() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	line := rewriteChunkModel(w.pending, w.model)
+	w.pending = nil
+	_, err := w.ResponseWriter.Write(line)
+	return err
+}
+
+// rewriteChunkModel replaces the model of an SSE data line holding a JSON chunk.
+// Other lines, and chunks without a model, are returned unchanged.
+func rewriteChunkModel(line []byte, model string) []byte {
+	payload, found := bytes.CutPrefix(line, []byte("data: "))
+	if !found {
+		return line
+	}
+	body := bytes.TrimRight(payload, "\r\n")
+	ending := payload[len(body):]
+	if !bytes.HasPrefix(body, []byte("{")) {
+		return line
+	}
+
+	var chunk map[string]json.RawMessage
+	if err := json.Unmarshal(body, &chunk); err != nil {
+		return line
+	}
+	if _, ok := chunk["model"]; !ok {
+		return line
+	}
+	encodedModel, err := json.Marshal(model)
+	if err != nil {
+		return line
+	}
+	chunk["model"] = encodedModel
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return line
+	}
+
+	rewritten := make([]byte, 0, len(data)+len(ending)+6)
+	rewritten = append(rewritten, "data: "...)
+	rewritten = append(rewritten, data...)
+	return append(rewritten, ending...)
+}
@@ -0,0 +1,307 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/mocks"
+	"qwen-go-proxy/internal/usecases/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func testRoutes() []entities.ModelRoute {
+	temperature := 0.2
+	return []entities.ModelRoute{
+		{Match: "gpt-4o", Target: "qwen3-coder-plus", MaxTokens: 1024, Temperature: &temperature},
+		{Match: "gpt-*", Target: "qwen3-coder-flash"},
+	}
+}
+
+func newRoutingTestUseCase(t *testing.T) (*RoutingProxyUseCase, *mocks.MockProxyUseCaseInterface) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+	return NewRoutingProxyUseCase(next, testRoutes(), logger), next
+}
+
+func TestNewRoutingProxyUseCase_NilArguments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+
+	assert.PanicsWithValue(t, "next cannot be nil", func() { NewRoutingProxyUseCase(nil, nil, logger) })
+	assert.PanicsWithValue(t, "logger cannot be nil", func() { NewRoutingProxyUseCase(next, nil, nil) })
+}
+
+func TestMatchRoute(t *testing.T) {
+	routes := testRoutes()
+
+	assert.Equal(t, "qwen3-coder-plus", MatchRoute(routes, "gpt-4o").Target, "the first matching route wins")
+	assert.Equal(t, "qwen3-coder-flash", MatchRoute(routes, "gpt-4o-mini").Target)
+	assert.Nil(t, MatchRoute(routes, "qwen3-coder-plus"))
+	assert.Nil(t, MatchRoute(routes, ""))
+}
+
+func TestRoutingProxyUseCase_ChatCompletions(t *testing.T) {
+	uc, next := newRoutingTestUseCase(t)
+	temperature := 1.0
+	req := &entities.ChatCompletionRequest{
+		Model:       "gpt-4o",
+		Messages:    []entities.ChatMessage{{Role: "user", Content: "Hello"}},
+		MaxTokens:   4096,
+		Temperature: &temperature,
+	}
+	upstream := upstreamResponse("Hi")
+
+	next.EXPECT().ChatCompletions(gomock.Any(), req).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+			assert.Equal(t, "qwen3-coder-plus", req.Model)
+			assert.Equal(t, 1024, req.MaxTokens, "max_tokens is capped")
+			assert.Equal(t, 0.2, *req.Temperature, "temperature is forced")
+			return upstream, nil
+		})
+
+	response, err := uc.ChatCompletions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", response.Model)
+	assert.Equal(t, "qwen3-coder-plus", upstream.Model, "the upstream response must not be modified")
+}
+
+func TestRoutingProxyUseCase_ChatCompletions_ForcesZeroTemperature(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	logger := &logging.Logger{Logger: logging.NewLogger("error")}
+	zero := 0.0
+	uc := NewRoutingProxyUseCase(next, []entities.ModelRoute{{Match: "gpt-*", Target: "qwen3-coder-plus", Temperature: &zero}}, logger)
+
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+			// The forced temperature is sent upstream even though it is the zero value
+			body, err := json.Marshal(req)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), `"temperature":0`)
+			return upstreamResponse("Hi"), nil
+		})
+
+	_, err := uc.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+}
+
+func TestRoutingProxyUseCase_ChatCompletions_KeepsLowerMaxTokens(t *testing.T) {
+	uc, next := newRoutingTestUseCase(t)
+	req := &entities.ChatCompletionRequest{Model: "gpt-4o", MaxTokens: 100}
+
+	next.EXPECT().ChatCompletions(gomock.Any(), req).Return(upstreamResponse("Hi"), nil)
+
+	_, err := uc.ChatCompletions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 100, req.MaxTokens)
+}
+
+func TestRoutingProxyUseCase_ChatCompletions_Unrouted(t *testing.T) {
+	uc, next := newRoutingTestUseCase(t)
+	temperature := 1.0
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Temperature: &temperature}
+
+	next.EXPECT().ChatCompletions(gomock.Any(), req).Return(upstreamResponse("Hi"), nil)
+
+	response, err := uc.ChatCompletions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "qwen3-coder-plus", response.Model)
+	assert.Equal(t, 1.0, *req.Temperature)
+}
+
+func TestRoutingProxyUseCase_ChatCompletions_Error(t *testing.T) {
+	uc, next := newRoutingTestUseCase(t)
+	upstreamErr := errors.New("upstream failed")
+
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, upstreamErr)
+
+	_, err := uc.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "gpt-4o"})
+	assert.ErrorIs(t, err, upstreamErr)
+}
+
+func TestRoutingProxyUseCase_Embeddings(t *testing.T) {
+	uc, next := newRoutingTestUseCase(t)
+	req := &entities.EmbeddingRequest{Model: "gpt-embedding", Input: "Hello"}
+
+	next.EXPECT().Embeddings(gomock.Any(), req).DoAndReturn(
+		func(ctx context.Context, req *entities.EmbeddingRequest) (*entities.EmbeddingResponse, error) {
+			assert.Equal(t, "qwen3-coder-flash", req.Model)
+			return &entities.EmbeddingResponse{Object: "list", Model: req.Model}, nil
+		})
+
+	response, err := uc.Embeddings(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-embedding", response.Model)
+
+	// Unrouted models are passed on unchanged
+	unrouted := &entities.EmbeddingRequest{Model: "text-embedding-v3", Input: "Hello"}
+	next.EXPECT().Embeddings(gomock.Any(), unrouted).Return(&entities.EmbeddingResponse{Model: "text-embedding-v3"}, nil)
+
+	response, err = uc.Embeddings(context.Background(), unrouted)
+	require.NoError(t, err)
+	assert.Equal(t, "text-embedding-v3", response.Model)
+}
+
+func TestRoutingProxyUseCase_StreamChatCompletions(t *testing.T) {
+	uc, next := newRoutingTestUseCase(t)
+	req := &entities.ChatCompletionRequest{Model: "gpt-4o-mini", Stream: true}
+	recorder := httptest.NewRecorder()
+
+	next.EXPECT().StreamChatCompletions(gomock.Any(), req, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+			assert.Equal(t, "qwen3-coder-flash", req.Model)
+			// A chunk split across writes is still rewritten
+			fmt.Fprint(writer, `data: {"id":"1","model":"qwen3-coder-flash",`)
+			fmt.Fprint(writer, `"choices":[]}`+"\n\n")
+			fmt.Fprint(writer, ": keep-alive\n\n")
+			fmt.Fprint(writer, "data: [DONE]\n\n")
+			return nil
+		})
+
+	require.NoError(t, uc.StreamChatCompletions(context.Background(), req, recorder))
+	assert.Equal(t,
+		`data: {"choices":[],"id":"1","model":"gpt-4o-mini"}`+"\n\n: keep-alive\n\ndata: [DONE]\n\n",
+		recorder.Body.String())
+}
+
+func TestRewriteChunkModel(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"chunk", "data: {\"model\":\"qwen\"}\n", "data: {\"model\":\"gpt-4o\"}\n"},
+		{"crlf ending", "data: {\"model\":\"qwen\"}\r\n", "data: {\"model\":\"gpt-4o\"}\r\n"},
+		{"chunk without model", "data: {\"id\":\"1\"}\n", "data: {\"id\":\"1\"}\n"},
+		{"done", "data: [DONE]\n", "data: [DONE]\n"},
+		{"malformed", "data: {\"model\":\n", "data: {\"model\":\n"},
+		{"comment", ": ping\n", ": ping\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(rewriteChunkModel([]byte(tt.line), "gpt-4o")))
+		})
+	}
+}
+
+func fallbackRoutes(fallbackOn ...string) []entities.ModelRoute {
+	return []entities.ModelRoute{{
+		Match:      "qwen3-coder-plus",
+		Target:     "qwen3-coder-plus",
+		Fallbacks:  []string{"qwen3-coder-flash", "qwen-turbo"},
+		FallbackOn: fallbackOn,
+	}}
+}
+
+func TestRoutingProxyUseCase_ChatCompletions_FallsBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	uc := NewRoutingProxyUseCase(next, fallbackRoutes(), &logging.Logger{Logger: logging.NewLogger("error")})
+	req := &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Messages: []entities.ChatMessage{{Role: "user", Content: "Hello"}}}
+
+	var tried []string
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *entities.ChatCompletionRequest) (*entities.ChatCompletionResponse, error) {
+			tried = append(tried, req.Model)
+			switch req.Model {
+			case "qwen3-coder-plus":
+				return nil, &UpstreamError{StatusCode: http.StatusInternalServerError, Message: "internal error"}
+			case "qwen3-coder-flash":
+				return nil, &UpstreamError{StatusCode: http.StatusTooManyRequests, Message: "slow down"}
+			}
+			response := upstreamResponse("Hi")
+			response.Model = req.Model
+			return response, nil
+		}).Times(3)
+
+	response, err := uc.ChatCompletions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qwen3-coder-plus", "qwen3-coder-flash", "qwen-turbo"}, tried)
+	assert.Equal(t, "qwen3-coder-plus", response.Model, "the requested model is reported")
+}
+
+func TestRoutingProxyUseCase_ChatCompletions_FallbackClasses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	uc := NewRoutingProxyUseCase(next, fallbackRoutes(entities.FallbackOnTimeout), &logging.Logger{Logger: logging.NewLogger("error")})
+	serverErr := &UpstreamError{StatusCode: http.StatusBadGateway, Message: "bad gateway"}
+
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, serverErr).Times(1)
+
+	_, err := uc.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "qwen3-coder-plus"})
+	assert.ErrorIs(t, err, serverErr, "server errors do not fall back when only timeouts are configured")
+}
+
+func TestRoutingProxyUseCase_ChatCompletions_AllModelsFail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	uc := NewRoutingProxyUseCase(next, fallbackRoutes(), &logging.Logger{Logger: logging.NewLogger("error")})
+	overloaded := &UpstreamError{StatusCode: http.StatusServiceUnavailable, Message: "model overloaded"}
+
+	next.EXPECT().ChatCompletions(gomock.Any(), gomock.Any()).Return(nil, overloaded).Times(3)
+
+	_, err := uc.ChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "qwen3-coder-plus"})
+	assert.ErrorIs(t, err, overloaded)
+}
+
+func TestRoutingProxyUseCase_StreamChatCompletions_FallsBackBeforeFirstWrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockProxyUseCaseInterface(ctrl)
+	uc := NewRoutingProxyUseCase(next, fallbackRoutes(), &logging.Logger{Logger: logging.NewLogger("error")})
+	recorder := httptest.NewRecorder()
+
+	gomock.InOrder(
+		next.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).Return(&UpstreamError{StatusCode: http.StatusInternalServerError}),
+		next.EXPECT().StreamChatCompletions(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *entities.ChatCompletionRequest, writer http.ResponseWriter) error {
+				assert.Equal(t, "qwen3-coder-flash", req.Model)
+				fmt.Fprint(writer, "data: {\"model\":\"qwen3-coder-flash\"}\n\n")
+				return errors.New("connection lost mid-stream")
+			}),
+	)
+
+	err := uc.StreamChatCompletions(context.Background(), &entities.ChatCompletionRequest{Model: "qwen3-coder-plus", Stream: true}, recorder)
+	assert.EqualError(t, err, "connection lost mid-stream", "a stream that has started never falls back")
+	assert.Equal(t, "data: {\"model\":\"qwen3-coder-plus\"}\n\n", recorder.Body.String())
+}
+
+func TestShouldFallBack(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		classes []string
+		want    bool
+	}{
+		{"server error", &UpstreamError{StatusCode: 500}, []string{entities.FallbackOnServerError}, true},
+		{"client error", &UpstreamError{StatusCode: 400}, nil, false},
+		{"overloaded status", &UpstreamError{StatusCode: 529}, []string{entities.FallbackOnOverloaded}, true},
+		{"overloaded message", &UpstreamError{StatusCode: 400, Message: "Model is Overloaded"}, []string{entities.FallbackOnOverloaded}, true},
+		{"rate limited", &UpstreamError{StatusCode: 429}, []string{entities.FallbackOnRateLimited}, true},
+		{"quota", &UpstreamError{StatusCode: 403, Message: "quota exceeded"}, []string{entities.FallbackOnRateLimited}, true},
+		{"gateway timeout", &UpstreamError{StatusCode: 504}, []string{entities.FallbackOnTimeout}, true},
+		{"deadline", fmt.Errorf("API request failed: %w", context.DeadlineExceeded), []string{entities.FallbackOnTimeout}, true},
+		{"connection reset", fmt.Errorf("API request failed: %w", syscall.ECONNRESET), []string{entities.FallbackOnConnection}, true},
+		{"connection reset is no timeout", fmt.Errorf("API request failed: %w", syscall.ECONNRESET), []string{entities.FallbackOnTimeout}, false},
+		{"unknown model", &models.ModelNotFoundError{Model: "x"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ShouldFallBack(tt.err, tt.classes))
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/domain/interfaces"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/usecases/streaming"
+)
+
+// usageStreamWriter makes a stream requested with stream_options.include_usage end with a usage chunk.
+// Usage the upstream reports is held back and sent as the final chunk before [DONE]; when the
+// upstream reports none, usage is estimated with a local tokenizer and marked as estimated.
+type usageStreamWriter struct {
+	http.ResponseWriter
+	req         *entities.ChatCompletionRequest
+	tokenizer   interfaces.Tokenizer
+	pending     []byte
+	accumulator *streaming.StreamAccumulator
+	usage       *entities.Usage
+	skipBlank   bool
+	finished    bool
+}
+
+// newUsageStreamWriter creates a usage stream writer around the client response writer
+func newUsageStreamWriter(w http.ResponseWriter, req *entities.ChatCompletionRequest, tokenizer interfaces.Tokenizer) *usageStreamWriter {
+	return &usageStreamWriter{
+		ResponseWriter: w,
+		req:            req,
+		tokenizer:      tokenizer,
+		accumulator:    streaming.NewStreamAccumulator(),
+	}
+}
+
+// Write forwards every complete line to the client, holding back usage until the end of the stream
+func (w *usageStreamWriter) Write(b []byte) (int, error) {
+	w.pending = append(w.pending, b...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+		line := w.pending[:end+1]
+		w.pending = w.pending[end+1:]
+		if err := w.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements the http.Flusher interface
+func (w *usageStreamWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeLine forwards a single line, taking the usage out of chunks that carry it
+func (w *usageStreamWriter) writeLine(line []byte) error {
+	content := bytes.TrimRight(line, "\r\n")
+	if len(content) == 0 && w.skipBlank {
+		// The blank line ended an event that was held back
+		w.skipBlank = false
+		return nil
+	}
+	w.skipBlank = false
+
+	data, found := bytes.CutPrefix(content, []byte("data: "))
+	if !found {
+		_, err := w.ResponseWriter.Write(line)
+		return err
+	}
+	if string(bytes.TrimSpace(data)) == "[DONE]" {
+		if err := w.writeUsage(); err != nil {
+			return err
+		}
+		_, err := w.ResponseWriter.Write(line)
+		return err
+	}
+	if !w.accumulator.AddData(string(data)) {
+		_, err := w.ResponseWriter.Write(line)
+		return err
+	}
+
+	var chunk map[string]json.RawMessage
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		_, err := w.ResponseWriter.Write(line)
+		return err
+	}
+	rawUsage, hasUsage := chunk["usage"]
+	if !hasUsage || string(rawUsage) == "null" {
+		_, err := w.ResponseWriter.Write(line)
+		return err
+	}
+	var usage entities.Usage
+	if err := json.Unmarshal(rawUsage, &usage); err == nil {
+		w.usage = &usage
+	}
+
+	var choices []json.RawMessage
+	if err := json.Unmarshal(chunk["choices"], &choices); err != nil || len(choices) == 0 {
+		// A usage-only chunk is sent again at the end of the stream
+		w.skipBlank = true
+		return nil
+	}
+	delete(chunk, "usage")
+	stripped, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.ResponseWriter, "data: %s\n", stripped)
+	return err
+}
+
+// Finish ends a stream that did not end with [DONE] by writing the usage chunk
+func (w *usageStreamWriter) Finish() error {
+	if len(w.pending) > 0 {
+		if err := w.writeLine(w.pending); err != nil {
+			return err
+		}
+		w.pending = nil
+	}
+	return w.writeUsage()
+}
+
+// writeUsage writes the usage chunk unless it was already written or nothing was streamed
+func (w *usageStreamWriter) writeUsage() error {
+	if w.finished {
+		return nil
+	}
+	w.finished = true
+	response := w.accumulator.Response()
+	if response == nil {
+		return nil
+	}
+
+	usage := w.usage
+	if usage == nil {
+		usage = &entities.Usage{
+			PromptTokens:     tokenizer.CountPromptTokens(w.tokenizer, w.req),
+			CompletionTokens: tokenizer.CountCompletionTokens(w.tokenizer, response),
+			Estimated:        true,
+		}
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	data, err := json.Marshal(&entities.ChatCompletionResponse{
+		ID:      response.ID,
+		Object:  "chat.completion.chunk",
+		Created: response.Created,
+		Model:   response.Model,
+		Choices: []entities.ChatCompletionChoice{},
+		Usage:   usage,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w.ResponseWriter, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
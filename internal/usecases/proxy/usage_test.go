@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/tokenizer"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// streamEvents returns the data payloads of an SSE stream in order
+func streamEvents(body string) []string {
+	var events []string
+	for _, line := range strings.Split(body, "\n") {
+		if data, found := strings.CutPrefix(line, "data: "); found {
+			events = append(events, data)
+		}
+	}
+	return events
+}
+
+// decodeUsageChunk decodes a stream chunk and asserts that it only carries usage
+func decodeUsageChunk(t *testing.T, data string) *entities.Usage {
+	t.Helper()
+	var chunk entities.ChatCompletionResponse
+	require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+	assert.Equal(t, "chat.completion.chunk", chunk.Object)
+	assert.Empty(t, chunk.Choices)
+	require.NotNil(t, chunk.Usage)
+	return chunk.Usage
+}
+
+func usageRequest() *entities.ChatCompletionRequest {
+	return &entities.ChatCompletionRequest{
+		Model:         "qwen3-coder-plus",
+		Messages:      []entities.ChatMessage{{Role: "user", Content: "Say hello"}},
+		Stream:        true,
+		StreamOptions: &entities.StreamOptions{IncludeUsage: true},
+	}
+}
+
+func TestUsageStreamWriter_MovesUpstreamUsageBeforeDone(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := newUsageStreamWriter(recorder, usageRequest(), tokenizer.NewEstimator())
+
+	// The upstream attaches usage to the last content chunk and sends a keep-alive comment after it
+	fmt.Fprint(writer, "data: {\"id\":\"chatcmpl-1\",\"model\":\"qwen3-coder-plus\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+	fmt.Fprint(writer, "data: {\"id\":\"chatcmpl-1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":2,\"total_tokens\":12}}\n\n")
+	fmt.Fprint(writer, ": keep-alive\n\n")
+	fmt.Fprint(writer, "data: [DONE]\n\n")
+	require.NoError(t, writer.Finish())
+
+	body := recorder.Body.String()
+	events := streamEvents(body)
+	require.Len(t, events, 4)
+	assert.NotContains(t, events[1], "usage")
+	assert.Contains(t, events[1], `"finish_reason":"stop"`)
+	usage := decodeUsageChunk(t, events[2])
+	assert.Equal(t, entities.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}, *usage)
+	assert.Equal(t, "[DONE]", events[3])
+	assert.Contains(t, body, ": keep-alive\n")
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
+
+func TestUsageStreamWriter_HoldsUsageOnlyChunk(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := newUsageStreamWriter(recorder, usageRequest(), tokenizer.NewEstimator())
+
+	// Events may arrive split across writes
+	stream := "data: {\"id\":\"chatcmpl-1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: {\"id\":\"chatcmpl-1\",\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":1,\"total_tokens\":6}}\n\n" +
+		"data: [DONE]\n\n"
+	for len(stream) > 0 {
+		n := min(7, len(stream))
+		fmt.Fprint(writer, stream[:n])
+		stream = stream[n:]
+	}
+	require.NoError(t, writer.Finish())
+
+	events := streamEvents(recorder.Body.String())
+	require.Len(t, events, 3)
+	assert.Equal(t, 6, decodeUsageChunk(t, events[1]).TotalTokens)
+	assert.Equal(t, "[DONE]", events[2])
+	assert.NotContains(t, recorder.Body.String(), "\n\n\n")
+}
+
+func TestUsageStreamWriter_EstimatesMissingUsage(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := newUsageStreamWriter(recorder, usageRequest(), tokenizer.NewEstimator())
+
+	fmt.Fprint(writer, "data: {\"id\":\"chatcmpl-1\",\"created\":1700000000,\"model\":\"qwen3-coder-plus\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hello there\"},\"finish_reason\":\"stop\"}]}\n\n")
+	// The upstream ends the stream without [DONE]
+	require.NoError(t, writer.Finish())
+	require.NoError(t, writer.Finish())
+
+	events := streamEvents(recorder.Body.String())
+	require.Len(t, events, 2)
+	var chunk entities.ChatCompletionResponse
+	require.NoError(t, json.Unmarshal([]byte(events[1]), &chunk))
+	assert.Equal(t, "chatcmpl-1", chunk.ID)
+	assert.Equal(t, "qwen3-coder-plus", chunk.Model)
+	assert.Equal(t, int64(1700000000), chunk.Created)
+
+	usage := decodeUsageChunk(t, events[1])
+	assert.True(t, usage.Estimated)
+	assert.Equal(t, 4, usage.CompletionTokens)
+	assert.Positive(t, usage.PromptTokens)
+	assert.Equal(t, usage.PromptTokens+usage.CompletionTokens, usage.TotalTokens)
+}
+
+func TestUsageStreamWriter_EmptyStream(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := newUsageStreamWriter(recorder, usageRequest(), tokenizer.NewEstimator())
+
+	require.NoError(t, writer.Finish())
+
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestProxyUseCase_StreamChatCompletions_IncludeUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthUseCase := mocks.NewMockAuthUseCaseInterface(ctrl)
+	mockQwenGateway := mocks.NewMockQwenAPIGateway(ctrl)
+	mockStreamingUseCase := mocks.NewMockStreamingUseCaseInterface(ctrl)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewProxyUseCase(mockAuthUseCase, mockQwenGateway, mockStreamingUseCase, passThroughCatalog(ctrl), mockLogger, "qwen3-coder-plus", tokenizer.NewEstimator(), ContextPolicy{})
+
+	req := usageRequest()
+	credentials := &entities.Credentials{ResourceURL: "https://api.example.com"}
+	streamingResponse := createMockStreamingHttpResponse()
+	writer := httptest.NewRecorder()
+
+	mockAuthUseCase.EXPECT().EnsureAuthenticated(gomock.Any()).Return(credentials, nil)
+	mockQwenGateway.EXPECT().ChatCompletions(gomock.Any(), req, credentials).Return(streamingResponse, nil)
+	mockStreamingUseCase.EXPECT().ProcessStreamingResponse(gomock.Any(), streamingResponse, gomock.Not(writer)).
+		DoAndReturn(func(_ context.Context, _ *http.Response, w http.ResponseWriter) error {
+			fmt.Fprint(w, "data: {\"id\":\"chatcmpl-1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"},\"finish_reason\":\"stop\"}]}\n\n")
+			return nil
+		})
+
+	err := useCase.StreamChatCompletions(context.Background(), req, writer)
+
+	require.NoError(t, err)
+	events := streamEvents(writer.Body.String())
+	require.Len(t, events, 2)
+	assert.True(t, decodeUsageChunk(t, events[1]).Estimated)
+}
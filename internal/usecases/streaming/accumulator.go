@@ -0,0 +1,207 @@
+package streaming
+
+import (
+	"encoding/json"
+	"strings"
+
+	"qwen-go-proxy/internal/domain/entities"
+)
+
+// StreamAccumulator merges the chunks of a chat completion stream into a complete response.
+// Content and reasoning deltas are concatenated per choice, and incremental tool calls are
+// merged by their index with their arguments appended in order.
+type StreamAccumulator struct {
+	response  *entities.ChatCompletionResponse
+	content   map[int]*strings.Builder
+	reasoning map[int]*strings.Builder
+	toolCalls map[int]*ToolCallIndexer
+}
+
+// NewStreamAccumulator creates an empty stream accumulator
+func NewStreamAccumulator() *StreamAccumulator {
+	return &StreamAccumulator{
+		content:   make(map[int]*strings.Builder),
+		reasoning: make(map[int]*strings.Builder),
+		toolCalls: make(map[int]*ToolCallIndexer),
+	}
+}
+
+// AddData merges the JSON payload of an SSE data line, without the "data: " prefix.
+// It reports whether the payload was a chunk; [DONE] and malformed payloads are ignored.
+func (a *StreamAccumulator) AddData(data string) bool {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, "{") {
+		return false
+	}
+	var chunk entities.ChatCompletionResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return false
+	}
+	a.AddChunk(&chunk)
+	return true
+}
+
+// AddChunk merges a decoded stream chunk
+func (a *StreamAccumulator) AddChunk(chunk *entities.ChatCompletionResponse) {
+	if chunk == nil {
+		return
+	}
+	if a.response == nil {
+		a.response = &entities.ChatCompletionResponse{Object: "chat.completion"}
+	}
+	if chunk.ID != "" {
+		a.response.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		a.response.Model = chunk.Model
+	}
+	if chunk.Created != 0 {
+		a.response.Created = chunk.Created
+	}
+	if chunk.Usage != nil {
+		usage := *chunk.Usage
+		a.response.Usage = &usage
+	}
+
+	for _, delta := range chunk.Choices {
+		choice := a.choice(delta.Index)
+		if delta.Delta.Role != "" {
+			choice.Message.Role = delta.Delta.Role
+		}
+		if text, ok := delta.Delta.Content.(string); ok {
+			a.content[delta.Index].WriteString(text)
+		}
+		a.reasoning[delta.Index].WriteString(delta.Delta.ReasoningContent)
+		for _, toolCall := range delta.Delta.ToolCalls {
+			mergeToolCall(&choice.Message, a.toolCalls[delta.Index].Index(toolCall), toolCall)
+		}
+		if delta.Logprobs != nil {
+			if choice.Logprobs == nil {
+				choice.Logprobs = &entities.Logprobs{}
+			}
+			choice.Logprobs.Content = append(choice.Logprobs.Content, delta.Logprobs.Content...)
+		}
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+	}
+}
+
+// Response returns the response assembled so far, or nil when no chunk was added.
+// The returned response is a copy that later chunks do not change.
+func (a *StreamAccumulator) Response() *entities.ChatCompletionResponse {
+	if a.response == nil {
+		return nil
+	}
+	response := *a.response
+	response.Choices = make([]entities.ChatCompletionChoice, len(a.response.Choices))
+	for i, choice := range a.response.Choices {
+		choice.Message.Content = a.content[choice.Index].String()
+		choice.Message.ReasoningContent = a.reasoning[choice.Index].String()
+		if len(choice.Message.ToolCalls) > 0 {
+			toolCalls := make([]entities.ToolCall, len(choice.Message.ToolCalls))
+			for j, toolCall := range choice.Message.ToolCalls {
+				// The index only identifies tool calls within a stream
+				toolCall.Index = nil
+				toolCalls[j] = toolCall
+			}
+			choice.Message.ToolCalls = toolCalls
+		}
+		if choice.Logprobs != nil {
+			logprobs := *choice.Logprobs
+			logprobs.Content = append([]entities.TokenLogprob(nil), choice.Logprobs.Content...)
+			choice.Logprobs = &logprobs
+		}
+		response.Choices[i] = choice
+	}
+	return &response
+}
+
+// Complete reports whether every choice of the stream has finished
+func (a *StreamAccumulator) Complete() bool {
+	if a.response == nil || len(a.response.Choices) == 0 {
+		return false
+	}
+	for _, choice := range a.response.Choices {
+		if choice.FinishReason == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// choice returns the assembled choice with the given index, adding it when it is new
+func (a *StreamAccumulator) choice(index int) *entities.ChatCompletionChoice {
+	for i := range a.response.Choices {
+		if a.response.Choices[i].Index == index {
+			return &a.response.Choices[i]
+		}
+	}
+	a.response.Choices = append(a.response.Choices, entities.ChatCompletionChoice{
+		Index:   index,
+		Message: entities.ChatMessage{Role: "assistant"},
+	})
+	a.content[index] = &strings.Builder{}
+	a.reasoning[index] = &strings.Builder{}
+	a.toolCalls[index] = &ToolCallIndexer{}
+	return &a.response.Choices[len(a.response.Choices)-1]
+}
+
+// mergeToolCall merges a streamed tool call delta with the given index into the message. The first
+// delta of a tool call carries its ID and name, and later deltas with the same index append to its arguments.
+func mergeToolCall(message *entities.ChatMessage, index int, delta entities.ToolCall) {
+	for i := range message.ToolCalls {
+		existing := &message.ToolCalls[i]
+		if *existing.Index != index {
+			continue
+		}
+		if delta.ID != "" {
+			existing.ID = delta.ID
+		}
+		if delta.Type != "" {
+			existing.Type = delta.Type
+		}
+		if delta.Function.Name != "" {
+			existing.Function.Name = delta.Function.Name
+		}
+		existing.Function.Arguments += delta.Function.Arguments
+		return
+	}
+
+	delta.Index = &index
+	if delta.Type == "" {
+		delta.Type = "function"
+	}
+	message.ToolCalls = append(message.ToolCalls, delta)
+}
+
+// ToolCallIndexer assigns indexes to the tool call deltas of a streamed choice. Deltas with an index
+// keep it. Upstreams that omit the index send the ID and name only on the first delta of a call, so
+// a delta without an index continues the last tool call, unless it carries the ID of a new one.
+// The zero value is ready to use.
+type ToolCallIndexer struct {
+	ids     map[int]string
+	last    int
+	next    int
+	started bool
+}
+
+// Index returns the index of the tool call a delta belongs to
+func (t *ToolCallIndexer) Index(delta entities.ToolCall) int {
+	if t.ids == nil {
+		t.ids = make(map[int]string)
+	}
+	index := t.next
+	switch {
+	case delta.Index != nil:
+		index = *delta.Index
+	case t.started && (delta.ID == "" || t.ids[t.last] == "" || delta.ID == t.ids[t.last]):
+		index = t.last
+	}
+	if delta.ID != "" {
+		t.ids[index] = delta.ID
+	}
+	t.last, t.started = index, true
+	t.next = max(t.next, index+1)
+	return index
+}
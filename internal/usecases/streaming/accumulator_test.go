@@ -0,0 +1,118 @@
+package streaming
+
+import (
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamAccumulator_MergesDeltas(t *testing.T) {
+	accumulator := NewStreamAccumulator()
+	assert.Nil(t, accumulator.Response())
+	assert.False(t, accumulator.Complete())
+
+	chunks := []string{
+		`{"id":"chatcmpl-1","created":1700000000,"model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Let me "}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"reasoning_content":"look.","content":"Checking"}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":" now","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":"}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","function":{"name":"fetch","arguments":"{}"}}]}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"x\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`,
+	}
+	for _, chunk := range chunks {
+		assert.True(t, accumulator.AddData(chunk))
+	}
+	assert.False(t, accumulator.AddData("[DONE]"))
+	assert.False(t, accumulator.AddData(`{"broken`))
+
+	response := accumulator.Response()
+	require.NotNil(t, response)
+	assert.Equal(t, "chatcmpl-1", response.ID)
+	assert.Equal(t, "chat.completion", response.Object)
+	assert.Equal(t, int64(1700000000), response.Created)
+	assert.Equal(t, "qwen3-coder-plus", response.Model)
+	require.NotNil(t, response.Usage)
+	assert.Equal(t, 7, response.Usage.TotalTokens)
+
+	require.Len(t, response.Choices, 1)
+	message := response.Choices[0].Message
+	assert.Equal(t, "assistant", message.Role)
+	assert.Equal(t, "Checking now", message.Content)
+	assert.Equal(t, "Let me look.", message.ReasoningContent)
+	assert.Equal(t, "tool_calls", response.Choices[0].FinishReason)
+	require.Len(t, message.ToolCalls, 2)
+	assert.Equal(t, "call_1", message.ToolCalls[0].ID)
+	assert.Equal(t, "lookup", message.ToolCalls[0].Function.Name)
+	assert.Equal(t, `{"q":"x"}`, message.ToolCalls[0].Function.Arguments)
+	assert.Nil(t, message.ToolCalls[0].Index)
+	assert.Equal(t, "call_2", message.ToolCalls[1].ID)
+	assert.Equal(t, "function", message.ToolCalls[1].Type)
+	assert.True(t, accumulator.Complete())
+}
+
+func TestStreamAccumulator_ToolCallsWithoutIndex(t *testing.T) {
+	accumulator := NewStreamAccumulator()
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"function":{"arguments":"{\"q\":"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"function":{"arguments":"\"x\"}"}}]}}]}`,
+		// A delta with a new ID starts the next tool call
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_2","function":{"name":"fetch","arguments":"{"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"id":"call_2","function":{"arguments":"}"}}]},"finish_reason":"tool_calls"}]}`,
+	}
+	for _, chunk := range chunks {
+		require.True(t, accumulator.AddData(chunk))
+	}
+
+	message := accumulator.Response().Choices[0].Message
+	require.Len(t, message.ToolCalls, 2)
+	assert.Equal(t, "call_1", message.ToolCalls[0].ID)
+	assert.Equal(t, "lookup", message.ToolCalls[0].Function.Name)
+	assert.Equal(t, `{"q":"x"}`, message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "call_2", message.ToolCalls[1].ID)
+	assert.Equal(t, "fetch", message.ToolCalls[1].Function.Name)
+	assert.Equal(t, `{}`, message.ToolCalls[1].Function.Arguments)
+}
+
+func TestToolCallIndexer(t *testing.T) {
+	index := func(i int) *int { return &i }
+	var indexer ToolCallIndexer
+
+	assert.Equal(t, 0, indexer.Index(entities.ToolCall{ID: "call_1"}))
+	assert.Equal(t, 0, indexer.Index(entities.ToolCall{}), "fragments continue the last call")
+	assert.Equal(t, 3, indexer.Index(entities.ToolCall{Index: index(3), ID: "call_4"}))
+	assert.Equal(t, 3, indexer.Index(entities.ToolCall{}))
+	assert.Equal(t, 0, indexer.Index(entities.ToolCall{Index: index(0)}))
+	assert.Equal(t, 0, indexer.Index(entities.ToolCall{ID: "call_1"}))
+	assert.Equal(t, 4, indexer.Index(entities.ToolCall{ID: "call_5"}), "a new ID starts a new call")
+}
+
+func TestStreamAccumulator_MultipleChoices(t *testing.T) {
+	accumulator := NewStreamAccumulator()
+	accumulator.AddData(`{"choices":[{"index":0,"delta":{"content":"A"}},{"index":1,"delta":{"content":"B"}}]}`)
+	accumulator.AddData(`{"choices":[{"index":1,"delta":{"content":"b"},"finish_reason":"stop"}]}`)
+
+	response := accumulator.Response()
+	require.Len(t, response.Choices, 2)
+	assert.Equal(t, "A", response.Choices[0].Message.Content)
+	assert.Equal(t, "Bb", response.Choices[1].Message.Content)
+	// The first choice never finished
+	assert.False(t, accumulator.Complete())
+
+	accumulator.AddData(`{"choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`)
+	assert.True(t, accumulator.Complete())
+}
+
+func TestStreamAccumulator_ResponseIsACopy(t *testing.T) {
+	accumulator := NewStreamAccumulator()
+	accumulator.AddData(`{"choices":[{"index":0,"delta":{"content":"Hello"}}]}`)
+
+	first := accumulator.Response()
+	accumulator.AddData(`{"choices":[{"index":0,"delta":{"content":" world"}}]}`)
+
+	assert.Equal(t, "Hello", first.Choices[0].Message.Content)
+	assert.Equal(t, "Hello world", accumulator.Response().Choices[0].Message.Content)
+}
@@ -0,0 +1,40 @@
+package streaming
+
+import (
+	"context"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/infrastructure/logging"
+)
+
+// LoggingHook returns a stream hook that logs what every choice of a stream said once it is processed:
+// its finish reason, the length of its content and reasoning, and the tool calls it emitted.
+// Message content is never logged, the request journal records it when enabled.
+func LoggingHook(logger logging.LoggerInterface) StreamHook {
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	return func(ctx context.Context, response *entities.ChatCompletionResponse, err error) {
+		if response == nil {
+			logger.Debug("Stream ended without a response", "error", err)
+			return
+		}
+
+		for _, choice := range response.Choices {
+			content, _ := choice.Message.Content.(string)
+			var toolCalls []string
+			for _, call := range choice.Message.ToolCalls {
+				toolCalls = append(toolCalls, call.Function.Name)
+			}
+			logger.Debug("Streamed response assembled",
+				"id", response.ID,
+				"model", response.Model,
+				"choice", choice.Index,
+				"finish_reason", choice.FinishReason,
+				"content_length", len(content),
+				"reasoning_length", len(choice.Message.ReasoningContent),
+				"tool_calls", toolCalls,
+				"error", err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"qwen-go-proxy/internal/domain/entities"
+	"qwen-go-proxy/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLoggingHook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	assert.PanicsWithValue(t, "logger cannot be nil", func() { LoggingHook(nil) })
+
+	response := &entities.ChatCompletionResponse{
+		ID:    "chatcmpl-1",
+		Model: "qwen3-coder-plus",
+		Choices: []entities.ChatCompletionChoice{{
+			Message: entities.ChatMessage{
+				Role:             "assistant",
+				Content:          "Hello",
+				ReasoningContent: "Greet",
+				ToolCalls:        []entities.ToolCall{{ID: "call-1", Type: "function", Function: entities.Function{Name: "lookup"}}},
+			},
+			FinishReason: "tool_calls",
+		}},
+	}
+	// The summary describes the content without logging it
+	mockLogger.EXPECT().Debug("Streamed response assembled",
+		"id", "chatcmpl-1",
+		"model", "qwen3-coder-plus",
+		"choice", 0,
+		"finish_reason", "tool_calls",
+		"content_length", 5,
+		"reasoning_length", 5,
+		"tool_calls", []string{"lookup"},
+		"error", nil)
+	LoggingHook(mockLogger)(context.Background(), response, nil)
+
+	streamErr := errors.New("upstream closed")
+	mockLogger.EXPECT().Debug("Stream ended without a response", "error", streamErr)
+	LoggingHook(mockLogger)(context.Background(), nil, streamErr)
+}
@@ -8,7 +8,12 @@ import (
 	"time"
 
 	"net/http"
+	"qwen-go-proxy/internal/domain/entities"
 	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // responseWriterWrapper wraps http.ResponseWriter to capture response size and status code
@@ -75,6 +80,7 @@ type StreamState struct {
 	Buffer         string
 	ChunkCount     int
 	ErrorCount     int
+	StutterCount   int
 	LastValidChunk time.Time
 	StartTime      time.Time
 }
@@ -87,6 +93,7 @@ func NewStreamState() *StreamState {
 		Buffer:         "",
 		ChunkCount:     0,
 		ErrorCount:     0,
+		StutterCount:   0,
 		LastValidChunk: time.Now(),
 		StartTime:      time.Now(),
 	}
@@ -420,31 +427,68 @@ func (erm *ErrorRecoveryManager) HandleError(err *UpstreamError, state *StreamSt
 
 // StreamProcessor coordinates the stream processing components
 type StreamProcessor struct {
-	state    *StreamState
-	parser   *ChunkParser
-	recovery *ErrorRecoveryManager
-	writer   *responseWriterWrapper
-	ctx      context.Context
-	logger   logging.LoggerInterface
-}
-
-// NewStreamProcessor creates a new stream processor
+	state       *StreamState
+	parser      *ChunkParser
+	recovery    *ErrorRecoveryManager
+	accumulator *StreamAccumulator
+	writer      *responseWriterWrapper
+	ctx         context.Context
+	logger      logging.LoggerInterface
+	span        trace.Span
+}
+
+// NewStreamProcessor creates a new stream processor.
+// The processor's lifetime is traced as a span that ends when Finish is called.
 func NewStreamProcessor(writer *responseWriterWrapper, ctx context.Context, logger logging.LoggerInterface) *StreamProcessor {
+	_, span := tracing.Start(ctx, "StreamProcessor")
 	return &StreamProcessor{
-		state:    NewStreamState(),
-		parser:   NewChunkParser(logger),
-		recovery: NewErrorRecoveryManager(logger),
-		writer:   writer,
-		ctx:      ctx,
-		logger:   logger,
+		state:       NewStreamState(),
+		parser:      NewChunkParser(logger),
+		recovery:    NewErrorRecoveryManager(logger),
+		accumulator: NewStreamAccumulator(),
+		writer:      writer,
+		ctx:         ctx,
+		logger:      logger,
+		span:        span,
+	}
+}
+
+// transitionTo changes the stream state and records the transition on the span
+func (sp *StreamProcessor) transitionTo(newState StreamingState) {
+	if sp.state.Current != newState {
+		sp.span.AddEvent("stream.state_transition", trace.WithAttributes(
+			attribute.String("stream.state.from", sp.state.Current.String()),
+			attribute.String("stream.state.to", newState.String()),
+		))
 	}
+	sp.state.TransitionTo(newState)
+}
+
+// Finish records the final stream statistics and ends the processor span
+func (sp *StreamProcessor) Finish(err error) {
+	sp.span.SetAttributes(
+		attribute.String("stream.state", sp.state.Current.String()),
+		attribute.Int("stream.chunks", sp.state.ChunkCount),
+		attribute.Int("stream.errors", sp.state.ErrorCount),
+		attribute.Int("stream.stutter_events", sp.state.StutterCount),
+	)
+	if response := sp.accumulator.Response(); response != nil {
+		sp.span.SetAttributes(tracing.UsageAttributes(response.Usage)...)
+	}
+	tracing.End(sp.span, err)
+}
+
+// Response returns the chunks forwarded to the client so far, merged into a complete response,
+// or nil when no chunk was forwarded
+func (sp *StreamProcessor) Response() *entities.ChatCompletionResponse {
+	return sp.accumulator.Response()
 }
 
 // ProcessLine processes a single line from the upstream
 func (sp *StreamProcessor) ProcessLine(rawLine string) error {
 	select {
 	case <-sp.ctx.Done():
-		sp.state.TransitionTo(StateTerminating)
+		sp.transitionTo(StateTerminating)
 		sp.logger.Debug("Client disconnected during streaming, stopping response")
 		return sp.ctx.Err()
 	default:
@@ -484,17 +528,17 @@ func (sp *StreamProcessor) handleInitialChunk(chunk *ParsedChunk) error {
 		if chunk.HasContent {
 			// First content chunk - enter stuttering mode
 			sp.state.Buffer = chunk.Content
-			sp.state.TransitionTo(StateStuttering)
+			sp.transitionTo(StateStuttering)
 			sp.logger.Debug("Entering stuttering mode with first chunk", "content_text", chunk.ContentText)
 		} else {
 			// Non-content data chunk - forward directly
 			sp.forwardChunk(chunk)
-			sp.state.TransitionTo(StateNormalFlow)
+			sp.transitionTo(StateNormalFlow)
 		}
 	case ChunkTypeDone:
 		// Immediate DONE - forward and terminate
 		sp.forwardChunk(chunk)
-		sp.state.TransitionTo(StateTerminating)
+		sp.transitionTo(StateTerminating)
 	case ChunkTypeMalformed, ChunkTypeUnknown:
 		// Handle error
 		return sp.handleChunkError(chunk)
@@ -520,19 +564,20 @@ func (sp *StreamProcessor) handleStutteringChunk(chunk *ParsedChunk) error {
 		if sp.isStillStuttering(sp.state.Buffer, chunk.Content) {
 			// Update buffer and continue stuttering
 			sp.state.Buffer = chunk.Content
+			sp.state.StutterCount++
 			sp.logger.Debug("Stuttering continues, buffering", "content_text", chunk.ContentText)
 		} else {
 			// Stuttering resolved - flush buffer and current chunk
 			sp.flushBufferedContent()
 			sp.forwardChunk(chunk)
-			sp.state.TransitionTo(StateNormalFlow)
+			sp.transitionTo(StateNormalFlow)
 			sp.logger.Debug("Stuttering resolved, flushed buffer and current chunk")
 		}
 	case ChunkTypeDone:
 		// DONE during stuttering - flush buffer and terminate
 		sp.flushBufferedContent()
 		sp.forwardChunk(chunk)
-		sp.state.TransitionTo(StateTerminating)
+		sp.transitionTo(StateTerminating)
 	case ChunkTypeMalformed, ChunkTypeUnknown:
 		return sp.handleChunkError(chunk)
 	}
@@ -550,7 +595,7 @@ func (sp *StreamProcessor) handleNormalChunk(chunk *ParsedChunk) error {
 		sp.forwardChunk(chunk)
 	case ChunkTypeDone:
 		sp.forwardChunk(chunk)
-		sp.state.TransitionTo(StateTerminating)
+		sp.transitionTo(StateTerminating)
 	case ChunkTypeMalformed:
 		return sp.handleChunkError(chunk)
 	}
@@ -563,7 +608,7 @@ func (sp *StreamProcessor) handleNormalChunk(chunk *ParsedChunk) error {
 func (sp *StreamProcessor) handleRecoveryChunk(chunk *ParsedChunk) error {
 	// During recovery, try to get back to normal flow
 	if chunk.IsValid {
-		sp.state.TransitionTo(StateNormalFlow)
+		sp.transitionTo(StateNormalFlow)
 		return sp.handleNormalChunk(chunk)
 	}
 
@@ -595,10 +640,10 @@ func (sp *StreamProcessor) handleChunkError(chunk *ParsedChunk) error {
 	case ActionSkip:
 		return nil
 	case ActionRetry:
-		sp.state.TransitionTo(StateRecovering)
+		sp.transitionTo(StateRecovering)
 		return nil
 	case ActionTerminate:
-		sp.state.TransitionTo(StateTerminating)
+		sp.transitionTo(StateTerminating)
 		return upstreamErr
 	default:
 		return upstreamErr
@@ -644,6 +689,7 @@ func (sp *StreamProcessor) flushBufferedContent() {
 	if sp.state.Buffer != "" {
 		fmt.Fprintf(sp.writer, "data: %s\n\n", sp.state.Buffer)
 		sp.writer.Flush()
+		sp.accumulator.AddData(sp.state.Buffer)
 		sp.logger.Debug("Flushed buffered content", "buffer", sp.state.Buffer)
 		sp.state.Buffer = ""
 	}
@@ -654,6 +700,7 @@ func (sp *StreamProcessor) forwardChunk(chunk *ParsedChunk) {
 	switch chunk.Type {
 	case ChunkTypeData:
 		fmt.Fprintf(sp.writer, "data: %s\n\n", chunk.Content)
+		sp.accumulator.AddData(chunk.Content)
 		sp.logger.Debug("Forwarded data chunk", "content", chunk.Content)
 	case ChunkTypeDone:
 		fmt.Fprintf(sp.writer, "data: [DONE]\n\n")
@@ -8,6 +8,12 @@ import (
 	"qwen-go-proxy/internal/mocks"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/mock/gomock"
 )
 
@@ -128,3 +134,36 @@ func TestStreamProcessor_ProcessLine_ClientDisconnect(t *testing.T) {
 	assert.Equal(t, context.Canceled, err)
 	assert.Equal(t, StateTerminating, processor.state.Current)
 }
+
+func TestStreamProcessor_Finish_RecordsSpan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	writer := &responseWriterWrapper{ResponseWriter: httptest.NewRecorder()}
+
+	processor := NewStreamProcessor(writer, context.Background(), mockLogger)
+	processor.transitionTo(StateNormalFlow)
+	processor.transitionTo(StateNormalFlow)
+	processor.transitionTo(StateTerminating)
+	processor.state.ChunkCount = 4
+	processor.Finish(nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "StreamProcessor", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("stream.chunks", 4))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("stream.state", "Terminating"))
+
+	// Only actual state changes are recorded
+	events := spans[0].Events()
+	require.Len(t, events, 2)
+	assert.Contains(t, events[0].Attributes, attribute.String("stream.state.from", "Initial"))
+	assert.Contains(t, events[0].Attributes, attribute.String("stream.state.to", "NormalFlow"))
+	assert.Contains(t, events[1].Attributes, attribute.String("stream.state.to", "Terminating"))
+}
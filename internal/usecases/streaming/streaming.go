@@ -6,19 +6,29 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"qwen-go-proxy/internal/domain/entities"
 	"qwen-go-proxy/internal/infrastructure/logging"
+	"qwen-go-proxy/internal/infrastructure/metrics"
 	"time"
 )
 
+// StreamHook is called once a stream has been processed, with the chunks forwarded to the client
+// merged into a complete response and the error that ended the stream, if any.
+// The response is nil when no chunk was forwarded, and hooks must not modify it.
+type StreamHook func(ctx context.Context, response *entities.ChatCompletionResponse, err error)
+
 // StreamingUseCase defines the streaming use case with simplified architecture
 type StreamingUseCase struct {
 	logger logging.LoggerInterface
+	hooks  []StreamHook
 }
 
-// NewStreamingUseCase creates a new streaming use case
-func NewStreamingUseCase(logger logging.LoggerInterface) *StreamingUseCase {
+// NewStreamingUseCase creates a new streaming use case.
+// The hooks are called in order after every processed stream.
+func NewStreamingUseCase(logger logging.LoggerInterface, hooks ...StreamHook) *StreamingUseCase {
 	return &StreamingUseCase{
 		logger: logger,
+		hooks:  hooks,
 	}
 }
 
@@ -27,7 +37,7 @@ func (uc *StreamingUseCase) ProcessStreamingResponse(
 	ctx context.Context,
 	resp *http.Response,
 	writer http.ResponseWriter,
-) error {
+) (err error) {
 	uc.logger.Info("Starting streaming response processing",
 		"response_status", resp.StatusCode,
 		"response_headers", resp.Header)
@@ -44,6 +54,16 @@ func (uc *StreamingUseCase) ProcessStreamingResponse(
 
 	// Create stream processor
 	processor := NewStreamProcessor(wrappedWriter, ctx, uc.logger)
+	defer func() {
+		metrics.ObserveStream(processor.state.ChunkCount, processor.state.ErrorCount, processor.state.StutterCount)
+		processor.Finish(err)
+		if len(uc.hooks) > 0 {
+			response := processor.Response()
+			for _, hook := range uc.hooks {
+				hook(ctx, response, err)
+			}
+		}
+	}()
 
 	// Process the stream
 	reader := bufio.NewReader(resp.Body)
@@ -60,6 +80,11 @@ func (uc *StreamingUseCase) ProcessStreamingResponse(
 
 		line, err := reader.ReadString('\n')
 		if err != nil {
+			// The upstream body is bound to ctx, so a cancelled request surfaces as a read error
+			if ctx.Err() != nil {
+				uc.logger.Debug("Context cancelled while reading upstream stream", "error", err)
+				return ctx.Err()
+			}
 			if err != io.EOF {
 				uc.logger.Error("Error reading from upstream", "error", err)
 				return err
@@ -82,10 +107,16 @@ func (uc *StreamingUseCase) ProcessStreamingResponse(
 	}
 
 	// Log final statistics
-	uc.logger.Info("Streaming completed",
+	fields := []any{
 		"chunks_processed", processor.state.ChunkCount,
 		"errors", processor.state.ErrorCount,
-		"duration", time.Since(processor.state.StartTime))
+		"stutter_events", processor.state.StutterCount,
+		"duration", time.Since(processor.state.StartTime),
+	}
+	if response := processor.Response(); response != nil && response.Usage != nil {
+		fields = append(fields, "prompt_tokens", response.Usage.PromptTokens, "completion_tokens", response.Usage.CompletionTokens)
+	}
+	uc.logger.Info("Streaming completed", fields...)
 
 	return nil
 }
@@ -8,9 +8,11 @@ import (
 	"strings"
 	"testing"
 
+	"qwen-go-proxy/internal/domain/entities"
 	"qwen-go-proxy/internal/mocks"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -102,6 +104,50 @@ data: [DONE]
 	assert.Equal(t, context.Canceled, err)
 }
 
+func TestStreamingUseCase_ProcessStreamingResponse_UpstreamCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+
+	useCase := NewStreamingUseCase(mockLogger)
+
+	// The upstream body is bound to the request context and fails once the client disconnects
+	ctx, cancel := context.WithCancel(context.Background())
+	body := &cancellingReader{data: "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n", cancel: cancel}
+	resp := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(body),
+		Header:     make(http.Header),
+	}
+
+	writer := httptest.NewRecorder()
+
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).Times(0)
+
+	err := useCase.ProcessStreamingResponse(ctx, resp, writer)
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+// cancellingReader returns its data once, then cancels the context and fails like an aborted request body
+type cancellingReader struct {
+	data   string
+	cancel context.CancelFunc
+}
+
+func (c *cancellingReader) Read(p []byte) (int, error) {
+	if c.data != "" {
+		n := copy(p, c.data)
+		c.data = c.data[n:]
+		return n, nil
+	}
+	c.cancel()
+	return 0, context.Canceled
+}
+
 func TestStreamingUseCase_ProcessStreamingResponse_InvalidJSON(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -138,3 +184,86 @@ data: [DONE]
 	responseBody := writer.Body.String()
 	assert.Contains(t, responseBody, "[DONE]")
 }
+
+func TestStreamingUseCase_ProcessStreamingResponse_HooksReceiveResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+
+	var calls []string
+	var hookResponse *entities.ChatCompletionResponse
+	var hookErr error
+	useCase := NewStreamingUseCase(mockLogger,
+		func(ctx context.Context, response *entities.ChatCompletionResponse, err error) {
+			calls = append(calls, "first")
+			hookResponse, hookErr = response, err
+		},
+		func(ctx context.Context, response *entities.ChatCompletionResponse, err error) {
+			calls = append(calls, "second")
+		})
+
+	// The stuttered first chunk is replaced by its longer repeat, so only what reached the client is merged
+	streamingData := `data: {"id":"chatcmpl-1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}
+
+data: {"id":"chatcmpl-1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"content":"Hello"}}]}
+
+data: {"id":"chatcmpl-1","model":"qwen3-coder-plus","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":"stop"}]}
+
+data: {"id":"chatcmpl-1","model":"qwen3-coder-plus","choices":[],"usage":{"prompt_tokens":2,"completion_tokens":3,"total_tokens":5}}
+
+data: [DONE]
+
+`
+	resp := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(streamingData)),
+		Header:     make(http.Header),
+	}
+
+	err := useCase.ProcessStreamingResponse(context.Background(), resp, httptest.NewRecorder())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, calls)
+	assert.NoError(t, hookErr)
+	require.NotNil(t, hookResponse)
+	assert.Equal(t, "chatcmpl-1", hookResponse.ID)
+	require.Len(t, hookResponse.Choices, 1)
+	assert.Equal(t, "Hello world", hookResponse.Choices[0].Message.Content)
+	assert.Equal(t, "stop", hookResponse.Choices[0].FinishReason)
+	require.NotNil(t, hookResponse.Usage)
+	assert.Equal(t, 5, hookResponse.Usage.TotalTokens)
+}
+
+func TestStreamingUseCase_ProcessStreamingResponse_HooksReceiveError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLoggerInterface(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+
+	var hookErr error
+	called := false
+	useCase := NewStreamingUseCase(mockLogger, func(ctx context.Context, response *entities.ChatCompletionResponse, err error) {
+		called = true
+		hookErr = err
+		assert.Nil(t, response)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader("data: [DONE]\n\n")),
+		Header:     make(http.Header),
+	}
+
+	err := useCase.ProcessStreamingResponse(ctx, resp, httptest.NewRecorder())
+
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, called)
+	assert.Equal(t, context.Canceled, hookErr)
+}